@@ -55,6 +55,18 @@ Note: please disable disable-charging-pre-sleep and prevent-idle-sleep, while th
 	)
 }
 
+func NewSetWeakAdapterBoostCommand() *cobra.Command {
+	return newEnableDisableCommand(
+		"weak-adapter-boost",
+		"Set whether to charge to 100% while plugged into a weak power adapter",
+		`Set whether to charge to 100% while plugged into a weak power adapter.
+
+batt warns you when your power adapter cannot keep up with your Mac, e.g. a 30W charger under heavy load, so the battery drains although it is plugged in. With this option, batt also lifts the charge limit until you unplug, so the battery charges fully whenever the load drops and lasts longer at the desk.`,
+		func() (string, error) { return apiClient.SetWeakAdapterBoost(true) },
+		func() (string, error) { return apiClient.SetWeakAdapterBoost(false) },
+	)
+}
+
 func NewSetControlMagSafeLEDCommand() *cobra.Command {
 	use := "magsafe-led"
 	cmd := &cobra.Command{
@@ -117,3 +129,61 @@ func NewSetControlMagSafeLEDCommand() *cobra.Command {
 	cmd.AddCommand(enable, disable, alwaysOff)
 	return cmd
 }
+
+func NewOptimizedChargingCommand() *cobra.Command {
+	use := "optimized-charging"
+	cmd := &cobra.Command{
+		Use:     use,
+		GroupID: gAdvanced,
+		Short:   "Set how batt interacts with macOS Optimized Battery Charging",
+		Long: `Set how batt interacts with macOS Optimized Battery Charging and the built-in charge limit of newer macOS.
+
+While one of them is holding the charge (usually at 80%), macOS and batt both control charging. Without a subcommand, this shows whether macOS is currently holding the charge.
+
+There is no API to turn Optimized Battery Charging off. To do so, open System Settings -> Battery -> Battery Health -> i -> Turn OFF Optimized Battery Charging.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			st, err := apiClient.GetOptimizedChargingStatus()
+			if err != nil {
+				return fmt.Errorf("failed to get optimized charging status: %v", err)
+			}
+			cmd.Printf("Mode: %s\n", st.Mode)
+			switch {
+			case st.Engaged:
+				cmd.Println("macOS Optimized Battery Charging is holding the charge.")
+			case st.BuiltInLimit:
+				cmd.Println("macOS seems to limit charging to 80%.")
+			default:
+				cmd.Println("macOS is not holding the charge.")
+			}
+			return nil
+		},
+	}
+
+	modes := []struct {
+		mode  config.OptimizedChargingMode
+		short string
+	}{
+		{config.OptimizedChargingModeCoexist, "Keep enforcing the batt limit while macOS holds the charge. The lower limit wins. (default)"},
+		{config.OptimizedChargingModeDefer, "Let macOS manage charging while it holds the charge, and resume the batt limit afterwards."},
+		{config.OptimizedChargingModeDisable, "Keep enforcing the batt limit and warn when macOS holds the charge, so you can turn Optimized Battery Charging off."},
+	}
+	for _, m := range modes {
+		cmd.AddCommand(&cobra.Command{
+			Use:   string(m.mode),
+			Short: m.short,
+			RunE: func(_ *cobra.Command, _ []string) error {
+				ret, err := apiClient.SetOptimizedChargingMode(m.mode)
+				if err != nil {
+					return fmt.Errorf("failed to set %s to %s: %v", use, m.mode, err)
+				}
+				if ret != "" {
+					logrus.Infof("daemon responded: %s", ret)
+				}
+				logrus.Infof("successfully set %s to %s", use, m.mode)
+				return nil
+			},
+		})
+	}
+
+	return cmd
+}
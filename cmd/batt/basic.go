@@ -7,6 +7,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
+	"github.com/charlie0129/batt/pkg/limits"
 	"github.com/charlie0129/batt/pkg/version"
 )
 
@@ -51,6 +52,10 @@ Setting the limit to 10-99 will enable the battery charge limit. However, settin
 			if err != nil {
 				return err
 			}
+			// The daemon also checks the limit against the lower limit delta.
+			if err := limits.ValidateUpper(limit, 0); err != nil {
+				return err
+			}
 
 			ret, err := apiClient.SetLimit(limit)
 			if err != nil {
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewBypassCommand .
+func NewBypassCommand() *cobra.Command {
+	cmd := newEnableDisableCommand(
+		"bypass",
+		"running from the power adapter at the current level",
+		`Run your Mac from the power adapter without cycling the battery, holding the battery at its current charge.
+
+Charging is disabled, so the power adapter powers your Mac alone. If the charge drops anyway, e.g. because the adapter is too weak, batt charges back up to the held level. This needs a Mac that lets batt disable charging, otherwise enabling fails and the charge limit stays in effect.
+
+Bypass is not saved, so restarting the batt daemon (e.g. by rebooting) goes back to the charge limit.`,
+		func() (string, error) { return apiClient.SetBypass(true) },
+		func() (string, error) { return apiClient.SetBypass(false) },
+	)
+	cmd.GroupID = gBasic
+
+	return cmd
+}
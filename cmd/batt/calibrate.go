@@ -9,6 +9,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/charlie0129/batt/pkg/calibration"
+	"github.com/charlie0129/batt/pkg/limits"
 )
 
 func NewCalibrationCommand() *cobra.Command {
@@ -107,8 +108,8 @@ Must be between 10 and 50 percent. Default is 15%.`,
 			if _, err := fmt.Sscanf(args[0], "%d", &threshold); err != nil {
 				return fmt.Errorf("invalid threshold: %w", err)
 			}
-			if threshold < 10 || threshold > 50 {
-				return fmt.Errorf("threshold must be between 10 and 50, got %d", threshold)
+			if err := limits.ValidateDischargeThreshold(threshold); err != nil {
+				return err
 			}
 			msg, err := apiClient.SetCalibrationDischargeThreshold(threshold)
 			if err != nil {
@@ -131,8 +132,8 @@ Must be between 10 and 1440 minutes (24 hours). Default is 120 minutes.`,
 			if _, err := fmt.Sscanf(args[0], "%d", &minutes); err != nil {
 				return fmt.Errorf("invalid duration: %w", err)
 			}
-			if minutes < 10 || minutes > 1440 {
-				return fmt.Errorf("duration must be between 10 and 1440 minutes (24 hours), got %d", minutes)
+			if err := limits.ValidateHoldMinutes(minutes); err != nil {
+				return err
 			}
 			msg, err := apiClient.SetCalibrationHoldDurationMinutes(minutes)
 			if err != nil {
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/charlie0129/batt/pkg/config"
+)
+
+func NewChargeByCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "charge-by <HH:MM|off>",
+		Short:   "Have the battery full by a time of day",
+		GroupID: gBasic,
+		Long: `Have the battery full by a time of day, e.g. when you leave for work.
+
+batt estimates how long charging to 100% takes from the current charge, using how fast your Mac charged before, and starts charging just in time. Until then, and after that time, the charge limit applies as usual. This keeps the battery at full charge as short as possible, e.g. when it is plugged in overnight.
+
+The time is in 24-hour format, e.g. "batt charge-by 07:30". Use "batt charge-by off" to turn it off.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			at := args[0]
+			if at == "off" {
+				at = ""
+			} else if _, err := time.Parse(config.ChargeByLayout, at); err != nil {
+				return fmt.Errorf("invalid time %q, use 24-hour HH:MM format, e.g. 07:30", at)
+			}
+
+			ret, err := apiClient.SetChargeBy(at)
+			if err != nil {
+				return fmt.Errorf("failed to set charge-by time: %v", err)
+			}
+			logrus.Infof("daemon responded: %s", ret)
+			return nil
+		},
+	}
+}
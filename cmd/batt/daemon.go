@@ -1,16 +1,28 @@
 package main
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
 	"github.com/charlie0129/batt/pkg/daemon"
+	"github.com/charlie0129/batt/pkg/smc"
 	"github.com/charlie0129/batt/pkg/version"
 )
 
 var (
 	// alwaysAllowNonRootAccess indicates whether to always allow non-root users to access the batt daemon.
 	alwaysAllowNonRootAccess = false
+
+	// simulate runs the daemon against a simulated battery instead of the SMC.
+	simulate         = false
+	simulateOptions  smc.SimulationOptions
+	simulateScenario = ""
+
+	// daemonLogPath is where launchd writes the daemon's output.
+	daemonLogPath = "/tmp/batt.log"
 )
 
 // NewDaemonCommand .
@@ -25,7 +37,20 @@ func NewDaemonCommand() *cobra.Command {
 				"version": version.Version,
 				"commit":  version.GitCommit,
 			}).Info("batt daemon starting")
-			return daemon.Run(configPath, unixSocketPath, alwaysAllowNonRootAccess)
+
+			var sim *smc.Simulation
+			if simulateScenario != "" {
+				sc, err := smc.LoadScenario(simulateScenario)
+				if err != nil {
+					return err
+				}
+				simulateOptions.Scenario = sc
+				simulate = true
+			}
+			if simulate {
+				sim = smc.NewSimulation(simulateOptions)
+			}
+			return daemon.Run(configPath, unixSocketPath, daemonLogPath, alwaysAllowNonRootAccess, sim)
 		},
 	}
 
@@ -33,6 +58,16 @@ func NewDaemonCommand() *cobra.Command {
 
 	f.BoolVar(&alwaysAllowNonRootAccess, "always-allow-non-root-access", false,
 		"Always allow non-root users to access the daemon.")
+	f.StringVar(&daemonLogPath, "log-path", daemonLogPath,
+		"Where launchd writes the daemon log, to show, rotate and clear it through the API. Empty if not written to a file.")
+
+	f.BoolVar(&simulate, "simulate", false,
+		"Control a simulated battery instead of the real one. SMC writes are logged, not performed. Use with --config and --daemon-socket to keep it apart from the installed daemon.")
+	f.IntVar(&simulateOptions.Charge, "simulate-charge", 50, "Initial charge of the simulated battery, in percent.")
+	f.BoolVar(&simulateOptions.Unplugged, "simulate-unplugged", false, "Start the simulated battery without a power adapter.")
+	f.StringVar(&simulateScenario, "simulate-scenario", "",
+		fmt.Sprintf("Script the simulated battery with a built-in scenario (%s) or a JSON file. Implies --simulate.", strings.Join(smc.ScenarioNames(), ", ")))
+	f.Float64Var(&simulateOptions.Speed, "simulate-speed", 1, "How much faster than real time the simulated battery charges and drains.")
 
 	return cmd
 }
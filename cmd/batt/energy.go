@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func NewEnergyCommand() *cobra.Command {
+	cmd := newEnableDisableCommand(
+		"energy",
+		"per-process energy sampling on battery",
+		`Show which processes used the most energy during the current (or last) discharging session.
+
+Sampling is optional and off by default. Turn it on with "batt energy enable". While on battery, the daemon then samples the energy used by every process once a minute.`,
+		func() (string, error) { return apiClient.SetEnergySampling(true) },
+		func() (string, error) { return apiClient.SetEnergySampling(false) },
+	)
+	cmd.Short = "Show which processes used the most energy on battery"
+	cmd.RunE = func(cmd *cobra.Command, _ []string) error {
+		r, err := apiClient.GetEnergyImpactReport()
+		if err != nil {
+			return fmt.Errorf("failed to get energy impact report: %v", err)
+		}
+		if !r.Enabled {
+			cmd.Println(`Energy sampling is disabled. Run "batt energy enable" to enable it.`)
+		}
+		if r.Since.IsZero() || len(r.Processes) == 0 {
+			cmd.Println("No energy data on battery yet.")
+			return nil
+		}
+
+		cmd.Printf("On battery since %s (%s sampled)\n\n", r.Since.Format(time.DateTime), time.Duration(r.Duration*float64(time.Second)).String())
+		cmd.Printf("%-32s %12s %10s\n", "Process", "Energy", "Avg Power")
+		for _, p := range r.Processes {
+			cmd.Printf("%-32s %10.1f J %8.2f W\n", p.Name, p.Energy, p.AveragePower)
+		}
+		return nil
+	}
+
+	return cmd
+}
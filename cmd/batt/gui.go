@@ -0,0 +1,31 @@
+//go:build !headless
+
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/charlie0129/batt/pkg/gui"
+)
+
+// guiPrefs override preferences of the menubar app for this run.
+var guiPrefs []string
+
+// runGUI runs the menubar app.
+func runGUI() {
+	gui.Run(unixSocketPath, guiPrefs)
+}
+
+// addGUIFlags adds the flags of the menubar app to cmd, when it runs as one.
+func addGUIFlags(cmd *cobra.Command) {
+	gui.AddPrefFlag(cmd, &guiPrefs)
+}
+
+// newGUICommands returns the commands of the menubar app.
+func newGUICommands() []*cobra.Command {
+	return []*cobra.Command{
+		gui.NewGUICommand(""),
+		gui.NewMenubarIconCommand(gAdvanced),
+		gui.NewShortcutCommand(gAdvanced),
+	}
+}
@@ -0,0 +1,25 @@
+//go:build headless
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// runGUI fails, since headless builds leave out the menubar app and AppKit.
+func runGUI() {
+	fmt.Fprintln(os.Stderr, "This batt is built without the menubar app (headless). Use the batt command line instead.")
+	os.Exit(1)
+}
+
+// addGUIFlags adds nothing, since headless builds leave out the menubar app.
+func addGUIFlags(_ *cobra.Command) {}
+
+// newGUICommands returns nothing, since headless builds leave out the
+// menubar app.
+func newGUICommands() []*cobra.Command {
+	return nil
+}
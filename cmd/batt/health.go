@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/charlie0129/batt/pkg/history"
+)
+
+// NewHealthCommand .
+func NewHealthCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "health",
+		Short:   "Compare battery health with the baseline of this Mac model",
+		GroupID: gBasic,
+		Long: `Compare the battery health (maximum capacity) at its cycle count with the health expected for this Mac model, and list the health recorded over the cycles.
+
+` + history.Caveats,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := apiClient.GetHealthComparison()
+			if err != nil {
+				return fmt.Errorf("failed to get health comparison: %v", err)
+			}
+
+			cmd.Println(r.Summary())
+			cmd.Println()
+			cmd.Printf("Cycle count:     %d\n", r.CycleCount)
+			cmd.Printf("Battery health:  %d%%\n", r.Health)
+			cmd.Printf("Expected health: %.0f%% (%+.0f%%)\n", r.Expected, r.Difference)
+			if len(r.Curve) > 1 {
+				cmd.Println()
+				cmd.Println("Recorded health:")
+				for _, p := range r.Curve {
+					cmd.Printf("  %5d cycles  %3d%%  (expected %.0f%%)\n", p.Cycles, p.Health, r.Baseline.Expected(p.Cycles))
+				}
+			}
+			cmd.Println()
+			cmd.Println(history.Caveats)
+			return nil
+		},
+	}
+}
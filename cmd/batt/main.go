@@ -15,7 +15,6 @@ import (
 	"golang.org/x/term"
 
 	"github.com/charlie0129/batt/pkg/client"
-	"github.com/charlie0129/batt/pkg/gui"
 	"github.com/charlie0129/batt/pkg/utils/osver"
 )
 
@@ -132,8 +131,9 @@ Report issues: https://github.com/charlie0129/batt/issues`,
 
 	if os.Getenv("BATT_RUN_GUI") != "" || path.Base(os.Args[0]) == "batt-gui" {
 		cmd.Run = func(_ *cobra.Command, _ []string) {
-			gui.Run(unixSocketPath)
+			runGUI()
 		}
+		addGUIFlags(cmd)
 	}
 
 	// Direct cmd.Print* output to stdout instead of the default stderr.
@@ -142,6 +142,15 @@ Report issues: https://github.com/charlie0129/batt/issues`,
 	// See: https://github.com/charlie0129/batt/issues/120
 	cmd.SetOut(os.Stdout)
 
+	// Environment variables set the defaults of global flags, so launches
+	// that cannot pass flags, e.g. login items, can still change them.
+	if v := os.Getenv("BATT_LOG_LEVEL"); v != "" {
+		logLevel = v
+	}
+	if v := os.Getenv("BATT_DAEMON_SOCKET"); v != "" {
+		unixSocketPath = v
+	}
+
 	globalFlags := cmd.PersistentFlags()
 	globalFlags.StringVarP(&logLevel, "log-level", "l", logLevel, "log level (trace, debug, info, warn, error, fatal, panic)")
 	globalFlags.StringVar(&configPath, "config", configPath, "config file path")
@@ -160,19 +169,34 @@ Report issues: https://github.com/charlie0129/batt/issues`,
 		NewVersionCommand(),
 		NewLimitCommand(),
 		NewDisableCommand(),
+		NewTravelModeCommand(),
+		NewStorageModeCommand(),
+		NewNonRootAccessCommand(),
+		NewPauseCommand(),
+		NewResumeCommand(),
+		NewBypassCommand(),
+		NewChargeByCommand(),
+		NewSelfTestCommand(),
 		NewSetDisableChargingPreSleepCommand(),
 		NewSetPreventIdleSleepCommand(),
 		NewSetPreventSystemSleepCommand(),
+		NewSetWeakAdapterBoostCommand(),
 		NewStatusCommand(),
 		NewCalibrationCommand(),
 		NewAdapterCommand(),
 		NewLowerLimitDeltaCommand(),
 		NewSetControlMagSafeLEDCommand(),
+		NewOptimizedChargingCommand(),
+		NewEnergyCommand(),
+		NewUsageCommand(),
+		NewReportCommand(),
+		NewHealthCommand(),
+		NewTemperatureCommand(),
 		NewInstallCommand(),
 		NewUninstallCommand(),
 		NewScheduleCommand(),
-		gui.NewGUICommand(""),
 	)
+	cmd.AddCommand(newGUICommands()...)
 
 	return cmd
 }
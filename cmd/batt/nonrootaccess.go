@@ -0,0 +1,18 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewNonRootAccessCommand .
+func NewNonRootAccessCommand() *cobra.Command {
+	return newEnableDisableCommand(
+		"non-root-access",
+		"access to the daemon without sudo",
+		`Allow or forbid users other than root to access the batt daemon, the same as installing with or without --allow-non-root-access.
+
+When disabled, every batt command, and the menubar app, needs root. Only administrators can change this, e.g. "sudo batt non-root-access enable". The daemon checks the permissions of its socket every few minutes and repairs them if something else, e.g. a macOS update, changed them.`,
+		func() (string, error) { return apiClient.SetNonRootAccess(true) },
+		func() (string, error) { return apiClient.SetNonRootAccess(false) },
+	)
+}
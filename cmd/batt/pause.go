@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// NewPauseCommand .
+func NewPauseCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "pause",
+		Short:   "Pause charging until resumed",
+		GroupID: gBasic,
+		Long: `Stop charging right away, regardless of the charge limit, until you run "batt resume".
+
+Your Mac keeps running on wall power while charging is paused. The pause is not saved, so restarting the batt daemon (e.g. by rebooting) resumes charging.`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			ret, err := apiClient.SetChargingPaused(true)
+			if err != nil {
+				return fmt.Errorf("failed to pause charging: %v", err)
+			}
+
+			if ret != "" {
+				logrus.Infof("daemon responded: %s", ret)
+			}
+
+			return nil
+		},
+	}
+}
+
+// NewResumeCommand .
+func NewResumeCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "resume",
+		Short:   "Resume charging paused by batt pause",
+		GroupID: gBasic,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			ret, err := apiClient.SetChargingPaused(false)
+			if err != nil {
+				return fmt.Errorf("failed to resume charging: %v", err)
+			}
+
+			if ret != "" {
+				logrus.Infof("daemon responded: %s", ret)
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/charlie0129/batt/pkg/history"
+)
+
+// NewReportCommand .
+func NewReportCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "report [YYYY-MM]",
+		Short:   "Show the monthly battery report",
+		GroupID: gBasic,
+		Long: `Show the battery report of a month, by default last month: charge cycles added, battery health change, average charge and hours held at the limit.
+
+The menubar app also notifies you with this summary when a month ends.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			month := ""
+			if len(args) > 0 {
+				if _, err := time.Parse(history.MonthLayout, args[0]); err != nil {
+					return fmt.Errorf("invalid month %q, use YYYY-MM, e.g. 2025-09", args[0])
+				}
+				month = args[0]
+			}
+
+			r, err := apiClient.GetMonthlyReport(month)
+			if err != nil {
+				return fmt.Errorf("failed to get monthly report: %v", err)
+			}
+			m, _ := time.Parse(history.MonthLayout, r.Month)
+			if r.Days == 0 {
+				cmd.Printf("No usage recorded in %s.\n", m.Format("January 2006"))
+				return nil
+			}
+
+			cmd.Printf("Battery report for %s (%d days with data)\n\n", m.Format("January 2006"), r.Days)
+			cmd.Printf("Charge cycles added: %d\n", r.CyclesAdded)
+			if r.HealthEnd > 0 {
+				cmd.Printf("Battery health:      %d%% -> %d%% (%+d%%)\n", r.HealthStart, r.HealthEnd, r.HealthEnd-r.HealthStart)
+			}
+			cmd.Printf("Average charge:      %.0f%%\n", r.AverageCharge)
+			cmd.Printf("Held at the limit:   %.1f h\n", r.HeldHours)
+			cmd.Printf("On battery:          %.1f h\n", r.OnBatteryHours)
+			cmd.Printf("Above %d%%:           %.1f h\n", history.HighCharge, r.HighChargeHours)
+			return nil
+		},
+	}
+}
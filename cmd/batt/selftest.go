@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// NewSelfTestCommand .
+func NewSelfTestCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "self-test",
+		Short:   "Check that batt can control charging on this Mac",
+		GroupID: gAdvanced,
+		Long: `Check that batt can control charging on this Mac.
+
+The self-test reads the battery charge, power adapter state and battery telemetry, and briefly flips charging on or off to check that the SMC follows, then restores it. The power adapter is never turned off. Include the output when reporting that batt does nothing on your Mac.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			t, err := apiClient.RunSelfTest()
+			if err != nil {
+				return fmt.Errorf("failed to run self-test: %v", err)
+			}
+
+			cmd.Print(t.String())
+			if !t.Passed() {
+				return errors.New("self-test failed")
+			}
+			cmd.Println(bold("All checks passed."))
+			return nil
+		},
+	}
+}
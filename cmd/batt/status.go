@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/fatih/color"
@@ -211,12 +212,41 @@ func NewStatusCommand() *cobra.Command {
 
 			// Config.
 			cmd.Println(bold("Battery configuration:"))
+			// Older daemons cannot pause charging.
+			if paused, err := apiClient.GetChargingPaused(); err == nil && paused {
+				cmd.Printf("  Charging paused: %s (run \"batt resume\" to resume)\n", bool2Text(true))
+			}
+			if level, err := apiClient.GetBypass(); err == nil && level > 0 {
+				cmd.Printf("  Running from adapter: %s, holding %s\n", bool2Text(true), bold("%d%%", level))
+			}
+			if sm := cfg.StorageMode(); sm != nil {
+				cmd.Printf("  Storage mode: %s since %s, %d%% restored when turned off\n", bool2Text(true), sm.Since.Local().Format(time.DateTime), sm.Limit)
+			}
+			if at := cfg.ChargeBy(); at != "" {
+				cmd.Printf("  Full charge by: %s every day\n", bold("%s", at))
+			}
 			if cfg.UpperLimit() < 100 {
 				cmd.Printf("  Upper limit: %s\n", bold("%d%%", cfg.UpperLimit()))
 				cmd.Printf("  Lower limit: %s\n", bold("%d%%", cfg.LowerLimit()))
+			} else if tm := cfg.TravelMode(); tm != nil {
+				until := "when travel mode is turned off"
+				if !tm.Until.IsZero() {
+					until = "at " + tm.Until.Local().Format(time.DateTime)
+				}
+				cmd.Printf("  Charge limit: %s, %d%% restored %s\n", bold("100%% (travel mode)"), tm.Limit, until)
 			} else {
 				cmd.Printf("  Charge limit: %s\n", bold("100%% (batt disabled)"))
 			}
+			if by, at := cfg.LimitChangedBy(); by != "" {
+				cmd.Printf("  Limit last changed by: %s at %s\n", bold("%s", by), at.Local().Format(time.DateTime))
+			}
+			if cfg.LimitPolicy() == config.LimitPolicyAdminLocked {
+				cmd.Printf("  Limit locked: %s (only administrators can change it)\n", bool2Text(true))
+			}
+			// Older daemons do not know about configuration profiles.
+			if m, err := apiClient.GetManaged(); err == nil && (m.LimitLocked() || m.EnergySamplingLocked()) {
+				cmd.Printf("  Managed by a configuration profile: %s\n", bold("%s", describeManaged(m)))
+			}
 			cmd.Printf("  Prevent idle-sleep when charging: %s\n", bool2Text(cfg.PreventIdleSleep()))
 			cmd.Printf("  Disable charging before sleep if charge limit is enabled: %s\n", bool2Text(cfg.DisableChargingPreSleep()))
 			cmd.Printf("  Prevent system-sleep when charging: %s\n", bool2Text(cfg.PreventSystemSleep()))
@@ -229,6 +259,8 @@ func NewStatusCommand() *cobra.Command {
 				ledStatus += " (" + bold("always off") + ")"
 			}
 			cmd.Printf("  Control MagSafe LED: %s\n", ledStatus)
+			cmd.Printf("  Optimized Battery Charging interplay: %s\n", bold("%s", string(cfg.OptimizedChargingMode())))
+			cmd.Printf("  Charge to 100%% on a weak power adapter: %s\n", bool2Text(cfg.WeakAdapterBoost()))
 
 			cmd.Println()
 
@@ -267,3 +299,15 @@ func bool2Text(b bool) string {
 func bold(format string, a ...interface{}) string {
 	return color.New(color.Bold).Sprintf(format, a...)
 }
+
+// describeManaged lists the settings enforced by a configuration profile.
+func describeManaged(m *config.Managed) string {
+	var s []string
+	if m.LimitLocked() {
+		s = append(s, "charge limit")
+	}
+	if m.EnergySamplingLocked() {
+		s = append(s, "energy sampling")
+	}
+	return strings.Join(s, ", ")
+}
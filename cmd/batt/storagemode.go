@@ -0,0 +1,22 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func NewStorageModeCommand() *cobra.Command {
+	cmd := newEnableDisableCommand(
+		"storage-mode",
+		"storage mode for long-term shelving",
+		`Prepare your Mac to be stored unused for a long time.
+
+Storage mode holds the battery between 45% and 50%, the charge it ages the slowest at, and pauses the calibration schedule. If the battery is above 50%, the power adapter is disabled until it drains to 50%. When you turn storage mode off, the previous limits and schedule are restored.
+
+Check on a stored Mac every month or so: batteries slowly self-discharge even when the Mac is off.`,
+		func() (string, error) { return apiClient.SetStorageMode(true) },
+		func() (string, error) { return apiClient.SetStorageMode(false) },
+	)
+	cmd.GroupID = gBasic
+
+	return cmd
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// NewTemperatureCommand .
+func NewTemperatureCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "temperature",
+		Short:   "Show temperatures and stop charging above them",
+		GroupID: gAdvanced,
+		Long: `Show the temperature sensors of this Mac and the limits set on them.
+
+Charging stops while a sensor is at or above its limit, and resumes once it has cooled 3°C below it. Heat ages batteries faster than anything else, so a battery limit of 35-40°C avoids charging it while it is already warm, e.g. in the sun or under heavy load.`,
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			temps, err := apiClient.GetTemperatures()
+			if err != nil {
+				return fmt.Errorf("failed to get temperatures: %v", err)
+			}
+			if len(temps) == 0 {
+				fmt.Println("No temperature sensors found.")
+				return nil
+			}
+			for _, t := range temps {
+				limit := "no limit"
+				if t.Limit > 0 {
+					limit = fmt.Sprintf("limit %d°C", t.Limit)
+				}
+				if t.TooHot {
+					limit += ", charging stopped"
+				}
+				fmt.Printf("%-10s (%s): %.1f°C, %s\n", t.Name, t.Sensor, t.Celsius, limit)
+			}
+			return nil
+		},
+	}
+
+	limitCmd := &cobra.Command{
+		Use:   "limit <sensor> <celsius|off>",
+		Short: "Stop charging while a sensor is at or above a temperature",
+		Long: `Stop charging while a sensor is at or above a temperature, in degrees Celsius.
+
+The sensor is its name or SMC key as shown by "batt temperature", e.g. "batt temperature limit battery 38". Use "off" to remove the limit.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			temps, err := apiClient.GetTemperatures()
+			if err != nil {
+				return fmt.Errorf("failed to get temperatures: %v", err)
+			}
+			sensor := ""
+			for _, t := range temps {
+				if strings.EqualFold(args[0], t.Sensor) || strings.EqualFold(args[0], t.Name) {
+					sensor = t.Sensor
+				}
+			}
+			if sensor == "" {
+				return fmt.Errorf("unknown sensor %q, see batt temperature", args[0])
+			}
+
+			celsius := 0
+			if args[1] != "off" {
+				celsius, err = strconv.Atoi(strings.TrimSuffix(args[1], "°C"))
+				if err != nil || celsius <= 0 {
+					return fmt.Errorf("invalid temperature %q, use whole degrees Celsius or off", args[1])
+				}
+			}
+
+			ret, err := apiClient.SetTemperatureLimit(sensor, celsius)
+			if err != nil {
+				return fmt.Errorf("failed to set temperature limit: %v", err)
+			}
+			logrus.Infof("daemon responded: %s", ret)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(limitCmd)
+
+	return cmd
+}
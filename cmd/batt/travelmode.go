@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func NewTravelModeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "travel-mode",
+		Short:   "Temporarily charge to 100% for travel",
+		GroupID: gBasic,
+		Long: `Temporarily charge to 100% for travel.
+
+Travel mode sets the charge limit to 100% and pauses the calibration schedule. When you turn it off, or it expires, the previous limit and schedule are restored. Setting a limit by hand also ends travel mode, without restoring anything.`,
+	}
+
+	var days int
+	enableCmd := &cobra.Command{
+		Use:   "enable",
+		Short: "Enable travel mode",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			ret, err := apiClient.SetTravelMode(true, days)
+			if err != nil {
+				return fmt.Errorf("failed to enable travel mode: %v", err)
+			}
+			logrus.Infof("daemon responded: %s", ret)
+			return nil
+		},
+	}
+	enableCmd.Flags().IntVar(&days, "days", 0, "End travel mode by itself after this many days. 0 means never.")
+
+	disableCmd := &cobra.Command{
+		Use:   "disable",
+		Short: "Disable travel mode and restore the previous limit",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			ret, err := apiClient.SetTravelMode(false, 0)
+			if err != nil {
+				return fmt.Errorf("failed to disable travel mode: %v", err)
+			}
+			logrus.Infof("daemon responded: %s", ret)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(enableCmd, disableCmd)
+
+	return cmd
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/charlie0129/batt/pkg/history"
+)
+
+// NewUsageCommand .
+func NewUsageCommand() *cobra.Command {
+	var days int
+	cmd := &cobra.Command{
+		Use:     "usage",
+		Short:   "Show how you use your battery",
+		GroupID: gBasic,
+		Long: `Show how you used your battery over the last days: time on battery and plugged in, how deep you discharge, and how long the charge stayed high.
+
+The daemon keeps these statistics locally, one record per day for up to 400 days. Nothing leaves your Mac.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			s, err := apiClient.GetUsage(days)
+			if err != nil {
+				return fmt.Errorf("failed to get usage statistics: %v", err)
+			}
+			if s.Days == 0 {
+				cmd.Println("No usage recorded yet.")
+				return nil
+			}
+
+			total := s.OnBatteryHours + s.PluggedInHours
+			cmd.Printf("Usage over %d days with data since %s\n\n", s.Days, s.Since.Format("Jan 2, 2006"))
+			cmd.Printf("On battery:          %6.1f h (%.0f%%)\n", s.OnBatteryHours, 100*s.OnBatteryHours/total)
+			cmd.Printf("Plugged in:          %6.1f h (%.0f%%)\n", s.PluggedInHours, 100*s.PluggedInHours/total)
+			cmd.Printf("Held at the limit:   %6.1f h\n", s.HeldHours)
+			cmd.Printf("Above %d%%:           %6.1f h (%.0f%%)\n", history.HighCharge, s.HighChargeHours, 100*s.HighChargeHours/total)
+			cmd.Printf("Average charge:      %6.0f%%\n", s.AverageCharge)
+			if s.Discharges > 0 {
+				cmd.Printf("Average discharge:   %6.0f%% over %d times on battery\n", s.AverageDischarge, s.Discharges)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&days, "days", 30, "Number of days to show, up to 400")
+
+	return cmd
+}
@@ -0,0 +1,60 @@
+package capability
+
+// Check is one row of the self-test matrix.
+type Check struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Skipped bool   `json:"skipped,omitempty"`
+	// Detail is the value read, or why the check failed or was skipped.
+	Detail string `json:"detail,omitempty"`
+}
+
+// SelfTest is returned by the daemon's /self-test endpoint.
+type SelfTest struct {
+	Checks []Check `json:"checks"`
+}
+
+// Pass records a passed check.
+func (t *SelfTest) Pass(name, detail string) {
+	t.Checks = append(t.Checks, Check{Name: name, Passed: true, Detail: detail})
+}
+
+// Fail records a failed check.
+func (t *SelfTest) Fail(name, detail string) {
+	t.Checks = append(t.Checks, Check{Name: name, Detail: detail})
+}
+
+// Skip records a check that does not apply or could not run safely.
+func (t *SelfTest) Skip(name, detail string) {
+	t.Checks = append(t.Checks, Check{Name: name, Skipped: true, Detail: detail})
+}
+
+// Passed is true if no check failed.
+func (t *SelfTest) Passed() bool {
+	for _, c := range t.Checks {
+		if !c.Passed && !c.Skipped {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the checks as a pass/fail matrix, one check per line.
+func (t *SelfTest) String() string {
+	s := ""
+	for _, c := range t.Checks {
+		status := "FAIL"
+		switch {
+		case c.Skipped:
+			status = "SKIP"
+		case c.Passed:
+			status = "PASS"
+		}
+		s += status + "  " + c.Name
+		if c.Detail != "" {
+			s += ": " + c.Detail
+		}
+		s += "\n"
+	}
+	return s
+}
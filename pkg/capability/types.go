@@ -0,0 +1,88 @@
+// Package capability describes which SMC keys batt can use on this Mac. The
+// daemon probes the keys once when it opens the SMC connection; the types are
+// shared across daemon, client and GUI code to keep JSON contracts consistent.
+package capability
+
+// Key is a probed SMC key.
+type Key struct {
+	Key         string `json:"key"`
+	Description string `json:"description"`
+	Available   bool   `json:"available"`
+}
+
+// Strategy is the active way of toggling a feature with SMC keys.
+type Strategy struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Keys        []string `json:"keys"`
+}
+
+// Feature is a user-facing feature of batt.
+type Feature struct {
+	Name      string `json:"name"`
+	Supported bool   `json:"supported"`
+}
+
+// Report is returned by the daemon's /capabilities endpoint.
+type Report struct {
+	// Model is the hardware model identifier, e.g. Mac14,2.
+	Model string `json:"model,omitempty"`
+	Keys  []Key  `json:"keys"`
+	// ChargingControl is true if batt can enable and disable charging.
+	ChargingControl bool `json:"chargingControl"`
+	// AdapterControl is true if batt can enable and disable the power
+	// adapter, which force discharge and calibration need.
+	AdapterControl bool `json:"adapterControl"`
+	// MagSafeLED is true if this Mac has a MagSafe LED batt can control.
+	MagSafeLED bool `json:"magSafeLED"`
+	// ChargingStrategy and AdapterStrategy are the active strategies, nil
+	// if unsupported.
+	ChargingStrategy *Strategy `json:"chargingStrategy,omitempty"`
+	AdapterStrategy  *Strategy `json:"adapterStrategy,omitempty"`
+	// StrategyOverridden is true if a strategy was forced in the config.
+	StrategyOverridden bool `json:"strategyOverridden"`
+}
+
+// Available returns the keys that exist on this Mac.
+func (r *Report) Available() []string {
+	var keys []string
+	for _, k := range r.Keys {
+		if k.Available {
+			keys = append(keys, k.Key)
+		}
+	}
+	return keys
+}
+
+// Missing returns the keys that do not exist on this Mac.
+func (r *Report) Missing() []string {
+	var keys []string
+	for _, k := range r.Keys {
+		if !k.Available {
+			keys = append(keys, k.Key)
+		}
+	}
+	return keys
+}
+
+// Features returns the user-facing features and whether this Mac supports
+// them, as far as the probed keys tell.
+func (r *Report) Features() []Feature {
+	return []Feature{
+		{Name: "Charge limit", Supported: r.ChargingControl},
+		{Name: "Run from adapter at current level", Supported: r.ChargingControl},
+		{Name: "Force discharge and calibration", Supported: r.AdapterControl},
+		{Name: "MagSafe LED", Supported: r.MagSafeLED},
+	}
+}
+
+// Unsupported returns the names of the features this Mac does not support.
+func (r *Report) Unsupported() []string {
+	var names []string
+	for _, f := range r.Features() {
+		if !f.Supported {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
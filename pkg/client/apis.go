@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -14,8 +15,12 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/charlie0129/batt/pkg/calibration"
+	"github.com/charlie0129/batt/pkg/capability"
 	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/conflict"
+	"github.com/charlie0129/batt/pkg/diagnostics"
 	"github.com/charlie0129/batt/pkg/events"
+	"github.com/charlie0129/batt/pkg/history"
 	"github.com/charlie0129/batt/pkg/powerinfo"
 )
 
@@ -27,6 +32,39 @@ func (c *Client) SetAdapter(enabled bool) (string, error) {
 	return c.Put("/adapter", strconv.FormatBool(enabled))
 }
 
+// SetChargingPaused pauses or resumes charging regardless of the limit.
+func (c *Client) SetChargingPaused(paused bool) (string, error) {
+	return c.Put("/charging-paused", strconv.FormatBool(paused))
+}
+
+func (c *Client) GetChargingPaused() (bool, error) {
+	ret, err := c.Get("/charging-paused")
+	if err != nil {
+		return false, pkgerrors.Wrapf(err, "failed to get charging paused status")
+	}
+	return parseBoolResponse(ret)
+}
+
+// SetBypass runs the Mac from the power adapter, holding the battery at its
+// current charge, or goes back to the charge limit.
+func (c *Client) SetBypass(enabled bool) (string, error) {
+	return c.Put("/bypass", strconv.FormatBool(enabled))
+}
+
+// GetBypass returns the charge held while running from the power adapter,
+// or 0 if bypass is off.
+func (c *Client) GetBypass() (int, error) {
+	ret, err := c.Get("/bypass")
+	if err != nil {
+		return 0, pkgerrors.Wrapf(err, "failed to get bypass status")
+	}
+	level, err := strconv.Atoi(ret)
+	if err != nil {
+		return 0, pkgerrors.Wrapf(err, "failed to unmarshal bypass status")
+	}
+	return level, nil
+}
+
 func (c *Client) GetAdapter() (bool, error) {
 	ret, err := c.Get("/adapter")
 	if err != nil {
@@ -51,6 +89,14 @@ func (c *Client) SetPreventSystemSleep(enabled bool) (string, error) {
 	return c.Put("/prevent-system-sleep", strconv.FormatBool(enabled))
 }
 
+func (c *Client) SetEnergySampling(enabled bool) (string, error) {
+	return c.Put("/energy-sampling", strconv.FormatBool(enabled))
+}
+
+func (c *Client) SetWeakAdapterBoost(enabled bool) (string, error) {
+	return c.Put("/weak-adapter-boost", strconv.FormatBool(enabled))
+}
+
 func (c *Client) SetControlMagSafeLED(mode config.ControlMagSafeMode) (string, error) {
 	payload, err := json.Marshal(mode)
 	if err != nil {
@@ -139,6 +185,212 @@ func (c *Client) GetVersion() (string, error) {
 	return ret, nil
 }
 
+// GetCapabilities returns which SMC keys exist on this Mac.
+func (c *Client) GetCapabilities() (*capability.Report, error) {
+	ret, err := c.Get("/capabilities")
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to get capabilities")
+	}
+
+	var r capability.Report
+	if err := json.Unmarshal([]byte(ret), &r); err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to unmarshal capabilities")
+	}
+	return &r, nil
+}
+
+// GetConflictStatus reports whether another program is controlling charging at the same time.
+func (c *Client) GetConflictStatus() (*conflict.Status, error) {
+	ret, err := c.Get("/conflict")
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to get conflict status")
+	}
+
+	var st conflict.Status
+	if err := json.Unmarshal([]byte(ret), &st); err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to unmarshal conflict status")
+	}
+	return &st, nil
+}
+
+// GetOptimizedChargingStatus reports whether macOS itself is holding the charge.
+func (c *Client) GetOptimizedChargingStatus() (*conflict.OptimizedChargingStatus, error) {
+	ret, err := c.Get("/optimized-charging")
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to get optimized charging status")
+	}
+
+	var st conflict.OptimizedChargingStatus
+	if err := json.Unmarshal([]byte(ret), &st); err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to unmarshal optimized charging status")
+	}
+	return &st, nil
+}
+
+// GetManaged returns the settings enforced by a configuration profile.
+func (c *Client) GetManaged() (*config.Managed, error) {
+	ret, err := c.Get("/managed")
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to get managed settings")
+	}
+
+	var m config.Managed
+	if err := json.Unmarshal([]byte(ret), &m); err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to unmarshal managed settings")
+	}
+	return &m, nil
+}
+
+func (c *Client) SetOptimizedChargingMode(mode config.OptimizedChargingMode) (string, error) {
+	payload, err := json.Marshal(mode)
+	if err != nil {
+		return "", err
+	}
+	return c.Put("/optimized-charging", string(payload))
+}
+
+// GetPolicy returns the effective charging policy.
+func (c *Client) GetPolicy() (*config.Policy, error) {
+	ret, err := c.Get("/policy")
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to get policy")
+	}
+
+	var p config.Policy
+	if err := json.Unmarshal([]byte(ret), &p); err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to unmarshal policy")
+	}
+	return &p, nil
+}
+
+// GetDebugReport returns recent charging decisions and SMC writes.
+func (c *Client) GetDebugReport() (*diagnostics.Report, error) {
+	ret, err := c.Get("/debug")
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to get debug report")
+	}
+
+	var r diagnostics.Report
+	if err := json.Unmarshal([]byte(ret), &r); err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to unmarshal debug report")
+	}
+	return &r, nil
+}
+
+// SetChargeBy sets the time of day (config.ChargeByLayout) the battery should
+// be full by. An empty string turns it off.
+func (c *Client) SetChargeBy(at string) (string, error) {
+	payload, err := json.Marshal(at)
+	if err != nil {
+		return "", err
+	}
+	return c.Put("/charge-by", string(payload))
+}
+
+// SetTravelMode turns travel mode on or off. It ends by itself after days,
+// unless days is 0.
+func (c *Client) SetTravelMode(enabled bool, days int) (string, error) {
+	payload, err := json.Marshal(config.TravelModeRequest{Enabled: enabled, Days: days})
+	if err != nil {
+		return "", err
+	}
+	return c.Put("/travel-mode", string(payload))
+}
+
+// GetTemperatures returns the readings of the temperature sensors and
+// their limits.
+func (c *Client) GetTemperatures() ([]powerinfo.Temperature, error) {
+	ret, err := c.Get("/temperatures")
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to get temperatures")
+	}
+
+	var temps []powerinfo.Temperature
+	if err := json.Unmarshal([]byte(ret), &temps); err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to unmarshal temperatures")
+	}
+	return temps, nil
+}
+
+// SetTemperatureLimit stops charging while sensor is at or above celsius.
+// 0 removes the limit.
+func (c *Client) SetTemperatureLimit(sensor string, celsius int) (string, error) {
+	payload, err := json.Marshal(config.TemperatureLimitRequest{Sensor: sensor, Celsius: celsius})
+	if err != nil {
+		return "", err
+	}
+	return c.Put("/temperature-limit", string(payload))
+}
+
+// GetUsage returns the usage statistics of the last days.
+func (c *Client) GetUsage(days int) (*history.Stats, error) {
+	ret, err := c.Get("/usage?days=" + strconv.Itoa(days))
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to get usage statistics")
+	}
+
+	var s history.Stats
+	if err := json.Unmarshal([]byte(ret), &s); err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to unmarshal usage statistics")
+	}
+	return &s, nil
+}
+
+// GetMonthlyReport returns the report of month, in history.MonthLayout, or
+// of last month if month is empty.
+func (c *Client) GetMonthlyReport(month string) (*history.Report, error) {
+	ret, err := c.Get("/report?month=" + url.QueryEscape(month))
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to get monthly report")
+	}
+
+	var r history.Report
+	if err := json.Unmarshal([]byte(ret), &r); err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to unmarshal monthly report")
+	}
+	return &r, nil
+}
+
+// GetHealthComparison compares the battery health with the baseline of this
+// Mac model.
+func (c *Client) GetHealthComparison() (*history.Comparison, error) {
+	ret, err := c.Get("/health-comparison")
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to get health comparison")
+	}
+
+	var r history.Comparison
+	if err := json.Unmarshal([]byte(ret), &r); err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to unmarshal health comparison")
+	}
+	return &r, nil
+}
+
+// SetNonRootAccess allows or forbids users other than root to access the
+// daemon.
+func (c *Client) SetNonRootAccess(enabled bool) (string, error) {
+	return c.Put("/non-root-access", strconv.FormatBool(enabled))
+}
+
+// SetStorageMode turns storage mode on or off.
+func (c *Client) SetStorageMode(enabled bool) (string, error) {
+	return c.Put("/storage-mode", strconv.FormatBool(enabled))
+}
+
+// GetEnergyImpactReport returns the processes that used the most energy on battery.
+func (c *Client) GetEnergyImpactReport() (*powerinfo.EnergyImpactReport, error) {
+	ret, err := c.Get("/energy-impact")
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to get energy impact report")
+	}
+
+	var r powerinfo.EnergyImpactReport
+	if err := json.Unmarshal([]byte(ret), &r); err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to unmarshal energy impact report")
+	}
+	return &r, nil
+}
+
 func (c *Client) GetPowerTelemetry() (*powerinfo.PowerTelemetry, error) {
 	ret, err := c.Get("/power-telemetry")
 	if err != nil {
@@ -284,6 +536,29 @@ func (c *Client) SubscribeEvents(ctx context.Context) <-chan events.Event {
 	return ch
 }
 
+// RunSelfTest runs the daemon's self-test. A check of the API round-trip
+// itself is added in front.
+func (c *Client) RunSelfTest() (*capability.SelfTest, error) {
+	start := time.Now()
+	v, err := c.GetVersion()
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to reach the daemon")
+	}
+	t := &capability.SelfTest{}
+	t.Pass("Daemon API round-trip", fmt.Sprintf("version %s in %s", v, time.Since(start).Round(time.Millisecond)))
+
+	ret, err := c.Send("POST", "/self-test", "")
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to run self-test")
+	}
+	var daemonTest capability.SelfTest
+	if err := json.Unmarshal([]byte(ret), &daemonTest); err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to unmarshal self-test")
+	}
+	t.Checks = append(t.Checks, daemonTest.Checks...)
+	return t, nil
+}
+
 func (c *Client) StartCalibration() (string, error) {
 	return c.Send("POST", "/calibration/start", "")
 }
@@ -339,3 +614,44 @@ func parseBoolResponse(resp string) (bool, error) {
 		return false, pkgerrors.Errorf("unexpected response: %s", resp)
 	}
 }
+
+// GetLog returns the last lines of the daemon log.
+func (c *Client) GetLog(lines int) (string, error) {
+	ret, err := c.Get("/log?lines=" + strconv.Itoa(lines))
+	if err != nil {
+		return "", pkgerrors.Wrapf(err, "failed to get log")
+	}
+	var s string
+	if err := json.Unmarshal([]byte(ret), &s); err != nil {
+		return "", pkgerrors.Wrapf(err, "failed to unmarshal log")
+	}
+	return s, nil
+}
+
+// GetLogLevel returns the log level of the daemon, e.g. debug.
+func (c *Client) GetLogLevel() (string, error) {
+	ret, err := c.Get("/log-level")
+	if err != nil {
+		return "", pkgerrors.Wrapf(err, "failed to get log level")
+	}
+	var s string
+	if err := json.Unmarshal([]byte(ret), &s); err != nil {
+		return "", pkgerrors.Wrapf(err, "failed to unmarshal log level")
+	}
+	return s, nil
+}
+
+// SetLogLevel changes the log level of the daemon until it restarts.
+func (c *Client) SetLogLevel(level string) (string, error) {
+	return c.Put("/log-level", strconv.Quote(level))
+}
+
+// RotateLog keeps the daemon log as a .1 file and empties it.
+func (c *Client) RotateLog() (string, error) {
+	return c.Send("POST", "/log/rotate", "")
+}
+
+// ClearLog empties the daemon log.
+func (c *Client) ClearLog() (string, error) {
+	return c.Send("POST", "/log/clear", "")
+}
@@ -45,6 +45,11 @@ func NewClient(socketPath string) *Client {
 	}
 }
 
+// SocketPath returns the unix socket of the daemon.
+func (c *Client) SocketPath() string {
+	return c.socketPath
+}
+
 // Send is a method for sending a request to the batt daemon
 func (c *Client) Send(method string, path string, data string) (string, error) {
 	logrus.WithFields(logrus.Fields{
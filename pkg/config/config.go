@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/sirupsen/logrus"
 )
 
@@ -12,9 +14,27 @@ type Config interface {
 	PreventSystemSleep() bool
 	AllowNonRootAccess() bool
 	ControlMagSafeLED() ControlMagSafeMode
+	OptimizedChargingMode() OptimizedChargingMode
+	EnergySampling() bool
+	WeakAdapterBoost() bool
 	CalibrationDischargeThreshold() int
 	CalibrationHoldDurationMinutes() int
 	Cron() string
+	TravelMode() *TravelMode
+	StorageMode() *StorageMode
+	ChargeBy() string
+	// TemperatureLimits are the temperatures, in degrees Celsius, above
+	// which charging stops, keyed by SMC sensor key.
+	TemperatureLimits() map[string]int
+	// LimitPolicy is only set by editing the config.
+	LimitPolicy() LimitPolicy
+	LimitChangedBy() (string, time.Time)
+	// ChargingStrategy and AdapterStrategy override the auto-selected SMC
+	// strategies. Empty means auto. They are only set by editing the config.
+	ChargingStrategy() string
+	AdapterStrategy() string
+	// Webhooks are only set by editing the config.
+	Webhooks() []Webhook
 
 	SetUpperLimit(int)
 	SetLowerLimit(int)
@@ -23,7 +43,15 @@ type Config interface {
 	SetPreventSystemSleep(bool)
 	SetAllowNonRootAccess(bool)
 	SetControlMagSafeLED(ControlMagSafeMode)
+	SetOptimizedChargingMode(OptimizedChargingMode)
+	SetEnergySampling(bool)
+	SetWeakAdapterBoost(bool)
 	SetCron(string)
+	SetTravelMode(*TravelMode)
+	SetStorageMode(*StorageMode)
+	SetChargeBy(string) error
+	SetTemperatureLimit(sensor string, celsius int)
+	SetLimitChangedBy(string, time.Time)
 	SetCalibrationDischargeThreshold(int)
 	SetCalibrationHoldDurationMinutes(int)
 
@@ -3,13 +3,16 @@ package config
 import (
 	"encoding/json"
 	"io"
+	"maps"
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	pkgerrors "github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
+	"github.com/charlie0129/batt/pkg/limits"
 	"github.com/charlie0129/batt/pkg/utils/ptr"
 )
 
@@ -27,6 +30,90 @@ const (
 	ControlMagSafeModeAlwaysOff ControlMagSafeMode = ctrlMagSafeModeAlwaysOffStr
 )
 
+const (
+	optimizedChargingCoexistStr = "coexist"
+	optimizedChargingDeferStr   = "defer"
+	optimizedChargingDisableStr = "disable"
+)
+
+// OptimizedChargingMode controls how batt interacts with macOS Optimized
+// Battery Charging (and the built-in 80% limit on newer macOS) while macOS
+// is holding the charge.
+type OptimizedChargingMode string
+
+const (
+	// OptimizedChargingModeCoexist keeps enforcing batt's limit. Whichever
+	// limit is lower wins.
+	OptimizedChargingModeCoexist OptimizedChargingMode = optimizedChargingCoexistStr
+	// OptimizedChargingModeDefer lets macOS manage charging while it is
+	// holding the charge, and resumes batt's limit afterwards.
+	OptimizedChargingModeDefer OptimizedChargingMode = optimizedChargingDeferStr
+	// OptimizedChargingModeDisable keeps enforcing batt's limit and asks the
+	// user to turn off Optimized Battery Charging, since there is no API to
+	// turn it off programmatically.
+	OptimizedChargingModeDisable OptimizedChargingMode = optimizedChargingDisableStr
+)
+
+// LimitPolicy decides who may change the charge limit when several users
+// share a Mac. The limit itself is system-wide.
+type LimitPolicy string
+
+const (
+	// LimitPolicyLastWriter lets any user who can reach the daemon change
+	// the limit. The last change wins.
+	LimitPolicyLastWriter LimitPolicy = "last-writer"
+	// LimitPolicyAdminLocked only lets administrators (and root) change the
+	// limit.
+	LimitPolicyAdminLocked LimitPolicy = "admin-locked"
+)
+
+// TravelMode is a temporary override that charges to 100% and pauses the
+// calibration schedule. It keeps the settings it overrides, so they can be
+// restored when it is turned off or expires.
+type TravelMode struct {
+	// Until is when travel mode ends by itself. Zero means never.
+	Until time.Time `json:"until,omitempty"`
+	// Limit, LowerLimitDelta and Cron are restored when travel mode ends.
+	// LowerLimitDelta is 0 in travel modes saved by older versions.
+	Limit           int    `json:"limit"`
+	LowerLimitDelta int    `json:"lowerLimitDelta,omitempty"`
+	Cron            string `json:"cron,omitempty"`
+}
+
+// StorageMode holds the battery around half charge for long-term storage. Like
+// TravelMode, it keeps the settings it overrides.
+type StorageMode struct {
+	Since time.Time `json:"since"`
+	// Limit, LowerLimitDelta and Cron are restored when storage mode ends.
+	Limit           int    `json:"limit"`
+	LowerLimitDelta int    `json:"lowerLimitDelta"`
+	Cron            string `json:"cron,omitempty"`
+	// Discharging is set while storage mode has the power adapter disabled
+	// to discharge down to its limit. It is saved so the adapter is enabled
+	// again even if the daemon restarted in between.
+	Discharging bool `json:"discharging,omitempty"`
+}
+
+// ChargeByLayout is the time.Parse layout of the charge-by time, in local
+// time, e.g. "07:30".
+const ChargeByLayout = "15:04"
+
+// TemperatureLimitRequest is the body of PUT /temperature-limit.
+type TemperatureLimitRequest struct {
+	// Sensor is the SMC key of the sensor, e.g. "TB0T".
+	Sensor string `json:"sensor"`
+	// Celsius is the temperature above which charging stops. 0 removes the
+	// limit.
+	Celsius int `json:"celsius"`
+}
+
+// TravelModeRequest is the body of PUT /travel-mode.
+type TravelModeRequest struct {
+	Enabled bool `json:"enabled"`
+	// Days after which travel mode ends by itself. 0 means never.
+	Days int `json:"days"`
+}
+
 var (
 	defaultFileConfig = &RawFileConfig{
 		Limit:                   ptr.To(80),
@@ -43,6 +130,10 @@ var (
 		// explicitly enables this feature. In the future, we might add a check
 		// that disables this feature if the Mac does not have a MagSafe LED.
 		ControlMagSafeLED: ptr.To(ControlMagSafeModeDisabled),
+
+		OptimizedCharging: ptr.To(OptimizedChargingModeCoexist),
+		EnergySampling:    ptr.To(false),
+		WeakAdapterBoost:  ptr.To(false),
 	}
 )
 
@@ -109,6 +200,20 @@ func (c *ControlMagSafeMode) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+func (m *OptimizedChargingMode) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	switch s {
+	case optimizedChargingCoexistStr, optimizedChargingDeferStr, optimizedChargingDisableStr:
+		*m = OptimizedChargingMode(s)
+	default:
+		return pkgerrors.Errorf("invalid optimized charging mode %q, must be one of %q, %q or %q", s, optimizedChargingCoexistStr, optimizedChargingDeferStr, optimizedChargingDisableStr)
+	}
+	return nil
+}
+
 type RawFileConfig struct {
 	Limit                   *int                `json:"limit,omitempty"`
 	PreventIdleSleep        *bool               `json:"preventIdleSleep,omitempty"`
@@ -118,9 +223,37 @@ type RawFileConfig struct {
 	LowerLimitDelta         *int                `json:"lowerLimitDelta,omitempty"`
 	ControlMagSafeLED       *ControlMagSafeMode `json:"controlMagSafeLED,omitempty"`
 
+	OptimizedCharging *OptimizedChargingMode `json:"optimizedCharging,omitempty"`
+	EnergySampling    *bool                  `json:"energySampling,omitempty"`
+	// WeakAdapterBoost charges to 100% while plugged into an adapter that
+	// cannot keep up with the Mac.
+	WeakAdapterBoost *bool `json:"weakAdapterBoost,omitempty"`
+
 	CalibrationDischargeThreshold  *int    `json:"calibrationDischargeThreshold,omitempty"`
 	CalibrationHoldDurationMinutes *int    `json:"calibrationHoldDurationMinutes,omitempty"`
 	Cron                           *string `json:"cron,omitempty"`
+
+	// ChargingStrategy and AdapterStrategy force an SMC strategy (see
+	// pkg/smc), e.g. "chte", when auto-selection picks the wrong one.
+	ChargingStrategy *string `json:"chargingStrategy,omitempty"`
+	AdapterStrategy  *string `json:"adapterStrategy,omitempty"`
+
+	TravelMode  *TravelMode  `json:"travelMode,omitempty"`
+	StorageMode *StorageMode `json:"storageMode,omitempty"`
+	// ChargeBy is the time of day the battery should be full by, in
+	// ChargeByLayout. Empty means off.
+	ChargeBy *string `json:"chargeBy,omitempty"`
+	// TemperatureLimits stop charging above a temperature, in degrees
+	// Celsius, keyed by SMC sensor key, e.g. "TB0T" for the battery.
+	TemperatureLimits map[string]int `json:"temperatureLimits,omitempty"`
+
+	// LimitPolicy is only set by editing the config.
+	LimitPolicy *LimitPolicy `json:"limitPolicy,omitempty"`
+	// LimitChangedBy is the user who last changed the limit, and when.
+	LimitChangedBy *string    `json:"limitChangedBy,omitempty"`
+	LimitChangedAt *time.Time `json:"limitChangedAt,omitempty"`
+
+	Webhooks []Webhook `json:"webhooks,omitempty"`
 }
 
 func NewRawFileConfigFromConfig(c Config) (*RawFileConfig, error) {
@@ -136,7 +269,27 @@ func NewRawFileConfigFromConfig(c Config) (*RawFileConfig, error) {
 		AllowNonRootAccess:      ptr.To(c.AllowNonRootAccess()),
 		LowerLimitDelta:         ptr.To(c.UpperLimit() - c.LowerLimit()),
 		ControlMagSafeLED:       ptr.To(c.ControlMagSafeLED()),
+		OptimizedCharging:       ptr.To(c.OptimizedChargingMode()),
+		EnergySampling:          ptr.To(c.EnergySampling()),
+		WeakAdapterBoost:        ptr.To(c.WeakAdapterBoost()),
 		Cron:                    ptr.To(c.Cron()),
+		TravelMode:              c.TravelMode(),
+		StorageMode:             c.StorageMode(),
+		ChargeBy:                ptr.To(c.ChargeBy()),
+		TemperatureLimits:       c.TemperatureLimits(),
+		LimitPolicy:             ptr.To(c.LimitPolicy()),
+	}
+	if by, at := c.LimitChangedBy(); by != "" {
+		rawConfig.LimitChangedBy = &by
+		rawConfig.LimitChangedAt = &at
+	}
+	// This is served to any user allowed to access the daemon, so leave the
+	// webhook credentials out. The URL path is a credential for many
+	// services, so only the host is kept.
+	for _, w := range c.Webhooks() {
+		w.URL = w.RedactedURL()
+		w.Secret, w.Token, w.User, w.ChatID = "", "", "", ""
+		rawConfig.Webhooks = append(rawConfig.Webhooks, w)
 	}
 
 	return rawConfig, nil
@@ -288,14 +441,8 @@ func (f *File) CalibrationDischargeThreshold() int {
 	if f.c.CalibrationDischargeThreshold == nil {
 		return 15
 	}
-	val := *f.c.CalibrationDischargeThreshold
-	if val < 10 {
-		return 10
-	}
-	if val > 50 { // upper sanity bound; calibration below 50% is reasonable
-		return 50
-	}
-	return val
+	// Clamp to the valid range to avoid pathological values.
+	return min(max(*f.c.CalibrationDischargeThreshold, limits.MinDischargeThreshold), limits.MaxDischargeThreshold)
 }
 
 // CalibrationHoldDurationMinutes returns duration minutes to hold at full charge.
@@ -401,6 +548,32 @@ func (f *File) SetControlMagSafeLED(mode ControlMagSafeMode) {
 	f.c.ControlMagSafeLED = ptr.To(mode)
 }
 
+func (f *File) OptimizedChargingMode() OptimizedChargingMode {
+	if f.c == nil {
+		panic("config is nil")
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.c.OptimizedCharging != nil {
+		return *f.c.OptimizedCharging
+	}
+
+	return *defaultFileConfig.OptimizedCharging
+}
+
+func (f *File) SetOptimizedChargingMode(mode OptimizedChargingMode) {
+	if f.c == nil {
+		panic("config is nil")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.c.OptimizedCharging = ptr.To(mode)
+}
+
 func (f *File) Cron() string {
 	if f.c == nil {
 		panic("config is nil")
@@ -418,6 +591,289 @@ func (f *File) Cron() string {
 	return cron
 }
 
+func (f *File) EnergySampling() bool {
+	if f.c == nil {
+		panic("config is nil")
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var energySampling bool
+
+	if f.c.EnergySampling != nil {
+		energySampling = *f.c.EnergySampling
+	} else {
+		energySampling = *defaultFileConfig.EnergySampling
+	}
+
+	return energySampling
+}
+
+func (f *File) SetEnergySampling(b bool) {
+	if f.c == nil {
+		panic("config is nil")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.c.EnergySampling = &b
+}
+
+func (f *File) WeakAdapterBoost() bool {
+	if f.c == nil {
+		panic("config is nil")
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var weakAdapterBoost bool
+
+	if f.c.WeakAdapterBoost != nil {
+		weakAdapterBoost = *f.c.WeakAdapterBoost
+	} else {
+		weakAdapterBoost = *defaultFileConfig.WeakAdapterBoost
+	}
+
+	return weakAdapterBoost
+}
+
+func (f *File) SetWeakAdapterBoost(b bool) {
+	if f.c == nil {
+		panic("config is nil")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.c.WeakAdapterBoost = &b
+}
+
+// TravelMode returns the active travel mode, or nil if it is off.
+func (f *File) TravelMode() *TravelMode {
+	if f.c == nil {
+		panic("config is nil")
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.c.TravelMode == nil {
+		return nil
+	}
+	tm := *f.c.TravelMode
+	return &tm
+}
+
+// SetTravelMode turns travel mode on, or off if tm is nil. It only records
+// the travel mode; applying it is up to the caller.
+func (f *File) SetTravelMode(tm *TravelMode) {
+	if f.c == nil {
+		panic("config is nil")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.c.TravelMode = tm
+}
+
+// StorageMode returns the active storage mode, or nil if it is off.
+func (f *File) StorageMode() *StorageMode {
+	if f.c == nil {
+		panic("config is nil")
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.c.StorageMode == nil {
+		return nil
+	}
+	sm := *f.c.StorageMode
+	return &sm
+}
+
+// SetStorageMode turns storage mode on, or off if sm is nil. It only records
+// the storage mode; applying it is up to the caller.
+func (f *File) SetStorageMode(sm *StorageMode) {
+	if f.c == nil {
+		panic("config is nil")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.c.StorageMode = sm
+}
+
+// ChargeBy returns the time of day the battery should be full by, or "" if
+// the full-charge alarm is off.
+func (f *File) ChargeBy() string {
+	if f.c == nil {
+		panic("config is nil")
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.c.ChargeBy == nil {
+		return ""
+	}
+	return *f.c.ChargeBy
+}
+
+// SetChargeBy sets the time of day, in ChargeByLayout, the battery should be
+// full by, or turns the full-charge alarm off if s is empty.
+func (f *File) SetChargeBy(s string) error {
+	if f.c == nil {
+		panic("config is nil")
+	}
+
+	if s != "" {
+		if _, err := time.Parse(ChargeByLayout, s); err != nil {
+			return pkgerrors.Errorf("invalid charge-by time %q, must be in 24-hour HH:MM format", s)
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.c.ChargeBy = &s
+	return nil
+}
+
+// TemperatureLimits returns a copy of the temperature limits.
+func (f *File) TemperatureLimits() map[string]int {
+	if f.c == nil {
+		panic("config is nil")
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if len(f.c.TemperatureLimits) == 0 {
+		return nil
+	}
+	return maps.Clone(f.c.TemperatureLimits)
+}
+
+// SetTemperatureLimit stops charging above celsius on sensor. 0 removes the
+// limit.
+func (f *File) SetTemperatureLimit(sensor string, celsius int) {
+	if f.c == nil {
+		panic("config is nil")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if celsius <= 0 {
+		delete(f.c.TemperatureLimits, sensor)
+		return
+	}
+	if f.c.TemperatureLimits == nil {
+		f.c.TemperatureLimits = make(map[string]int)
+	}
+	f.c.TemperatureLimits[sensor] = celsius
+}
+
+// LimitPolicy returns who may change the limit. Invalid values fall back to
+// LimitPolicyLastWriter.
+func (f *File) LimitPolicy() LimitPolicy {
+	if f.c == nil {
+		panic("config is nil")
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.c.LimitPolicy != nil && *f.c.LimitPolicy == LimitPolicyAdminLocked {
+		return LimitPolicyAdminLocked
+	}
+
+	return LimitPolicyLastWriter
+}
+
+// LimitChangedBy returns the user who last changed the limit and when, or an
+// empty user if unknown.
+func (f *File) LimitChangedBy() (string, time.Time) {
+	if f.c == nil {
+		panic("config is nil")
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.c.LimitChangedBy == nil {
+		return "", time.Time{}
+	}
+	var at time.Time
+	if f.c.LimitChangedAt != nil {
+		at = *f.c.LimitChangedAt
+	}
+
+	return *f.c.LimitChangedBy, at
+}
+
+// Webhooks returns a copy of the configured webhooks.
+func (f *File) Webhooks() []Webhook {
+	if f.c == nil {
+		panic("config is nil")
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	hooks := make([]Webhook, len(f.c.Webhooks))
+	copy(hooks, f.c.Webhooks)
+	return hooks
+}
+
+func (f *File) SetLimitChangedBy(user string, at time.Time) {
+	if f.c == nil {
+		panic("config is nil")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.c.LimitChangedBy = &user
+	f.c.LimitChangedAt = &at
+}
+
+func (f *File) ChargingStrategy() string {
+	if f.c == nil {
+		panic("config is nil")
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.c.ChargingStrategy != nil {
+		return *f.c.ChargingStrategy
+	}
+
+	return ""
+}
+
+func (f *File) AdapterStrategy() string {
+	if f.c == nil {
+		panic("config is nil")
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.c.AdapterStrategy != nil {
+		return *f.c.AdapterStrategy
+	}
+
+	return ""
+}
+
 func (f *File) SetCron(cron string) {
 	if f.c == nil {
 		panic("config is nil")
@@ -505,7 +961,7 @@ func (f *File) Save() error {
 		return pkgerrors.New("config is nil")
 	}
 
-	fp, err := os.OpenFile(f.filepath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	fp, err := os.OpenFile(f.filepath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return pkgerrors.Wrapf(err, "failed to open file %s", f.filepath)
 	}
@@ -516,6 +972,13 @@ func (f *File) Save() error {
 		}
 	}(fp)
 
+	// The config holds webhook credentials, so only root may read it. The
+	// mode passed to OpenFile only applies to new files, so fix up configs
+	// written by older versions too.
+	if err := fp.Chmod(0600); err != nil {
+		return pkgerrors.Wrapf(err, "failed to chmod file %s", f.filepath)
+	}
+
 	enc := json.NewEncoder(fp)
 	enc.SetIndent("", "  ")
 	err = enc.Encode(f.c)
@@ -539,5 +1002,14 @@ func (f *File) LogrusFields() logrus.Fields {
 		"preventSystemSleep":      f.PreventSystemSleep(),
 		"allowNonRootAccess":      f.AllowNonRootAccess(),
 		"controlMagsafeLed":       f.ControlMagSafeLED(),
+		"optimizedCharging":       f.OptimizedChargingMode(),
+		"energySampling":          f.EnergySampling(),
+		"weakAdapterBoost":        f.WeakAdapterBoost(),
+		"travelMode":              f.TravelMode() != nil,
+		"storageMode":             f.StorageMode() != nil,
+		"chargeBy":                f.ChargeBy(),
+		"temperatureLimits":       f.TemperatureLimits(),
+		"limitPolicy":             f.LimitPolicy(),
+		"webhooks":                len(f.Webhooks()),
 	}
 }
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestNewRawFileConfigFromConfigRedactsWebhooks(t *testing.T) {
+	f := NewFileFromConfig(&RawFileConfig{
+		Webhooks: []Webhook{
+			{URL: "https://hooks.slack.com/services/T000/B000/XXXX", Secret: "s3cret", Events: []string{"limit.reached"}},
+			{Type: WebhookTypeNtfy, URL: "https://ntfy.sh/my-secret-topic", Token: "tk_abc"},
+			{Type: WebhookTypePushover, Token: "app-token", User: "user-key"},
+			{Type: WebhookTypeTelegram, Token: "123:bot-token", ChatID: "42"},
+		},
+	}, filepath.Join(t.TempDir(), "batt.json"))
+
+	raw, err := NewRawFileConfigFromConfig(f)
+	if err != nil {
+		t.Fatalf("NewRawFileConfigFromConfig() error = %v", err)
+	}
+	wantURLs := []string{"https://hooks.slack.com", "https://ntfy.sh", "", ""}
+	if len(raw.Webhooks) != len(wantURLs) {
+		t.Fatalf("got %d webhooks, want %d", len(raw.Webhooks), len(wantURLs))
+	}
+	for i, w := range raw.Webhooks {
+		if w.URL != wantURLs[i] {
+			t.Errorf("webhook %d: URL = %q, want %q", i, w.URL, wantURLs[i])
+		}
+		if w.Secret != "" || w.Token != "" || w.User != "" || w.ChatID != "" {
+			t.Errorf("webhook %d: credentials not redacted: %+v", i, w)
+		}
+	}
+	if got := raw.Webhooks[0].Events; len(got) != 1 || got[0] != "limit.reached" {
+		t.Errorf("webhook 0: Events = %v, want them kept", got)
+	}
+	// The config itself keeps them.
+	if got := f.Webhooks()[1].URL; got != "https://ntfy.sh/my-secret-topic" {
+		t.Errorf("Webhooks()[1].URL = %q, want it unchanged", got)
+	}
+}
@@ -0,0 +1,23 @@
+package config
+
+// ManagedPath is where macOS installs the payload of configuration profiles
+// (e.g. pushed by MDM) for batt's preference domain.
+const ManagedPath = "/Library/Managed Preferences/cc.chlc.batt.plist"
+
+// Managed are the settings enforced by a configuration profile. They override
+// the config file and cannot be changed by users. A nil field is not managed.
+type Managed struct {
+	Limit           *int  `json:"limit,omitempty"`
+	LowerLimitDelta *int  `json:"lowerLimitDelta,omitempty"`
+	EnergySampling  *bool `json:"energySampling,omitempty"`
+}
+
+// LimitLocked reports whether the charge limit is managed.
+func (m *Managed) LimitLocked() bool {
+	return m != nil && (m.Limit != nil || m.LowerLimitDelta != nil)
+}
+
+// EnergySamplingLocked reports whether energy sampling is managed.
+func (m *Managed) EnergySamplingLocked() bool {
+	return m != nil && m.EnergySampling != nil
+}
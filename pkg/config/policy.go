@@ -0,0 +1,42 @@
+package config
+
+import "time"
+
+// PolicyMode is what currently decides when to charge.
+type PolicyMode string
+
+const (
+	// PolicyModeLimit keeps the charge between the lower and upper limit.
+	PolicyModeLimit PolicyMode = "limit"
+	// PolicyModeDisabled lets macOS charge to 100%.
+	PolicyModeDisabled    PolicyMode = "disabled"
+	PolicyModeTravel      PolicyMode = "travel"
+	PolicyModeStorage     PolicyMode = "storage"
+	PolicyModeChargeBy    PolicyMode = "charge-by"
+	PolicyModeWeakAdapter PolicyMode = "weak-adapter"
+	PolicyModePaused      PolicyMode = "paused"
+	// PolicyModeTooHot stops charging while a sensor is above its
+	// temperature limit.
+	PolicyModeTooHot PolicyMode = "too-hot"
+	// PolicyModeBypass holds the charge while the adapter powers the Mac.
+	PolicyModeBypass      PolicyMode = "bypass"
+	PolicyModeCalibration PolicyMode = "calibration"
+)
+
+// Policy is the effective charging policy, as returned by GET /policy. It
+// explains why charging stopped (or did not) at a given percent.
+type Policy struct {
+	Mode PolicyMode `json:"mode"`
+	// Upper and Lower are the configured limits. Charging stops at Upper and
+	// resumes below Lower, unless Mode overrides them.
+	Upper int `json:"upper"`
+	Lower int `json:"lower"`
+	// Detail explains Mode, e.g. when travel mode ends.
+	Detail string `json:"detail,omitempty"`
+	// SetBy is who last changed the limit: a user name, "configuration
+	// profile", or empty if unknown.
+	SetBy string    `json:"setBy,omitempty"`
+	SetAt time.Time `json:"setAt,omitempty"`
+	// Managed is whether the limit is enforced by a configuration profile.
+	Managed bool `json:"managed"`
+}
@@ -0,0 +1,67 @@
+package config
+
+import "net/url"
+
+// WebhookType is how events are sent to a webhook.
+type WebhookType string
+
+const (
+	// WebhookTypeJSON POSTs the event as JSON to URL.
+	WebhookTypeJSON WebhookType = ""
+	// WebhookTypeNtfy publishes a readable message to the ntfy topic at URL,
+	// e.g. https://ntfy.sh/my-topic. Token is an optional access token.
+	WebhookTypeNtfy WebhookType = "ntfy"
+	// WebhookTypePushover sends a Pushover message with the application
+	// Token to User.
+	WebhookTypePushover WebhookType = "pushover"
+	// WebhookTypeTelegram sends a message with the bot Token to ChatID.
+	WebhookTypeTelegram WebhookType = "telegram"
+)
+
+// Webhook receives daemon events, either as JSON POST requests or as
+// readable messages on a notification service. Webhooks are only set by
+// editing the config.
+type Webhook struct {
+	Type WebhookType `json:"type,omitempty"`
+	URL  string      `json:"url,omitempty"`
+	// Events are the event names to send, e.g. "limit.reached". Empty means
+	// all events.
+	Events []string `json:"events,omitempty"`
+	// Secret, if set, signs the body of JSON webhooks with HMAC-SHA256. The
+	// signature is sent as "sha256=<hex>" in the X-Batt-Signature header.
+	Secret string `json:"secret,omitempty"`
+
+	Token  string `json:"token,omitempty"`
+	User   string `json:"user,omitempty"`
+	ChatID string `json:"chatId,omitempty"`
+}
+
+// Wants reports whether the webhook subscribed to the event.
+func (w Webhook) Wants(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactedURL returns only the scheme and host of URL. The rest of it is a
+// credential for many services, e.g. the token of Slack and Discord webhooks
+// or the ntfy topic, so only this may be logged or shown to other users.
+func (w Webhook) RedactedURL() string {
+	return RedactURL(w.URL)
+}
+
+// RedactURL returns only the scheme and host of s, or "" if s is not an
+// absolute URL.
+func RedactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -0,0 +1,57 @@
+// Package conflict defines the types used to report other software that
+// controls battery charging at the same time as batt. They are shared across
+// daemon, client and GUI code to keep JSON contracts consistent.
+package conflict
+
+import (
+	"time"
+
+	"github.com/charlie0129/batt/pkg/config"
+)
+
+// Controller is a known third-party charge limiter.
+type Controller struct {
+	Name string
+	// LaunchdLabels are labels of its privileged helper in /Library/LaunchDaemons.
+	LaunchdLabels []string
+}
+
+// KnownControllers are charge limiters batt knows how to detect.
+var KnownControllers = []Controller{
+	{
+		Name:          "AlDente",
+		LaunchdLabels: []string{"com.apphousekitchen.aldente-pro.helper", "com.davidwernhart.Helper"},
+	},
+}
+
+// Status is returned by the daemon's /conflict endpoint.
+type Status struct {
+	// Detected is true if another program changed charging behind batt's
+	// back recently.
+	Detected bool `json:"detected"`
+	// Count is the number of external changes seen since the daemon started.
+	Count int `json:"count"`
+	// LastDetected is when the last external change was seen.
+	LastDetected time.Time `json:"lastDetected,omitempty"`
+	// Suspects are installed known controllers, which are likely the cause.
+	Suspects []string `json:"suspects,omitempty"`
+	Message  string   `json:"message,omitempty"`
+}
+
+// OptimizedChargingStatus is returned by the daemon's /optimized-charging
+// endpoint. It describes whether macOS itself is holding the charge.
+type OptimizedChargingStatus struct {
+	// Engaged is true if macOS reports that Optimized Battery Charging is
+	// currently holding the charge.
+	Engaged bool `json:"engaged"`
+	// BuiltInLimit is true if charging stops around 80% although batt allows
+	// it, which is what the built-in charge limit of newer macOS does.
+	BuiltInLimit bool `json:"builtInLimit"`
+	// Mode is the configured interplay mode.
+	Mode config.OptimizedChargingMode `json:"mode"`
+}
+
+// Holding reports whether macOS is holding the charge for either reason.
+func (s *OptimizedChargingStatus) Holding() bool {
+	return s.Engaged || s.BuiltInLimit
+}
@@ -0,0 +1,110 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/calibration"
+)
+
+// bypassMargin is how far the charge may drop below the bypass level before
+// charging resumes, e.g. when the power adapter cannot run the Mac alone.
+const bypassMargin = 2
+
+// ErrBypassUnsupported is returned when enabling bypass on a Mac whose SMC
+// does not let batt disable charging.
+var ErrBypassUnsupported = errors.New("running from the power adapter is not supported on this Mac")
+
+var (
+	bypassMu sync.Mutex
+	// bypassLevel is the charge held while the Mac runs from the power
+	// adapter without cycling the battery, 0 if bypass is off. Like a pause,
+	// it is not saved and a daemon restart turns it off.
+	bypassLevel int
+)
+
+func getBypassLevel() int {
+	bypassMu.Lock()
+	defer bypassMu.Unlock()
+	return bypassLevel
+}
+
+// enableBypass holds the current charge. It fails if charging cannot be
+// disabled on this Mac, instead of silently charging on.
+func enableBypass() (int, error) {
+	if !smcConn.IsChargingControlCapable() {
+		return 0, ErrBypassUnsupported
+	}
+	charge, err := smcConn.GetBatteryCharge()
+	if err != nil {
+		return 0, err
+	}
+	// A level of 0 means off, hold at least 1%.
+	level := max(charge, 1)
+
+	bypassMu.Lock()
+	bypassLevel = level
+	bypassMu.Unlock()
+	return level, nil
+}
+
+func disableBypass() {
+	bypassMu.Lock()
+	bypassLevel = 0
+	bypassMu.Unlock()
+}
+
+func getBypass(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, getBypassLevel())
+}
+
+func setBypass(c *gin.Context) {
+	var enabled bool
+	if err := c.BindJSON(&enabled); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, err.Error())
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if _, ok := authorizeLimitChange(c); !ok {
+		return
+	}
+
+	if !enabled {
+		disableBypass()
+		maintainLoopForced()
+		logrus.Info("bypass disabled")
+		c.IndentedJSON(http.StatusCreated, "No longer running from the power adapter, following the charge limit again")
+		return
+	}
+
+	if getCalibrationStatus().Phase != calibration.PhaseIdle {
+		c.IndentedJSON(http.StatusConflict, ErrCalibrationInProgress.Error())
+		_ = c.AbortWithError(http.StatusConflict, ErrCalibrationInProgress)
+		return
+	}
+
+	level, err := enableBypass()
+	if errors.Is(err, ErrBypassUnsupported) {
+		c.IndentedJSON(http.StatusNotImplemented, err.Error())
+		_ = c.AbortWithError(http.StatusNotImplemented, err)
+		return
+	}
+	if err != nil {
+		logrus.Errorf("setBypass failed: %v", err)
+		c.IndentedJSON(http.StatusInternalServerError, err.Error())
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	// Apply right away instead of waiting for the next loop.
+	maintainLoopForced()
+
+	logrus.WithField("level", level).Info("bypass enabled")
+	c.IndentedJSON(http.StatusCreated, fmt.Sprintf("Running from the power adapter, holding the battery at %d%%", level))
+}
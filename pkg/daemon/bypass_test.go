@@ -0,0 +1,75 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/charlie0129/batt/pkg/calibration"
+	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/smc"
+)
+
+func TestBypassHoldsCharge(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	sim := smc.NewSimulation(smc.SimulationOptions{
+		Charge:    70,
+		DrainRate: 1,
+		Now:       func() time.Time { return now },
+	})
+	useSimulatedSMC(t, sim)
+	conf = &mockConf{upper: 100, lower: 95}
+	calibrationState = &calibration.State{Phase: calibration.PhaseIdle}
+	sseHub = nil
+	t.Cleanup(disableBypass)
+
+	loopRecorder = NewTimeSeriesRecorder(60)
+	for i := 10; i > 0; i-- {
+		loopRecorder.AddRecord(time.Now().Add(-time.Duration(i) * loopInterval))
+	}
+
+	level, err := enableBypass()
+	if err != nil {
+		t.Fatalf("enableBypass failed: %v", err)
+	}
+	if level != 70 {
+		t.Fatalf("expected to hold 70%%, got %d%%", level)
+	}
+	if p := getEffectivePolicy(now); p.Mode != config.PolicyModeBypass || p.Upper != 70 {
+		t.Fatalf("expected bypass policy at 70%%, got %s at %d%%", p.Mode, p.Upper)
+	}
+
+	run := func(minutes int) {
+		for i := 0; i < minutes; i++ {
+			charging, _ := smcConn.IsChargingEnabled()
+			charge, _ := smcConn.GetBatteryCharge()
+			pluggedIn, _ := smcConn.IsPluggedIn()
+			if !handleChargingLogic(true, charging, pluggedIn, charge, level-bypassMargin, level) {
+				t.Fatalf("handleChargingLogic failed at %d%%", charge)
+			}
+			now = now.Add(time.Minute)
+		}
+	}
+
+	// The adapter powers the Mac and the charge is held.
+	run(30)
+	if charge, _ := smcConn.GetBatteryCharge(); charge != 70 {
+		t.Fatalf("expected the charge held at 70%%, got %d%%", charge)
+	}
+	if charging, _ := smcConn.IsChargingEnabled(); charging {
+		t.Fatal("expected charging disabled while bypassing the battery")
+	}
+
+	// If the charge drops anyway, it is topped up to the level again.
+	sim.SetPluggedIn(false)
+	run(5)
+	sim.SetPluggedIn(true)
+	run(30)
+	if charge, _ := smcConn.GetBatteryCharge(); charge != 70 {
+		t.Fatalf("expected the charge back at 70%%, got %d%%", charge)
+	}
+
+	disableBypass()
+	if p := getEffectivePolicy(now); p.Mode == config.PolicyModeBypass {
+		t.Fatal("expected bypass off")
+	}
+}
@@ -18,11 +18,11 @@ import (
 var (
 	smcGetBatteryCharge  = func() (int, error) { return smcConn.GetBatteryCharge() }
 	smcIsChargingEnabled = func() (bool, error) { return smcConn.IsChargingEnabled() }
-	smcEnableCharging    = func() error { return smcConn.EnableCharging() }
-	smcDisableCharging   = func() error { return smcConn.DisableCharging() }
+	smcEnableCharging    = func() error { return recordSMCWrite("enable charging", smcConn.EnableCharging()) }
+	smcDisableCharging   = func() error { return recordSMCWrite("disable charging", smcConn.DisableCharging()) }
 	smcIsAdapterEnabled  = func() (bool, error) { return smcConn.IsAdapterEnabled() }
-	smcEnableAdapter     = func() error { return smcConn.EnableAdapter() }
-	smcDisableAdapter    = func() error { return smcConn.DisableAdapter() }
+	smcEnableAdapter     = func() error { return recordSMCWrite("enable adapter", smcConn.EnableAdapter()) }
+	smcDisableAdapter    = func() error { return recordSMCWrite("disable adapter", smcConn.DisableAdapter()) }
 	smcIsPluggedIn       = func() (bool, error) { return smcConn.IsPluggedIn() }
 )
 
@@ -362,6 +362,7 @@ func cancelCalibration() error {
 	} else {
 		_ = smcDisableCharging()
 	}
+	forgetExpectedCharging()
 	if st.SnapshotAdapterOn {
 		_ = smcEnableAdapter()
 	} else {
@@ -17,6 +17,10 @@ import (
 type mockConf struct {
 	upper int
 	lower int
+
+	weakAdapterBoost  bool
+	webhooks          []config.Webhook
+	temperatureLimits map[string]int
 }
 
 func (m *mockConf) UpperLimit() int               { return m.upper }
@@ -39,11 +43,33 @@ func (m *mockConf) SetDisableChargingPreSleep(bool)                {}
 func (m *mockConf) SetPreventSystemSleep(bool)                     {}
 func (m *mockConf) SetAllowNonRootAccess(bool)                     {}
 func (m *mockConf) SetControlMagSafeLED(config.ControlMagSafeMode) {}
-func (m *mockConf) LogrusFields() logrus.Fields                    { return logrus.Fields{} }
-func (m *mockConf) Load() error                                    { return nil }
-func (m *mockConf) Save() error                                    { return nil }
-func (m *mockConf) Cron() string                                   { return "" }
-func (m *mockConf) SetCron(string)                                 {}
+func (m *mockConf) OptimizedChargingMode() config.OptimizedChargingMode {
+	return config.OptimizedChargingModeCoexist
+}
+func (m *mockConf) SetOptimizedChargingMode(config.OptimizedChargingMode) {}
+func (m *mockConf) LogrusFields() logrus.Fields                           { return logrus.Fields{} }
+func (m *mockConf) Load() error                                           { return nil }
+func (m *mockConf) Save() error                                           { return nil }
+func (m *mockConf) Cron() string                                          { return "" }
+func (m *mockConf) SetCron(string)                                        {}
+func (m *mockConf) TravelMode() *config.TravelMode                        { return nil }
+func (m *mockConf) SetTravelMode(*config.TravelMode)                      {}
+func (m *mockConf) StorageMode() *config.StorageMode                      { return nil }
+func (m *mockConf) SetStorageMode(*config.StorageMode)                    {}
+func (m *mockConf) ChargeBy() string                                      { return "" }
+func (m *mockConf) SetChargeBy(string) error                              { return nil }
+func (m *mockConf) TemperatureLimits() map[string]int                     { return m.temperatureLimits }
+func (m *mockConf) SetTemperatureLimit(string, int)                       {}
+func (m *mockConf) LimitPolicy() config.LimitPolicy                       { return config.LimitPolicyLastWriter }
+func (m *mockConf) LimitChangedBy() (string, time.Time)                   { return "", time.Time{} }
+func (m *mockConf) SetLimitChangedBy(string, time.Time)                   {}
+func (m *mockConf) EnergySampling() bool                                  { return false }
+func (m *mockConf) Webhooks() []config.Webhook                            { return m.webhooks }
+func (m *mockConf) WeakAdapterBoost() bool                                { return m.weakAdapterBoost }
+func (m *mockConf) SetWeakAdapterBoost(b bool)                            { m.weakAdapterBoost = b }
+func (m *mockConf) SetEnergySampling(bool)                                {}
+func (m *mockConf) ChargingStrategy() string                              { return "" }
+func (m *mockConf) AdapterStrategy() string                               { return "" }
 
 // Fake smcConn implementation.
 type fakeSMC struct {
@@ -0,0 +1,66 @@
+package daemon
+
+import (
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/charlie0129/batt/pkg/capability"
+	"github.com/charlie0129/batt/pkg/smc"
+)
+
+// getCapabilityReport summarizes the SMC keys probed when the connection was
+// opened. Probing is not repeated, the result is cached by smcConn.
+func getCapabilityReport() *capability.Report {
+	charging, adapter, overridden := smcConn.Strategies()
+	model, err := unix.Sysctl("hw.model")
+	if err != nil {
+		logrus.WithError(err).Debug("failed to read hardware model")
+	}
+	return &capability.Report{
+		Model:              model,
+		Keys:               smcConn.Capabilities(),
+		ChargingControl:    smcConn.IsChargingControlCapable(),
+		AdapterControl:     smcConn.IsAdapterControlCapable(),
+		MagSafeLED:         smcConn.CheckMagSafeExistence(),
+		ChargingStrategy:   toCapabilityStrategy(charging),
+		AdapterStrategy:    toCapabilityStrategy(adapter),
+		StrategyOverridden: overridden,
+	}
+}
+
+func toCapabilityStrategy(s *smc.Strategy) *capability.Strategy {
+	if s == nil {
+		return nil
+	}
+	return &capability.Strategy{
+		Name:        s.Name,
+		Description: s.Description,
+		Keys:        s.Keys,
+	}
+}
+
+func logCapabilities() {
+	r := getCapabilityReport()
+	fields := logrus.Fields{
+		"model":           r.Model,
+		"available":       strings.Join(r.Available(), ","),
+		"missing":         strings.Join(r.Missing(), ","),
+		"chargingControl": r.ChargingControl,
+		"adapterControl":  r.AdapterControl,
+		"magSafeLED":      r.MagSafeLED,
+		"overridden":      r.StrategyOverridden,
+	}
+	if r.ChargingStrategy != nil {
+		fields["chargingStrategy"] = r.ChargingStrategy.Name
+	}
+	if r.AdapterStrategy != nil {
+		fields["adapterStrategy"] = r.AdapterStrategy.Name
+	}
+	if !r.ChargingControl {
+		logrus.WithFields(fields).Warn("this Mac does not support charging control, batt will not be able to limit charging")
+		return
+	}
+	logrus.WithFields(fields).Info("probed SMC capabilities")
+}
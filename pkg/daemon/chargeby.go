@@ -0,0 +1,208 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/config"
+)
+
+const (
+	// chargeByMargin is how much earlier than estimated charging starts, so
+	// the battery is full by the target even if charging is a bit slower.
+	chargeByMargin = 15 * time.Minute
+	// chargeRateAlpha is the weight of a new sample in the learned rates.
+	chargeRateAlpha = 0.2
+	// maxChargeRateSampleGap drops samples across sleep or long holds, which
+	// would make charging look slower than it is.
+	maxChargeRateSampleGap = 30 * time.Minute
+)
+
+type chargeSample struct {
+	at     time.Time
+	charge int
+}
+
+var (
+	chargeByMu sync.Mutex
+	// chargeRates are the learned charge rates in % per minute, by 10% band
+	// of battery charge. Charging slows down a lot near full, so a single
+	// rate would be far off. They start with typical values of a MacBook.
+	chargeRates = [10]float64{1.2, 1.2, 1.2, 1.2, 1.2, 1.2, 1.2, 1.0, 0.6, 0.35}
+	// lastChargeSample is zero while not charging.
+	lastChargeSample chargeSample
+	// chargeByTarget is the target charging to full has started for. Once
+	// started, it goes on until the target even if charging is faster than
+	// estimated, so it does not flap.
+	chargeByTarget time.Time
+)
+
+// recordChargeRate learns how fast this Mac charges. It is called by the
+// maintain loop.
+func recordChargeRate(charging bool, charge int, now time.Time) {
+	chargeByMu.Lock()
+	defer chargeByMu.Unlock()
+
+	if !charging || charge >= 100 {
+		lastChargeSample = chargeSample{}
+		return
+	}
+	last := lastChargeSample
+	if last.at.IsZero() || charge < last.charge || now.Sub(last.at) > maxChargeRateSampleGap {
+		lastChargeSample = chargeSample{at: now, charge: charge}
+		return
+	}
+	// Wait for the charge to go up, since it only has a 1% resolution.
+	if charge == last.charge {
+		return
+	}
+
+	rate := float64(charge-last.charge) / now.Sub(last.at).Minutes()
+	band := last.charge / 10
+	chargeRates[band] += chargeRateAlpha * (rate - chargeRates[band])
+	lastChargeSample = chargeSample{at: now, charge: charge}
+}
+
+// estimateChargeTime estimates how long charging from charge to 100% takes.
+func estimateChargeTime(charge int) time.Duration {
+	chargeByMu.Lock()
+	defer chargeByMu.Unlock()
+
+	minutes := 0.0
+	for c := max(charge, 0); c < 100; c++ {
+		minutes += 1 / chargeRates[c/10]
+	}
+	return time.Duration(minutes * float64(time.Minute))
+}
+
+// nextChargeBy returns the next time after now the battery should be full
+// by, given the time of day s in config.ChargeByLayout.
+func nextChargeBy(s string, now time.Time) (time.Time, error) {
+	t, err := time.Parse(config.ChargeByLayout, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	target := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+	if !target.After(now) {
+		target = target.AddDate(0, 0, 1)
+	}
+	return target, nil
+}
+
+// chargeByStart returns when charging to full should start for the next
+// target, given the current charge.
+func chargeByStart(s string, charge int, now time.Time) (start, target time.Time, err error) {
+	target, err = nextChargeBy(s, now)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return target.Add(-estimateChargeTime(charge) - chargeByMargin), target, nil
+}
+
+// chargeByActive returns true if the battery should be charged to 100% now
+// to be full by the charge-by time. It is called by the maintain loop.
+func chargeByActive(charge int, now time.Time) bool {
+	s := conf.ChargeBy()
+
+	chargeByMu.Lock()
+	active := chargeByTarget
+	chargeByMu.Unlock()
+
+	if !active.IsZero() {
+		if s != "" && now.Before(active) {
+			return true
+		}
+		logrus.WithField("target", active).Info("charge-by time reached, back to the charge limit")
+		resetChargeBy()
+	}
+	// Storage mode is for a Mac that is not used, so there is no point. A
+	// managed limit must not be bypassed.
+	if s == "" || conf.StorageMode() != nil || currentManaged().LimitLocked() {
+		return false
+	}
+
+	start, target, err := chargeByStart(s, charge, now)
+	if err != nil {
+		logrus.WithError(err).Error("invalid charge-by time")
+		return false
+	}
+	if now.Before(start) {
+		return false
+	}
+
+	chargeByMu.Lock()
+	chargeByTarget = target
+	chargeByMu.Unlock()
+	logrus.WithFields(logrus.Fields{
+		"charge": charge,
+		"target": target,
+	}).Info("charging to 100% to be full by the charge-by time")
+	return true
+}
+
+func resetChargeBy() {
+	chargeByMu.Lock()
+	defer chargeByMu.Unlock()
+	chargeByTarget = time.Time{}
+}
+
+func setChargeBy(c *gin.Context) {
+	var s string
+	if err := c.BindJSON(&s); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, err.Error())
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if s != "" {
+		if _, err := time.Parse(config.ChargeByLayout, s); err != nil {
+			err = errors.New("the time must be in 24-hour HH:MM format, e.g. 07:30")
+			c.IndentedJSON(http.StatusBadRequest, err.Error())
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	// Charging to full bypasses the limit, so it is subject to the same
+	// policy.
+	if _, ok := authorizeLimitChange(c); !ok {
+		return
+	}
+
+	if err := conf.SetChargeBy(s); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, err.Error())
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	if err := conf.Save(); err != nil {
+		logrus.Errorf("saveConfig failed: %v", err)
+		c.IndentedJSON(http.StatusInternalServerError, err.Error())
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	resetChargeBy()
+
+	if s == "" {
+		logrus.Info("turned off charge-by")
+		maintainLoopForced()
+		c.IndentedJSON(http.StatusCreated, "Full charge by is turned off")
+		return
+	}
+
+	logrus.WithField("chargeBy", s).Info("set charge-by time")
+	maintainLoopForced()
+
+	msg := fmt.Sprintf("Battery will be full by %s every day", s)
+	if charge, err := smcConn.GetBatteryCharge(); err == nil {
+		if start, _, err := chargeByStart(s, charge, time.Now()); err == nil {
+			msg += fmt.Sprintf(", charging to 100%% starts around %s", start.Format(config.ChargeByLayout))
+		}
+	}
+	c.IndentedJSON(http.StatusCreated, msg)
+}
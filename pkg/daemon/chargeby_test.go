@@ -0,0 +1,105 @@
+package daemon
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/utils/ptr"
+)
+
+func TestNextChargeBy(t *testing.T) {
+	now := time.Date(2025, 3, 10, 6, 0, 0, 0, time.Local)
+	tests := []struct {
+		at   string
+		want time.Time
+	}{
+		{at: "07:30", want: time.Date(2025, 3, 10, 7, 30, 0, 0, time.Local)},
+		{at: "06:00", want: time.Date(2025, 3, 11, 6, 0, 0, 0, time.Local)},
+		{at: "05:00", want: time.Date(2025, 3, 11, 5, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		got, err := nextChargeBy(tt.at, now)
+		if err != nil {
+			t.Fatalf("nextChargeBy(%q) error = %v", tt.at, err)
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("nextChargeBy(%q) = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+	if _, err := nextChargeBy("7:30am", now); err == nil {
+		t.Error("nextChargeBy() should reject times not in HH:MM")
+	}
+}
+
+func TestRecordChargeRate(t *testing.T) {
+	saved := chargeRates
+	lastChargeSample = chargeSample{}
+	t.Cleanup(func() { chargeRates = saved; lastChargeSample = chargeSample{} })
+
+	full := estimateChargeTime(80)
+	if full <= 0 || estimateChargeTime(100) != 0 {
+		t.Fatalf("estimateChargeTime() = %v from 80%%, %v from 100%%", full, estimateChargeTime(100))
+	}
+
+	// Charging 1% every 10 minutes in the 80s is slower than the default.
+	now := time.Now()
+	recordChargeRate(true, 85, now)
+	recordChargeRate(true, 86, now.Add(10*time.Minute))
+	if chargeRates[8] >= saved[8] {
+		t.Errorf("chargeRates[8] = %v, should have decreased from %v", chargeRates[8], saved[8])
+	}
+	if got := estimateChargeTime(80); got <= full {
+		t.Errorf("estimateChargeTime(80) = %v, should be longer than %v", got, full)
+	}
+
+	// Samples across a long gap are dropped.
+	rate := chargeRates[8]
+	recordChargeRate(true, 87, now.Add(2*time.Hour))
+	if chargeRates[8] != rate {
+		t.Errorf("chargeRates[8] changed across a gap")
+	}
+}
+
+func TestChargeByActive(t *testing.T) {
+	resetChargeBy()
+	t.Cleanup(resetChargeBy)
+	conf = config.NewFileFromConfig(&config.RawFileConfig{
+		Limit:    ptr.To(80),
+		ChargeBy: ptr.To("07:30"),
+	}, filepath.Join(t.TempDir(), "batt.json"))
+
+	target := time.Date(2025, 3, 10, 7, 30, 0, 0, time.Local)
+	start := target.Add(-estimateChargeTime(80) - chargeByMargin)
+
+	if chargeByActive(80, start.Add(-time.Minute)) {
+		t.Error("chargeByActive() = true before it is time to start charging")
+	}
+	if !chargeByActive(80, start) {
+		t.Fatal("chargeByActive() = false when it is time to start charging")
+	}
+	// Charging faster than estimated does not stop it before the target.
+	if !chargeByActive(99, start.Add(time.Minute)) {
+		t.Error("chargeByActive() = false before the target")
+	}
+	if chargeByActive(100, target) {
+		t.Error("chargeByActive() = true after the target")
+	}
+}
+
+func TestSetChargeByRejectsMalformedTime(t *testing.T) {
+	conf = config.NewFileFromConfig(&config.RawFileConfig{
+		ChargeBy: ptr.To("07:30"),
+	}, filepath.Join(t.TempDir(), "batt.json"))
+
+	if err := conf.SetChargeBy("7:30am"); err == nil {
+		t.Error("SetChargeBy() should reject times not in HH:MM")
+	}
+	if got := conf.ChargeBy(); got != "07:30" {
+		t.Errorf("ChargeBy() = %q after a rejected time, want %q", got, "07:30")
+	}
+	if err := conf.SetChargeBy(""); err != nil {
+		t.Errorf("SetChargeBy(\"\") error = %v", err)
+	}
+}
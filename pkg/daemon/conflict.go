@@ -0,0 +1,128 @@
+package daemon
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/conflict"
+	"github.com/charlie0129/batt/pkg/events"
+)
+
+// conflictWindow is how long a detected conflict is reported as active.
+const conflictWindow = 10 * time.Minute
+
+var (
+	conflictMu sync.Mutex
+	// expectedChargingEnabled is the charging state the maintain loop left
+	// the SMC in. nil means unknown, e.g. right after startup, sleep or
+	// calibration, where batt itself changes charging outside the loop.
+	expectedChargingEnabled *bool
+	conflictCount           int
+	lastConflictTime        time.Time
+	// launchdPlistDir is a test seam.
+	launchdPlistDir = "/Library/LaunchDaemons"
+)
+
+// expectChargingEnabled records the charging state batt left the SMC in, so
+// the next loop can tell whether someone else changed it.
+func expectChargingEnabled(enabled bool) {
+	conflictMu.Lock()
+	defer conflictMu.Unlock()
+	expectedChargingEnabled = &enabled
+}
+
+// forgetExpectedCharging is called when batt changes charging outside the
+// maintain loop, or stops controlling it.
+func forgetExpectedCharging() {
+	conflictMu.Lock()
+	defer conflictMu.Unlock()
+	expectedChargingEnabled = nil
+}
+
+// checkChargingConflict compares the current charging state with what the
+// previous loop left. A mismatch means another program (e.g. AlDente) wrote
+// the same SMC keys. It returns true if a conflict was detected.
+func checkChargingConflict(isChargingEnabled, maintainLoopsMissed bool) bool {
+	conflictMu.Lock()
+	defer conflictMu.Unlock()
+
+	expected := expectedChargingEnabled
+	expectedChargingEnabled = nil
+	// After sleep or when loops were interrupted, state could have been
+	// changed by batt's own sleep handling.
+	if expected == nil || maintainLoopsMissed || *expected == isChargingEnabled {
+		return false
+	}
+
+	conflictCount++
+	lastConflictTime = time.Now()
+	msg := fmt.Sprintf("Charging was turned %s by another program. Another charge limiter may be running.", onOff(isChargingEnabled))
+	if suspects := findConflictSuspects(); len(suspects) > 0 {
+		msg = fmt.Sprintf("Charging was turned %s by another program, likely %s. Running two charge limiters at the same time makes them fight.", onOff(isChargingEnabled), strings.Join(suspects, ", "))
+	}
+	logrus.WithFields(logrus.Fields{
+		"expectedChargingEnabled": *expected,
+		"chargingEnabled":         isChargingEnabled,
+		"count":                   conflictCount,
+	}).Warn("charging state changed outside batt, another charge limiter may be running")
+
+	if sseHub != nil {
+		sseHub.Publish(events.ConflictDetected, events.ConflictDetectedEvent{
+			Message: msg,
+			Ts:      lastConflictTime.Unix(),
+		})
+	}
+	return true
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+// findConflictSuspects returns the names of known charge limiters that have
+// their helper installed.
+func findConflictSuspects() []string {
+	var suspects []string
+	for _, c := range conflict.KnownControllers {
+		for _, label := range c.LaunchdLabels {
+			if _, err := os.Stat(launchdPlistDir + "/" + label + ".plist"); err == nil {
+				suspects = append(suspects, c.Name)
+				break
+			}
+		}
+	}
+	return suspects
+}
+
+func getConflictStatus() *conflict.Status {
+	conflictMu.Lock()
+	defer conflictMu.Unlock()
+
+	st := &conflict.Status{
+		Count:        conflictCount,
+		LastDetected: lastConflictTime,
+		Suspects:     findConflictSuspects(),
+	}
+	st.Detected = !lastConflictTime.IsZero() && time.Since(lastConflictTime) < conflictWindow
+	if st.Detected {
+		st.Message = "Another program changed charging recently."
+		if len(st.Suspects) > 0 {
+			st.Message = fmt.Sprintf("Another program changed charging recently, likely %s. Quit or uninstall it to let batt work reliably.", strings.Join(st.Suspects, ", "))
+		}
+	}
+	return st
+}
+
+func getConflict(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, getConflictStatus())
+}
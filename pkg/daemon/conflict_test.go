@@ -0,0 +1,65 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charlie0129/batt/pkg/utils/ptr"
+)
+
+func resetConflictState(t *testing.T) {
+	t.Helper()
+	launchdPlistDir = t.TempDir()
+	expectedChargingEnabled = nil
+	conflictCount = 0
+	lastConflictTime = time.Time{}
+	sseHub = nil
+}
+
+func TestCheckChargingConflict(t *testing.T) {
+	tests := []struct {
+		name                string
+		expected            *bool
+		isChargingEnabled   bool
+		maintainLoopsMissed bool
+		want                bool
+	}{
+		{name: "no expectation", expected: nil, isChargingEnabled: true, want: false},
+		{name: "unchanged", expected: ptr.To(false), isChargingEnabled: false, want: false},
+		{name: "changed by another program", expected: ptr.To(false), isChargingEnabled: true, want: true},
+		{name: "changed after missed loops", expected: ptr.To(true), isChargingEnabled: false, maintainLoopsMissed: true, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetConflictState(t)
+			expectedChargingEnabled = tt.expected
+			if got := checkChargingConflict(tt.isChargingEnabled, tt.maintainLoopsMissed); got != tt.want {
+				t.Errorf("checkChargingConflict() = %v, want %v", got, tt.want)
+			}
+			if expectedChargingEnabled != nil {
+				t.Errorf("expectation should be consumed by the check")
+			}
+			if got := getConflictStatus().Detected; got != tt.want {
+				t.Errorf("getConflictStatus().Detected = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindConflictSuspects(t *testing.T) {
+	resetConflictState(t)
+	if got := findConflictSuspects(); len(got) != 0 {
+		t.Fatalf("findConflictSuspects() = %v, want none", got)
+	}
+
+	plist := filepath.Join(launchdPlistDir, "com.davidwernhart.Helper.plist")
+	if err := os.WriteFile(plist, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got := findConflictSuspects()
+	if len(got) != 1 || got[0] != "AlDente" {
+		t.Fatalf("findConflictSuspects() = %v, want [AlDente]", got)
+	}
+}
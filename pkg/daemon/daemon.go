@@ -52,7 +52,36 @@ func setupRoutes() *gin.Engine {
 	router.GET("/current-charge", getCurrentCharge)
 	router.GET("/plugged-in", getPluggedIn)
 	router.GET("/charging-control-capable", getChargingControlCapable)
+	router.GET("/capabilities", getCapabilities)
+	router.GET("/energy-impact", getEnergyImpact)
+	router.PUT("/energy-sampling", setEnergySampling)
+	router.PUT("/weak-adapter-boost", setWeakAdapterBoost)
 	router.GET("/version", getVersion)
+	router.GET("/conflict", getConflict)
+	router.GET("/optimized-charging", getOptimizedCharging)
+	router.PUT("/optimized-charging", setOptimizedCharging)
+	router.PUT("/travel-mode", setTravelMode)
+	router.PUT("/storage-mode", setStorageMode)
+	router.GET("/managed", getManaged)
+	router.GET("/charging-paused", getChargingPaused)
+	router.PUT("/charge-by", setChargeBy)
+	router.GET("/policy", getPolicy)
+	router.GET("/debug", getDebug)
+	router.PUT("/charging-paused", setChargingPaused)
+	router.GET("/bypass", getBypass)
+	router.PUT("/bypass", setBypass)
+	router.GET("/temperatures", getTemperatures)
+	router.PUT("/temperature-limit", setTemperatureLimit)
+	router.GET("/usage", getUsage)
+	router.GET("/report", getReport)
+	router.GET("/health-comparison", getHealthComparison)
+	router.PUT("/non-root-access", setNonRootAccess)
+	router.POST("/self-test", postSelfTest)
+	router.GET("/log", getLog)
+	router.GET("/log-level", getLogLevel)
+	router.PUT("/log-level", setLogLevel)
+	router.POST("/log/rotate", postRotateLog)
+	router.POST("/log/clear", postClearLog)
 	// Deprecated
 	router.GET("/power-telemetry", getPowerTelemetry)
 	router.GET("/telemetry", getUnifiedTelemetry)
@@ -74,8 +103,12 @@ func setupRoutes() *gin.Engine {
 	return router
 }
 
-func Run(configPath string, unixSocketPath string, allowNonRoot bool) error {
+// Run runs the daemon until SIGINT or SIGTERM. If sim is not nil, the
+// daemon controls the simulated battery instead of the SMC. logFile is where
+// launchd writes the daemon's output, empty if unknown.
+func Run(configPath string, unixSocketPath string, logFile string, allowNonRoot bool, sim *smc.Simulation) error {
 	router := setupRoutes()
+	logPath = logFile
 
 	// Initialize global SSE hub
 	sseHub = events.NewEventHub()
@@ -97,6 +130,10 @@ func Run(configPath string, unixSocketPath string, allowNonRoot bool) error {
 				logrus.Errorf("failed to reload config: %v", err)
 				continue
 			}
+			forgetManaged()
+			if err := applySocketPermissions(); err != nil {
+				logrus.WithError(err).Error("failed to apply socket permissions")
+			}
 			logrus.Infof("config reloaded")
 		}
 	}()
@@ -147,7 +184,8 @@ func Run(configPath string, unixSocketPath string, allowNonRoot bool) error {
 	}
 
 	srv := &http.Server{
-		Handler: router,
+		Handler:     router,
+		ConnContext: saveConn,
 	}
 
 	// Create the socket to listen on:
@@ -156,12 +194,11 @@ func Run(configPath string, unixSocketPath string, allowNonRoot bool) error {
 		logrus.Fatal(err)
 	}
 
-	if conf.AllowNonRootAccess() || allowNonRoot {
-		logrus.Infof("non-root access is allowed, chaning permissions of %s to 0777", unixSocketPath)
-		err = os.Chmod(unixSocketPath, 0777)
-		if err != nil {
-			logrus.Fatal(err)
-		}
+	socketMu.Lock()
+	socketPath, alwaysAllowNonRoot = unixSocketPath, allowNonRoot
+	socketMu.Unlock()
+	if err := applySocketPermissions(); err != nil {
+		logrus.Fatal(err)
 	}
 
 	// Serve HTTP on unix socket
@@ -183,9 +220,25 @@ func Run(configPath string, unixSocketPath string, allowNonRoot bool) error {
 
 	// Open Apple SMC for read/writing
 	smcConn = smc.New()
+	if sim != nil {
+		smcConn = smc.NewSimulated(sim)
+	}
 	if err := smcConn.Open(); err != nil {
 		logrus.Fatal(err)
 	}
+	if err := smcConn.SetStrategyOverrides(conf.ChargingStrategy(), conf.AdapterStrategy()); err != nil {
+		logrus.WithError(err).Error("ignoring SMC strategy override in config")
+	}
+	logCapabilities()
+
+	// Load the history before the main loop starts recording to it.
+	historyFile := "/etc/batt.history.json"
+	if configPath != "" {
+		historyFile = filepath.Join(filepath.Dir(configPath), "batt.history.json")
+	}
+	initHistory(historyFile)
+	stopHistory := events.On(sseHub, events.BatterySample, recordSample)
+	defer stopHistory()
 
 	go func() {
 		logrus.Debugln("main loop starts")
@@ -195,6 +248,12 @@ func Run(configPath string, unixSocketPath string, allowNonRoot bool) error {
 		logrus.Errorf("main loop exited unexpectedly")
 	}()
 
+	go energySamplingLoop()
+	go runWebhooks(sseHub)
+	go logRotateLoop()
+	go batteryHealthLoop()
+	go socketPermissionLoop()
+
 	// Initialize calibration state file next to config path (derive directory from configPath)
 	if configPath != "" {
 		dir := filepath.Dir(configPath)
@@ -219,6 +278,8 @@ func Run(configPath string, unixSocketPath string, allowNonRoot bool) error {
 	}
 	cancel()
 
+	flushHistory()
+
 	logrus.Info("stopping listening notifications")
 	stopListeningNotifications()
 
@@ -0,0 +1,93 @@
+package daemon
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/diagnostics"
+	"github.com/charlie0129/batt/pkg/utils/lru"
+)
+
+// maxDebugEntries is how many recent entries the debug report keeps.
+const maxDebugEntries = 100
+
+var (
+	debugMu      sync.Mutex
+	debugEntries []diagnostics.Entry
+	// lastPolicyMode is the mode of the previous loop, to record
+	// transitions.
+	lastPolicyMode config.PolicyMode
+)
+
+func recordDebug(kind diagnostics.EntryKind, message string, err error) {
+	e := diagnostics.Entry{Time: time.Now(), Kind: kind, Message: message}
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	debugMu.Lock()
+	defer debugMu.Unlock()
+	debugEntries = append(debugEntries, e)
+	if len(debugEntries) > maxDebugEntries {
+		debugEntries = debugEntries[len(debugEntries)-maxDebugEntries:]
+	}
+}
+
+// recordSMCWrite records an SMC write and passes its error through.
+func recordSMCWrite(what string, err error) error {
+	recordDebug(diagnostics.KindSMCWrite, what, err)
+	return err
+}
+
+// recordPolicyTransition records when the effective policy changes. It is
+// called by the maintain loop.
+func recordPolicyTransition(now time.Time) {
+	p := getEffectivePolicy(now)
+
+	debugMu.Lock()
+	prev := lastPolicyMode
+	lastPolicyMode = p.Mode
+	debugMu.Unlock()
+
+	if prev == p.Mode {
+		return
+	}
+	msg := fmt.Sprintf("%s: %s", p.Mode, p.Detail)
+	if prev != "" {
+		msg = fmt.Sprintf("%s -> %s", prev, msg)
+	}
+	recordDebug(diagnostics.KindTransition, msg, nil)
+}
+
+func getDebugReport() *diagnostics.Report {
+	interval, reason := poller.current()
+	r := &diagnostics.Report{
+		LoopInterval: interval.Seconds(),
+		PollReason:   reason,
+		RecentLoops:  loopRecorder.GetLastRecords(continuousLoopThreshold),
+		LoopsMissed:  checkMissedMaintainLoops(false),
+		Self:         getSelfAudit(),
+		Caches:       []lru.Stats{reportCache.Stats(), curveCache.Stats()},
+	}
+	if scheduler != nil {
+		r.NextCalibration, _ = scheduler.Status()
+	}
+
+	debugMu.Lock()
+	defer debugMu.Unlock()
+	r.Mode = string(lastPolicyMode)
+	r.Entries = make([]diagnostics.Entry, 0, len(debugEntries))
+	for i := len(debugEntries) - 1; i >= 0; i-- {
+		r.Entries = append(r.Entries, debugEntries[i])
+	}
+	return r
+}
+
+func getDebug(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, getDebugReport())
+}
@@ -0,0 +1,48 @@
+package daemon
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charlie0129/batt/pkg/calibration"
+	"github.com/charlie0129/batt/pkg/diagnostics"
+)
+
+func TestDebugReport(t *testing.T) {
+	calibrationState = &calibration.State{Phase: calibration.PhaseIdle}
+	conf = &mockConf{upper: 80, lower: 78}
+	debugEntries, lastPolicyMode = nil, ""
+	t.Cleanup(func() { chargingPaused = false })
+
+	recordPolicyTransition(time.Now())
+	recordPolicyTransition(time.Now())
+	_ = recordSMCWrite("disable charging", nil)
+	chargingPaused = true
+	recordPolicyTransition(time.Now())
+	if err := recordSMCWrite("enable charging", errors.New("boom")); err == nil {
+		t.Fatal("recordSMCWrite should pass the error through")
+	}
+
+	r := getDebugReport()
+	if r.Mode != "paused" {
+		t.Errorf("Mode = %q, want paused", r.Mode)
+	}
+	if len(r.Entries) != 4 {
+		t.Fatalf("got %d entries, want 4 (no entry without a transition)", len(r.Entries))
+	}
+	if e := r.Entries[0]; e.Kind != diagnostics.KindSMCWrite || e.Error != "boom" {
+		t.Errorf("newest entry = %+v, want the failed write", e)
+	}
+	if e := r.Entries[1]; e.Kind != diagnostics.KindTransition || !strings.HasPrefix(e.Message, "limit -> paused") {
+		t.Errorf("entry = %+v, want the transition to paused", e)
+	}
+
+	for i := 0; i < maxDebugEntries+10; i++ {
+		_ = recordSMCWrite("disable charging", nil)
+	}
+	if n := len(getDebugReport().Entries); n != maxDebugEntries {
+		t.Errorf("got %d entries, want at most %d", n, maxDebugEntries)
+	}
+}
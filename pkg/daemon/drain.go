@@ -0,0 +1,82 @@
+package daemon
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/events"
+)
+
+// drainAlertDrop is how many percent the battery may lose while batt wants
+// it to charge and the adapter is attached, before the user is alerted.
+const drainAlertDrop = 3
+
+var (
+	drainMu sync.Mutex
+	// drainStartCharge is the charge when the battery started draining
+	// while plugged in, or -1 if it is not draining.
+	drainStartCharge = -1
+	drainLastCharge  = -1
+	drainLastCheck   time.Time
+	drainAlerted     bool
+)
+
+// checkDrainWhilePluggedIn alerts when the battery keeps draining below the
+// lower limit, even though charging is enabled and the adapter is attached.
+// This usually means a failing adapter or one too weak for the load, which
+// users would otherwise only notice when the Mac dies. It returns true if
+// the user was alerted.
+func checkDrainWhilePluggedIn(isChargingEnabled, isPluggedIn bool, batteryCharge, lower int, now time.Time) bool {
+	drainMu.Lock()
+	defer drainMu.Unlock()
+
+	// Sleep or loops skipped by pause and calibration leave a gap, where
+	// the charge may have changed for other reasons.
+	gap := !drainLastCheck.IsZero() && now.Sub(drainLastCheck) > continuousLoopThreshold
+	drainLastCheck = now
+
+	draining := isPluggedIn && isChargingEnabled && batteryCharge < lower
+	if draining {
+		// Disabling the adapter on purpose (e.g. storage mode) also drains.
+		adapter, err := smcIsAdapterEnabled()
+		draining = err == nil && adapter
+	}
+	if !draining || gap {
+		drainStartCharge, drainLastCharge = -1, -1
+		drainAlerted = false
+		if !draining {
+			return false
+		}
+	}
+
+	// Any gain means the adapter keeps up at least some of the time.
+	rising := batteryCharge > drainLastCharge
+	drainLastCharge = batteryCharge
+	if rising {
+		drainStartCharge = batteryCharge
+		drainAlerted = false
+		return false
+	}
+	if drainAlerted || drainStartCharge-batteryCharge < drainAlertDrop {
+		return false
+	}
+	drainAlerted = true
+
+	msg := fmt.Sprintf("Your Mac is plugged in, but the battery dropped from %d%% to %d%%. The power adapter may be failing or too weak for the current load.", drainStartCharge, batteryCharge)
+	logrus.WithFields(logrus.Fields{
+		"from":  drainStartCharge,
+		"to":    batteryCharge,
+		"lower": lower,
+	}).Warn("battery is draining while plugged in")
+
+	if sseHub != nil {
+		sseHub.Publish(events.BatteryDraining, events.BatteryDrainingEvent{
+			Message: msg,
+			Ts:      now.Unix(),
+		})
+	}
+	return true
+}
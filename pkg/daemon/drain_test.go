@@ -0,0 +1,75 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+)
+
+func resetDrainState(t *testing.T) {
+	t.Helper()
+	drainStartCharge = -1
+	drainLastCharge = -1
+	drainLastCheck = time.Time{}
+	drainAlerted = false
+	sseHub = nil
+}
+
+func TestCheckDrainWhilePluggedIn(t *testing.T) {
+	fake := newFakeSMC(70, 1, true)
+	fake.inject()
+	resetDrainState(t)
+
+	now := time.Now()
+	check := func(charge int) bool {
+		now = now.Add(loopInterval)
+		return checkDrainWhilePluggedIn(true, true, charge, 78, now)
+	}
+
+	for _, charge := range []int{70, 69, 68} {
+		if check(charge) {
+			t.Fatalf("alerted at %d%%, before dropping %d%%", charge, drainAlertDrop)
+		}
+	}
+	if !check(67) {
+		t.Fatal("expected an alert after dropping from 70% to 67%")
+	}
+	if check(66) {
+		t.Fatal("alerted twice for the same drain")
+	}
+
+	// Charging again starts over.
+	if check(67) {
+		t.Fatal("alerted while charging")
+	}
+	if check(65) {
+		t.Fatal("alerted before dropping again")
+	}
+	if !check(64) {
+		t.Fatal("expected an alert after dropping from 67% to 64%")
+	}
+
+	// A gap, e.g. sleep, starts over as well.
+	now = now.Add(time.Hour)
+	if check(60) {
+		t.Fatal("alerted right after a gap")
+	}
+}
+
+func TestCheckDrainWhilePluggedInIgnoresIntendedDrain(t *testing.T) {
+	fake := newFakeSMC(70, 1, false)
+	fake.inject()
+	resetDrainState(t)
+
+	now := time.Now()
+	for charge := 70; charge > 60; charge-- {
+		now = now.Add(loopInterval)
+		// Adapter disabled, e.g. by storage mode.
+		if checkDrainWhilePluggedIn(true, true, charge, 78, now) {
+			t.Fatalf("alerted at %d%% while the adapter is disabled", charge)
+		}
+		// Charging disabled, i.e. above the limit or paused.
+		if checkDrainWhilePluggedIn(false, true, charge, 78, now) {
+			t.Fatalf("alerted at %d%% while charging is disabled", charge)
+		}
+	}
+}
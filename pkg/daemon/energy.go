@@ -0,0 +1,222 @@
+package daemon
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+#include <libproc.h>
+#include <sys/resource.h>
+
+typedef struct {
+	int pid;
+	uint64_t energy;
+	char name[64];
+} batt_proc_energy;
+
+// batt_sampleProcessEnergy fills out with the billed energy (in nanojoules)
+// of up to max running processes. It returns the number of entries filled.
+static int batt_sampleProcessEnergy(batt_proc_energy *out, int max) {
+	int n = proc_listallpids(NULL, 0);
+	if (n <= 0) {
+		return 0;
+	}
+	// Leave room for processes started in between.
+	n += 64;
+	pid_t *pids = malloc(sizeof(pid_t) * n);
+	if (pids == NULL) {
+		return 0;
+	}
+	n = proc_listallpids(pids, sizeof(pid_t) * n);
+
+	int count = 0;
+	for (int i = 0; i < n && count < max; i++) {
+		struct rusage_info_v4 ri;
+		if (proc_pid_rusage(pids[i], RUSAGE_INFO_V4, (rusage_info_t *)&ri) != 0) {
+			continue;
+		}
+		out[count].pid = pids[i];
+		out[count].energy = ri.ri_billed_energy;
+		out[count].name[0] = '\0';
+		proc_name(pids[i], out[count].name, sizeof(out[count].name));
+		count++;
+	}
+	free(pids);
+	return count;
+}
+*/
+import "C"
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/powerinfo"
+)
+
+const (
+	energySampleInterval = time.Minute
+	energyReportTopN     = 15
+	maxSampledProcesses  = 4096
+)
+
+// processSample is the cumulative energy a process has used since it started.
+type processSample struct {
+	pid  int
+	name string
+	// energy is in nanojoules.
+	energy uint64
+}
+
+var (
+	energyMu sync.Mutex
+	// energyPrev are the samples of the previous interval on battery, by pid.
+	energyPrev     map[int]processSample
+	energyPrevTime time.Time
+	// energyTotals are joules used by process name during the session.
+	energyTotals   map[string]float64
+	energySince    time.Time
+	energyDuration time.Duration
+)
+
+// readProcessEnergy samples the energy used by all running processes.
+func readProcessEnergy() []processSample {
+	buf := make([]C.batt_proc_energy, maxSampledProcesses)
+	n := int(C.batt_sampleProcessEnergy(&buf[0], C.int(len(buf))))
+
+	samples := make([]processSample, 0, n)
+	for i := 0; i < n; i++ {
+		samples = append(samples, processSample{
+			pid:    int(buf[i].pid),
+			name:   C.GoString(&buf[i].name[0]),
+			energy: uint64(buf[i].energy),
+		})
+	}
+	return samples
+}
+
+// recordEnergySample adds the energy used since the previous sample to the
+// session totals. A new session starts whenever we go on battery, so the
+// report covers the current (or last) discharging session.
+func recordEnergySample(samples []processSample, onBattery bool, now time.Time) {
+	energyMu.Lock()
+	defer energyMu.Unlock()
+
+	if !onBattery {
+		energyPrev = nil
+		return
+	}
+
+	cur := make(map[int]processSample, len(samples))
+	for _, s := range samples {
+		cur[s.pid] = s
+	}
+
+	if energyPrev == nil {
+		energyTotals = map[string]float64{}
+		energySince = now
+		energyDuration = 0
+	} else {
+		for pid, s := range cur {
+			prev, ok := energyPrev[pid]
+			// Skip reused pids and processes started in between, since we
+			// cannot tell how much of their energy was used on battery.
+			if !ok || prev.name != s.name || s.energy < prev.energy {
+				continue
+			}
+			energyTotals[s.name] += float64(s.energy-prev.energy) / 1e9
+		}
+		energyDuration += now.Sub(energyPrevTime)
+	}
+
+	energyPrev = cur
+	energyPrevTime = now
+}
+
+// sampleEnergy is called periodically by energySamplingLoop.
+func sampleEnergy() {
+	if !conf.EnergySampling() {
+		recordEnergySample(nil, false, time.Now())
+		return
+	}
+
+	pluggedIn, err := smcConn.IsPluggedIn()
+	if err != nil {
+		logrus.Errorf("IsPluggedIn failed: %v", err)
+		return
+	}
+
+	var samples []processSample
+	if !pluggedIn {
+		samples = readProcessEnergy()
+	}
+	recordEnergySample(samples, !pluggedIn, time.Now())
+}
+
+func energySamplingLoop() {
+	ticker := time.NewTicker(energySampleInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		timers.Fire(timerEnergySample, energySampleInterval)
+		sampleEnergy()
+	}
+}
+
+func getEnergyImpactReport() *powerinfo.EnergyImpactReport {
+	energyMu.Lock()
+	defer energyMu.Unlock()
+
+	r := &powerinfo.EnergyImpactReport{
+		Enabled:   conf.EnergySampling(),
+		Since:     energySince,
+		Duration:  energyDuration.Seconds(),
+		Processes: []powerinfo.ProcessEnergy{},
+	}
+	for name, energy := range energyTotals {
+		p := powerinfo.ProcessEnergy{Name: name, Energy: energy}
+		if r.Duration > 0 {
+			p.AveragePower = energy / r.Duration
+		}
+		r.Processes = append(r.Processes, p)
+	}
+	sort.Slice(r.Processes, func(i, j int) bool {
+		return r.Processes[i].Energy > r.Processes[j].Energy
+	})
+	if len(r.Processes) > energyReportTopN {
+		r.Processes = r.Processes[:energyReportTopN]
+	}
+	return r
+}
+
+func getEnergyImpact(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, getEnergyImpactReport())
+}
+
+func setEnergySampling(c *gin.Context) {
+	var e bool
+	if err := c.BindJSON(&e); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, err.Error())
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if rejectManaged(c, currentManaged().EnergySamplingLocked(), "energy sampling") {
+		return
+	}
+
+	conf.SetEnergySampling(e)
+	if err := conf.Save(); err != nil {
+		logrus.Errorf("saveConfig failed: %v", err)
+		c.IndentedJSON(http.StatusInternalServerError, err.Error())
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	logrus.Infof("set energy sampling to %t", e)
+
+	c.IndentedJSON(http.StatusCreated, "ok")
+}
@@ -0,0 +1,59 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordEnergySample(t *testing.T) {
+	energyPrev = nil
+	energyTotals = nil
+	conf = &mockConf{upper: 80, lower: 78}
+
+	start := time.Now()
+	// On AC, nothing is recorded.
+	recordEnergySample(nil, false, start)
+	// Going on battery starts a session.
+	recordEnergySample([]processSample{
+		{pid: 1, name: "a", energy: 1e9},
+		{pid: 2, name: "b", energy: 5e9},
+	}, true, start.Add(time.Minute))
+	recordEnergySample([]processSample{
+		{pid: 1, name: "a", energy: 3e9},
+		// Reused pid, skipped.
+		{pid: 2, name: "c", energy: 9e9},
+		// New process, skipped.
+		{pid: 3, name: "d", energy: 1e9},
+	}, true, start.Add(2*time.Minute))
+	recordEnergySample([]processSample{
+		{pid: 1, name: "a", energy: 4e9},
+		{pid: 2, name: "c", energy: 12e9},
+	}, true, start.Add(3*time.Minute))
+
+	r := getEnergyImpactReport()
+	if r.Duration != 120 {
+		t.Errorf("Duration = %v, want 120", r.Duration)
+	}
+	if !r.Since.Equal(start.Add(time.Minute)) {
+		t.Errorf("Since = %v, want %v", r.Since, start.Add(time.Minute))
+	}
+	want := map[string]float64{"c": 3, "a": 3}
+	if len(r.Processes) != len(want) {
+		t.Fatalf("Processes = %+v, want %v", r.Processes, want)
+	}
+	for _, p := range r.Processes {
+		if p.Energy != want[p.Name] {
+			t.Errorf("%s: Energy = %v, want %v", p.Name, p.Energy, want[p.Name])
+		}
+		if p.AveragePower != want[p.Name]/120 {
+			t.Errorf("%s: AveragePower = %v, want %v", p.Name, p.AveragePower, want[p.Name]/120)
+		}
+	}
+
+	// Going on battery again starts a new session.
+	recordEnergySample(nil, false, start.Add(4*time.Minute))
+	recordEnergySample([]processSample{{pid: 1, name: "a", energy: 10e9}}, true, start.Add(5*time.Minute))
+	if r := getEnergyImpactReport(); len(r.Processes) != 0 || r.Duration != 0 {
+		t.Errorf("new session should start empty, got %+v", r)
+	}
+}
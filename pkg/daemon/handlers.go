@@ -14,6 +14,7 @@ import (
 
 	"github.com/charlie0129/batt/pkg/calibration"
 	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/limits"
 	"github.com/charlie0129/batt/pkg/powerinfo"
 	"github.com/charlie0129/batt/pkg/version"
 )
@@ -39,21 +40,41 @@ func setLimit(c *gin.Context) {
 		return
 	}
 
-	if l < 10 || l > 100 {
-		err := fmt.Errorf("limit must be between 10 and 100, got %d", l)
+	// Leaving storage mode restores the lower limit delta it overrode.
+	delta := conf.UpperLimit() - conf.LowerLimit()
+	if sm := conf.StorageMode(); sm != nil {
+		delta = sm.LowerLimitDelta
+	}
+	if err := limits.ValidateUpper(l, delta); err != nil {
 		c.IndentedJSON(http.StatusBadRequest, err.Error())
 		_ = c.AbortWithError(http.StatusBadRequest, err)
 		return
 	}
 
-	if delta := conf.UpperLimit() - conf.LowerLimit(); l-delta <= 10 {
-		err := fmt.Errorf("upper limit must be greater than lower limit + 10, got %d", l-delta)
-		c.IndentedJSON(http.StatusBadRequest, err.Error())
-		_ = c.AbortWithError(http.StatusBadRequest, err)
+	changedBy, ok := authorizeLimitChange(c)
+	if !ok {
 		return
 	}
 
+	if conf.TravelMode() != nil {
+		// Setting a limit by hand takes over from travel mode. The paused
+		// calibration schedule is not restored.
+		logrus.Info("limit set manually, leaving travel mode")
+		conf.SetTravelMode(nil)
+	}
+	if conf.StorageMode() != nil {
+		// Unlike travel mode, storage mode also changed the lower limit
+		// delta, so restore everything it saved before setting the limit.
+		logrus.Info("limit set manually, leaving storage mode")
+		if err := disableStorageMode(); err != nil {
+			logrus.Errorf("disableStorageMode failed: %v", err)
+			c.IndentedJSON(http.StatusInternalServerError, err.Error())
+			_ = c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+	}
 	conf.SetUpperLimit(l)
+	conf.SetLimitChangedBy(changedBy, time.Now())
 	if err := conf.Save(); err != nil {
 		logrus.Errorf("saveConfig failed: %v", err)
 		c.IndentedJSON(http.StatusInternalServerError, err.Error())
@@ -61,7 +82,7 @@ func setLimit(c *gin.Context) {
 		return
 	}
 
-	logrus.Infof("set charging limit to %d", l)
+	logrus.WithField("user", changedBy).Infof("set charging limit to %d", l)
 
 	var msg string
 	charge, err := smcConn.GetBatteryCharge()
@@ -84,6 +105,33 @@ func setLimit(c *gin.Context) {
 	c.IndentedJSON(http.StatusCreated, msg)
 }
 
+// setLimits sets the upper limit and the lower limit delta together. The
+// config stores the delta and its setters panic if the lower limit reaches
+// the upper limit, so they are called in the order that keeps the lower
+// limit valid in between.
+func setLimits(upper, delta int) error {
+	if err := limits.ValidateUpper(upper, delta); err != nil {
+		return err
+	}
+	if err := limits.ValidateLowerDelta(upper, delta); err != nil {
+		return err
+	}
+	if upper > conf.UpperLimit() {
+		conf.SetUpperLimit(upper)
+		conf.SetLowerLimit(upper - delta)
+	} else {
+		conf.SetLowerLimit(conf.UpperLimit() - delta)
+		conf.SetUpperLimit(upper)
+	}
+	return nil
+}
+
+// clampLowerDelta returns the closest valid lower limit delta for upper, for
+// restoring settings that may no longer fit together.
+func clampLowerDelta(upper, delta int) int {
+	return max(min(delta, upper-limits.MinLower), limits.MinLowerDelta)
+}
+
 func setPreventIdleSleep(c *gin.Context) {
 	var p bool
 	if err := c.BindJSON(&p); err != nil {
@@ -156,7 +204,7 @@ func setAdapter(c *gin.Context) {
 	}
 
 	if d {
-		if err := smcConn.EnableAdapter(); err != nil {
+		if err := smcEnableAdapter(); err != nil {
 			logrus.Errorf("enablePowerAdapter failed: %v", err)
 			c.IndentedJSON(http.StatusInternalServerError, err.Error())
 			_ = c.AbortWithError(http.StatusInternalServerError, err)
@@ -164,7 +212,7 @@ func setAdapter(c *gin.Context) {
 		}
 		logrus.Infof("enabled power adapter")
 	} else {
-		if err := smcConn.DisableAdapter(); err != nil {
+		if err := smcDisableAdapter(); err != nil {
 			logrus.Errorf("disablePowerAdapter failed: %v", err)
 			c.IndentedJSON(http.StatusInternalServerError, err.Error())
 			_ = c.AbortWithError(http.StatusInternalServerError, err)
@@ -236,6 +284,7 @@ func getBatteryInfo(c *gin.Context) {
 		Design:        designmAh,
 		ChargeRate:    chargeRateMilliW,
 		DesignVoltage: info.IOKit.Battery.Voltage,
+		SystemCharge:  info.IOKit.Battery.CurrentCharge,
 	}
 
 	c.IndentedJSON(http.StatusOK, resp)
@@ -249,21 +298,19 @@ func setLowerLimitDelta(c *gin.Context) {
 		return
 	}
 
-	if d < 0 {
-		err := fmt.Errorf("lower limit delta must be positive, got %d", d)
+	if err := limits.ValidateLowerDelta(conf.UpperLimit(), d); err != nil {
 		c.IndentedJSON(http.StatusBadRequest, err.Error())
 		_ = c.AbortWithError(http.StatusBadRequest, err)
 		return
 	}
 
-	if conf.UpperLimit()-d < 10 {
-		err := fmt.Errorf("lower limit delta must be less than limit - 10, got %d", d)
-		c.IndentedJSON(http.StatusBadRequest, err.Error())
-		_ = c.AbortWithError(http.StatusBadRequest, err)
+	changedBy, ok := authorizeLimitChange(c)
+	if !ok {
 		return
 	}
 
 	conf.SetLowerLimit(conf.UpperLimit() - d)
+	conf.SetLimitChangedBy(changedBy, time.Now())
 	if err := conf.Save(); err != nil {
 		logrus.Errorf("saveConfig failed: %v", err)
 		c.IndentedJSON(http.StatusInternalServerError, err.Error())
@@ -335,11 +382,16 @@ func getChargingControlCapable(c *gin.Context) {
 	c.IndentedJSON(http.StatusOK, smcConn.IsChargingControlCapable())
 }
 
+func getCapabilities(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, getCapabilityReport())
+}
+
 func getVersion(c *gin.Context) {
 	c.IndentedJSON(http.StatusOK, version.Version)
 }
 
 func getPowerTelemetry(c *gin.Context) {
+	poller.noteActivity(time.Now())
 	// Use powerkit-go to fetch a snapshot of system power state
 	c.Header("X-Deprecated", "true")
 	c.Header("X-Deprecation-Info", "Use /telemetry?power=1 instead; /power-telemetry will be removed in a future release")
@@ -370,6 +422,7 @@ func getPowerTelemetry(c *gin.Context) {
 
 // Unified telemetry endpoint: /telemetry?power=1&calibration=1 (flags optional; default all)
 func getUnifiedTelemetry(c *gin.Context) {
+	poller.noteActivity(time.Now())
 	wantPower := c.Query("power") != "0"
 	wantCal := c.Query("calibration") != "0"
 
@@ -431,7 +484,7 @@ func getEventStream(c *gin.Context) {
 	flusher.Flush()
 
 	// Heartbeat ticker: send SSE comment periodically to keep the connection alive
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(sseHeartbeatInterval)
 	defer ticker.Stop()
 
 	// Stream loop
@@ -440,6 +493,7 @@ func getEventStream(c *gin.Context) {
 		case <-c.Request.Context().Done():
 			return
 		case <-ticker.C:
+			timers.Fire(timerSSEHeartbeat, sseHeartbeatInterval)
 			// SSE comment line as heartbeat
 			_, _ = c.Writer.WriteString(":ping\n\n")
 			flusher.Flush()
@@ -570,8 +624,7 @@ func setCalibrationDischargeThreshold(c *gin.Context) {
 		return
 	}
 
-	if threshold < 10 || threshold > 50 {
-		err := fmt.Errorf("calibration discharge threshold must be between 10 and 50, got %d", threshold)
+	if err := limits.ValidateDischargeThreshold(threshold); err != nil {
 		c.IndentedJSON(http.StatusBadRequest, err.Error())
 		_ = c.AbortWithError(http.StatusBadRequest, err)
 		return
@@ -605,8 +658,7 @@ func setCalibrationHoldDurationMinutes(c *gin.Context) {
 		return
 	}
 
-	if minutes < 10 || minutes > 24*60 {
-		err := fmt.Errorf("calibration hold duration must be between 10 and 1440 minutes (24 hours), got %d", minutes)
+	if err := limits.ValidateHoldMinutes(minutes); err != nil {
 		c.IndentedJSON(http.StatusBadRequest, err.Error())
 		_ = c.AbortWithError(http.StatusBadRequest, err)
 		return
@@ -0,0 +1,292 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/peterneutron/powerkit-go/pkg/powerkit"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/charlie0129/batt/pkg/events"
+	"github.com/charlie0129/batt/pkg/history"
+	"github.com/charlie0129/batt/pkg/utils/lru"
+)
+
+const (
+	// historyKeepDays is how many days of history are kept.
+	historyKeepDays = 400
+	// maxUsageSampleGap is the longest time between two maintain loops that
+	// is counted. Longer gaps are sleep. Idle loops are idlePollInterval
+	// apart plus the time the loop itself takes, so leave some slack.
+	maxUsageSampleGap = idlePollInterval + 30*time.Second
+	// historySaveInterval is how often the history is written to disk.
+	historySaveInterval = 10 * time.Minute
+	// batteryHealthInterval is how often the cycle count and health are
+	// recorded. They change slowly.
+	batteryHealthInterval = time.Hour
+	// defaultUsageDays and maxUsageDays bound GET /usage.
+	defaultUsageDays = 30
+	maxUsageDays     = historyKeepDays
+	// reportCacheSize is how many monthly reports are kept, a bit over a
+	// year's worth.
+	reportCacheSize = 16
+)
+
+var (
+	historyMu sync.Mutex
+	// historyPath is where the history is saved, empty to keep it in memory.
+	historyPath string
+	// usageDays are the daily usage records, oldest first.
+	usageDays       []history.Day
+	lastUsageSample time.Time
+	historySavedAt  time.Time
+	// discharging is true while on battery, and dischargeFrom the charge
+	// when the Mac was unplugged.
+	discharging   bool
+	dischargeFrom int
+
+	// reportCache holds the reports of past months, which do not change.
+	reportCache = lru.New[string, history.Report]("monthly reports", reportCacheSize)
+	// curveCache holds the health curve until the health is recorded again.
+	curveCache = lru.New[string, []history.CurvePoint]("health curve", 1)
+)
+
+func initHistory(path string) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	historyPath = path
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logrus.WithError(err).Warn("failed to read history")
+		}
+		return
+	}
+	var days []history.Day
+	if err := json.Unmarshal(b, &days); err != nil {
+		logrus.WithError(err).Warn("failed to unmarshal history")
+		return
+	}
+	usageDays = days
+	reportCache.Purge()
+	curveCache.Purge()
+}
+
+// saveHistory writes the history to disk. The caller must hold historyMu.
+func saveHistory() {
+	if historyPath == "" {
+		return
+	}
+	b, err := json.Marshal(usageDays)
+	if err != nil {
+		logrus.WithError(err).Error("marshal history")
+		return
+	}
+	if err := os.WriteFile(historyPath, b, 0644); err != nil {
+		logrus.WithError(err).Error("write history")
+	}
+}
+
+// usageDay returns the record of the day of now, adding it if needed. The
+// caller must hold historyMu.
+func usageDay(now time.Time) *history.Day {
+	date := now.Local().Format(history.DayLayout)
+	if n := len(usageDays); n > 0 && usageDays[n-1].Date == date {
+		return &usageDays[n-1]
+	}
+	usageDays = append(usageDays, history.Day{Date: date})
+	if len(usageDays) > historyKeepDays {
+		usageDays = usageDays[len(usageDays)-historyKeepDays:]
+		// The oldest months and the curve lost a day.
+		reportCache.Purge()
+		curveCache.Purge()
+	}
+	return &usageDays[len(usageDays)-1]
+}
+
+// recordSample records a battery.sample event in the history.
+func recordSample(e events.BatterySampleEvent) {
+	recordUsage(time.Unix(e.Ts, 0), e.PluggedIn, e.Charge, e.Held)
+}
+
+// recordUsage adds the time since the last maintain loop to today's record.
+// held is whether batt is holding the charge while plugged in.
+func recordUsage(now time.Time, pluggedIn bool, charge int, held bool) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	d := usageDay(now)
+	switch {
+	case !pluggedIn && !discharging:
+		discharging, dischargeFrom = true, charge
+	case pluggedIn && discharging:
+		discharging = false
+		d.Discharges++
+		d.DischargeDepth += max(0, dischargeFrom-charge)
+	}
+
+	last := lastUsageSample
+	lastUsageSample = now
+	if last.IsZero() || now.Before(last) || now.Sub(last) > maxUsageSampleGap {
+		return
+	}
+
+	dt := now.Sub(last).Seconds()
+	if pluggedIn {
+		d.PluggedInSeconds += dt
+	} else {
+		d.OnBatterySeconds += dt
+	}
+	if charge > history.HighCharge {
+		d.HighChargeSeconds += dt
+	}
+	if held {
+		d.HeldSeconds += dt
+	}
+	d.ChargeSum += float64(charge) * dt
+
+	if now.Sub(historySavedAt) >= historySaveInterval {
+		historySavedAt = now
+		saveHistory()
+	}
+}
+
+// recordBatteryHealth keeps the latest cycle count and health in today's
+// record.
+func recordBatteryHealth(now time.Time, cycles, health int) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	d := usageDay(now)
+	if d.CycleCount != cycles || d.Health != health {
+		curveCache.Purge()
+	}
+	d.CycleCount, d.Health = cycles, health
+}
+
+// batteryHealthLoop records the cycle count and health every hour.
+func batteryHealthLoop() {
+	for {
+		info, err := powerkit.GetSystemInfo(powerkit.FetchOptions{QueryIOKit: true, QuerySMC: false})
+		if err != nil || info == nil || info.IOKit == nil {
+			logrus.WithError(err).Debug("failed to get battery health for history")
+		} else {
+			recordBatteryHealth(time.Now(), info.IOKit.Battery.CycleCount, info.IOKit.Calculations.HealthByMaxCapacity)
+		}
+		time.Sleep(batteryHealthInterval)
+		timers.Fire(timerBatteryHealth, batteryHealthInterval)
+	}
+}
+
+// flushHistory writes the history to disk, e.g. before the daemon exits.
+func flushHistory() {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	saveHistory()
+}
+
+// usageStats summarizes the history of the last days up to now.
+func usageStats(now time.Time, days int) history.Stats {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	// Dates sort as strings.
+	from := now.Local().AddDate(0, 0, 1-days).Format(history.DayLayout)
+	i := 0
+	for i < len(usageDays) && usageDays[i].Date < from {
+		i++
+	}
+	return history.Summarize(usageDays[i:])
+}
+
+func getUsage(c *gin.Context) {
+	days := defaultUsageDays
+	if s := c.Query("days"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxUsageDays {
+			err = fmt.Errorf("days must be between 1 and %d", maxUsageDays)
+			c.IndentedJSON(http.StatusBadRequest, err.Error())
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		days = n
+	}
+
+	c.IndentedJSON(http.StatusOK, usageStats(time.Now(), days))
+}
+
+// monthReport sums up month, given as in history.MonthLayout. Reports of
+// past months are cached.
+func monthReport(month string, now time.Time) history.Report {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	if r, ok := reportCache.Get(month); ok {
+		return r
+	}
+	r := history.MonthReport(usageDays, month)
+	// Months sort as strings.
+	if month < now.Local().Format(history.MonthLayout) {
+		reportCache.Add(month, r)
+	}
+	return r
+}
+
+// getReport returns the report of ?month=YYYY-MM, by default last month.
+func getReport(c *gin.Context) {
+	month := c.Query("month")
+	if month == "" {
+		now := time.Now()
+		month = time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.Local).Format(history.MonthLayout)
+	} else if _, err := time.Parse(history.MonthLayout, month); err != nil {
+		err = errors.New("month must be in YYYY-MM format, e.g. 2025-09")
+		c.IndentedJSON(http.StatusBadRequest, err.Error())
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, monthReport(month, time.Now()))
+}
+
+// healthComparison compares the battery health with the baseline of model,
+// with the health curve recorded in the history.
+func healthComparison(model string, cycles, health int) history.Comparison {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	c := history.Compare(model, cycles, health)
+	curve, ok := curveCache.Get("")
+	if !ok {
+		curve = history.Curve(usageDays)
+		curveCache.Add("", curve)
+	}
+	c.Curve = curve
+	return c
+}
+
+func getHealthComparison(c *gin.Context) {
+	info, err := powerkit.GetSystemInfo(powerkit.FetchOptions{QueryIOKit: true, QuerySMC: false})
+	if err != nil || info == nil || info.IOKit == nil {
+		if err == nil {
+			err = errors.New("battery information unavailable")
+		}
+		c.IndentedJSON(http.StatusInternalServerError, err.Error())
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	model, err := unix.Sysctl("hw.model")
+	if err != nil {
+		logrus.WithError(err).Debug("failed to read hardware model")
+	}
+
+	c.IndentedJSON(http.StatusOK, healthComparison(model, info.IOKit.Battery.CycleCount, info.IOKit.Calculations.HealthByMaxCapacity))
+}
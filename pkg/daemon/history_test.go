@@ -0,0 +1,160 @@
+package daemon
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charlie0129/batt/pkg/history"
+)
+
+func TestRecordUsage(t *testing.T) {
+	usageDays, lastUsageSample, discharging = nil, time.Time{}, false
+	t.Cleanup(func() { usageDays, lastUsageSample, discharging = nil, time.Time{}, false })
+	initHistory(filepath.Join(t.TempDir(), "batt.history.json"))
+
+	now := time.Date(2026, 3, 1, 20, 0, 0, 0, time.Local)
+	step := func(minutes int, pluggedIn bool, charge int, held bool) {
+		for i := 0; i < minutes; i++ {
+			recordUsage(now, pluggedIn, charge, held)
+			now = now.Add(time.Minute)
+		}
+	}
+
+	// An hour held at 90%, then two hours on battery from 90% to 50%.
+	step(60, true, 90, true)
+	for charge := 90; charge > 50; charge-- {
+		step(3, false, charge, false)
+	}
+	step(1, true, 50, false)
+	// A night asleep is not counted, and the next day starts a new record.
+	now = now.Add(8 * time.Hour)
+	step(30, true, 60, false)
+
+	s := usageStats(now, 30)
+	if s.Days != 2 {
+		t.Fatalf("expected 2 days of data, got %d", s.Days)
+	}
+	if s.Discharges != 1 || s.AverageDischarge != 40 {
+		t.Errorf("expected one 40%% discharge, got %d of %.1f%%", s.Discharges, s.AverageDischarge)
+	}
+	if s.OnBatteryHours < 1.95 || s.OnBatteryHours > 2.05 {
+		t.Errorf("expected 2 hours on battery, got %.2f", s.OnBatteryHours)
+	}
+	if s.PluggedInHours < 1.45 || s.PluggedInHours > 1.55 {
+		t.Errorf("expected 1.5 hours plugged in, got %.2f", s.PluggedInHours)
+	}
+	if s.HeldHours < 0.95 || s.HeldHours > 1.05 {
+		t.Errorf("expected 1 hour held, got %.2f", s.HeldHours)
+	}
+	if s.HighChargeHours < 1.45 || s.HighChargeHours > 1.5 {
+		t.Errorf("expected about 1.5 hours above 80%%, got %.2f", s.HighChargeHours)
+	}
+
+	// Only the last day is within a 1-day window.
+	if s := usageStats(now, 1); s.Days != 1 || s.OnBatteryHours != 0 {
+		t.Errorf("expected only the last day, got %d days and %.2f hours on battery", s.Days, s.OnBatteryHours)
+	}
+
+	// The history survives a restart.
+	flushHistory()
+	usageDays = nil
+	initHistory(historyPath)
+	if got := usageStats(now, 30); got != s {
+		t.Errorf("expected the same statistics after reloading, got %+v, want %+v", got, s)
+	}
+}
+
+func TestRecordUsageWhileIdle(t *testing.T) {
+	usageDays, lastUsageSample, discharging = nil, time.Time{}, false
+	t.Cleanup(func() { usageDays, lastUsageSample, discharging = nil, time.Time{}, false })
+	initHistory(filepath.Join(t.TempDir(), "batt.history.json"))
+
+	// Held at the limit, polling backs off to idlePollInterval. Each loop
+	// also takes a moment, so they are a bit further apart than that.
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.Local)
+	step := idlePollInterval + 2*time.Second
+	for i := 0; i <= 60; i++ {
+		recordUsage(now, true, 80, true)
+		now = now.Add(step)
+	}
+
+	want := (60 * step).Hours()
+	s := usageStats(now, 1)
+	if s.PluggedInHours < want-0.01 || s.PluggedInHours > want+0.01 {
+		t.Errorf("expected %.2f hours plugged in, got %.2f", want, s.PluggedInHours)
+	}
+	if s.HeldHours < want-0.01 || s.HeldHours > want+0.01 {
+		t.Errorf("expected %.2f hours held, got %.2f", want, s.HeldHours)
+	}
+}
+
+func TestMonthReport(t *testing.T) {
+	usageDays = []history.Day{
+		{Date: "2026-08-30", PluggedInSeconds: 3600, CycleCount: 100, Health: 95},
+		{Date: "2026-09-01", PluggedInSeconds: 3600, HeldSeconds: 3600, ChargeSum: 80 * 3600, CycleCount: 101, Health: 95},
+		{Date: "2026-09-15", OnBatterySeconds: 3600, ChargeSum: 40 * 3600},
+		{Date: "2026-09-30", PluggedInSeconds: 3600, HeldSeconds: 1800, ChargeSum: 60 * 3600, CycleCount: 104, Health: 94},
+		{Date: "2026-10-01", PluggedInSeconds: 3600, CycleCount: 105, Health: 94},
+	}
+	reportCache.Purge()
+	t.Cleanup(func() { usageDays = nil; reportCache.Purge() })
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.Local)
+
+	r := monthReport("2026-09", now)
+	if r.Days != 3 {
+		t.Fatalf("expected 3 days in September, got %d", r.Days)
+	}
+	if r.CyclesAdded != 4 {
+		t.Errorf("expected 4 cycles added since the end of August, got %d", r.CyclesAdded)
+	}
+	if r.HealthStart != 95 || r.HealthEnd != 94 {
+		t.Errorf("expected health from 95%% to 94%%, got %d%% to %d%%", r.HealthStart, r.HealthEnd)
+	}
+	if r.AverageCharge != 60 || r.HeldHours != 1.5 {
+		t.Errorf("expected 60%% average charge and 1.5 h held, got %.1f%% and %.1f h", r.AverageCharge, r.HeldHours)
+	}
+
+	// Past months are cached, the current one is not.
+	hits := reportCache.Stats().Hits
+	if cached := monthReport("2026-09", now); cached != r || reportCache.Stats().Hits != hits+1 {
+		t.Errorf("expected the report of a past month from the cache, got %+v", cached)
+	}
+	monthReport("2026-10", now)
+	if _, ok := reportCache.Get("2026-10"); ok {
+		t.Error("the report of the current month was cached")
+	}
+
+	if r := monthReport("2026-07", now); r.Days != 0 || r.CyclesAdded != 0 || r.HealthEnd != 0 {
+		t.Errorf("expected an empty report for a month without data, got %+v", r)
+	}
+}
+
+func TestHealthComparison(t *testing.T) {
+	usageDays = []history.Day{
+		{Date: "2026-08-01", CycleCount: 300, Health: 96},
+		{Date: "2026-08-02"},
+		{Date: "2026-08-03", CycleCount: 300, Health: 95},
+		{Date: "2026-09-01", CycleCount: 320, Health: 95},
+	}
+	curveCache.Purge()
+	t.Cleanup(func() { usageDays = nil; curveCache.Purge() })
+
+	c := healthComparison("MacBookPro18,3", 500, 95)
+	if c.Expected != 90 {
+		t.Errorf("expected 90%% health at 500 cycles, got %.1f%%", c.Expected)
+	}
+	if c.Verdict != history.VerdictBetter || c.Baseline.Name != "14-inch MacBook Pro (2021)" {
+		t.Errorf("expected better than typical for a 14-inch MacBook Pro, got %s for %s", c.Verdict, c.Baseline.Name)
+	}
+	if len(c.Curve) != 2 || c.Curve[0] != (history.CurvePoint{Cycles: 300, Health: 95}) {
+		t.Errorf("expected the last reading at each cycle count, got %+v", c.Curve)
+	}
+
+	if c := healthComparison("Unknown1,1", 1200, 75); c.Verdict != history.VerdictTypical || c.Expected != 76 {
+		t.Errorf("expected 75%% to be typical against 76%% at 1200 cycles, got %s against %.1f%%", c.Verdict, c.Expected)
+	}
+	if c := healthComparison("MacBookAir10,1", 10, 101); c.Verdict != history.VerdictTooEarly {
+		t.Errorf("expected too few cycles to compare, got %s", c.Verdict)
+	}
+}
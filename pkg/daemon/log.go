@@ -0,0 +1,226 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// maxLogSize is the size above which the log is rotated.
+	maxLogSize = 10 << 20
+	// logRotateInterval is how often the log size is checked.
+	logRotateInterval = time.Hour
+	// defaultLogTailLines and maxLogTailLines bound GET /log.
+	defaultLogTailLines = 100
+	maxLogTailLines     = 5000
+)
+
+// logPath is where launchd writes the daemon's output, empty if unknown.
+var logPath string
+
+// tailLog returns the last lines of the file at path.
+func tailLog(path string, lines int) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	// Log lines are rarely longer than this, so read just the end.
+	offset := max(0, fi.Size()-int64(lines)*512)
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return "", err
+	}
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return "", err
+	}
+
+	s := strings.TrimSuffix(string(b), "\n")
+	all := strings.Split(s, "\n")
+	if offset > 0 && len(all) > 0 {
+		// The first line is likely cut off.
+		all = all[1:]
+	}
+	if len(all) > lines {
+		all = all[len(all)-lines:]
+	}
+	return strings.Join(all, "\n"), nil
+}
+
+// rotateLog keeps the log at path as path.1 and empties it. The file is
+// truncated instead of renamed, because launchd keeps writing to the file it
+// opened in append mode.
+//
+// The log lives in /tmp by default, where any user can create files, so
+// path.1 is removed and created anew instead of being opened, and symlinks
+// are never followed. Otherwise root could be made to overwrite any file.
+func rotateLog(path string) error {
+	src, err := os.OpenFile(path, os.O_RDONLY|unix.O_NOFOLLOW, 0)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	old := path + ".1"
+	if err := os.Remove(old); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	dst, err := os.OpenFile(old, os.O_WRONLY|os.O_CREATE|os.O_EXCL|unix.O_NOFOLLOW, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	return truncateLog(path)
+}
+
+// truncateLog empties the log at path without following symlinks.
+func truncateLog(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|unix.O_NOFOLLOW, 0)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// logRotateLoop rotates the log when it grows above maxLogSize.
+func logRotateLoop() {
+	if logPath == "" {
+		return
+	}
+	ticker := time.NewTicker(logRotateInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		timers.Fire(timerLogRotate, logRotateInterval)
+		fi, err := os.Stat(logPath)
+		if err != nil || fi.Size() <= maxLogSize {
+			continue
+		}
+		if err := rotateLog(logPath); err != nil {
+			logrus.WithError(err).Error("failed to rotate log")
+			continue
+		}
+		logrus.WithField("size", fi.Size()).Info("rotated log")
+	}
+}
+
+// requireLogPath aborts the request if the log location is unknown.
+func requireLogPath(c *gin.Context) bool {
+	if logPath != "" {
+		return true
+	}
+	err := errors.New("the daemon does not know where its log is written, see --log-path")
+	c.IndentedJSON(http.StatusNotImplemented, err.Error())
+	_ = c.AbortWithError(http.StatusNotImplemented, err)
+	return false
+}
+
+func getLog(c *gin.Context) {
+	if !requireLogPath(c) {
+		return
+	}
+	lines := defaultLogTailLines
+	if s := c.Query("lines"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxLogTailLines {
+			err = fmt.Errorf("lines must be between 1 and %d", maxLogTailLines)
+			c.IndentedJSON(http.StatusBadRequest, err.Error())
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		lines = n
+	}
+
+	s, err := tailLog(logPath, lines)
+	if err != nil {
+		logrus.Errorf("getLog failed: %v", err)
+		c.IndentedJSON(http.StatusInternalServerError, err.Error())
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, s)
+}
+
+func getLogLevel(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, logrus.GetLevel().String())
+}
+
+func setLogLevel(c *gin.Context) {
+	var s string
+	if err := c.BindJSON(&s); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, err.Error())
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	if !authorizeAdmin(c, "changing the log level") {
+		return
+	}
+	level, err := logrus.ParseLevel(s)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, err.Error())
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	logrus.SetLevel(level)
+	logrus.Infof("set log level to %s", level)
+
+	c.IndentedJSON(http.StatusCreated, fmt.Sprintf("log level set to %s until the daemon restarts", level))
+}
+
+func postRotateLog(c *gin.Context) {
+	if !requireLogPath(c) || !authorizeAdmin(c, "rotating the log") {
+		return
+	}
+	if err := rotateLog(logPath); err != nil {
+		logrus.Errorf("postRotateLog failed: %v", err)
+		c.IndentedJSON(http.StatusInternalServerError, err.Error())
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	logrus.Info("rotated log")
+
+	c.IndentedJSON(http.StatusCreated, "log rotated, the previous one is kept as "+logPath+".1")
+}
+
+func postClearLog(c *gin.Context) {
+	if !requireLogPath(c) || !authorizeAdmin(c, "clearing the log") {
+		return
+	}
+	if err := truncateLog(logPath); err != nil {
+		logrus.Errorf("postClearLog failed: %v", err)
+		c.IndentedJSON(http.StatusInternalServerError, err.Error())
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	logrus.Info("cleared log")
+
+	c.IndentedJSON(http.StatusCreated, "log cleared")
+}
@@ -0,0 +1,94 @@
+package daemon
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTailAndRotateLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "batt.log")
+	var b strings.Builder
+	for i := 1; i <= 1000; i++ {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := tailLog(path, 3)
+	if err != nil {
+		t.Fatalf("tailLog failed: %v", err)
+	}
+	if s != "line 998\nline 999\nline 1000" {
+		t.Errorf("tailLog = %q", s)
+	}
+	if s, _ := tailLog(path, 5000); !strings.HasPrefix(s, "line 1\n") {
+		t.Errorf("tailLog of the whole file starts with %q", s[:20])
+	}
+
+	if err := rotateLog(path); err != nil {
+		t.Fatalf("rotateLog failed: %v", err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Size() != 0 {
+		t.Errorf("expected an empty log after rotating, got %v, %v", fi, err)
+	}
+	if old, err := os.ReadFile(path + ".1"); err != nil || string(old) != b.String() {
+		t.Errorf("expected the previous log kept, got %d bytes, %v", len(old), err)
+	}
+}
+
+func TestRotateLogDoesNotFollowSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "batt.log")
+	if err := os.WriteFile(path, []byte("log\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Another user plants a symlink where the previous log is kept.
+	target := filepath.Join(dir, "important")
+	if err := os.WriteFile(target, []byte("keep me"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rotateLog(path); err != nil {
+		t.Fatalf("rotateLog failed: %v", err)
+	}
+	if b, _ := os.ReadFile(target); string(b) != "keep me" {
+		t.Errorf("rotateLog wrote through the symlink, target is now %q", b)
+	}
+	if fi, err := os.Lstat(path + ".1"); err != nil || !fi.Mode().IsRegular() || fi.Mode().Perm() != 0600 {
+		t.Errorf("expected the previous log as a regular 0600 file, got %v, %v", fi, err)
+	}
+}
+
+func TestLogActionsRequireAdmin(t *testing.T) {
+	origPath := logPath
+	logPath = filepath.Join(t.TempDir(), "batt.log")
+	t.Cleanup(func() { logPath = origPath })
+	if err := os.WriteFile(logPath, []byte("log\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, h := range []gin.HandlerFunc{postRotateLog, postClearLog} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		// Not over the unix socket, so the user is unknown.
+		c.Request = httptest.NewRequest(http.MethodPost, "/log", nil)
+		h(c)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+		}
+	}
+	if b, _ := os.ReadFile(logPath); string(b) != "log\n" {
+		t.Errorf("log changed by a non-admin request: %q", b)
+	}
+}
@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"fmt"
 	"reflect"
 	"sync"
 	"time"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/charlie0129/batt/pkg/calibration"
 	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/events"
 	"github.com/charlie0129/batt/pkg/smc"
 )
 
@@ -20,14 +22,34 @@ var (
 	loopInterval            = time.Duration(10) * time.Second
 	loopRecorder            = NewTimeSeriesRecorder(60)
 	continuousLoopThreshold = 1*time.Minute + 20*time.Second // add 20s to be sure
+	// unhealthyLoopFailures is how many maintain loops in a row may fail
+	// before the daemon reports itself unhealthy.
+	unhealthyLoopFailures = 6
 )
 
 // infiniteLoop runs forever and maintains the battery charge,
 // which is called by the daemon.
 func infiniteLoop() {
+	failures := 0
 	for {
-		maintainLoop()
-		time.Sleep(loopInterval)
+		if maintainLoop() {
+			failures = 0
+		} else {
+			failures++
+		}
+		if failures == unhealthyLoopFailures && sseHub != nil {
+			msg := fmt.Sprintf("The last %d maintain loops failed, batt may not be controlling charging.", failures)
+			if logPath != "" {
+				msg += " Check " + logPath + "."
+			}
+			sseHub.Publish(events.DaemonUnhealthy, events.DaemonUnhealthyEvent{
+				Message: msg,
+				Ts:      time.Now().Unix(),
+			})
+		}
+		poller.sleep()
+		interval, _ := poller.current()
+		timers.Fire(timerMaintainLoop, interval)
 	}
 }
 
@@ -36,6 +58,17 @@ func infiniteLoop() {
 // with the maintain loop execution.
 // It returns true if there are too many missed loops.
 func checkMissedMaintainLoops(logStatus bool) bool {
+	// Loops are up to idlePollInterval apart when polling backed off, so
+	// only a larger gap, e.g. sleep, counts as missed then.
+	if poller.sparseLoops(time.Now()) {
+		start, ok := loopRecorder.ContinuousSince(idlePollInterval + time.Second)
+		missed := !ok || time.Since(start) < continuousLoopThreshold
+		if missed && logStatus {
+			logrus.WithField("recentRecords", formatRelativeTimes(loopRecorder.GetLastRecords(continuousLoopThreshold))).Infof("Possibly missed maintain loop")
+		}
+		return missed
+	}
+
 	maintainLoopCount := loopRecorder.GetRecordsIn(continuousLoopThreshold)
 	expectedMaintainLoopCount := int(continuousLoopThreshold / loopInterval)
 	minMaintainLoopCount := expectedMaintainLoopCount - 1
@@ -109,7 +142,7 @@ func maintainLoopForced() bool {
 func handleNoMaintain(isChargingEnabled bool) bool {
 	if !isChargingEnabled {
 		logrus.Debug("limit set to 100%, but charging is disabled, enabling")
-		err := smcConn.EnableCharging()
+		err := smcEnableCharging()
 		if err != nil {
 			logrus.Errorf("EnableCharging failed: %v", err)
 			return false
@@ -180,6 +213,8 @@ func handleNoMaintain(isChargingEnabled bool) bool {
 func handleChargingLogic(ignoreMissedLoops, isChargingEnabled, isPluggedIn bool, batteryCharge, lower, upper int) bool {
 	maintainLoopsMissed := checkMissedMaintainLoops(false)
 
+	_ = checkChargingConflict(isChargingEnabled, maintainLoopsMissed)
+
 	// Fix for #123.
 	// Consider this case:
 	//   1. charging is enabled (batteryCharge < lower)
@@ -195,7 +230,7 @@ func handleChargingLogic(ignoreMissedLoops, isChargingEnabled, isPluggedIn bool,
 			"lower":         lower,
 			"upper":         upper,
 		}).Infof("Too many missed maintain loops detected while charging is enabled. Disabling charging to prevent overcharging.")
-		err := smcConn.DisableCharging()
+		err := smcDisableCharging()
 		if err != nil {
 			logrus.Errorf("DisableCharging failed: %v", err)
 			return false
@@ -226,7 +261,7 @@ func handleChargingLogic(ignoreMissedLoops, isChargingEnabled, isPluggedIn bool,
 			"lower":         lower,
 			"upper":         upper,
 		}).Infof("Battery charge is below lower limit, enabling charging")
-		err := smcConn.EnableCharging()
+		err := smcEnableCharging()
 		if err != nil {
 			logrus.Errorf("EnableCharging failed: %v", err)
 			return false
@@ -242,13 +277,21 @@ func handleChargingLogic(ignoreMissedLoops, isChargingEnabled, isPluggedIn bool,
 			"lower":         lower,
 			"upper":         upper,
 		}).Infof("Battery charge is above upper limit, disabling charging")
-		err := smcConn.DisableCharging()
+		err := smcDisableCharging()
 		if err != nil {
 			logrus.Errorf("DisableCharging failed: %v", err)
 			return false
 		}
 		isChargingEnabled = false
 		maintainedChargingInProgress = false
+
+		if sseHub != nil {
+			sseHub.Publish(events.LimitReached, events.LimitReachedEvent{
+				Charge: batteryCharge,
+				Limit:  upper,
+				Ts:     time.Now().Unix(),
+			})
+		}
 	}
 
 	switch conf.ControlMagSafeLED() {
@@ -277,6 +320,8 @@ func handleChargingLogic(ignoreMissedLoops, isChargingEnabled, isPluggedIn bool,
 	// batteryCharge >= upper - delta && batteryCharge < upper
 	// do nothing, keep as-is
 
+	expectChargingEnabled(isChargingEnabled)
+
 	return true
 }
 
@@ -284,6 +329,10 @@ func maintainLoopInner(ignoreMissedLoops bool) bool {
 	maintainLoopInnerLock.Lock()
 	defer maintainLoopInnerLock.Unlock()
 
+	refreshManaged()
+	checkTravelModeExpiry(time.Now())
+	recordPolicyTransition(time.Now())
+
 	upper := conf.UpperLimit()
 	lower := conf.LowerLimit()
 	maintain := upper < 100
@@ -307,11 +356,43 @@ func maintainLoopInner(ignoreMissedLoops bool) bool {
 		return false
 	}
 
+	publishPowerSourceChange(isPluggedIn, batteryCharge)
+	recordChargeRate(isChargingEnabled && isPluggedIn, batteryCharge, time.Now())
+	if maintain && chargeByActive(batteryCharge, time.Now()) {
+		upper, lower, maintain = 100, 100-(upper-lower), false
+	}
+	if checkWeakAdapter(isPluggedIn, time.Now()) && maintain {
+		upper, lower, maintain = 100, 100-(upper-lower), false
+	}
+
+	pollLower := lower
+	if !maintain {
+		pollLower = 0
+	}
+	if level := getBypassLevel(); level > 0 {
+		pollLower = level - bypassMargin
+	}
+	poller.observe(pollInput{
+		pluggedIn:       isPluggedIn,
+		chargingEnabled: isChargingEnabled,
+		charge:          batteryCharge,
+		lower:           pollLower,
+		calibrating:     calibrationState.Phase != calibration.PhaseIdle,
+	}, time.Now())
+
+	sseHub.Publish(events.BatterySample, events.BatterySampleEvent{
+		PluggedIn: isPluggedIn,
+		Charge:    batteryCharge,
+		Held:      isPluggedIn && !isChargingEnabled && maintain,
+		Ts:        time.Now().Unix(),
+	})
 	maintainedChargingInProgress = isChargingEnabled && isPluggedIn && calibrationState.Phase == calibration.PhaseIdle
 	printStatus(batteryCharge, lower, upper, isChargingEnabled, isPluggedIn, maintainedChargingInProgress, calibrationState.Phase != calibration.PhaseIdle)
 
 	// If calibration is active, advance it and skip normal maintain logic.
 	if applyCalibrationWithinLoop(batteryCharge) {
+		// Calibration drives charging itself.
+		forgetExpectedCharging()
 		switch conf.ControlMagSafeLED() {
 		case config.ControlMagSafeModeAlwaysOff:
 			_ = smcConn.DisableMagSafeLed()
@@ -323,14 +404,62 @@ func maintainLoopInner(ignoreMissedLoops bool) bool {
 		return true
 	}
 
+	applyStorageDischarge(batteryCharge)
+
+	if applyChargingPause(isChargingEnabled) {
+		return true
+	}
+
+	if applyThermalLimits(isChargingEnabled) {
+		return true
+	}
+
+	// While bypassing the battery, charging stays disabled so the adapter
+	// powers the Mac, and only resumes if the charge drops anyway.
+	if level := getBypassLevel(); level > 0 {
+		return handleChargingLogic(ignoreMissedLoops, isChargingEnabled, isPluggedIn, batteryCharge, level-bypassMargin, level)
+	}
+
 	// If maintain is disabled, we don't care about the battery charge, enable charging anyway.
 	if !maintain {
+		forgetExpectedCharging()
 		return handleNoMaintain(isChargingEnabled)
 	}
 
+	// Let macOS decide while it is holding the charge, if the user asked to.
+	if handleOptimizedCharging(isChargingEnabled, isPluggedIn, batteryCharge, upper) {
+		forgetExpectedCharging()
+		return handleNoMaintain(isChargingEnabled)
+	}
+
+	_ = checkDrainWhilePluggedIn(isChargingEnabled, isPluggedIn, batteryCharge, lower, time.Now())
+
 	return handleChargingLogic(ignoreMissedLoops, isChargingEnabled, isPluggedIn, batteryCharge, lower, upper)
 }
 
+// lastPluggedIn is nil until the first loop, so starting up is not reported
+// as a change.
+var lastPluggedIn *bool
+
+// publishPowerSourceChange tells subscribers when the power adapter is
+// connected or disconnected.
+func publishPowerSourceChange(isPluggedIn bool, batteryCharge int) {
+	prev := lastPluggedIn
+	lastPluggedIn = &isPluggedIn
+	if prev == nil || *prev == isPluggedIn || sseHub == nil {
+		return
+	}
+
+	name := events.AdapterDisconnected
+	if isPluggedIn {
+		name = events.AdapterConnected
+	}
+	sseHub.Publish(name, events.PowerSourceEvent{
+		Charge: batteryCharge,
+		Ts:     time.Now().Unix(),
+	})
+}
+
 func updateMagSafeLed(isChargingEnabled bool) {
 	err := smcConn.SetMagSafeCharging(isChargingEnabled)
 	if err != nil {
@@ -383,8 +512,9 @@ func printStatus(
 
 	defer func() { lastPrintTime = time.Now() }()
 
-	// Skip printing if the last print was less than loopInterval+1 seconds ago and everything is the same.
-	if time.Since(lastPrintTime) < loopInterval+time.Second && reflect.DeepEqual(lastStatus, currentStatus) {
+	// Skip printing if the last print was less than one poll interval plus 1 second ago and everything is the same.
+	interval, _ := poller.current()
+	if time.Since(lastPrintTime) < interval+time.Second && reflect.DeepEqual(lastStatus, currentStatus) {
 		logrus.WithFields(fields).Trace("status")
 		return
 	}
@@ -4,6 +4,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/charlie0129/batt/pkg/events"
 )
 
 func TestMaintainLoopRecorder_GetRecordsIn(t *testing.T) {
@@ -90,3 +92,28 @@ func TestMaintainLoopRecorder_GetRecordsIn(t *testing.T) {
 		})
 	}
 }
+
+func TestPublishPowerSourceChange(t *testing.T) {
+	sseHub = events.NewEventHub()
+	ch := sseHub.Subscribe()
+	t.Cleanup(func() { sseHub.Unsubscribe(ch); sseHub = nil; lastPluggedIn = nil })
+
+	lastPluggedIn = nil
+	publishPowerSourceChange(true, 50)
+	publishPowerSourceChange(true, 51)
+	publishPowerSourceChange(false, 51)
+
+	select {
+	case ev := <-ch:
+		if ev.Name != events.AdapterDisconnected {
+			t.Fatalf("event = %s, want %s", ev.Name, events.AdapterDisconnected)
+		}
+	default:
+		t.Fatal("expected an event when unplugged")
+	}
+	select {
+	case ev := <-ch:
+		t.Fatalf("unexpected event %s, startup and no change should not be reported", ev.Name)
+	default:
+	}
+}
@@ -0,0 +1,173 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/limits"
+)
+
+var (
+	managedMu sync.Mutex
+	// managedPath is a variable so tests can point it elsewhere.
+	managedPath = config.ManagedPath
+	// managed is nil when no configuration profile is installed.
+	managed        *config.Managed
+	managedModTime time.Time
+)
+
+// loadManaged reads the managed preferences plist. macOS may store it in
+// binary form, so let plutil convert it to JSON.
+func loadManaged(path string) (*config.Managed, error) {
+	out, err := exec.Command("/usr/bin/plutil", "-convert", "json", "-o", "-", path).Output()
+	if err != nil {
+		return nil, err
+	}
+	var m config.Managed
+	if err := json.Unmarshal(out, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// refreshManaged picks up configuration profiles that were installed,
+// changed or removed, and enforces the managed settings on the config. It
+// is called on every maintain loop, and only does real work when the plist
+// changes.
+func refreshManaged() {
+	managedMu.Lock()
+	defer managedMu.Unlock()
+
+	var modTime time.Time
+	fi, err := os.Stat(managedPath)
+	if err == nil {
+		modTime = fi.ModTime()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		logrus.WithError(err).Warn("failed to stat managed preferences")
+		return
+	}
+	if modTime.Equal(managedModTime) {
+		return
+	}
+	managedModTime = modTime
+
+	if modTime.IsZero() {
+		if managed != nil {
+			logrus.Info("configuration profile removed, settings are no longer managed")
+		}
+		managed = nil
+		return
+	}
+
+	m, err := loadManaged(managedPath)
+	if err != nil {
+		logrus.WithError(err).Error("failed to load managed preferences")
+		return
+	}
+	managed = m
+	applyManaged(m)
+}
+
+// applyManaged enforces m on the config. Invalid values are ignored.
+func applyManaged(m *config.Managed) {
+	changed, limitChanged := false, false
+
+	// Reject a bad delta up front, before ending travel or storage mode.
+	// The config setters panic if the lower limit reaches the upper limit.
+	if m.LowerLimitDelta != nil && *m.LowerLimitDelta < limits.MinLowerDelta {
+		logrus.WithField("lowerLimitDelta", *m.LowerLimitDelta).Error("ignoring managed limit, lower limit delta must be at least 1")
+		m.Limit, m.LowerLimitDelta = nil, nil
+	}
+
+	if m.LimitLocked() {
+		// Travel and storage mode change the limit on their own, so end
+		// them before applying the managed limit.
+		if err := disableTravelMode(); err != nil {
+			logrus.WithError(err).Error("failed to disable travel mode")
+		}
+		if err := disableStorageMode(); err != nil {
+			logrus.WithError(err).Error("failed to disable storage mode")
+		}
+
+		upper, delta := conf.UpperLimit(), conf.UpperLimit()-conf.LowerLimit()
+		if m.Limit != nil {
+			upper = *m.Limit
+		}
+		if m.LowerLimitDelta != nil {
+			delta = *m.LowerLimitDelta
+		}
+		err := limits.ValidateUpper(upper, delta)
+		if err == nil {
+			err = limits.ValidateLowerDelta(upper, delta)
+		}
+		if err != nil {
+			logrus.WithError(err).Error("ignoring managed limit")
+			m.Limit, m.LowerLimitDelta = nil, nil
+		} else if upper != conf.UpperLimit() || delta != conf.UpperLimit()-conf.LowerLimit() {
+			if err := setLimits(upper, delta); err != nil {
+				logrus.WithError(err).Error("failed to apply managed limit")
+			} else {
+				limitChanged = true
+			}
+		}
+	}
+	if limitChanged {
+		conf.SetLimitChangedBy("configuration profile", time.Now())
+		changed = true
+	}
+	if m.EnergySampling != nil && *m.EnergySampling != conf.EnergySampling() {
+		conf.SetEnergySampling(*m.EnergySampling)
+		changed = true
+	}
+
+	logrus.WithField("managed", m).Info("applied configuration profile")
+	if !changed {
+		return
+	}
+	if err := conf.Save(); err != nil {
+		logrus.Errorf("saveConfig failed: %v", err)
+	}
+}
+
+func currentManaged() *config.Managed {
+	managedMu.Lock()
+	defer managedMu.Unlock()
+	return managed
+}
+
+// rejectManaged aborts the request if locked is true.
+func rejectManaged(c *gin.Context, locked bool, what string) bool {
+	if !locked {
+		return false
+	}
+	err := errors.New(what + " is managed by your organization")
+	c.IndentedJSON(http.StatusForbidden, err.Error())
+	_ = c.AbortWithError(http.StatusForbidden, err)
+	return true
+}
+
+// forgetManaged makes the next refreshManaged apply the profile again, e.g.
+// after the config is reloaded from disk.
+func forgetManaged() {
+	managedMu.Lock()
+	defer managedMu.Unlock()
+	managedModTime = time.Time{}
+	managed = nil
+}
+
+func getManaged(c *gin.Context) {
+	m := currentManaged()
+	if m == nil {
+		m = &config.Managed{}
+	}
+	c.IndentedJSON(http.StatusOK, m)
+}
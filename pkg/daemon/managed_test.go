@@ -0,0 +1,68 @@
+package daemon
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/utils/ptr"
+)
+
+func TestApplyManaged(t *testing.T) {
+	tests := []struct {
+		name      string
+		managed   config.Managed
+		wantUpper int
+		wantLower int
+		wantBy    string
+	}{
+		{name: "raise limit", managed: config.Managed{Limit: ptr.To(90)}, wantUpper: 90, wantLower: 85, wantBy: "configuration profile"},
+		{name: "lower limit", managed: config.Managed{Limit: ptr.To(60)}, wantUpper: 60, wantLower: 55, wantBy: "configuration profile"},
+		{name: "limit and delta", managed: config.Managed{Limit: ptr.To(60), LowerLimitDelta: ptr.To(10)}, wantUpper: 60, wantLower: 50, wantBy: "configuration profile"},
+		{name: "invalid limit", managed: config.Managed{Limit: ptr.To(5)}, wantUpper: 80, wantLower: 75},
+		{name: "zero delta", managed: config.Managed{Limit: ptr.To(60), LowerLimitDelta: ptr.To(0)}, wantUpper: 80, wantLower: 75},
+		{name: "delta too large", managed: config.Managed{LowerLimitDelta: ptr.To(75)}, wantUpper: 80, wantLower: 75},
+		{name: "unchanged", managed: config.Managed{Limit: ptr.To(80)}, wantUpper: 80, wantLower: 75},
+		{name: "energy sampling only", managed: config.Managed{EnergySampling: ptr.To(true)}, wantUpper: 80, wantLower: 75},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf = config.NewFileFromConfig(&config.RawFileConfig{
+				Limit:           ptr.To(80),
+				LowerLimitDelta: ptr.To(5),
+			}, filepath.Join(t.TempDir(), "batt.json"))
+
+			m := tt.managed
+			applyManaged(&m)
+			if got := conf.UpperLimit(); got != tt.wantUpper {
+				t.Errorf("UpperLimit() = %d, want %d", got, tt.wantUpper)
+			}
+			if got := conf.LowerLimit(); got != tt.wantLower {
+				t.Errorf("LowerLimit() = %d, want %d", got, tt.wantLower)
+			}
+			if got, _ := conf.LimitChangedBy(); got != tt.wantBy {
+				t.Errorf("LimitChangedBy() = %q, want %q", got, tt.wantBy)
+			}
+			if tt.managed.EnergySampling != nil && conf.EnergySampling() != *tt.managed.EnergySampling {
+				t.Errorf("EnergySampling() = %t, want %t", conf.EnergySampling(), *tt.managed.EnergySampling)
+			}
+		})
+	}
+}
+
+func TestApplyManagedEndsTravelMode(t *testing.T) {
+	sseHub = nil
+	conf = config.NewFileFromConfig(&config.RawFileConfig{
+		Limit:           ptr.To(100),
+		LowerLimitDelta: ptr.To(2),
+		TravelMode:      &config.TravelMode{Limit: 80},
+	}, filepath.Join(t.TempDir(), "batt.json"))
+
+	applyManaged(&config.Managed{Limit: ptr.To(70)})
+	if conf.TravelMode() != nil {
+		t.Error("travel mode should end when the limit is managed")
+	}
+	if got := conf.UpperLimit(); got != 70 {
+		t.Errorf("UpperLimit() = %d, want 70", got)
+	}
+}
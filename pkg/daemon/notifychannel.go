@@ -0,0 +1,115 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/events"
+)
+
+var (
+	// pushoverURL and telegramAPI are test seams.
+	pushoverURL = "https://api.pushover.net/1/messages.json"
+	telegramAPI = "https://api.telegram.org"
+)
+
+// eventTitles are the notification titles of events.
+var eventTitles = map[string]string{
+	events.LimitReached:      "Charge limit reached",
+	events.BatteryDraining:   "Battery draining while plugged in",
+	events.WeakAdapter:       "Power adapter too weak",
+	events.ConflictDetected:  "Charge limiter conflict",
+	events.TravelModeEnded:   "Travel mode ended",
+	events.CalibrationPhase:  "Calibration",
+	events.CalibrationAction: "Calibration",
+	events.DaemonUnhealthy:   "batt is not working",
+}
+
+// describeEvent turns an event into a readable notification, for the
+// notification services that show text rather than JSON.
+func describeEvent(ev events.Event, host string) (title, message string) {
+	title = eventTitles[ev.Name]
+	switch ev.Name {
+	case events.LimitReached:
+		p, _ := events.DecodeAs[events.LimitReachedEvent](ev)
+		message = fmt.Sprintf("Charging stopped at %d%% (limit %d%%).", p.Charge, p.Limit)
+	case events.ChargingPaused:
+		p, _ := events.DecodeAs[events.ChargingPausedEvent](ev)
+		title, message = "Charging resumed", "Charging follows the charge limit again."
+		if p.Paused {
+			title, message = "Charging paused", "Charging is paused until resumed."
+		}
+	default:
+		// The other events carry a readable message.
+		message = ev.Message()
+	}
+
+	if title == "" {
+		title = ev.Name
+	}
+	if message == "" {
+		message = title + "."
+	}
+	if host != "" {
+		title += " on " + host
+	}
+	return title, message
+}
+
+func newNtfyRequest(w config.Webhook, ev events.Event, host string) (*http.Request, error) {
+	title, message := describeEvent(ev, host)
+	req, err := http.NewRequest(http.MethodPost, w.URL, strings.NewReader(message))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Title", title)
+	req.Header.Set("Tags", "battery")
+	if w.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+w.Token)
+	}
+	return req, nil
+}
+
+func newPushoverRequest(w config.Webhook, ev events.Event, host string) (*http.Request, error) {
+	if w.Token == "" || w.User == "" {
+		return nil, fmt.Errorf("pushover needs token and user")
+	}
+	title, message := describeEvent(ev, host)
+	form := url.Values{
+		"token":   {w.Token},
+		"user":    {w.User},
+		"title":   {title},
+		"message": {message},
+	}
+	req, err := http.NewRequest(http.MethodPost, pushoverURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
+
+func newTelegramRequest(w config.Webhook, ev events.Event, host string) (*http.Request, error) {
+	if w.Token == "" || w.ChatID == "" {
+		return nil, fmt.Errorf("telegram needs token and chatId")
+	}
+	title, message := describeEvent(ev, host)
+	body, err := json.Marshal(map[string]string{
+		"chat_id": w.ChatID,
+		"text":    title + "\n" + message,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, telegramAPI+"/bot"+w.Token+"/sendMessage", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
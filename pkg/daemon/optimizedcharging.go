@@ -0,0 +1,209 @@
+package daemon
+
+/*
+#cgo LDFLAGS: -framework CoreFoundation -framework IOKit
+
+#include <stdbool.h>
+#include <CoreFoundation/CoreFoundation.h>
+#include <IOKit/ps/IOPowerSources.h>
+#include <IOKit/ps/IOPSKeys.h>
+
+// Not in IOPSKeys.h, but present in the internal battery's description.
+#define kOptimizedChargingEngagedKey "Optimized Battery Charging Engaged"
+
+static bool cfIsTrue(CFTypeRef v) {
+	if (v == NULL) {
+		return false;
+	}
+	if (CFGetTypeID(v) == CFBooleanGetTypeID()) {
+		return CFBooleanGetValue((CFBooleanRef)v);
+	}
+	if (CFGetTypeID(v) == CFNumberGetTypeID()) {
+		int n = 0;
+		CFNumberGetValue((CFNumberRef)v, kCFNumberIntType, &n);
+		return n != 0;
+	}
+	return false;
+}
+
+// readInternalBattery reads the internal battery's power source description.
+// It returns false if there is no internal battery.
+static bool readInternalBattery(bool *optimizedEngaged, bool *isCharging) {
+	bool found = false;
+	CFTypeRef info = IOPSCopyPowerSourcesInfo();
+	if (info == NULL) {
+		return false;
+	}
+	CFArrayRef list = IOPSCopyPowerSourcesList(info);
+	if (list != NULL) {
+		for (CFIndex i = 0; i < CFArrayGetCount(list) && !found; i++) {
+			CFDictionaryRef desc = IOPSGetPowerSourceDescription(info, CFArrayGetValueAtIndex(list, i));
+			if (desc == NULL) {
+				continue;
+			}
+			CFStringRef type = CFDictionaryGetValue(desc, CFSTR(kIOPSTypeKey));
+			if (type == NULL || CFStringCompare(type, CFSTR(kIOPSInternalBatteryType), 0) != kCFCompareEqualTo) {
+				continue;
+			}
+			found = true;
+			*optimizedEngaged = cfIsTrue(CFDictionaryGetValue(desc, CFSTR(kOptimizedChargingEngagedKey)));
+			*isCharging = cfIsTrue(CFDictionaryGetValue(desc, CFSTR(kIOPSIsChargingKey)));
+		}
+		CFRelease(list);
+	}
+	CFRelease(info);
+	return found;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/conflict"
+	"github.com/charlie0129/batt/pkg/events"
+)
+
+// builtInLimit is the charge level the built-in limit of macOS holds at.
+const builtInLimit = 80
+
+var (
+	optimizedChargingMu sync.Mutex
+	// optimizedChargingWarned is set once the user has been warned during
+	// the current holding episode, so we do not warn every loop.
+	optimizedChargingWarned bool
+)
+
+// getOptimizedChargingStatus checks whether macOS is holding the charge,
+// either because Optimized Battery Charging is engaged or because of the
+// built-in charge limit of newer macOS.
+func getOptimizedChargingStatus(isChargingEnabled, isPluggedIn bool, batteryCharge, upper int) *conflict.OptimizedChargingStatus {
+	st := &conflict.OptimizedChargingStatus{
+		Mode: conf.OptimizedChargingMode(),
+	}
+
+	var engaged, isCharging C.bool
+	if !C.readInternalBattery(&engaged, &isCharging) {
+		return st
+	}
+	st.Engaged = bool(engaged)
+
+	// The built-in limit is not reported anywhere, so infer it: charging is
+	// allowed by batt, yet the battery is not charging right at 80%.
+	if !st.Engaged && isPluggedIn && isChargingEnabled && !bool(isCharging) &&
+		batteryCharge >= builtInLimit-2 && batteryCharge <= builtInLimit+2 && upper > batteryCharge+1 {
+		adapterEnabled, err := smcConn.IsAdapterEnabled()
+		st.BuiltInLimit = err == nil && adapterEnabled
+	}
+
+	return st
+}
+
+// handleOptimizedCharging applies the configured interplay with macOS. It
+// returns true if batt should hand charging over to macOS for this loop.
+func handleOptimizedCharging(isChargingEnabled, isPluggedIn bool, batteryCharge, upper int) bool {
+	mode := conf.OptimizedChargingMode()
+	if mode == config.OptimizedChargingModeCoexist {
+		return false
+	}
+	// Handing over would charge up to where macOS holds, past a lower limit
+	// of the user's, so only defer when batt's limit is at least as high.
+	if mode == config.OptimizedChargingModeDefer && upper < builtInLimit {
+		return false
+	}
+
+	st := getOptimizedChargingStatus(isChargingEnabled, isPluggedIn, batteryCharge, upper)
+
+	optimizedChargingMu.Lock()
+	defer optimizedChargingMu.Unlock()
+
+	if !st.Holding() {
+		optimizedChargingWarned = false
+		return false
+	}
+
+	switch mode {
+	case config.OptimizedChargingModeDefer:
+		if !optimizedChargingWarned {
+			logrus.WithFields(logrus.Fields{
+				"engaged":      st.Engaged,
+				"builtInLimit": st.BuiltInLimit,
+			}).Info("macOS is holding the charge, deferring to macOS")
+			optimizedChargingWarned = true
+		}
+		return true
+	case config.OptimizedChargingModeDisable:
+		if optimizedChargingWarned {
+			return false
+		}
+		optimizedChargingWarned = true
+		msg := "macOS Optimized Battery Charging is holding the charge, which interferes with batt. Turn it off in System Settings -> Battery -> Battery Health."
+		if st.BuiltInLimit {
+			msg = fmt.Sprintf("macOS seems to limit charging to %d%%, which interferes with batt. Turn off the charge limit in System Settings -> Battery -> Battery Health.", builtInLimit)
+		}
+		logrus.Warn(msg)
+		if sseHub != nil {
+			sseHub.Publish(events.ConflictDetected, events.ConflictDetectedEvent{
+				Message: msg,
+				Ts:      time.Now().Unix(),
+			})
+		}
+	}
+
+	return false
+}
+
+func getOptimizedCharging(c *gin.Context) {
+	isChargingEnabled, err := smcConn.IsChargingEnabled()
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, err.Error())
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	isPluggedIn, err := smcConn.IsPluggedIn()
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, err.Error())
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	batteryCharge, err := smcConn.GetBatteryCharge()
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, err.Error())
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, getOptimizedChargingStatus(isChargingEnabled, isPluggedIn, batteryCharge, conf.UpperLimit()))
+}
+
+func setOptimizedCharging(c *gin.Context) {
+	var mode config.OptimizedChargingMode
+	if err := c.BindJSON(&mode); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, err.Error())
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	conf.SetOptimizedChargingMode(mode)
+	if err := conf.Save(); err != nil {
+		logrus.Errorf("saveConfig failed: %v", err)
+		c.IndentedJSON(http.StatusInternalServerError, err.Error())
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	optimizedChargingMu.Lock()
+	optimizedChargingWarned = false
+	optimizedChargingMu.Unlock()
+
+	logrus.Infof("set optimized charging mode to %s", mode)
+
+	c.IndentedJSON(http.StatusCreated, fmt.Sprintf("Optimized charging mode set to %s", mode))
+}
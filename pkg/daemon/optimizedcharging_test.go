@@ -0,0 +1,22 @@
+package daemon
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/utils/ptr"
+)
+
+func TestHandleOptimizedChargingKeepsLowerLimit(t *testing.T) {
+	conf = config.NewFileFromConfig(&config.RawFileConfig{
+		Limit:             ptr.To(60),
+		OptimizedCharging: ptr.To(config.OptimizedChargingModeDefer),
+	}, filepath.Join(t.TempDir(), "batt.json"))
+
+	// macOS would hold at builtInLimit, above the user's limit of 60%, so
+	// batt keeps its own limit instead of handing charging over.
+	if handleOptimizedCharging(true, true, 60, conf.UpperLimit()) {
+		t.Error("handleOptimizedCharging() = true with a limit below the built-in limit")
+	}
+}
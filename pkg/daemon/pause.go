@@ -0,0 +1,119 @@
+package daemon
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/calibration"
+	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/events"
+)
+
+var (
+	chargingPausedMu sync.Mutex
+	// chargingPaused stops charging regardless of the limit until resumed.
+	// It is a quick action, so it is not saved and a daemon restart resumes
+	// charging.
+	chargingPaused bool
+)
+
+func isChargingPaused() bool {
+	chargingPausedMu.Lock()
+	defer chargingPausedMu.Unlock()
+	return chargingPaused
+}
+
+// applyChargingPause keeps charging disabled while charging is paused. It
+// returns true if the maintain loop should stop here.
+func applyChargingPause(isChargingEnabled bool) bool {
+	if !isChargingPaused() {
+		return false
+	}
+
+	keepChargingDisabled(isChargingEnabled, "charging is paused")
+	return true
+}
+
+// keepChargingDisabled disables charging regardless of the limit. why is
+// logged when charging gets disabled.
+func keepChargingDisabled(isChargingEnabled bool, why string) {
+	forgetExpectedCharging()
+	maintainedChargingInProgress = false
+	if isChargingEnabled {
+		logrus.Infof("%s, disabling charging", why)
+		if err := smcDisableCharging(); err != nil {
+			logrus.Errorf("DisableCharging failed: %v", err)
+			return
+		}
+	}
+
+	switch conf.ControlMagSafeLED() {
+	case config.ControlMagSafeModeAlwaysOff:
+		_ = smcConn.DisableMagSafeLed()
+	case config.ControlMagSafeModeEnabled:
+		updateMagSafeLed(false)
+	default:
+		// nothing
+	}
+
+	if err := AllowSleepOnAC(); err != nil {
+		logrus.Errorf("AllowSleepOnAC failed: %v", err)
+	}
+}
+
+func getChargingPaused(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, isChargingPaused())
+}
+
+func setChargingPaused(c *gin.Context) {
+	var p bool
+	if err := c.BindJSON(&p); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, err.Error())
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if p && getCalibrationStatus().Phase != calibration.PhaseIdle {
+		c.IndentedJSON(http.StatusConflict, ErrCalibrationInProgress.Error())
+		_ = c.AbortWithError(http.StatusConflict, ErrCalibrationInProgress)
+		return
+	}
+
+	// Pausing stops charging regardless of the limit, so it is subject to
+	// the same policy.
+	changedBy, ok := authorizeLimitChange(c)
+	if !ok {
+		return
+	}
+	conf.SetLimitChangedBy(changedBy, time.Now())
+	if err := conf.Save(); err != nil {
+		logrus.Errorf("saveConfig failed: %v", err)
+	}
+
+	chargingPausedMu.Lock()
+	changed := chargingPaused != p
+	chargingPaused = p
+	chargingPausedMu.Unlock()
+
+	if changed && sseHub != nil {
+		sseHub.Publish(events.ChargingPaused, events.ChargingPausedEvent{
+			Paused: p,
+			Ts:     time.Now().Unix(),
+		})
+	}
+
+	// Apply right away instead of waiting for the next loop.
+	maintainLoopForced()
+
+	if p {
+		logrus.WithField("user", changedBy).Info("paused charging")
+		c.IndentedJSON(http.StatusCreated, "Charging paused until resumed")
+	} else {
+		logrus.WithField("user", changedBy).Info("resumed charging")
+		c.IndentedJSON(http.StatusCreated, "Charging resumed, following the charge limit again")
+	}
+}
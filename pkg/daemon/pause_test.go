@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/utils/ptr"
+)
+
+func TestApplyChargingPause(t *testing.T) {
+	fake := newFakeSMC(60, 1, true)
+	fake.inject()
+	conf = &mockConf{upper: 80, lower: 78}
+	t.Cleanup(func() { chargingPaused = false })
+
+	chargingPaused = false
+	if applyChargingPause(fake.charging) {
+		t.Fatal("applyChargingPause() = true while not paused")
+	}
+	if !fake.charging {
+		t.Fatal("charging should be left alone while not paused")
+	}
+
+	chargingPaused = true
+	if !applyChargingPause(fake.charging) {
+		t.Fatal("applyChargingPause() = false while paused")
+	}
+	if fake.charging {
+		t.Fatal("charging should be disabled while paused")
+	}
+}
+
+func TestSetChargingPausedDeniedByPolicy(t *testing.T) {
+	sseHub = nil
+	chargingPaused = false
+	t.Cleanup(func() { chargingPaused = false })
+	conf = config.NewFileFromConfig(&config.RawFileConfig{
+		Limit:       ptr.To(80),
+		LimitPolicy: ptr.To(config.LimitPolicyAdminLocked),
+	}, filepath.Join(t.TempDir(), "batt.json"))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	// Not over the unix socket, so the user is unknown and not an admin.
+	c.Request = httptest.NewRequest(http.MethodPut, "/charging-paused", strings.NewReader("true"))
+	setChargingPaused(c)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if isChargingPaused() {
+		t.Error("charging paused by a non-admin user under the admin-locked policy")
+	}
+}
@@ -0,0 +1,130 @@
+package daemon
+
+/*
+#include <sys/types.h>
+#include <unistd.h>
+*/
+import "C"
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os/user"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/config"
+)
+
+// adminGroupID is the gid of the macOS "admin" group.
+const adminGroupID = "80"
+
+type connContextKey struct{}
+
+// saveConn is used as http.Server.ConnContext, so handlers can find out who is
+// on the other end of the unix socket.
+func saveConn(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connContextKey{}, c)
+}
+
+// peerUID returns the uid of the process on the other end of the unix socket.
+func peerUID(r *http.Request) (int, error) {
+	conn, ok := r.Context().Value(connContextKey{}).(*net.UnixConn)
+	if !ok {
+		return 0, errors.New("not a unix socket connection")
+	}
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	var uid C.uid_t
+	var gid C.gid_t
+	var ret C.int
+	if err := raw.Control(func(fd uintptr) {
+		ret = C.getpeereid(C.int(fd), &uid, &gid)
+	}); err != nil {
+		return 0, err
+	}
+	if ret != 0 {
+		return 0, errors.New("getpeereid failed")
+	}
+
+	return int(uid), nil
+}
+
+// peerUser describes the user making a request.
+type peerUser struct {
+	name  string
+	admin bool
+}
+
+func getPeerUser(r *http.Request) (*peerUser, error) {
+	uid, err := peerUID(r)
+	if err != nil {
+		return nil, err
+	}
+	if uid == 0 {
+		return &peerUser{name: "root", admin: true}, nil
+	}
+
+	u, err := user.LookupId(strconv.Itoa(uid))
+	if err != nil {
+		return &peerUser{name: strconv.Itoa(uid)}, nil
+	}
+	groups, err := u.GroupIds()
+	if err != nil {
+		logrus.WithError(err).WithField("user", u.Username).Warn("failed to get groups of user")
+	}
+
+	return &peerUser{name: u.Username, admin: slices.Contains(groups, adminGroupID)}, nil
+}
+
+// authorizeLimitChange checks the limit policy for the user making the
+// request. On success, it returns the user name to record as the one who
+// changed the limit. Otherwise, it aborts the request.
+func authorizeLimitChange(c *gin.Context) (string, bool) {
+	u, err := getPeerUser(c.Request)
+	if err != nil {
+		logrus.WithError(err).Debug("failed to identify the user changing the limit")
+		u = &peerUser{name: "unknown"}
+	}
+
+	if rejectManaged(c, currentManaged().LimitLocked(), "the charge limit") {
+		logrus.WithField("user", u.name).Warn("refused change of managed limit")
+		return "", false
+	}
+
+	if conf.LimitPolicy() == config.LimitPolicyAdminLocked && !u.admin {
+		err := errors.New("the charge limit is locked by an administrator of this Mac")
+		logrus.WithField("user", u.name).Warn("refused limit change by non-admin user")
+		c.IndentedJSON(http.StatusForbidden, err.Error())
+		_ = c.AbortWithError(http.StatusForbidden, err)
+		return "", false
+	}
+
+	return u.name, true
+}
+
+// authorizeAdmin aborts the request unless it comes from root or an admin,
+// for actions that act on root-owned files, e.g. the log.
+func authorizeAdmin(c *gin.Context, what string) bool {
+	u, err := getPeerUser(c.Request)
+	if err != nil {
+		logrus.WithError(err).Debug("failed to identify the user making the request")
+		u = &peerUser{name: "unknown"}
+	}
+	if u.admin {
+		return true
+	}
+	err = errors.New(what + " requires an administrator")
+	logrus.WithField("user", u.name).Warnf("refused %s by non-admin user", what)
+	c.IndentedJSON(http.StatusForbidden, err.Error())
+	_ = c.AbortWithError(http.StatusForbidden, err)
+	return false
+}
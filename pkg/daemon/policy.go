@@ -0,0 +1,78 @@
+package daemon
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/charlie0129/batt/pkg/calibration"
+	"github.com/charlie0129/batt/pkg/config"
+)
+
+// getEffectivePolicy works out what decides charging right now, in the same
+// order of precedence as the maintain loop.
+func getEffectivePolicy(now time.Time) *config.Policy {
+	p := &config.Policy{
+		Mode:    config.PolicyModeLimit,
+		Upper:   conf.UpperLimit(),
+		Lower:   conf.LowerLimit(),
+		Managed: currentManaged().LimitLocked(),
+	}
+	p.SetBy, p.SetAt = conf.LimitChangedBy()
+
+	chargeByMu.Lock()
+	chargeBy := chargeByTarget
+	chargeByMu.Unlock()
+
+	calibrationMu.Lock()
+	phase := calibrationState.Phase
+	calibrationMu.Unlock()
+
+	bypass := getBypassLevel()
+	hot := getTooHotSensor()
+
+	switch {
+	case phase != calibration.PhaseIdle:
+		p.Mode = config.PolicyModeCalibration
+		p.Detail = "Auto calibration is controlling charging"
+	case isChargingPaused():
+		p.Mode = config.PolicyModePaused
+		p.Detail = "Charging is paused until resumed"
+	case hot != "":
+		p.Mode = config.PolicyModeTooHot
+		p.Detail = fmt.Sprintf("Charging is stopped until %s cools %d°C below its limit of %d°C", hot, thermalHysteresis, conf.TemperatureLimits()[hot])
+	case bypass > 0:
+		p.Mode = config.PolicyModeBypass
+		p.Upper, p.Lower = bypass, bypass-bypassMargin
+		p.Detail = fmt.Sprintf("Running from the power adapter, holding the battery at %d%%", bypass)
+	case conf.StorageMode() != nil:
+		p.Mode = config.PolicyModeStorage
+		p.Detail = fmt.Sprintf("Storage mode holds the battery at %d%%", storageLimit)
+	case conf.TravelMode() != nil:
+		p.Mode = config.PolicyModeTravel
+		if tm := conf.TravelMode(); tm.Until.IsZero() {
+			p.Detail = "Travel mode charges to 100% until turned off"
+		} else {
+			p.Detail = "Travel mode charges to 100% until " + tm.Until.Local().Format("Jan _2 15:04")
+		}
+	case p.Upper < 100 && !chargeBy.IsZero() && now.Before(chargeBy):
+		p.Mode = config.PolicyModeChargeBy
+		p.Detail = "Charging to 100% to be full by " + chargeBy.Local().Format(config.ChargeByLayout)
+	case p.Upper < 100 && isWeakAdapterBoostActive():
+		p.Mode = config.PolicyModeWeakAdapter
+		p.Detail = "Charging to 100% because the power adapter cannot keep up"
+	case p.Upper >= 100:
+		p.Mode = config.PolicyModeDisabled
+		p.Detail = "The charge limit is off, macOS charges to 100%"
+	default:
+		p.Detail = fmt.Sprintf("Charging stops at %d%% and resumes below %d%%", p.Upper, p.Lower)
+	}
+
+	return p
+}
+
+func getPolicy(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, getEffectivePolicy(time.Now()))
+}
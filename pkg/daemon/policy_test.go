@@ -0,0 +1,48 @@
+package daemon
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charlie0129/batt/pkg/calibration"
+	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/utils/ptr"
+)
+
+func TestGetEffectivePolicy(t *testing.T) {
+	calibrationState = &calibration.State{Phase: calibration.PhaseIdle}
+	now := time.Now()
+	tests := []struct {
+		name   string
+		raw    config.RawFileConfig
+		paused bool
+		want   config.PolicyMode
+	}{
+		{name: "limit", raw: config.RawFileConfig{Limit: ptr.To(80)}, want: config.PolicyModeLimit},
+		{name: "disabled", raw: config.RawFileConfig{Limit: ptr.To(100)}, want: config.PolicyModeDisabled},
+		{name: "travel", raw: config.RawFileConfig{Limit: ptr.To(100), TravelMode: &config.TravelMode{Limit: 80}}, want: config.PolicyModeTravel},
+		{name: "storage", raw: config.RawFileConfig{Limit: ptr.To(50), StorageMode: &config.StorageMode{Limit: 80}}, want: config.PolicyModeStorage},
+		{name: "paused wins", raw: config.RawFileConfig{Limit: ptr.To(100), TravelMode: &config.TravelMode{Limit: 80}}, paused: true, want: config.PolicyModePaused},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := tt.raw
+			raw.LimitChangedBy = ptr.To("alice")
+			conf = config.NewFileFromConfig(&raw, filepath.Join(t.TempDir(), "batt.json"))
+			chargingPaused = tt.paused
+			t.Cleanup(func() { chargingPaused = false })
+
+			p := getEffectivePolicy(now)
+			if p.Mode != tt.want {
+				t.Errorf("Mode = %s, want %s", p.Mode, tt.want)
+			}
+			if p.SetBy != "alice" {
+				t.Errorf("SetBy = %q, want alice", p.SetBy)
+			}
+			if p.Detail == "" {
+				t.Error("Detail is empty")
+			}
+		})
+	}
+}
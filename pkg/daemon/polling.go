@@ -0,0 +1,179 @@
+package daemon
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// fastPollInterval is used while a client shows live values, e.g. the
+	// menu is open, and right after the power source or charging changed.
+	fastPollInterval = 2 * time.Second
+	// idlePollInterval is used when nothing changed for pollStableAfter and
+	// batt has nothing to do until something does.
+	idlePollInterval = time.Minute
+	// pollActivityWindow is how long a telemetry request keeps polling fast.
+	// The menu asks every second while open.
+	pollActivityWindow = 10 * time.Second
+	// pollTransitionWindow is how long polling stays fast after the adapter
+	// was plugged in or out or charging was turned on or off.
+	pollTransitionWindow = 30 * time.Second
+	// pollStableAfter is how long the state must not change, the charge
+	// included, before polling backs off.
+	pollStableAfter = 5 * time.Minute
+	// pollIdleMargin keeps polling at loopInterval while the charge is this
+	// close to the lower limit, so charging starts on time.
+	pollIdleMargin = 2
+)
+
+// Reasons for the poll interval, shown in the debug report.
+const (
+	pollReasonClient     = "client polling"
+	pollReasonTransition = "power state changing"
+	pollReasonCharging   = "charging"
+	pollReasonCalibrate  = "calibrating"
+	pollReasonNearLower  = "near lower limit"
+	pollReasonChanging   = "charge changing"
+	pollReasonStable     = "stable"
+)
+
+// pollInput is what a maintain loop read, which decides how soon the next
+// one runs.
+type pollInput struct {
+	pluggedIn       bool
+	chargingEnabled bool
+	charge          int
+	lower           int
+	calibrating     bool
+}
+
+// pollSampler adapts the maintain loop interval. Every loop reads the SMC,
+// so polling every loopInterval around the clock keeps waking the Mac for
+// nothing while the state is stable, e.g. on battery or held at the limit.
+type pollSampler struct {
+	mu   sync.Mutex
+	last *pollInput
+	// lastActivity is the last telemetry request.
+	lastActivity time.Time
+	// lastTransition is the last time the adapter or charging changed,
+	// lastChange the last time anything did.
+	lastTransition time.Time
+	lastChange     time.Time
+	// lastIdle is the last time the idle interval was in effect.
+	lastIdle time.Time
+	interval time.Duration
+	reason   string
+	// wake cuts the sleep short when a client starts polling.
+	wake chan struct{}
+}
+
+var poller = newPollSampler()
+
+func newPollSampler() *pollSampler {
+	return &pollSampler{
+		interval: loopInterval,
+		reason:   pollReasonChanging,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// next returns the interval until the next maintain loop and why.
+func (p *pollSampler) next(now time.Time) (time.Duration, string) {
+	switch {
+	case now.Sub(p.lastActivity) < pollActivityWindow:
+		return fastPollInterval, pollReasonClient
+	case now.Sub(p.lastTransition) < pollTransitionWindow:
+		return fastPollInterval, pollReasonTransition
+	case p.last == nil:
+		return loopInterval, pollReasonChanging
+	case p.last.calibrating:
+		return loopInterval, pollReasonCalibrate
+	case p.last.pluggedIn && p.last.chargingEnabled && p.last.charge < 100:
+		return loopInterval, pollReasonCharging
+	case p.last.charge < p.last.lower+pollIdleMargin:
+		return loopInterval, pollReasonNearLower
+	case now.Sub(p.lastChange) < pollStableAfter:
+		return loopInterval, pollReasonChanging
+	default:
+		return idlePollInterval, pollReasonStable
+	}
+}
+
+// observe records what a maintain loop read and returns the interval until
+// the next one.
+func (p *pollSampler) observe(in pollInput, now time.Time) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch {
+	case p.last == nil:
+		p.lastChange = now
+	case p.last.pluggedIn != in.pluggedIn || p.last.chargingEnabled != in.chargingEnabled:
+		p.lastTransition, p.lastChange = now, now
+		// The battery may start charging, so loops are expected every
+		// loopInterval again right away.
+		p.lastIdle = time.Time{}
+	case *p.last != in:
+		p.lastChange = now
+	}
+	p.last = &in
+	return p.update(now)
+}
+
+// update recomputes the interval. p.mu must be held.
+func (p *pollSampler) update(now time.Time) time.Duration {
+	p.interval, p.reason = p.next(now)
+	if p.interval == idlePollInterval {
+		p.lastIdle = now
+	}
+	return p.interval
+}
+
+// noteActivity polls fast for a while, waking the loop if it is idle, since
+// a client asking for telemetry shows it live.
+func (p *pollSampler) noteActivity(now time.Time) {
+	p.mu.Lock()
+	p.lastActivity = now
+	wasSlow := p.interval > fastPollInterval
+	p.update(now)
+	p.mu.Unlock()
+
+	if wasSlow {
+		select {
+		case p.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// sleep waits for the current interval, or until woken by noteActivity.
+func (p *pollSampler) sleep() {
+	p.mu.Lock()
+	d := p.interval
+	p.mu.Unlock()
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-p.wake:
+	}
+}
+
+// sparseLoops reports whether loops were recently further apart than
+// loopInterval on purpose, so only longer gaps, e.g. sleep, count as missed.
+// Polling only backs off while the battery is not charging and the charge
+// is above the lower limit, where a late loop changes nothing. Plugging in
+// or turning charging on or off ends that right away.
+func (p *pollSampler) sparseLoops(now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return !p.lastIdle.IsZero() && now.Sub(p.lastIdle) < continuousLoopThreshold+idlePollInterval
+}
+
+// current returns the interval in effect and why.
+func (p *pollSampler) current() (time.Duration, string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.interval, p.reason
+}
@@ -0,0 +1,99 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPollSampler(t *testing.T) {
+	p := newPollSampler()
+	now := time.Now()
+
+	// On battery above the lower limit: backs off once stable.
+	onBattery := pollInput{charge: 90, lower: 70}
+	if d := p.observe(onBattery, now); d != loopInterval {
+		t.Fatalf("first loop: interval = %v, want %v", d, loopInterval)
+	}
+	if d := p.observe(onBattery, now.Add(pollStableAfter)); d != idlePollInterval {
+		t.Fatalf("stable: interval = %v, want %v", d, idlePollInterval)
+	}
+
+	// A client asking for telemetry speeds it up and wakes the loop.
+	now = now.Add(pollStableAfter)
+	p.noteActivity(now)
+	if d, reason := p.current(); d != fastPollInterval || reason != pollReasonClient {
+		t.Fatalf("client polling: interval = %v (%s), want %v", d, reason, fastPollInterval)
+	}
+	select {
+	case <-p.wake:
+	default:
+		t.Fatal("loop not woken by client polling")
+	}
+	now = now.Add(pollActivityWindow)
+	if d := p.observe(onBattery, now); d != idlePollInterval {
+		t.Fatalf("client gone: interval = %v, want %v", d, idlePollInterval)
+	}
+
+	// Plugging in polls fast for a while, then normally while charging.
+	charging := pollInput{pluggedIn: true, chargingEnabled: true, charge: 90, lower: 70}
+	if d := p.observe(charging, now); d != fastPollInterval {
+		t.Fatalf("plugged in: interval = %v, want %v", d, fastPollInterval)
+	}
+	now = now.Add(pollStableAfter)
+	if d := p.observe(charging, now); d != loopInterval {
+		t.Fatalf("charging: interval = %v, want %v", d, loopInterval)
+	}
+
+	// Never backs off near the lower limit, where charging must start on time.
+	near := pollInput{charge: 71, lower: 70}
+	p.observe(near, now)
+	if d := p.observe(near, now.Add(2*pollStableAfter)); d != loopInterval {
+		t.Fatalf("near lower limit: interval = %v, want %v", d, loopInterval)
+	}
+}
+
+func TestCheckMissedMaintainLoopsWhileIdle(t *testing.T) {
+	origPoller, origRecorder := poller, loopRecorder
+	t.Cleanup(func() { poller, loopRecorder = origPoller, origRecorder })
+
+	poller = newPollSampler()
+	poller.lastIdle = time.Now()
+
+	// A loop every idlePollInterval is not missed.
+	loopRecorder = NewTimeSeriesRecorder(60)
+	for i := 3; i > 0; i-- {
+		loopRecorder.AddRecord(time.Now().Add(-time.Duration(i) * idlePollInterval).Add(time.Second))
+	}
+	if checkMissedMaintainLoops(false) {
+		t.Fatal("loops at the idle interval counted as missed")
+	}
+
+	// A longer gap, e.g. sleep, is.
+	loopRecorder = NewTimeSeriesRecorder(60)
+	loopRecorder.AddRecord(time.Now().Add(-time.Hour))
+	loopRecorder.AddRecord(time.Now().Add(-30 * time.Second))
+	if !checkMissedMaintainLoops(false) {
+		t.Fatal("a gap longer than the idle interval was not counted as missed")
+	}
+}
+
+func TestSparseLoopsEndOnTransition(t *testing.T) {
+	p := newPollSampler()
+	now := time.Now()
+
+	onBattery := pollInput{charge: 90, lower: 70}
+	p.observe(onBattery, now)
+	now = now.Add(pollStableAfter)
+	if d := p.observe(onBattery, now); d != idlePollInterval {
+		t.Fatalf("stable: interval = %v, want %v", d, idlePollInterval)
+	}
+	if !p.sparseLoops(now) {
+		t.Fatal("sparseLoops() = false while idle")
+	}
+
+	// Plugging in may start charging, so missed loops count again.
+	p.observe(pollInput{pluggedIn: true, chargingEnabled: true, charge: 90, lower: 70}, now.Add(time.Second))
+	if p.sparseLoops(now.Add(time.Second)) {
+		t.Fatal("sparseLoops() = true after plugging in")
+	}
+}
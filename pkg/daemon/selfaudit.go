@@ -0,0 +1,65 @@
+package daemon
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/diagnostics"
+	"github.com/charlie0129/batt/pkg/utils/procusage"
+)
+
+// Names of the daemon's own timers, as counted in the self-audit.
+const (
+	timerMaintainLoop  = "maintain loop"
+	timerEnergySample  = "energy sampling"
+	timerSocketCheck   = "socket permissions"
+	timerBatteryHealth = "battery health"
+	timerLogRotate     = "log rotation"
+	timerSSEHeartbeat  = "event stream heartbeat"
+)
+
+// sseHeartbeatInterval keeps event streams from timing out. Each connected
+// client, e.g. the menu bar app, has its own.
+const sseHeartbeatInterval = 30 * time.Second
+
+// idleWakeupBudget is how many times per hour the daemon's timers may fire
+// while nothing changes and only the menu bar app is connected. Going over
+// it means a change made batt noticeably less lightweight.
+const idleWakeupBudget = 300
+
+var (
+	timers = diagnostics.NewTimerCounter()
+	// auditSince is when the daemon started.
+	auditSince = time.Now()
+)
+
+// idleTimers are the timers other than the maintain loop and how often they
+// fire, regardless of the state.
+var idleTimers = map[string]time.Duration{
+	timerEnergySample:  energySampleInterval,
+	timerSocketCheck:   socketCheckInterval,
+	timerBatteryHealth: batteryHealthInterval,
+	timerLogRotate:     logRotateInterval,
+	timerSSEHeartbeat:  sseHeartbeatInterval,
+}
+
+// getSelfAudit reports what the daemon has cost since it started.
+func getSelfAudit() *diagnostics.SelfAudit {
+	a := &diagnostics.SelfAudit{
+		Since:      auditSince,
+		CgoCalls:   runtime.NumCgoCall(),
+		Goroutines: runtime.NumGoroutine(),
+		Timers:     timers.Stats(),
+		IdleBudget: idleWakeupBudget,
+	}
+	u, err := procusage.Self()
+	if err != nil {
+		logrus.WithError(err).Debug("failed to read own resource usage")
+	}
+	a.CPUSeconds = u.CPU.Seconds()
+	a.Wakeups = u.Wakeups
+	a.Energy = float64(u.Energy) / 1e9
+	return a
+}
@@ -0,0 +1,42 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdleWakeupBudget(t *testing.T) {
+	p := newPollSampler()
+	now := time.Now()
+	stable := pollInput{pluggedIn: true, charge: 80, lower: 70}
+
+	// An hour held at the limit, after the state settled.
+	p.observe(stable, now)
+	now = now.Add(pollStableAfter)
+	loops := 0
+	for end := now.Add(time.Hour); now.Before(end); loops++ {
+		now = now.Add(p.observe(stable, now))
+	}
+
+	wakeups := float64(loops)
+	for name, interval := range idleTimers {
+		perHour := float64(time.Hour / interval)
+		t.Logf("%s: %.0f/h", name, perHour)
+		wakeups += perHour
+	}
+	t.Logf("%s: %d/h", timerMaintainLoop, loops)
+	if wakeups > idleWakeupBudget {
+		t.Fatalf("idle wakeups = %.0f/h, budget %d/h", wakeups, idleWakeupBudget)
+	}
+}
+
+func TestGetSelfAudit(t *testing.T) {
+	timers.Fire(timerMaintainLoop, idlePollInterval)
+	a := getSelfAudit()
+	if a.TimerFires() == 0 || a.CgoCalls < 0 || a.Goroutines == 0 {
+		t.Fatalf("self-audit = %+v", a)
+	}
+	if a.IdleBudget != idleWakeupBudget {
+		t.Errorf("IdleBudget = %v, want %d", a.IdleBudget, idleWakeupBudget)
+	}
+}
@@ -0,0 +1,124 @@
+package daemon
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/peterneutron/powerkit-go/pkg/powerkit"
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/calibration"
+	"github.com/charlie0129/batt/pkg/capability"
+)
+
+// runSelfTest checks that batt can read and control the SMC on this Mac.
+// Only charging is written to, since cutting the adapter can put a Mac in
+// clamshell mode to sleep, and charging is always restored before
+// returning.
+func runSelfTest() *capability.SelfTest {
+	t := &capability.SelfTest{}
+
+	if charge, err := smcGetBatteryCharge(); err != nil {
+		t.Fail("Read battery charge", err.Error())
+	} else if charge < 0 || charge > 100 {
+		t.Fail("Read battery charge", fmt.Sprintf("implausible value %d%%", charge))
+	} else {
+		t.Pass("Read battery charge", fmt.Sprintf("%d%%", charge))
+	}
+
+	pluggedIn, errPlugged := smcIsPluggedIn()
+	adapter, errAdapter := smcIsAdapterEnabled()
+	switch {
+	case errPlugged != nil:
+		t.Fail("Read power adapter state", errPlugged.Error())
+	case errAdapter != nil:
+		t.Fail("Read power adapter state", errAdapter.Error())
+	default:
+		t.Pass("Read power adapter state", fmt.Sprintf("plugged in: %t, adapter enabled: %t", pluggedIn, adapter))
+	}
+
+	info, err := powerkit.GetSystemInfo(powerkit.FetchOptions{QueryIOKit: true, QuerySMC: false})
+	if err != nil || info == nil || info.IOKit == nil {
+		detail := "no IOKit data available"
+		if err != nil {
+			detail = err.Error()
+		}
+		t.Fail("Read battery telemetry", detail)
+	} else {
+		t.Pass("Read battery telemetry", fmt.Sprintf("%.2f V, %.2f A", info.IOKit.Battery.Voltage, info.IOKit.Battery.Amperage))
+	}
+
+	selfTestChargingControl(t)
+
+	if !smcConn.CheckMagSafeExistence() {
+		t.Skip("Read MagSafe LED", "this Mac has no MagSafe LED")
+	} else if state, err := smcConn.GetMagSafeLedState(); err != nil {
+		t.Fail("Read MagSafe LED", err.Error())
+	} else {
+		t.Pass("Read MagSafe LED", fmt.Sprintf("state %v", state))
+	}
+
+	return t
+}
+
+// selfTestChargingControl flips charging and reads it back, then restores
+// it. The maintain loop is held off meanwhile, so it neither interferes nor
+// mistakes the flip for another program's.
+func selfTestChargingControl(t *capability.SelfTest) {
+	const name = "Control charging (write and read back)"
+
+	if !smcConn.IsChargingControlCapable() {
+		t.Fail(name, "no known charging control SMC keys on this Mac")
+		return
+	}
+	if getCalibrationStatus().Phase != calibration.PhaseIdle {
+		t.Skip(name, "auto calibration is in progress")
+		return
+	}
+
+	maintainLoopInnerLock.Lock()
+	defer maintainLoopInnerLock.Unlock()
+	defer forgetExpectedCharging()
+
+	was, err := smcIsChargingEnabled()
+	if err != nil {
+		t.Fail(name, "read: "+err.Error())
+		return
+	}
+	set := func(enabled bool) error {
+		if enabled {
+			return smcEnableCharging()
+		}
+		return smcDisableCharging()
+	}
+
+	flipErr := set(!was)
+	got, readErr := smcIsChargingEnabled()
+	// Restore before anything else.
+	if err := set(was); err != nil {
+		logrus.WithError(err).Error("self-test failed to restore charging")
+		t.Fail(name, "failed to restore charging: "+err.Error())
+		return
+	}
+	restored, _ := smcIsChargingEnabled()
+
+	switch {
+	case flipErr != nil:
+		t.Fail(name, "write: "+flipErr.Error())
+	case readErr != nil:
+		t.Fail(name, "read back: "+readErr.Error())
+	case got == was:
+		t.Fail(name, "the SMC accepted the write, but charging did not change")
+	case restored != was:
+		t.Fail(name, "charging was not restored")
+	default:
+		t.Pass(name, fmt.Sprintf("charging enabled: %t -> %t -> %t", was, got, restored))
+	}
+}
+
+func postSelfTest(c *gin.Context) {
+	t := runSelfTest()
+	logrus.WithField("passed", t.Passed()).Info("self-test finished")
+	c.IndentedJSON(http.StatusOK, t)
+}
@@ -0,0 +1,121 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/charlie0129/batt/pkg/smc"
+)
+
+// useSimulatedSMC points smcConn and the SMC seams at a simulated battery.
+func useSimulatedSMC(t *testing.T, sim *smc.Simulation) {
+	t.Helper()
+	s := smc.NewSimulated(sim)
+	if err := s.Open(); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	smcConn = s
+	smcGetBatteryCharge = s.GetBatteryCharge
+	smcIsChargingEnabled = s.IsChargingEnabled
+	smcEnableCharging = s.EnableCharging
+	smcDisableCharging = s.DisableCharging
+	smcIsAdapterEnabled = s.IsAdapterEnabled
+	smcEnableAdapter = s.EnableAdapter
+	smcDisableAdapter = s.DisableAdapter
+	smcIsPluggedIn = s.IsPluggedIn
+}
+
+// TestChargingLogicWithSimulatedBattery runs the charging logic against a
+// simulated battery for a few hours of simulated time.
+func TestChargingLogicWithSimulatedBattery(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	sim := smc.NewSimulation(smc.SimulationOptions{
+		Charge:     70,
+		ChargeRate: 1,
+		DrainRate:  1,
+		Now:        func() time.Time { return now },
+	})
+	useSimulatedSMC(t, sim)
+	conf = &mockConf{upper: 80, lower: 75}
+	sseHub = nil
+
+	// Keep the maintain loop looking continuous, so charging is not held
+	// off as if after sleep.
+	loopRecorder = NewTimeSeriesRecorder(60)
+	for i := 10; i > 0; i-- {
+		loopRecorder.AddRecord(time.Now().Add(-time.Duration(i) * loopInterval))
+	}
+
+	var minCharge, maxCharge = 100, 0
+	run := func(minutes int) {
+		for i := 0; i < minutes; i++ {
+			charging, _ := smcConn.IsChargingEnabled()
+			charge, _ := smcConn.GetBatteryCharge()
+			pluggedIn, _ := smcConn.IsPluggedIn()
+			minCharge, maxCharge = min(minCharge, charge), max(maxCharge, charge)
+			if !handleChargingLogic(true, charging, pluggedIn, charge, 75, 80) {
+				t.Fatalf("handleChargingLogic failed at %d%%", charge)
+			}
+			now = now.Add(time.Minute)
+		}
+	}
+
+	// Charges up to the limit and holds there.
+	run(60)
+	if maxCharge != 80 {
+		t.Fatalf("expected the charge to stop at 80%%, got up to %d%%", maxCharge)
+	}
+	if charging, _ := smcConn.IsChargingEnabled(); charging {
+		t.Fatal("expected charging disabled at the limit")
+	}
+
+	// Drains on battery, and charging is enabled again below 75%.
+	sim.SetPluggedIn(false)
+	run(10)
+	if charging, _ := smcConn.IsChargingEnabled(); !charging {
+		t.Fatalf("expected charging enabled below the lower limit, charge went down to %d%%", minCharge)
+	}
+
+	// Charges back up to the limit once plugged in again.
+	sim.SetPluggedIn(true)
+	run(60)
+	if charge, _ := smcConn.GetBatteryCharge(); charge != 80 {
+		t.Fatalf("expected 80%% after charging again, got %d%%", charge)
+	}
+	if maxCharge != 80 {
+		t.Fatalf("expected the charge to never exceed 80%%, got up to %d%%", maxCharge)
+	}
+}
+
+func TestSimulatedScenario(t *testing.T) {
+	sc, err := smc.LoadScenario("adapter-flapping")
+	if err != nil {
+		t.Fatalf("LoadScenario failed: %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	useSimulatedSMC(t, smc.NewSimulation(smc.SimulationOptions{
+		Scenario: sc,
+		Now:      func() time.Time { return now },
+	}))
+
+	want := []bool{true, false, true, false, true}
+	for i, w := range want {
+		pluggedIn, err := smcConn.IsPluggedIn()
+		if err != nil {
+			t.Fatalf("IsPluggedIn failed: %v", err)
+		}
+		if pluggedIn != w {
+			t.Fatalf("minute %d: expected pluggedIn %t, got %t", i, w, pluggedIn)
+		}
+		now = now.Add(time.Minute)
+	}
+	// Charged 1% per minute for 3 minutes plugged in and drained 0.5% per
+	// minute for 2 minutes unplugged.
+	if charge, _ := smcConn.GetBatteryCharge(); charge != 62 {
+		t.Fatalf("expected 62%%, got %d%%", charge)
+	}
+
+	if _, err := smc.LoadScenario("no-such-scenario"); err == nil {
+		t.Fatal("expected an error for an unknown scenario")
+	}
+}
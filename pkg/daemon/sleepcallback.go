@@ -111,7 +111,8 @@ func systemWillSleepCallback() {
 			sleep(preSleepLoopDelaySeconds)
 			wg.Done()
 		}()
-		err := smcConn.DisableCharging()
+		err := smcDisableCharging()
+		forgetExpectedCharging()
 		if err != nil {
 			logrus.Errorf("DisableCharging failed: %v", err)
 			return
@@ -0,0 +1,120 @@
+package daemon
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// socketModeNonRoot lets every user connect to the socket, socketModeRoot
+	// only root, like a socket created by net.Listen.
+	socketModeNonRoot fs.FileMode = 0777
+	socketModeRoot    fs.FileMode = 0755
+	// socketCheckInterval is how often the socket permissions are checked,
+	// in case something else, e.g. a macOS update, changed them.
+	socketCheckInterval = 5 * time.Minute
+)
+
+var (
+	socketMu sync.Mutex
+	// socketPath is the unix socket the daemon listens on.
+	socketPath string
+	// alwaysAllowNonRoot is set by --always-allow-non-root-access, which
+	// overrides the config.
+	alwaysAllowNonRoot bool
+)
+
+// wantSocketMode returns the permissions the socket should have.
+func wantSocketMode(allowNonRoot bool) fs.FileMode {
+	if allowNonRoot {
+		return socketModeNonRoot
+	}
+	return socketModeRoot
+}
+
+// applySocketPermissions changes the socket permissions to match the config
+// if they differ.
+func applySocketPermissions() error {
+	socketMu.Lock()
+	defer socketMu.Unlock()
+
+	if socketPath == "" {
+		return nil
+	}
+	fi, err := os.Stat(socketPath)
+	if err != nil {
+		return err
+	}
+	want := wantSocketMode(conf.AllowNonRootAccess() || alwaysAllowNonRoot)
+	if got := fi.Mode().Perm(); got == want {
+		return nil
+	}
+	logrus.WithFields(logrus.Fields{
+		"socket": socketPath,
+		"from":   fi.Mode().Perm(),
+		"to":     want,
+	}).Info("changing socket permissions")
+	return os.Chmod(socketPath, want)
+}
+
+// socketPermissionLoop repairs the socket permissions if they drift.
+func socketPermissionLoop() {
+	ticker := time.NewTicker(socketCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		timers.Fire(timerSocketCheck, socketCheckInterval)
+		if err := applySocketPermissions(); err != nil {
+			logrus.WithError(err).Error("failed to check socket permissions")
+		}
+	}
+}
+
+func setNonRootAccess(c *gin.Context) {
+	var enabled bool
+	if err := c.BindJSON(&enabled); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, err.Error())
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	// Turning it off locks out every other user, so only administrators may.
+	u, err := getPeerUser(c.Request)
+	if err != nil || !u.admin {
+		err = errors.New("only administrators can change who may access the daemon")
+		c.IndentedJSON(http.StatusForbidden, err.Error())
+		_ = c.AbortWithError(http.StatusForbidden, err)
+		return
+	}
+
+	conf.SetAllowNonRootAccess(enabled)
+	if err := conf.Save(); err != nil {
+		logrus.Errorf("saveConfig failed: %v", err)
+		c.IndentedJSON(http.StatusInternalServerError, err.Error())
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if err := applySocketPermissions(); err != nil {
+		logrus.WithError(err).Error("failed to change socket permissions")
+		c.IndentedJSON(http.StatusInternalServerError, err.Error())
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{"enabled": enabled, "user": u.name}).Info("set non-root access")
+	msg := "only root can access the daemon now, use sudo"
+	if enabled {
+		msg = "all users can access the daemon now"
+	}
+	if alwaysAllowNonRoot && !enabled {
+		msg = "saved, but the daemon runs with --always-allow-non-root-access, so all users can still access it"
+	}
+	c.IndentedJSON(http.StatusCreated, msg)
+}
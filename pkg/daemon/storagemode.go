@@ -0,0 +1,186 @@
+package daemon
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/config"
+)
+
+const (
+	// storageLimit and storageLowerLimit are the charge range lithium-ion
+	// batteries age the slowest at while stored.
+	storageLimit      = 50
+	storageLowerLimit = 45
+)
+
+// storageMu serializes storage mode's adapter changes.
+var storageMu sync.Mutex
+
+// enableStorageMode holds the charge between storageLowerLimit and
+// storageLimit and pauses the calibration schedule, remembering the previous
+// settings so disableStorageMode can restore them.
+func enableStorageMode(now time.Time) error {
+	if conf.StorageMode() != nil {
+		return nil
+	}
+	if err := disableTravelMode(); err != nil {
+		return err
+	}
+
+	sm := &config.StorageMode{
+		Since:           now,
+		Limit:           conf.UpperLimit(),
+		LowerLimitDelta: conf.UpperLimit() - conf.LowerLimit(),
+		Cron:            conf.Cron(),
+	}
+	if err := setLimits(storageLimit, storageLimit-storageLowerLimit); err != nil {
+		return err
+	}
+	conf.SetStorageMode(sm)
+	if err := conf.Save(); err != nil {
+		return err
+	}
+	// Saves the config again with the schedule cleared.
+	if _, err := schedule(""); err != nil {
+		return err
+	}
+
+	logrus.Info("storage mode enabled")
+	return nil
+}
+
+// disableStorageMode restores the settings saved by enableStorageMode.
+func disableStorageMode() error {
+	sm := conf.StorageMode()
+	if sm == nil {
+		return nil
+	}
+
+	// Enable the adapter before forgetting that storage mode disabled it.
+	if err := stopStorageDischarge(); err != nil {
+		return err
+	}
+	if err := setLimits(sm.Limit, clampLowerDelta(sm.Limit, sm.LowerLimitDelta)); err != nil {
+		return fmt.Errorf("failed to restore charge limit: %w", err)
+	}
+	conf.SetStorageMode(nil)
+	if err := conf.Save(); err != nil {
+		return err
+	}
+	if sm.Cron != "" {
+		if _, err := schedule(sm.Cron); err != nil {
+			return fmt.Errorf("failed to restore calibration schedule: %w", err)
+		}
+	}
+
+	logrus.WithField("limit", sm.Limit).Info("storage mode disabled")
+	return nil
+}
+
+// applyStorageDischarge discharges the battery down to storageLimit while
+// storage mode is on, by disabling the power adapter. Only stopping charging
+// would leave a fully charged battery at 100% for months. It is called by the
+// maintain loop.
+func applyStorageDischarge(batteryCharge int) {
+	sm := conf.StorageMode()
+	if sm == nil || batteryCharge <= storageLimit {
+		if err := stopStorageDischarge(); err != nil {
+			logrus.WithError(err).Error("failed to stop storage discharge")
+		}
+		return
+	}
+
+	storageMu.Lock()
+	defer storageMu.Unlock()
+
+	// Check the adapter itself: the daemon re-enables it when it exits.
+	adapterEnabled, err := smcIsAdapterEnabled()
+	if err != nil {
+		logrus.WithError(err).Error("failed to check adapter for storage mode")
+		return
+	}
+	if adapterEnabled {
+		if err := smcDisableAdapter(); err != nil {
+			logrus.WithError(err).Error("failed to disable adapter for storage mode")
+			return
+		}
+		logrus.WithField("batteryCharge", batteryCharge).Infof("storage mode: discharging to %d%%", storageLimit)
+	}
+	if sm.Discharging {
+		return
+	}
+	sm.Discharging = true
+	conf.SetStorageMode(sm)
+	if err := conf.Save(); err != nil {
+		logrus.Errorf("saveConfig failed: %v", err)
+	}
+}
+
+// stopStorageDischarge enables the adapter again if storage mode disabled
+// it, also in a previous run of the daemon.
+func stopStorageDischarge() error {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+
+	sm := conf.StorageMode()
+	if sm == nil || !sm.Discharging {
+		return nil
+	}
+	adapterEnabled, err := smcIsAdapterEnabled()
+	if err != nil {
+		return fmt.Errorf("failed to check adapter: %w", err)
+	}
+	if !adapterEnabled {
+		if err := smcEnableAdapter(); err != nil {
+			return fmt.Errorf("failed to re-enable adapter after storage discharge: %w", err)
+		}
+	}
+	sm.Discharging = false
+	conf.SetStorageMode(sm)
+	if err := conf.Save(); err != nil {
+		return err
+	}
+	logrus.Info("storage mode: discharge finished")
+	return nil
+}
+
+func setStorageMode(c *gin.Context) {
+	var enabled bool
+	if err := c.BindJSON(&enabled); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, err.Error())
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	changedBy, ok := authorizeLimitChange(c)
+	if !ok {
+		return
+	}
+	conf.SetLimitChangedBy(changedBy, time.Now())
+
+	var err error
+	if enabled {
+		err = enableStorageMode(time.Now())
+	} else {
+		err = disableStorageMode()
+	}
+	if err != nil {
+		logrus.Errorf("setStorageMode failed: %v", err)
+		c.IndentedJSON(http.StatusInternalServerError, err.Error())
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	msg := fmt.Sprintf("storage mode disabled, charge limit restored to %d%%", conf.UpperLimit())
+	if enabled {
+		msg = fmt.Sprintf("storage mode enabled, holding the charge between %d%% and %d%%", storageLowerLimit, storageLimit)
+	}
+
+	c.IndentedJSON(http.StatusCreated, msg)
+}
@@ -0,0 +1,122 @@
+package daemon
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/utils/ptr"
+)
+
+func TestStorageMode(t *testing.T) {
+	fake := newFakeSMC(80, 1, true)
+	fake.inject()
+	conf = config.NewFileFromConfig(&config.RawFileConfig{
+		Limit:           ptr.To(80),
+		LowerLimitDelta: ptr.To(5),
+	}, filepath.Join(t.TempDir(), "batt.json"))
+
+	if err := enableStorageMode(time.Now()); err != nil {
+		t.Fatalf("enableStorageMode() error = %v", err)
+	}
+	if got, want := conf.UpperLimit(), storageLimit; got != want {
+		t.Errorf("UpperLimit() = %d in storage mode, want %d", got, want)
+	}
+	if got, want := conf.LowerLimit(), storageLowerLimit; got != want {
+		t.Errorf("LowerLimit() = %d in storage mode, want %d", got, want)
+	}
+
+	// Above the storage limit, discharge by disabling the adapter.
+	applyStorageDischarge(fake.charge)
+	if fake.adapter {
+		t.Fatal("adapter should be disabled to discharge")
+	}
+	fake.charge = storageLimit
+	applyStorageDischarge(fake.charge)
+	if !fake.adapter {
+		t.Fatal("adapter should be enabled again at the storage limit")
+	}
+
+	if err := disableStorageMode(); err != nil {
+		t.Fatalf("disableStorageMode() error = %v", err)
+	}
+	if got, want := conf.UpperLimit(), 80; got != want {
+		t.Errorf("UpperLimit() = %d after storage mode, want %d", got, want)
+	}
+	if got, want := conf.LowerLimit(), 75; got != want {
+		t.Errorf("LowerLimit() = %d after storage mode, want %d", got, want)
+	}
+}
+
+func TestStorageDischargeSurvivesRestart(t *testing.T) {
+	fake := newFakeSMC(80, 1, true)
+	fake.inject()
+	path := filepath.Join(t.TempDir(), "batt.json")
+	conf = config.NewFileFromConfig(&config.RawFileConfig{
+		Limit:           ptr.To(80),
+		LowerLimitDelta: ptr.To(5),
+	}, path)
+
+	if err := enableStorageMode(time.Now()); err != nil {
+		t.Fatalf("enableStorageMode() error = %v", err)
+	}
+	applyStorageDischarge(fake.charge)
+	if fake.adapter {
+		t.Fatal("adapter should be disabled to discharge")
+	}
+
+	// The daemon dies mid-discharge and starts again.
+	var err error
+	conf, err = config.NewFile(path)
+	if err != nil {
+		t.Fatalf("NewFile() error = %v", err)
+	}
+	fake.charge = storageLimit
+	applyStorageDischarge(fake.charge)
+	if !fake.adapter {
+		t.Fatal("adapter should be enabled again after a restart")
+	}
+	if sm := conf.StorageMode(); sm == nil || sm.Discharging {
+		t.Errorf("StorageMode() = %+v, want storage mode without discharging", sm)
+	}
+
+	// A clean exit enables the adapter, so it is disabled again above the
+	// storage limit.
+	fake.charge = storageLimit + 10
+	applyStorageDischarge(fake.charge)
+	fake.adapter = true
+	applyStorageDischarge(fake.charge)
+	if fake.adapter {
+		t.Fatal("adapter should be disabled again after a clean restart")
+	}
+}
+
+func TestStorageModeWithLargeDelta(t *testing.T) {
+	fake := newFakeSMC(80, 1, true)
+	fake.inject()
+	conf = config.NewFileFromConfig(&config.RawFileConfig{
+		Limit:           ptr.To(80),
+		LowerLimitDelta: ptr.To(60),
+	}, filepath.Join(t.TempDir(), "batt.json"))
+
+	if err := enableStorageMode(time.Now()); err != nil {
+		t.Fatalf("enableStorageMode() error = %v", err)
+	}
+	if got, want := conf.UpperLimit(), storageLimit; got != want {
+		t.Errorf("UpperLimit() = %d in storage mode, want %d", got, want)
+	}
+	if got, want := conf.LowerLimit(), storageLowerLimit; got != want {
+		t.Errorf("LowerLimit() = %d in storage mode, want %d", got, want)
+	}
+
+	if err := disableStorageMode(); err != nil {
+		t.Fatalf("disableStorageMode() error = %v", err)
+	}
+	if got, want := conf.UpperLimit(), 80; got != want {
+		t.Errorf("UpperLimit() = %d after storage mode, want %d", got, want)
+	}
+	if got, want := conf.LowerLimit(), 20; got != want {
+		t.Errorf("LowerLimit() = %d after storage mode, want %d", got, want)
+	}
+}
@@ -0,0 +1,161 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/powerinfo"
+	"github.com/charlie0129/batt/pkg/smc"
+)
+
+const (
+	// thermalHysteresis is how many degrees a sensor must cool below its
+	// limit before charging resumes, so charging does not flap around it.
+	thermalHysteresis = 3
+	// maxTemperatureLimit bounds PUT /temperature-limit. Batteries should
+	// never get this hot.
+	maxTemperatureLimit = 60
+)
+
+var (
+	thermalMu sync.Mutex
+	// tooHotSensor is the sensor above its temperature limit, which keeps
+	// charging disabled. Empty if none.
+	tooHotSensor string
+)
+
+func getTooHotSensor() string {
+	thermalMu.Lock()
+	defer thermalMu.Unlock()
+	return tooHotSensor
+}
+
+// nextTooHotSensor returns the sensor that keeps charging disabled, given
+// the one that did before. A sensor keeps holding until it has cooled
+// thermalHysteresis degrees below its limit.
+func nextTooHotSensor(prev string, temps map[string]float64, limits map[string]int) string {
+	if limit, ok := limits[prev]; ok {
+		if t, ok := temps[prev]; ok && t > float64(limit-thermalHysteresis) {
+			return prev
+		}
+	}
+	for _, sensor := range slices.Sorted(maps.Keys(limits)) {
+		if t, ok := temps[sensor]; ok && t >= float64(limits[sensor]) {
+			return sensor
+		}
+	}
+	return ""
+}
+
+// applyThermalLimits keeps charging disabled while a sensor is above its
+// temperature limit. It returns true if the maintain loop should stop here.
+func applyThermalLimits(isChargingEnabled bool) bool {
+	limits := conf.TemperatureLimits()
+	var temps map[string]float64
+	if len(limits) > 0 {
+		temps = smcConn.GetTemperatures()
+	}
+
+	thermalMu.Lock()
+	prev := tooHotSensor
+	tooHotSensor = nextTooHotSensor(prev, temps, limits)
+	hot := tooHotSensor
+	thermalMu.Unlock()
+
+	switch {
+	case hot != "" && hot != prev:
+		logrus.WithFields(logrus.Fields{
+			"sensor":      hot,
+			"temperature": temps[hot],
+			"limit":       limits[hot],
+		}).Info("temperature above limit, stopping charging")
+	case hot == "" && prev != "":
+		logrus.WithField("sensor", prev).Info("temperature back below limit, following the charge limit again")
+	}
+
+	if hot == "" {
+		return false
+	}
+	keepChargingDisabled(isChargingEnabled, "temperature is above limit")
+	return true
+}
+
+func getTemperatures(c *gin.Context) {
+	temps := smcConn.GetTemperatures()
+	limits := conf.TemperatureLimits()
+	hot := getTooHotSensor()
+
+	ret := []powerinfo.Temperature{}
+	for _, s := range smc.TemperatureSensors() {
+		t, ok := temps[s.Key]
+		if !ok {
+			continue
+		}
+		ret = append(ret, powerinfo.Temperature{
+			Sensor:  s.Key,
+			Name:    s.Name,
+			Celsius: t,
+			Limit:   limits[s.Key],
+			TooHot:  s.Key == hot,
+		})
+	}
+
+	c.IndentedJSON(http.StatusOK, ret)
+}
+
+func setTemperatureLimit(c *gin.Context) {
+	var req config.TemperatureLimitRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, err.Error())
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if !slices.ContainsFunc(smc.TemperatureSensors(), func(s smc.TemperatureSensor) bool { return s.Key == req.Sensor }) {
+		err := fmt.Errorf("unknown temperature sensor %q", req.Sensor)
+		c.IndentedJSON(http.StatusBadRequest, err.Error())
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	if req.Celsius < 0 || req.Celsius > maxTemperatureLimit {
+		err := errors.New("temperature limit must be between 1 and 60 degrees Celsius, or 0 to remove it")
+		c.IndentedJSON(http.StatusBadRequest, err.Error())
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	// A low limit stops charging, so it is as much a limit change as
+	// setting the charge limit.
+	changedBy, ok := authorizeLimitChange(c)
+	if !ok {
+		return
+	}
+
+	conf.SetTemperatureLimit(req.Sensor, req.Celsius)
+	conf.SetLimitChangedBy(changedBy, time.Now())
+	if err := conf.Save(); err != nil {
+		logrus.Errorf("saveConfig failed: %v", err)
+		c.IndentedJSON(http.StatusInternalServerError, err.Error())
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	maintainLoopForced()
+
+	if req.Celsius == 0 {
+		logrus.WithFields(logrus.Fields{"sensor": req.Sensor, "user": changedBy}).Info("removed temperature limit")
+		c.IndentedJSON(http.StatusCreated, fmt.Sprintf("Removed the temperature limit of %s", req.Sensor))
+		return
+	}
+	logrus.WithFields(logrus.Fields{"sensor": req.Sensor, "celsius": req.Celsius, "user": changedBy}).Info("set temperature limit")
+	c.IndentedJSON(http.StatusCreated, fmt.Sprintf("Charging stops while %s is at or above %d°C", req.Sensor, req.Celsius))
+}
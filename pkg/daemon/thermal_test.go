@@ -0,0 +1,35 @@
+package daemon
+
+import "testing"
+
+func TestNextTooHotSensor(t *testing.T) {
+	limits := map[string]int{"TB0T": 38, "Ts0P": 45}
+
+	steps := []struct {
+		battery float64
+		want    string
+	}{
+		{battery: 30, want: ""},
+		{battery: 37.9, want: ""},
+		{battery: 38, want: "TB0T"},
+		// It keeps holding until it cools 3°C below the limit.
+		{battery: 36, want: "TB0T"},
+		{battery: 35.1, want: "TB0T"},
+		{battery: 35, want: ""},
+		{battery: 37, want: ""},
+	}
+	hot := ""
+	for i, s := range steps {
+		hot = nextTooHotSensor(hot, map[string]float64{"TB0T": s.battery, "Ts0P": 30}, limits)
+		if hot != s.want {
+			t.Fatalf("step %d at %.1f°C: got %q, want %q", i, s.battery, hot, s.want)
+		}
+	}
+
+	if got := nextTooHotSensor("", map[string]float64{"TB0T": 50}, nil); got != "" {
+		t.Errorf("expected no limits to never stop charging, got %q", got)
+	}
+	if got := nextTooHotSensor("TB0T", map[string]float64{}, limits); got != "" {
+		t.Errorf("expected a sensor that cannot be read to stop holding, got %q", got)
+	}
+}
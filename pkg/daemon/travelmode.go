@@ -0,0 +1,147 @@
+package daemon
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/events"
+	"github.com/charlie0129/batt/pkg/limits"
+)
+
+// enableTravelMode charges to 100% and pauses the calibration schedule,
+// remembering both so disableTravelMode can restore them. If travel mode is
+// already on, only its expiry is updated.
+func enableTravelMode(days int, now time.Time) error {
+	var until time.Time
+	if days > 0 {
+		until = now.AddDate(0, 0, days)
+	}
+
+	if tm := conf.TravelMode(); tm != nil {
+		tm.Until = until
+		conf.SetTravelMode(tm)
+		return conf.Save()
+	}
+	if err := disableStorageMode(); err != nil {
+		return err
+	}
+
+	conf.SetTravelMode(&config.TravelMode{
+		Until:           until,
+		Limit:           conf.UpperLimit(),
+		LowerLimitDelta: conf.UpperLimit() - conf.LowerLimit(),
+		Cron:            conf.Cron(),
+	})
+	conf.SetUpperLimit(limits.MaxUpper)
+	if err := conf.Save(); err != nil {
+		return err
+	}
+	// Saves the config again with the schedule cleared.
+	if _, err := schedule(""); err != nil {
+		return err
+	}
+
+	logrus.WithField("until", until).Info("travel mode enabled")
+	return nil
+}
+
+// disableTravelMode restores the settings saved by enableTravelMode.
+func disableTravelMode() error {
+	tm := conf.TravelMode()
+	if tm == nil {
+		return nil
+	}
+
+	// The lower limit delta can be changed while travel mode is on, so
+	// restore the saved one too, and keep it valid for the saved limit.
+	delta := tm.LowerLimitDelta
+	if delta == 0 {
+		delta = conf.UpperLimit() - conf.LowerLimit()
+	}
+	if err := setLimits(tm.Limit, clampLowerDelta(tm.Limit, delta)); err != nil {
+		return fmt.Errorf("failed to restore charge limit: %w", err)
+	}
+	conf.SetTravelMode(nil)
+	if err := conf.Save(); err != nil {
+		return err
+	}
+	if tm.Cron != "" {
+		if _, err := schedule(tm.Cron); err != nil {
+			return fmt.Errorf("failed to restore calibration schedule: %w", err)
+		}
+	}
+
+	logrus.WithField("limit", tm.Limit).Info("travel mode disabled")
+	return nil
+}
+
+// checkTravelModeExpiry ends travel mode once it expires. It is called by the
+// maintain loop.
+func checkTravelModeExpiry(now time.Time) {
+	tm := conf.TravelMode()
+	if tm == nil || tm.Until.IsZero() || now.Before(tm.Until) {
+		return
+	}
+
+	if err := disableTravelMode(); err != nil {
+		logrus.WithError(err).Error("failed to end expired travel mode")
+		return
+	}
+
+	if sseHub != nil {
+		sseHub.Publish(events.TravelModeEnded, events.TravelModeEndedEvent{
+			Message: fmt.Sprintf("Travel mode has ended. Charge limit restored to %d%%.", tm.Limit),
+			Ts:      now.Unix(),
+		})
+	}
+}
+
+func setTravelMode(c *gin.Context) {
+	var req config.TravelModeRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, err.Error())
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Days < 0 {
+		err := fmt.Errorf("travel mode days must not be negative, got %d", req.Days)
+		c.IndentedJSON(http.StatusBadRequest, err.Error())
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	changedBy, ok := authorizeLimitChange(c)
+	if !ok {
+		return
+	}
+	conf.SetLimitChangedBy(changedBy, time.Now())
+
+	var err error
+	if req.Enabled {
+		err = enableTravelMode(req.Days, time.Now())
+	} else {
+		err = disableTravelMode()
+	}
+	if err != nil {
+		logrus.Errorf("setTravelMode failed: %v", err)
+		c.IndentedJSON(http.StatusInternalServerError, err.Error())
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	msg := fmt.Sprintf("travel mode disabled, charge limit restored to %d%%", conf.UpperLimit())
+	if tm := conf.TravelMode(); tm != nil {
+		msg = "travel mode enabled, charging to 100%"
+		if !tm.Until.IsZero() {
+			msg += fmt.Sprintf(" until %s", tm.Until.Local().Format("Jan _2 15:04"))
+		}
+	}
+
+	c.IndentedJSON(http.StatusCreated, msg)
+}
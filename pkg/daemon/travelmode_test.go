@@ -0,0 +1,102 @@
+package daemon
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/utils/ptr"
+)
+
+func TestTravelMode(t *testing.T) {
+	sseHub = nil
+	conf = config.NewFileFromConfig(&config.RawFileConfig{
+		Limit:           ptr.To(80),
+		LowerLimitDelta: ptr.To(5),
+	}, filepath.Join(t.TempDir(), "batt.json"))
+
+	now := time.Now()
+	if err := enableTravelMode(3, now); err != nil {
+		t.Fatalf("enableTravelMode() error = %v", err)
+	}
+	if got := conf.UpperLimit(); got != 100 {
+		t.Errorf("UpperLimit() = %d in travel mode, want 100", got)
+	}
+	tm := conf.TravelMode()
+	if tm == nil || tm.Limit != 80 || !tm.Until.Equal(now.AddDate(0, 0, 3)) {
+		t.Fatalf("TravelMode() = %+v, want limit 80 for 3 days", tm)
+	}
+
+	// Enabling again only moves the expiry, keeping the original limit.
+	if err := enableTravelMode(0, now); err != nil {
+		t.Fatalf("enableTravelMode() error = %v", err)
+	}
+	if tm := conf.TravelMode(); tm == nil || tm.Limit != 80 || !tm.Until.IsZero() {
+		t.Fatalf("TravelMode() = %+v, want limit 80 without expiry", tm)
+	}
+
+	if err := enableTravelMode(1, now); err != nil {
+		t.Fatalf("enableTravelMode() error = %v", err)
+	}
+	checkTravelModeExpiry(now.Add(time.Hour))
+	if conf.TravelMode() == nil {
+		t.Fatal("travel mode ended before it expired")
+	}
+	checkTravelModeExpiry(now.AddDate(0, 0, 1))
+	if conf.TravelMode() != nil {
+		t.Fatal("travel mode did not end when it expired")
+	}
+	if got, want := conf.UpperLimit(), 80; got != want {
+		t.Errorf("UpperLimit() = %d after travel mode, want %d", got, want)
+	}
+	if got, want := conf.LowerLimit(), 75; got != want {
+		t.Errorf("LowerLimit() = %d after travel mode, want %d", got, want)
+	}
+}
+
+func TestTravelModeExpiryAfterDeltaChange(t *testing.T) {
+	sseHub = nil
+	conf = config.NewFileFromConfig(&config.RawFileConfig{
+		Limit:           ptr.To(80),
+		LowerLimitDelta: ptr.To(5),
+	}, filepath.Join(t.TempDir(), "batt.json"))
+
+	now := time.Now()
+	if err := enableTravelMode(1, now); err != nil {
+		t.Fatalf("enableTravelMode() error = %v", err)
+	}
+	// At 100%, a delta of 85 is valid, but not for the saved limit of 80.
+	conf.SetLowerLimit(100 - 85)
+
+	checkTravelModeExpiry(now.AddDate(0, 0, 1))
+	if conf.TravelMode() != nil {
+		t.Fatal("travel mode did not end when it expired")
+	}
+	if got, want := conf.UpperLimit(), 80; got != want {
+		t.Errorf("UpperLimit() = %d after travel mode, want %d", got, want)
+	}
+	if got, want := conf.LowerLimit(), 75; got != want {
+		t.Errorf("LowerLimit() = %d after travel mode, want %d", got, want)
+	}
+}
+
+func TestTravelModeExpiryFromOlderVersion(t *testing.T) {
+	sseHub = nil
+	// Travel modes saved by older versions have no lower limit delta.
+	conf = config.NewFileFromConfig(&config.RawFileConfig{
+		Limit:           ptr.To(100),
+		LowerLimitDelta: ptr.To(85),
+		TravelMode:      &config.TravelMode{Limit: 80},
+	}, filepath.Join(t.TempDir(), "batt.json"))
+
+	if err := disableTravelMode(); err != nil {
+		t.Fatalf("disableTravelMode() error = %v", err)
+	}
+	if got, want := conf.UpperLimit(), 80; got != want {
+		t.Errorf("UpperLimit() = %d after travel mode, want %d", got, want)
+	}
+	if got, want := conf.LowerLimit(), 10; got != want {
+		t.Errorf("LowerLimit() = %d after travel mode, want %d", got, want)
+	}
+}
@@ -190,6 +190,27 @@ func (r *TimeSeriesRecorder) GetRecordsIn(last time.Duration) int {
 	return count
 }
 
+// ContinuousSince returns the first of the records that end with the last one
+// and are no more than maxGap apart, or false if the last record is more than
+// maxGap ago.
+func (r *TimeSeriesRecorder) ContinuousSince(maxGap time.Duration) (time.Time, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := len(r.LastMaintainLoopTimes)
+	if n == 0 || time.Since(r.LastMaintainLoopTimes[n-1]) >= maxGap {
+		return time.Time{}, false
+	}
+	start := r.LastMaintainLoopTimes[n-1]
+	for i := n - 2; i >= 0; i-- {
+		if start.Sub(r.LastMaintainLoopTimes[i]) >= maxGap {
+			break
+		}
+		start = r.LastMaintainLoopTimes[i]
+	}
+	return start, true
+}
+
 // GetLastRecords returns the time differences between the records and the current time.
 func (r *TimeSeriesRecorder) GetLastRecords(last time.Duration) []time.Time {
 	r.mu.Lock()
@@ -0,0 +1,149 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/peterneutron/powerkit-go/pkg/powerkit"
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/events"
+)
+
+const (
+	// weakAdapterDuration is how long the adapter must fall short before
+	// the user is warned, so short load spikes are ignored.
+	weakAdapterDuration = 2 * time.Minute
+	// weakAdapterMinDrain is the battery discharge, in watts, above which
+	// the adapter counts as falling short rather than measurement noise.
+	weakAdapterMinDrain = 1.0
+)
+
+// powerFlow is what the adapter is rated for and where power goes, in watts.
+type powerFlow struct {
+	adapterWatts int
+	systemPower  float64
+	// batteryPower is negative while discharging.
+	batteryPower float64
+}
+
+var (
+	// readPowerFlow is a test seam.
+	readPowerFlow = func() (powerFlow, error) {
+		info, err := powerkit.GetSystemInfo(powerkit.FetchOptions{QueryIOKit: true, QuerySMC: false})
+		if err != nil {
+			return powerFlow{}, err
+		}
+		if info == nil || info.IOKit == nil {
+			return powerFlow{}, errors.New("failed to fetch IOKit power data")
+		}
+		return powerFlow{
+			adapterWatts: info.IOKit.Adapter.MaxWatts,
+			systemPower:  info.IOKit.Calculations.SystemPower,
+			batteryPower: info.IOKit.Calculations.BatteryPower,
+		}, nil
+	}
+
+	weakAdapterMu sync.Mutex
+	// weakAdapterSince is when the adapter started falling short, zero if
+	// it keeps up.
+	weakAdapterSince time.Time
+	// weakAdapterDetected lasts until unplugged, so a fluctuating load
+	// neither warns repeatedly nor flaps the boost.
+	weakAdapterDetected bool
+)
+
+// checkWeakAdapter warns when the power adapter cannot keep up with the
+// Mac, i.e. the battery drains while plugged in because the system draws
+// more than the adapter is rated for. It returns true if the limit should
+// be raised to 100% until unplugged, which is optional (WeakAdapterBoost).
+func checkWeakAdapter(isPluggedIn bool, now time.Time) bool {
+	weakAdapterMu.Lock()
+	defer weakAdapterMu.Unlock()
+
+	if !isPluggedIn {
+		weakAdapterSince = time.Time{}
+		weakAdapterDetected = false
+		return false
+	}
+	if weakAdapterDetected {
+		return conf.WeakAdapterBoost()
+	}
+
+	// The battery also drains while batt disables the adapter on purpose.
+	adapter, err := smcIsAdapterEnabled()
+	if err != nil || !adapter {
+		weakAdapterSince = time.Time{}
+		return false
+	}
+	pf, err := readPowerFlow()
+	if err != nil {
+		logrus.WithError(err).Debug("failed to read power flow")
+		return false
+	}
+	if pf.adapterWatts <= 0 || pf.batteryPower > -weakAdapterMinDrain || pf.systemPower <= float64(pf.adapterWatts) {
+		weakAdapterSince = time.Time{}
+		return false
+	}
+
+	if weakAdapterSince.IsZero() {
+		weakAdapterSince = now
+	}
+	if now.Sub(weakAdapterSince) < weakAdapterDuration {
+		return false
+	}
+	weakAdapterDetected = true
+
+	msg := fmt.Sprintf("Your %dW power adapter can't keep up with your Mac, which draws %.0fW. The battery is draining.", pf.adapterWatts, pf.systemPower)
+	if conf.WeakAdapterBoost() {
+		msg += " Charging to 100% whenever possible until unplugged."
+	}
+	logrus.WithFields(logrus.Fields{
+		"adapterWatts": pf.adapterWatts,
+		"systemPower":  pf.systemPower,
+		"batteryPower": pf.batteryPower,
+	}).Warn("power adapter cannot keep up with the system")
+
+	if sseHub != nil {
+		sseHub.Publish(events.WeakAdapter, events.WeakAdapterEvent{
+			Message: msg,
+			Ts:      now.Unix(),
+		})
+	}
+	return conf.WeakAdapterBoost()
+}
+
+// isWeakAdapterBoostActive reports whether the limit is raised because of
+// a weak adapter.
+func isWeakAdapterBoostActive() bool {
+	weakAdapterMu.Lock()
+	defer weakAdapterMu.Unlock()
+	return weakAdapterDetected && conf.WeakAdapterBoost()
+}
+
+func setWeakAdapterBoost(c *gin.Context) {
+	var b bool
+	if err := c.BindJSON(&b); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, err.Error())
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	conf.SetWeakAdapterBoost(b)
+	if err := conf.Save(); err != nil {
+		logrus.Errorf("saveConfig failed: %v", err)
+		c.IndentedJSON(http.StatusInternalServerError, err.Error())
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	logrus.Infof("set weak adapter boost to %t", b)
+
+	maintainLoopForced()
+
+	c.IndentedJSON(http.StatusCreated, "ok")
+}
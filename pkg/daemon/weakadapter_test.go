@@ -0,0 +1,70 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckWeakAdapter(t *testing.T) {
+	fake := newFakeSMC(60, 1, true)
+	fake.inject()
+	conf = &mockConf{upper: 80, lower: 78, weakAdapterBoost: true}
+	sseHub = nil
+	weakAdapterSince, weakAdapterDetected = time.Time{}, false
+
+	pf := powerFlow{adapterWatts: 30, systemPower: 42, batteryPower: -12}
+	orig := readPowerFlow
+	readPowerFlow = func() (powerFlow, error) { return pf, nil }
+	t.Cleanup(func() { readPowerFlow = orig })
+
+	now := time.Now()
+	if checkWeakAdapter(true, now) {
+		t.Fatal("boosted before the adapter fell short for long enough")
+	}
+	now = now.Add(weakAdapterDuration)
+	if !checkWeakAdapter(true, now) {
+		t.Fatal("expected a boost after the adapter fell short")
+	}
+	if !isWeakAdapterBoostActive() {
+		t.Fatal("isWeakAdapterBoostActive() = false")
+	}
+
+	// Lasts until unplugged, even if the load drops.
+	pf = powerFlow{adapterWatts: 30, systemPower: 10, batteryPower: 15}
+	if !checkWeakAdapter(true, now.Add(loopInterval)) {
+		t.Fatal("boost should last until unplugged")
+	}
+	if checkWeakAdapter(false, now.Add(2*loopInterval)) {
+		t.Fatal("boost should end when unplugged")
+	}
+
+	// Without the boost, only warn.
+	conf = &mockConf{upper: 80, lower: 78}
+	pf = powerFlow{adapterWatts: 30, systemPower: 42, batteryPower: -12}
+	_ = checkWeakAdapter(true, now)
+	if checkWeakAdapter(true, now.Add(weakAdapterDuration)) {
+		t.Fatal("boosted while weak adapter boost is disabled")
+	}
+	if !weakAdapterDetected {
+		t.Fatal("weak adapter should be detected regardless of the boost")
+	}
+}
+
+func TestCheckWeakAdapterIgnoresDisabledAdapter(t *testing.T) {
+	fake := newFakeSMC(60, 1, false)
+	fake.inject()
+	conf = &mockConf{upper: 80, lower: 78, weakAdapterBoost: true}
+	weakAdapterSince, weakAdapterDetected = time.Time{}, false
+
+	orig := readPowerFlow
+	readPowerFlow = func() (powerFlow, error) {
+		return powerFlow{adapterWatts: 30, systemPower: 20, batteryPower: -20}, nil
+	}
+	t.Cleanup(func() { readPowerFlow = orig })
+
+	now := time.Now()
+	_ = checkWeakAdapter(true, now)
+	if checkWeakAdapter(true, now.Add(weakAdapterDuration)) || weakAdapterDetected {
+		t.Fatal("a disabled adapter is not a weak adapter")
+	}
+}
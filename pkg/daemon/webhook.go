@@ -0,0 +1,142 @@
+package daemon
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/events"
+)
+
+var (
+	webhookClient = &http.Client{Timeout: 10 * time.Second}
+	// webhookRetryDelays are the waits before each retry of a failed
+	// delivery. A test seam.
+	webhookRetryDelays = []time.Duration{5 * time.Second, 30 * time.Second, 2 * time.Minute}
+)
+
+// webhookPayload is the body POSTed to webhooks.
+type webhookPayload struct {
+	Event string `json:"event"`
+	Host  string `json:"host"`
+	// Data is the typed payload of the event, see pkg/events.
+	Data json.RawMessage `json:"data"`
+}
+
+// runWebhooks delivers daemon events to the configured webhooks until hub
+// is closed. Webhooks are read for every event, so reloading the config is
+// enough to change them.
+func runWebhooks(hub *events.EventHub) {
+	ch := hub.Subscribe()
+	defer hub.Unsubscribe(ch)
+
+	host, _ := os.Hostname()
+	for ev := range ch {
+		for _, w := range conf.Webhooks() {
+			if !w.Wants(ev.Name) {
+				continue
+			}
+			// Deliveries are retried in the background, so a slow endpoint
+			// does not hold up the others.
+			go deliverWebhook(w, ev, host)
+		}
+	}
+}
+
+// deliverWebhook sends the event to the webhook, retrying on failure.
+func deliverWebhook(w config.Webhook, ev events.Event, host string) {
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = postWebhook(w, ev, host)
+		if err == nil {
+			return
+		}
+		if attempt >= len(webhookRetryDelays) {
+			break
+		}
+		time.Sleep(webhookRetryDelays[attempt])
+	}
+	// The full URL is often the credential, so only log the host.
+	logrus.WithFields(logrus.Fields{
+		"type":  w.Type,
+		"host":  w.RedactedURL(),
+		"event": ev.Name,
+	}).WithError(err).Warn("failed to deliver webhook, giving up")
+}
+
+func postWebhook(w config.Webhook, ev events.Event, host string) error {
+	var (
+		req *http.Request
+		err error
+	)
+	switch w.Type {
+	case config.WebhookTypeJSON:
+		req, err = newJSONWebhookRequest(w, ev, host)
+	case config.WebhookTypeNtfy:
+		req, err = newNtfyRequest(w, ev, host)
+	case config.WebhookTypePushover:
+		req, err = newPushoverRequest(w, ev, host)
+	case config.WebhookTypeTelegram:
+		req, err = newTelegramRequest(w, ev, host)
+	default:
+		return fmt.Errorf("unknown webhook type %q", w.Type)
+	}
+	if err != nil {
+		return stripWebhookURL(err)
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return stripWebhookURL(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// stripWebhookURL leaves the URL out of err, since it often contains the
+// credential, e.g. the token for Telegram.
+func stripWebhookURL(err error) error {
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		return uerr.Err
+	}
+	return err
+}
+
+func newJSONWebhookRequest(w config.Webhook, ev events.Event, host string) (*http.Request, error) {
+	body, err := json.Marshal(webhookPayload{Event: ev.Name, Host: host, Data: ev.Data})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Batt-Event", ev.Name)
+	if w.Secret != "" {
+		req.Header.Set("X-Batt-Signature", signWebhook(w.Secret, body))
+	}
+	return req, nil
+}
+
+// signWebhook returns the X-Batt-Signature of body.
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
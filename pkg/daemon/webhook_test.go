@@ -0,0 +1,153 @@
+package daemon
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/events"
+)
+
+func TestDeliverWebhook(t *testing.T) {
+	origDelays := webhookRetryDelays
+	webhookRetryDelays = []time.Duration{0}
+	t.Cleanup(func() { webhookRetryDelays = origDelays })
+
+	var (
+		attempts  int
+		got       webhookPayload
+		signature string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		// Fail the first attempt to exercise the retry.
+		if attempts == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		signature = r.Header.Get("X-Batt-Signature")
+		if want := signWebhook("s3cret", body); signature != want {
+			t.Errorf("X-Batt-Signature = %q, want %q", signature, want)
+		}
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	data, _ := json.Marshal(events.LimitReachedEvent{Charge: 80, Limit: 80})
+	deliverWebhook(config.Webhook{URL: srv.URL, Secret: "s3cret"}, events.Event{Name: events.LimitReached, Data: data}, "mac")
+
+	if attempts != 2 {
+		t.Fatalf("attempts = %d, want 2", attempts)
+	}
+	if signature == "" {
+		t.Fatal("expected a signed delivery")
+	}
+	if got.Event != events.LimitReached || got.Host != "mac" {
+		t.Fatalf("payload = %+v", got)
+	}
+	p, err := events.DecodeAs[events.LimitReachedEvent](events.Event{Data: got.Data})
+	if err != nil || p.Limit != 80 {
+		t.Fatalf("data = %s, err = %v", got.Data, err)
+	}
+}
+
+func TestWebhookWants(t *testing.T) {
+	all := config.Webhook{URL: "http://example.com"}
+	if !all.Wants(events.ChargingPaused) {
+		t.Error("a webhook without events should get all events")
+	}
+	some := config.Webhook{URL: "http://example.com", Events: []string{events.LimitReached}}
+	if !some.Wants(events.LimitReached) || some.Wants(events.ChargingPaused) {
+		t.Error("a webhook with events should only get those")
+	}
+}
+
+func TestNotificationChannels(t *testing.T) {
+	type request struct {
+		path  string
+		title string
+		body  string
+	}
+	var got request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = request{path: r.URL.Path, title: r.Header.Get("Title"), body: string(body)}
+	}))
+	defer srv.Close()
+
+	origPushover, origTelegram := pushoverURL, telegramAPI
+	pushoverURL, telegramAPI = srv.URL+"/pushover", srv.URL
+	t.Cleanup(func() { pushoverURL, telegramAPI = origPushover, origTelegram })
+
+	data, _ := json.Marshal(events.ChargingPausedEvent{Paused: true})
+	ev := events.Event{Name: events.ChargingPaused, Data: data}
+
+	tests := []struct {
+		name      string
+		hook      config.Webhook
+		wantPath  string
+		want      string
+		wantTitle string
+	}{
+		{
+			name:      "ntfy",
+			hook:      config.Webhook{Type: config.WebhookTypeNtfy, URL: srv.URL + "/batt"},
+			wantPath:  "/batt",
+			want:      "Charging is paused until resumed.",
+			wantTitle: "Charging paused on mac",
+		},
+		{
+			name:     "pushover",
+			hook:     config.Webhook{Type: config.WebhookTypePushover, Token: "app", User: "me"},
+			wantPath: "/pushover",
+			want:     "message=Charging+is+paused+until+resumed.",
+		},
+		{
+			name:     "telegram",
+			hook:     config.Webhook{Type: config.WebhookTypeTelegram, Token: "bot", ChatID: "42"},
+			wantPath: "/botbot/sendMessage",
+			want:     `"chat_id":"42"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = request{}
+			if err := postWebhook(tt.hook, ev, "mac"); err != nil {
+				t.Fatalf("postWebhook() error = %v", err)
+			}
+			if got.path != tt.wantPath {
+				t.Errorf("path = %q, want %q", got.path, tt.wantPath)
+			}
+			if !strings.Contains(got.body, tt.want) {
+				t.Errorf("body = %q, want it to contain %q", got.body, tt.want)
+			}
+			if got.title != tt.wantTitle {
+				t.Errorf("Title = %q, want %q", got.title, tt.wantTitle)
+			}
+		})
+	}
+	if err := postWebhook(config.Webhook{Type: config.WebhookTypeTelegram}, ev, "mac"); err == nil {
+		t.Error("expected an error for telegram without a token")
+	}
+}
+
+func TestDescribeEvent(t *testing.T) {
+	data, _ := json.Marshal(events.LimitReachedEvent{Charge: 81, Limit: 80})
+	title, message := describeEvent(events.Event{Name: events.LimitReached, Data: data}, "mac")
+	if title != "Charge limit reached on mac" || message != "Charging stopped at 81% (limit 80%)." {
+		t.Errorf("describeEvent() = %q, %q", title, message)
+	}
+
+	data, _ = json.Marshal(events.WeakAdapterEvent{Message: "too weak"})
+	if _, message := describeEvent(events.Event{Name: events.WeakAdapter, Data: data}, ""); message != "too weak" {
+		t.Errorf("describeEvent() message = %q, want the event message", message)
+	}
+}
@@ -0,0 +1,87 @@
+package diagnostics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// TimerStat is how often one of batt's own timers fired.
+type TimerStat struct {
+	Name string `json:"name"`
+	// Interval is the current interval, in seconds.
+	Interval float64 `json:"interval"`
+	Fires    uint64  `json:"fires"`
+}
+
+// SelfAudit is what batt itself has cost since it started, to show that it
+// stays lightweight.
+type SelfAudit struct {
+	Since      time.Time `json:"since"`
+	CPUSeconds float64   `json:"cpuSeconds"`
+	// Wakeups are how often the process woke a CPU, as counted by the
+	// kernel. They include the Go runtime's own.
+	Wakeups uint64 `json:"wakeups"`
+	// Energy is the energy billed to the process, in joules.
+	Energy     float64     `json:"energy"`
+	CgoCalls   int64       `json:"cgoCalls"`
+	Goroutines int         `json:"goroutines"`
+	Timers     []TimerStat `json:"timers"`
+	// IdleBudget is how many timer wakeups per hour are allowed while the
+	// state is stable and no client is polling.
+	IdleBudget float64 `json:"idleBudget,omitempty"`
+}
+
+// PerHour returns n as a rate over the time since a.Since.
+func (a SelfAudit) PerHour(n float64, now time.Time) float64 {
+	h := now.Sub(a.Since).Hours()
+	if h <= 0 {
+		return 0
+	}
+	return n / h
+}
+
+// TimerFires returns how often all timers fired.
+func (a SelfAudit) TimerFires() uint64 {
+	var n uint64
+	for _, t := range a.Timers {
+		n += t.Fires
+	}
+	return n
+}
+
+// TimerCounter counts how often named timers fire.
+type TimerCounter struct {
+	mu        sync.Mutex
+	fires     map[string]uint64
+	intervals map[string]time.Duration
+}
+
+// NewTimerCounter returns a counter with no timers.
+func NewTimerCounter() *TimerCounter {
+	return &TimerCounter{
+		fires:     map[string]uint64{},
+		intervals: map[string]time.Duration{},
+	}
+}
+
+// Fire counts a firing of the timer name, which now runs every interval.
+func (t *TimerCounter) Fire(name string, interval time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.fires[name]++
+	t.intervals[name] = interval
+}
+
+// Stats returns the timers by name.
+func (t *TimerCounter) Stats() []TimerStat {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ret := make([]TimerStat, 0, len(t.fires))
+	for name, n := range t.fires {
+		ret = append(ret, TimerStat{Name: name, Interval: t.intervals[name].Seconds(), Fires: n})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
@@ -0,0 +1,53 @@
+// Package diagnostics has the types of the daemon debug report, which
+// explains recent charging decisions.
+package diagnostics
+
+import (
+	"time"
+
+	"github.com/charlie0129/batt/pkg/utils/lru"
+)
+
+// EntryKind is what an Entry records.
+type EntryKind string
+
+const (
+	// KindTransition is a change of the effective charging policy.
+	KindTransition EntryKind = "transition"
+	// KindSMCWrite is a write to the SMC, e.g. disabling charging.
+	KindSMCWrite EntryKind = "smc-write"
+)
+
+// Entry is a recent event in the daemon.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Kind    EntryKind `json:"kind"`
+	Message string    `json:"message"`
+	// Error is set if an SMC write failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Report is returned by GET /debug.
+type Report struct {
+	// Mode is the effective charging policy, see config.PolicyMode.
+	Mode string `json:"mode"`
+	// LoopInterval is the current maintain loop interval, in seconds. It
+	// adapts to what is going on, see PollReason.
+	LoopInterval float64 `json:"loopInterval"`
+	// PollReason is why the maintain loop runs at LoopInterval, e.g.
+	// "client polling" or "stable".
+	PollReason string `json:"pollReason,omitempty"`
+	// RecentLoops are the start times of recent maintain loops.
+	RecentLoops []time.Time `json:"recentLoops"`
+	// LoopsMissed is true if the maintain loop was recently interrupted,
+	// e.g. by sleep, which holds off enabling charging.
+	LoopsMissed bool `json:"loopsMissed"`
+	// NextCalibration is the next scheduled calibration, zero if none.
+	NextCalibration time.Time `json:"nextCalibration,omitempty"`
+	// Entries are the most recent first.
+	Entries []Entry `json:"entries"`
+	// Self is what the daemon itself costs.
+	Self *SelfAudit `json:"self,omitempty"`
+	// Caches are the daemon's caches and how well they work.
+	Caches []lru.Stats `json:"caches,omitempty"`
+}
@@ -5,17 +5,43 @@ import (
 	"sync"
 )
 
+// EventHub delivers published events to its subscribers. It is the one event
+// bus of the daemon: the SSE stream, webhooks and the history recorder all
+// subscribe to it instead of being called by the code that causes the event.
 type EventHub struct {
 	mu   sync.RWMutex
-	subs map[chan Event]struct{}
+	subs map[chan Event]subscription
 }
 
-func NewEventHub() *EventHub { return &EventHub{subs: make(map[chan Event]struct{})} }
+// subscription is the set of event names a subscriber asked for, nil for
+// all public events.
+type subscription map[string]struct{}
+
+func (s subscription) wants(name string) bool {
+	if s == nil {
+		return !IsInternal(name)
+	}
+	_, ok := s[name]
+	return ok
+}
+
+func NewEventHub() *EventHub { return &EventHub{subs: make(map[chan Event]subscription)} }
+
+// Subscribe returns a channel receiving the events named in names, or all
+// public events if no name is given. Internal events are only received when
+// named. Call Unsubscribe to stop receiving.
+func (h *EventHub) Subscribe(names ...string) chan Event {
+	var s subscription
+	if len(names) > 0 {
+		s = make(subscription, len(names))
+		for _, n := range names {
+			s[n] = struct{}{}
+		}
+	}
 
-func (h *EventHub) Subscribe() chan Event {
 	ch := make(chan Event, 16)
 	h.mu.Lock()
-	h.subs[ch] = struct{}{}
+	h.subs[ch] = s
 	h.mu.Unlock()
 	return ch
 }
@@ -39,7 +65,10 @@ func (h *EventHub) Publish(name string, payload any) {
 	}
 	msg := Event{Name: name, Data: b}
 	h.mu.RLock()
-	for ch := range h.subs {
+	for ch, s := range h.subs {
+		if !s.wants(name) {
+			continue
+		}
 		// Non-blocking send; drop if subscriber is slow
 		select {
 		case ch <- msg:
@@ -48,3 +77,28 @@ func (h *EventHub) Publish(name string, payload any) {
 	}
 	h.mu.RUnlock()
 }
+
+// On calls fn with the typed payload of every event named name, in order,
+// until stop is called. The subscription is in place when On returns, so no
+// event published afterwards is missed. Events that do not decode as T are
+// skipped.
+//
+// Example:
+//
+//	stop := events.On(hub, events.LimitReached, func(e events.LimitReachedEvent) {
+//		fmt.Println(e.Charge)
+//	})
+//	defer stop()
+func On[T any](h *EventHub, name string, fn func(T)) (stop func()) {
+	ch := h.Subscribe(name)
+	go func() {
+		for ev := range ch {
+			v, err := DecodeAs[T](ev)
+			if err != nil {
+				continue
+			}
+			fn(v)
+		}
+	}()
+	return func() { h.Unsubscribe(ch) }
+}
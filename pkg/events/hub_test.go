@@ -0,0 +1,73 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscribeFiltersEvents(t *testing.T) {
+	h := NewEventHub()
+	all := h.Subscribe()
+	defer h.Unsubscribe(all)
+	limit := h.Subscribe(LimitReached)
+	defer h.Unsubscribe(limit)
+	samples := h.Subscribe(BatterySample)
+	defer h.Unsubscribe(samples)
+
+	h.Publish(BatterySample, BatterySampleEvent{Charge: 79})
+	h.Publish(LimitReached, LimitReachedEvent{Charge: 80, Limit: 80})
+	h.Publish(ChargingPaused, ChargingPausedEvent{Paused: true})
+
+	names := func(ch chan Event) []string {
+		var got []string
+		for {
+			select {
+			case ev := <-ch:
+				got = append(got, ev.Name)
+			default:
+				return got
+			}
+		}
+	}
+	for _, tt := range []struct {
+		sub  string
+		ch   chan Event
+		want []string
+	}{
+		// Internal events are only received when named.
+		{sub: "all", ch: all, want: []string{LimitReached, ChargingPaused}},
+		{sub: LimitReached, ch: limit, want: []string{LimitReached}},
+		{sub: BatterySample, ch: samples, want: []string{BatterySample}},
+	} {
+		got := names(tt.ch)
+		if len(got) != len(tt.want) {
+			t.Errorf("subscriber %s received %v, want %v", tt.sub, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("subscriber %s received %v, want %v", tt.sub, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestOn(t *testing.T) {
+	h := NewEventHub()
+	got := make(chan LimitReachedEvent, 1)
+	stop := On(h, LimitReached, func(e LimitReachedEvent) { got <- e })
+	defer stop()
+
+	h.Publish(ChargingPaused, ChargingPausedEvent{Paused: true})
+	h.Publish(LimitReached, LimitReachedEvent{Charge: 80, Limit: 80})
+
+	select {
+	case e := <-got:
+		if e.Charge != 80 || e.Limit != 80 {
+			t.Errorf("payload = %+v, want charge and limit 80", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler was not called")
+	}
+}
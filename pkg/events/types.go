@@ -4,10 +4,30 @@ import "encoding/json"
 
 // Event name constants
 const (
-	CalibrationPhase  = "calibration.phase"
-	CalibrationAction = "calibration.action"
+	CalibrationPhase    = "calibration.phase"
+	CalibrationAction   = "calibration.action"
+	ConflictDetected    = "conflict.detected"
+	TravelModeEnded     = "travelmode.ended"
+	BatteryDraining     = "battery.draining"
+	WeakAdapter         = "adapter.weak"
+	LimitReached        = "limit.reached"
+	ChargingPaused      = "charging.paused"
+	DaemonUnhealthy     = "daemon.unhealthy"
+	AdapterConnected    = "adapter.connected"
+	AdapterDisconnected = "adapter.disconnected"
+
+	// BatterySample is sent by every maintain loop. It is internal to the
+	// daemon, see IsInternal.
+	BatterySample = "battery.sample"
 )
 
+// IsInternal reports whether the event is only for subscribers inside the
+// daemon. Internal events are too frequent for the SSE stream and webhooks,
+// and are only received by subscribers that name them.
+func IsInternal(name string) bool {
+	return name == BatterySample
+}
+
 // Event is a generic SSE event from daemon.
 type Event struct {
 	Name string          // SSE event name
@@ -49,3 +69,75 @@ func DecodeAs[T any](e Event) (T, error) {
 	}
 	return v, nil
 }
+
+// Message returns the readable message of events that carry one, such as
+// conflict.detected, or "" otherwise.
+func (e Event) Message() string {
+	p, err := DecodeAs[struct {
+		Message string `json:"message"`
+	}](e)
+	if err != nil {
+		return ""
+	}
+	return p.Message
+}
+
+// ConflictDetectedEvent is the typed payload for conflict.detected.
+type ConflictDetectedEvent struct {
+	Message string `json:"message,omitempty"`
+	Ts      int64  `json:"ts"`
+}
+
+// TravelModeEndedEvent is the typed payload for travelmode.ended.
+type TravelModeEndedEvent struct {
+	Message string `json:"message,omitempty"`
+	Ts      int64  `json:"ts"`
+}
+
+// BatteryDrainingEvent is the typed payload for battery.draining.
+type BatteryDrainingEvent struct {
+	Message string `json:"message,omitempty"`
+	Ts      int64  `json:"ts"`
+}
+
+// WeakAdapterEvent is the typed payload for adapter.weak.
+type WeakAdapterEvent struct {
+	Message string `json:"message,omitempty"`
+	Ts      int64  `json:"ts"`
+}
+
+// LimitReachedEvent is the typed payload for limit.reached.
+type LimitReachedEvent struct {
+	Charge int   `json:"charge"`
+	Limit  int   `json:"limit"`
+	Ts     int64 `json:"ts"`
+}
+
+// ChargingPausedEvent is the typed payload for charging.paused. It is also
+// sent when charging is resumed, with Paused set to false.
+type ChargingPausedEvent struct {
+	Paused bool  `json:"paused"`
+	Ts     int64 `json:"ts"`
+}
+
+// DaemonUnhealthyEvent is the typed payload for daemon.unhealthy.
+type DaemonUnhealthyEvent struct {
+	Message string `json:"message,omitempty"`
+	Ts      int64  `json:"ts"`
+}
+
+// PowerSourceEvent is the typed payload for adapter.connected and
+// adapter.disconnected.
+type PowerSourceEvent struct {
+	Charge int   `json:"charge"`
+	Ts     int64 `json:"ts"`
+}
+
+// BatterySampleEvent is the typed payload for battery.sample.
+type BatterySampleEvent struct {
+	PluggedIn bool `json:"pluggedIn"`
+	Charge    int  `json:"charge"`
+	// Held is whether batt is holding the charge while plugged in.
+	Held bool  `json:"held"`
+	Ts   int64 `json:"ts"`
+}
@@ -0,0 +1,71 @@
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/progrium/darwinkit/macos/appkit"
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/client"
+)
+
+// renderDaemonAccess shows the Repair Daemon Access item if the daemon is
+// installed but refuses the menubar app, e.g. because the permissions of its
+// socket changed. err is from the last request to the daemon.
+func (c *menuController) renderDaemonAccess(err error) {
+	setItemHidden(c.repairAccessItem, !errors.Is(err, client.ErrPermissionDenied) || !isDaemonInstalled())
+}
+
+// setNonRootAccess is the action of the Allow Access Without Password item.
+// Turning access off also locks out the menubar app, so it asks first.
+// Turning it on needs root, since only root can reach the daemon then.
+func (c *menuController) setNonRootAccess(enabled bool) {
+	if enabled {
+		c.repairDaemonAccess()
+		return
+	}
+
+	alert := appkit.NewAlert()
+	alert.SetAlertStyle(appkit.AlertStyleWarning)
+	alert.SetMessageText("Only Allow root to Access the batt Daemon?")
+	alert.SetInformativeText(disableNonRootAccessAlertText)
+	alert.AddButtonWithTitle("Only Allow root")
+	alert.AddButtonWithTitle("Cancel")
+	if alert.RunModal() != appkit.AlertFirstButtonReturn {
+		setCheckboxItem(c.nonRootAccessItem, true)
+		return
+	}
+
+	if _, err := c.api.SetNonRootAccess(false); err != nil {
+		logrus.WithError(err).Error("Failed to forbid non-root access")
+		showAlert("Failed to change daemon access", err.Error())
+		setCheckboxItem(c.nonRootAccessItem, true)
+		return
+	}
+	logrus.Info("Forbade non-root access to the daemon")
+}
+
+// repairDaemonAccess lets users other than root access the daemon again,
+// asking for an administrator password. If the daemon is too old to change
+// the setting, the socket permissions are repaired directly.
+func (c *menuController) repairDaemonAccess() {
+	exe, err := os.Executable()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get executable path")
+		showAlert("Failed to get executable path", err.Error())
+		return
+	}
+
+	shellScript := fmt.Sprintf(`
+"%s" --daemon-socket "%s" non-root-access enable || /bin/chmod 0777 "%s"
+`, exe, c.api.SocketPath(), c.api.SocketPath())
+	if err := runShellScript(shellScript, "repair daemon access"); err != nil {
+		logrus.WithError(err).Error("Failed to repair daemon access")
+		showAlert("Failed to repair daemon access", err.Error())
+		return
+	}
+	logrus.Info("Repaired daemon access")
+	c.refreshOnOpen()
+}
@@ -0,0 +1,48 @@
+package gui
+
+import (
+	"runtime/cgo"
+
+	"github.com/sirupsen/logrus"
+)
+
+// #include <stdint.h>
+// #include <stdbool.h>
+import "C"
+
+// appearance is the effective appearance of the app. Views built from system
+// (semantic) colors and template images adapt by themselves; anything that
+// bakes colors in must re-render when this changes.
+type appearance struct {
+	dark         bool
+	highContrast bool
+}
+
+// onAppearanceChanged is the hook for dark/light mode and increased contrast
+// changes. It is called once at startup with the initial appearance.
+func (c *menuController) onAppearanceChanged(a appearance) {
+	logrus.WithFields(logrus.Fields{
+		"dark":         a.dark,
+		"highContrast": a.highContrast,
+	}).Debug("Appearance changed")
+	c.appearance = a
+	c.renderPowerFlow()
+	if c.statusWin != nil {
+		c.statusWin.setHighContrast(a.highContrast)
+	}
+}
+
+//export battAppearanceChanged
+func battAppearanceChanged(h C.uintptr_t, dark, highContrast C.bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("panic in battAppearanceChanged: %v", r)
+		}
+	}()
+	handle := cgo.Handle(h)
+	if v := handle.Value(); v != nil {
+		if c, ok := v.(*menuController); ok {
+			c.onAppearanceChanged(appearance{dark: bool(dark), highContrast: bool(highContrast)})
+		}
+	}
+}
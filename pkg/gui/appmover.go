@@ -0,0 +1,144 @@
+package gui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/progrium/darwinkit/macos/appkit"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// prefMoveDeclined is set once the user chose to keep batt.app where it
+	// is, so we do not ask on every launch.
+	prefMoveDeclined = "MoveToApplicationsDeclined"
+	applicationsDir  = "/Applications"
+	daemonPlistPath  = "/Library/LaunchDaemons/cc.chlc.batt.plist"
+)
+
+// needsMove reports whether bundle runs from outside /Applications and
+// ~/Applications, e.g. Downloads, a disk image or a translocated copy. The
+// daemon runs the executable of the app it was installed from, so it breaks
+// once such a copy is deleted or ejected.
+func needsMove(bundle, home string) bool {
+	if bundle == "" {
+		// Not a bundle, e.g. the command line or a development build.
+		return false
+	}
+	for _, dir := range []string{applicationsDir, filepath.Join(home, "Applications")} {
+		if strings.HasPrefix(bundle, dir+"/") {
+			return false
+		}
+	}
+	return true
+}
+
+// isTranslocated reports whether macOS runs bundle from a randomized
+// read-only path because it is quarantined and was never moved.
+func isTranslocated(bundle string) bool {
+	return strings.Contains(bundle, "/AppTranslocation/")
+}
+
+// daemonProgramPath returns the executable the installed daemon runs, or ""
+// if unknown.
+func daemonProgramPath() string {
+	out, err := exec.Command("/usr/bin/plutil", "-extract", "ProgramArguments.0", "raw", "-o", "-", daemonPlistPath).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// offerMoveToApplications asks to move batt.app to /Applications if it runs
+// from elsewhere. If the user agrees, the app is moved and relaunched, and
+// this process exits.
+func offerMoveToApplications() {
+	exe, err := os.Executable()
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to get executable path")
+		return
+	}
+	home, _ := os.UserHomeDir()
+	bundle := appBundlePath(exe)
+	if !needsMove(bundle, home) || getBoolPref(prefMoveDeclined) {
+		return
+	}
+
+	alert := appkit.NewAlert()
+	alert.SetAlertStyle(appkit.AlertStyleInformational)
+	alert.SetMessageText("Move batt to the Applications Folder?")
+	alert.SetInformativeText(moveToApplicationsAlertText)
+	alert.AddButtonWithTitle("Move to Applications")
+	alert.AddButtonWithTitle("Do Not Move")
+	if alert.RunModal() != appkit.AlertFirstButtonReturn {
+		setBoolPref(prefMoveDeclined, true)
+		return
+	}
+
+	dst := filepath.Join(applicationsDir, filepath.Base(bundle))
+	if _, err := os.Stat(dst); err == nil {
+		confirm := appkit.NewAlert()
+		confirm.SetAlertStyle(appkit.AlertStyleWarning)
+		confirm.SetMessageText("Replace " + dst + "?")
+		confirm.SetInformativeText("Another copy of batt is already in the Applications folder. It is moved to the Trash and replaced by this one.")
+		confirm.AddButtonWithTitle("Replace")
+		confirm.AddButtonWithTitle("Cancel")
+		if confirm.RunModal() != appkit.AlertFirstButtonReturn {
+			return
+		}
+	}
+
+	if err := moveToApplications(bundle, dst); err != nil {
+		logrus.WithError(err).Error("Failed to move to Applications")
+		showAlert("Failed to move batt to the Applications folder", err.Error())
+		return
+	}
+
+	// The daemon runs the executable it was installed from. Point it at
+	// the moved app before the old copy goes away.
+	newExe := filepath.Join(dst, "Contents", "MacOS", filepath.Base(exe))
+	if isDaemonInstalled() && strings.HasPrefix(daemonProgramPath(), bundle+"/") {
+		if err := installDaemon(newExe); err != nil {
+			logrus.WithError(err).Error("Failed to reinstall daemon after moving")
+			showAlert("Failed to reinstall the batt daemon", err.Error()+"\n\nOpen batt from the Applications folder and install it again.")
+		}
+	}
+
+	// Disk images and translocated copies are read-only, the user ejects
+	// or deletes the download themselves.
+	if !strings.HasPrefix(bundle, "/Volumes/") && !isTranslocated(bundle) {
+		if !trashItem(bundle) {
+			logrus.WithField("bundle", bundle).Warn("Failed to move the old copy to Trash")
+		}
+	}
+
+	logrus.WithField("path", dst).Info("Moved to Applications, relaunching")
+	if err := exec.Command("/usr/bin/open", "-n", dst).Start(); err != nil {
+		logrus.WithError(err).Error("Failed to relaunch")
+		showAlert("batt was moved to the Applications folder", "Please open it from there.")
+	}
+	os.Exit(0)
+}
+
+// moveToApplications copies bundle to dst, replacing what is there, and
+// clears its quarantine so it is not translocated again. It asks for an
+// administrator password only if the user cannot write to dst.
+func moveToApplications(bundle, dst string) error {
+	shellScript := fmt.Sprintf(`
+set -e
+/bin/rm -rf "%s"
+/usr/bin/ditto "%s" "%s"
+/usr/bin/xattr -dr com.apple.quarantine "%s" || true
+`, dst, bundle, dst, dst)
+
+	if _, err := os.Stat(dst); err == nil {
+		if !trashItem(dst) {
+			logrus.WithField("path", dst).Warn("Failed to move the existing copy to Trash, replacing it")
+		}
+	}
+
+	return runShellScript(shellScript, "move batt to "+dst)
+}
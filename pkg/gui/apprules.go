@@ -0,0 +1,221 @@
+package gui
+
+import (
+	"encoding/json"
+	"runtime/cgo"
+	"slices"
+	"strings"
+	"unsafe"
+
+	"github.com/progrium/darwinkit/macos/appkit"
+	"github.com/progrium/darwinkit/objc"
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/config"
+)
+
+// #cgo LDFLAGS: -framework UniformTypeIdentifiers
+// #include <stdint.h>
+// #include <stdlib.h>
+// // Implemented in apprules.m.
+// void *batt_attachAppRulesObserver(uintptr_t handle);
+// void batt_releaseAppRulesObserver(void *obsPtr);
+// char *batt_runningBundleIDs(void);
+// char *batt_chooseApp(void);
+import "C"
+
+const (
+	// prefAppRules are the apps that charge to 100% while running, as JSON.
+	prefAppRules = "AppRules"
+	// prefAppRuleActive is set while travel mode is on because of an app
+	// rule, so it is turned off again when the apps quit, even after the
+	// menubar app restarts.
+	prefAppRuleActive = "AppRuleActive"
+)
+
+// appRule charges to 100% while the app with BundleID is running.
+type appRule struct {
+	BundleID string `json:"bundleID"`
+	Name     string `json:"name"`
+}
+
+func loadAppRules() []appRule {
+	s := getStringPref(prefAppRules)
+	if s == "" {
+		return nil
+	}
+	var rules []appRule
+	if err := json.Unmarshal([]byte(s), &rules); err != nil {
+		logrus.WithError(err).Warn("Ignoring invalid app rules")
+		return nil
+	}
+	return rules
+}
+
+func saveAppRules(rules []appRule) {
+	if len(rules) == 0 {
+		setStringPref(prefAppRules, "")
+		return
+	}
+	b, err := json.Marshal(rules)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to save app rules")
+		return
+	}
+	setStringPref(prefAppRules, string(b))
+}
+
+// runningRuleApps returns the rules whose app is among the running bundle IDs.
+func runningRuleApps(rules []appRule, running []string) []appRule {
+	var ret []appRule
+	for _, r := range rules {
+		if slices.Contains(running, r.BundleID) {
+			ret = append(ret, r)
+		}
+	}
+	return ret
+}
+
+func runningBundleIDs() []string {
+	cs := C.batt_runningBundleIDs()
+	if cs == nil {
+		return nil
+	}
+	defer C.free(unsafe.Pointer(cs))
+	return strings.Split(C.GoString(cs), "\n")
+}
+
+// chooseApp asks the user to pick an app, ok is false if cancelled.
+func chooseApp() (appRule, bool) {
+	cs := C.batt_chooseApp()
+	if cs == nil {
+		return appRule{}, false
+	}
+	defer C.free(unsafe.Pointer(cs))
+	id, name, _ := strings.Cut(C.GoString(cs), "\n")
+	return appRule{BundleID: id, Name: name}, true
+}
+
+// attachAppRulesObserver calls applyAppRules whenever an app launches or
+// quits. Call releaseAppRulesObserver to free.
+func attachAppRulesObserver(h cgo.Handle) unsafe.Pointer {
+	return C.batt_attachAppRulesObserver(C.uintptr_t(h))
+}
+
+func releaseAppRulesObserver(ptr unsafe.Pointer) {
+	C.batt_releaseAppRulesObserver(ptr)
+}
+
+// applyAppRules turns travel mode on while an app of the rules runs, and off
+// again once they have all quit. Travel mode the user turned on by
+// themselves is left alone, as is a limit of 100% that needs no raising.
+func (c *menuController) applyAppRules() {
+	running := runningRuleApps(loadAppRules(), runningBundleIDs())
+	if want := len(running) > 0; want == getBoolPref(prefAppRuleActive) {
+		return
+	}
+
+	rawConfig, err := c.api.GetConfig()
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to get config for app rules")
+		return
+	}
+	conf := config.NewFileFromConfig(rawConfig, "")
+
+	if len(running) > 0 {
+		if conf.TravelMode() != nil || conf.UpperLimit() >= 100 {
+			return
+		}
+		if _, err := c.api.SetTravelMode(true, 0); err != nil {
+			logrus.WithError(err).Error("Failed to charge to 100% for app rule")
+			return
+		}
+		setBoolPref(prefAppRuleActive, true)
+		logrus.WithField("app", running[0].BundleID).Info("Charging to 100% while app runs")
+		showNotification("Charging to 100%", running[0].Name+" is running. Your charge limit is restored when it quits.")
+		return
+	}
+
+	setBoolPref(prefAppRuleActive, false)
+	if conf.TravelMode() == nil {
+		// The user turned it off already.
+		return
+	}
+	if _, err := c.api.SetTravelMode(false, 0); err != nil {
+		logrus.WithError(err).Error("Failed to restore charge limit after app rule")
+		return
+	}
+	logrus.Info("Restored charge limit after apps quit")
+	showNotification("Charge Limit Restored", "The apps that need a full battery have quit.")
+}
+
+// renderAppRules lists the rules in the Charge to 100% While Running menu.
+// Clicking an app removes it.
+func (c *menuController) renderAppRules() {
+	menu := c.appRulesMenu
+	removeAllMenuItems(menu)
+
+	rules := loadAppRules()
+	if len(rules) == 0 {
+		none := appkit.NewMenuItemWithAction("No Apps", "", func(sender objc.Object) {})
+		none.SetEnabled(false)
+		menu.AddItem(none)
+	}
+	for _, r := range rules {
+		item := appkit.NewMenuItemWithAction(r.Name, "", func(sender objc.Object) {
+			c.removeAppRule(r)
+		})
+		item.SetToolTip(r.BundleID + "\nClick to remove.")
+		menu.AddItem(item)
+	}
+	menu.AddItem(appkit.MenuItem_SeparatorItem())
+	menu.AddItem(appkit.NewMenuItemWithAction("Add App...", "", func(sender objc.Object) {
+		c.addAppRule()
+	}))
+}
+
+func (c *menuController) addAppRule() {
+	r, ok := chooseApp()
+	if !ok {
+		return
+	}
+	rules := loadAppRules()
+	if slices.ContainsFunc(rules, func(e appRule) bool { return e.BundleID == r.BundleID }) {
+		return
+	}
+	saveAppRules(append(rules, r))
+	logrus.WithField("app", r.BundleID).Info("Added app rule")
+	c.renderAppRules()
+	c.applyAppRules()
+}
+
+func (c *menuController) removeAppRule(r appRule) {
+	alert := appkit.NewAlert()
+	alert.SetAlertStyle(appkit.AlertStyleInformational)
+	alert.SetMessageText("Stop Charging to 100% While " + r.Name + " Runs?")
+	alert.AddButtonWithTitle("Remove")
+	alert.AddButtonWithTitle("Cancel")
+	if alert.RunModal() != appkit.AlertFirstButtonReturn {
+		return
+	}
+
+	saveAppRules(slices.DeleteFunc(loadAppRules(), func(e appRule) bool { return e.BundleID == r.BundleID }))
+	logrus.WithField("app", r.BundleID).Info("Removed app rule")
+	c.renderAppRules()
+	c.applyAppRules()
+}
+
+//export battRunningAppsChanged
+func battRunningAppsChanged(h C.uintptr_t) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("panic in battRunningAppsChanged: %v", r)
+		}
+	}()
+	handle := cgo.Handle(h)
+	if v := handle.Value(); v != nil {
+		if c, ok := v.(*menuController); ok {
+			c.applyAppRules()
+		}
+	}
+}
@@ -0,0 +1,81 @@
+#import <Cocoa/Cocoa.h>
+#import <UniformTypeIdentifiers/UniformTypeIdentifiers.h>
+#include <stdint.h>
+#include <string.h>
+
+// Callbacks exported from Go
+extern void battRunningAppsChanged(uintptr_t handle);
+
+@interface BattAppRulesObserver : NSObject
+@property(nonatomic, assign) uintptr_t handle;
+- (instancetype)initWithHandle:(uintptr_t)handle;
+@end
+
+@implementation BattAppRulesObserver
+- (instancetype)initWithHandle:(uintptr_t)handle {
+    if ((self = [super init])) {
+        _handle = handle;
+    }
+    return self;
+}
+- (void)runningAppsChanged:(NSNotification *)note {
+    battRunningAppsChanged(_handle);
+}
+@end
+
+void *batt_attachAppRulesObserver(uintptr_t handle) {
+    BattAppRulesObserver *obs = [[BattAppRulesObserver alloc] initWithHandle:handle];
+    NSNotificationCenter *center = [[NSWorkspace sharedWorkspace] notificationCenter];
+    [center addObserver:obs
+               selector:@selector(runningAppsChanged:)
+                   name:NSWorkspaceDidLaunchApplicationNotification
+                 object:nil];
+    [center addObserver:obs
+               selector:@selector(runningAppsChanged:)
+                   name:NSWorkspaceDidTerminateApplicationNotification
+                 object:nil];
+    return (void *)CFBridgingRetain(obs);
+}
+
+void batt_releaseAppRulesObserver(void *obsPtr) {
+    if (obsPtr == NULL) return;
+    BattAppRulesObserver *obs = (__bridge BattAppRulesObserver *)obsPtr;
+    [[[NSWorkspace sharedWorkspace] notificationCenter] removeObserver:obs];
+    CFRelease(obsPtr);
+}
+
+// Returns the bundle IDs of the running apps, one per line. Caller frees.
+char *batt_runningBundleIDs(void) {
+    NSMutableArray<NSString *> *ids = [NSMutableArray array];
+    for (NSRunningApplication *app in [[NSWorkspace sharedWorkspace] runningApplications]) {
+        if (app.bundleIdentifier != nil) {
+            [ids addObject:app.bundleIdentifier];
+        }
+    }
+    return strdup([[ids componentsJoinedByString:@"\n"] UTF8String]);
+}
+
+// Lets the user pick an app and returns "bundleID\nname", or NULL if
+// cancelled or the app has no bundle ID. Caller frees.
+char *batt_chooseApp(void) {
+    NSOpenPanel *panel = [NSOpenPanel openPanel];
+    panel.title = @"Choose an App";
+    panel.prompt = @"Choose";
+    panel.canChooseFiles = YES;
+    panel.canChooseDirectories = NO;
+    panel.allowsMultipleSelection = NO;
+    panel.allowedContentTypes = @[ UTTypeApplicationBundle ];
+    panel.directoryURL = [NSURL fileURLWithPath:@"/Applications"];
+
+    [NSApp activateIgnoringOtherApps:YES];
+    if ([panel runModal] != NSModalResponseOK || panel.URL == nil) return NULL;
+
+    NSBundle *bundle = [NSBundle bundleWithURL:panel.URL];
+    if (bundle.bundleIdentifier == nil) return NULL;
+    NSString *name = [[NSFileManager defaultManager] displayNameAtPath:panel.URL.path];
+    if ([name hasSuffix:@".app"]) {
+        name = [name stringByDeletingPathExtension];
+    }
+    NSString *out = [NSString stringWithFormat:@"%@\n%@", bundle.bundleIdentifier, name];
+    return strdup(out.UTF8String);
+}
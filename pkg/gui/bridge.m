@@ -83,6 +83,45 @@ void batt_showNotification(const char* title, const char* body) {
     }
 }
 
+// BattNotificationDelegate opens the batt:// URL of a clicked notification,
+// which the app handles like any other batt:// URL.
+@interface BattNotificationDelegate : NSObject <NSUserNotificationCenterDelegate>
+@end
+
+@implementation BattNotificationDelegate
+- (void)userNotificationCenter:(NSUserNotificationCenter *)center didActivateNotification:(NSUserNotification *)notification {
+    NSString *url = notification.userInfo[@"url"];
+    if (url != nil) {
+        [[NSWorkspace sharedWorkspace] openURL:[NSURL URLWithString:url]];
+    }
+}
+@end
+
+// batt_showNotificationWithURL may be called from any thread. The strings are
+// copied before returning, and the notification is delivered on the main
+// thread, which also guards the lazily set delegate.
+void batt_showNotificationWithURL(const char* title, const char* body, const char* url) {
+    static BattNotificationDelegate *delegate;
+    @autoreleasepool {
+        NSString *nsTitle = [NSString stringWithUTF8String:title];
+        NSString *nsBody = [NSString stringWithUTF8String:body];
+        NSString *nsURL = [NSString stringWithUTF8String:url];
+        dispatch_async(dispatch_get_main_queue(), ^{
+            if (delegate == nil) {
+                delegate = [[BattNotificationDelegate alloc] init];
+                [NSUserNotificationCenter defaultUserNotificationCenter].delegate = delegate;
+            }
+
+            NSUserNotification *notification = [[NSUserNotification alloc] init];
+            notification.title = nsTitle;
+            notification.informativeText = nsBody;
+            notification.userInfo = @{@"url" : nsURL};
+            notification.soundName = NSUserNotificationDefaultSoundName;
+            [[NSUserNotificationCenter defaultUserNotificationCenter] deliverNotification:notification];
+        });
+    }
+}
+
 // need codesign app bundle
 // void batt_showNotification(const char* title, const char* body) {
 //     @autoreleasepool {
@@ -114,6 +153,19 @@ void batt_showNotification(const char* title, const char* body) {
 //     }
 // }
 
+// Sets the identifier (used by UI test automation) and the VoiceOver label of
+// a view or menu item. NULL arguments are left untouched.
+void batt_setAccessibility(uintptr_t objPtr, const char *identifier, const char *label) {
+    id obj = (id)objPtr;
+    if (obj == nil) return;
+    if (identifier && [obj respondsToSelector:@selector(setIdentifier:)]) {
+        [obj setIdentifier:[NSString stringWithUTF8String:identifier]];
+    }
+    if (label && [obj respondsToSelector:@selector(setAccessibilityLabel:)]) {
+        [obj setAccessibilityLabel:[NSString stringWithUTF8String:label]];
+    }
+}
+
 bool registerAppWithSMAppService(void) {
     if (@available(macOS 13.0, *)) {
         NSError *error = nil;
@@ -152,4 +204,30 @@ bool isRegisteredWithSMAppService(void) {
         return [service status] == SMAppServiceStatusEnabled;
     }
     return false;
-}
\ No newline at end of file
+}
+
+// Returns the raw SMAppServiceStatus of the main app, or -1 if SMAppService
+// is not available.
+int loginItemStatusWithSMAppService(void) {
+    if (@available(macOS 13.0, *)) {
+        SMAppService *service = [SMAppService mainAppService];
+        return (int)[service status];
+    }
+    return -1;
+}
+
+void openLoginItemsSettings(void) {
+    if (@available(macOS 13.0, *)) {
+        [SMAppService openSystemSettingsLoginItems];
+    }
+}
+// Moves the file at path to the Trash. Returns false and logs on failure.
+bool batt_trashItem(const char *path) {
+    NSURL *url = [NSURL fileURLWithPath:[NSString stringWithUTF8String:path]];
+    NSError *error = nil;
+    BOOL ok = [[NSFileManager defaultManager] trashItemAtURL:url resultingItemURL:nil error:&error];
+    if (!ok && error) {
+        NSLog(@"Failed to move %@ to Trash: %@", url, error);
+    }
+    return ok;
+}
@@ -0,0 +1,30 @@
+package gui
+
+// chargeByTimes are the choices in the Full Charge By menu, in
+// config.ChargeByLayout. "" turns it off.
+var chargeByTimes = []struct {
+	at    string
+	title string
+}{
+	{at: "", title: "Off"},
+	{at: "06:00", title: "6:00"},
+	{at: "06:30", title: "6:30"},
+	{at: "07:00", title: "7:00"},
+	{at: "07:30", title: "7:30"},
+	{at: "08:00", title: "8:00"},
+	{at: "08:30", title: "8:30"},
+	{at: "09:00", title: "9:00"},
+}
+
+// renderChargeBy updates the Full Charge By menu. A time set with the CLI
+// that is not one of the choices is only shown in the title.
+func (c *menuController) renderChargeBy(at string) {
+	for t, item := range c.chargeByItems {
+		setCheckboxItem(item, t == at)
+	}
+	if at == "" {
+		setItemTitle(c.chargeBySubMenuItem, "Full Charge By")
+	} else {
+		setItemTitle(c.chargeBySubMenuItem, "Full Charge By: "+at)
+	}
+}
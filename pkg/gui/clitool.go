@@ -0,0 +1,215 @@
+package gui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/progrium/darwinkit/macos/appkit"
+	"github.com/progrium/darwinkit/objc"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// prefSymlinkDir is the directory the batt command is linked in, empty
+	// for defaultSymlinkDir.
+	prefSymlinkDir    = "SymlinkDir"
+	defaultSymlinkDir = "/usr/local/bin"
+	// loginShellTimeout bounds reading PATH from the login shell, whose
+	// startup files may be slow.
+	loginShellTimeout = 3 * time.Second
+)
+
+// symlinkLocation returns where the batt command is linked.
+func symlinkLocation() string {
+	dir := getStringPref(prefSymlinkDir)
+	if dir == "" {
+		dir = defaultSymlinkDir
+	}
+	return filepath.Join(dir, "batt")
+}
+
+// symlinkDirs returns the directories offered for the link: the default,
+// Homebrew's on Apple silicon and the user's own bin directories, if they
+// exist. The current one is always included.
+func symlinkDirs(home string) []string {
+	dirs := []string{defaultSymlinkDir}
+	for _, dir := range []string{"/opt/homebrew/bin", filepath.Join(home, "bin"), filepath.Join(home, ".local", "bin")} {
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			dirs = append(dirs, dir)
+		}
+	}
+	if cur := filepath.Dir(symlinkLocation()); !slices.Contains(dirs, cur) {
+		dirs = append(dirs, cur)
+	}
+	return dirs
+}
+
+// loginShellPath returns the directories of PATH in the user's login shell.
+// Apps do not inherit it, so the shell is asked.
+func loginShellPath() ([]string, error) {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/zsh"
+	}
+	script := `printf %s "$PATH"`
+	if filepath.Base(shell) == "fish" {
+		script = `string join : $PATH`
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), loginShellTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, shell, "-l", "-c", script).Output()
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSpace(string(out)), ":"), nil
+}
+
+// symlinkState describes the link to the batt command.
+type symlinkState string
+
+const (
+	symlinkOK        symlinkState = "OK"
+	symlinkMissing   symlinkState = "Not Linked"
+	symlinkBroken    symlinkState = "Broken Link"
+	symlinkStale     symlinkState = "Links to Another batt"
+	symlinkNotOnPath symlinkState = "Not on PATH"
+)
+
+// checkSymlink checks that link points to exe and is on the login shell's
+// PATH. Only the state is checked if checkPath is false, which is fast.
+func checkSymlink(link, exe string, checkPath bool) symlinkState {
+	if _, err := os.Lstat(link); err != nil {
+		return symlinkMissing
+	}
+	target, err := filepath.EvalSymlinks(link)
+	if err != nil {
+		return symlinkBroken
+	}
+	if want, err := filepath.EvalSymlinks(exe); err == nil && target != want {
+		return symlinkStale
+	}
+	if !checkPath {
+		return symlinkOK
+	}
+	path, err := loginShellPath()
+	if err != nil {
+		logrus.WithError(err).Debug("Failed to read PATH of the login shell")
+		return symlinkOK
+	}
+	if !slices.Contains(path, filepath.Dir(link)) {
+		return symlinkNotOnPath
+	}
+	return symlinkOK
+}
+
+// linkCommand links the batt command in dir, removing the old link.
+func linkCommand(exe, dir string) error {
+	old := symlinkLocation()
+	link := filepath.Join(dir, "batt")
+	shellScript := fmt.Sprintf(`
+set -e
+/bin/rm -f "%s" || true
+mkdir -p "%s"
+/bin/ln -sf "%s" "%s"
+`, old, dir, exe, link)
+	if err := runShellScript(shellScript, "link batt command"); err != nil {
+		return err
+	}
+	if dir == defaultSymlinkDir {
+		dir = ""
+	}
+	setStringPref(prefSymlinkDir, dir)
+	logrus.WithField("link", link).Info("Linked batt command")
+	return nil
+}
+
+// relinkCommand links the batt command in dir and reports the outcome.
+func relinkCommand(dir string) {
+	exe, err := os.Executable()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get executable path")
+		showAlert("Failed to get executable path", err.Error())
+		return
+	}
+	if err := linkCommand(exe, dir); err != nil {
+		logrus.WithError(err).Error("Failed to link batt command")
+		showAlert("Failed to link the batt command", err.Error())
+		return
+	}
+	if st := checkSymlink(symlinkLocation(), exe, true); st == symlinkNotOnPath {
+		showAlert("batt Is Not on Your PATH", fmt.Sprintf(symlinkNotOnPathAlertText, dir))
+	}
+}
+
+// renderCommandLineTool lists the link state and directories in the Command
+// Line Tool menu.
+func (c *menuController) renderCommandLineTool() {
+	menu := c.cliMenu
+	removeAllMenuItems(menu)
+
+	exe, _ := os.Executable()
+	link := symlinkLocation()
+	state := checkSymlink(link, exe, false)
+	status := appkit.NewMenuItemWithAction("Status: "+string(state), "", func(sender objc.Object) {})
+	status.SetEnabled(false)
+	status.SetToolTip(link)
+	menu.AddItem(status)
+	menu.AddItem(appkit.MenuItem_SeparatorItem())
+
+	home, _ := os.UserHomeDir()
+	for _, dir := range symlinkDirs(home) {
+		item := appkit.NewMenuItemWithAction("Link in "+strings.Replace(dir, home, "~", 1), "", func(sender objc.Object) {
+			relinkCommand(dir)
+			c.renderCommandLineTool()
+		})
+		setCheckboxItem(item, dir == filepath.Dir(link))
+		menu.AddItem(item)
+	}
+	menu.AddItem(appkit.MenuItem_SeparatorItem())
+	menu.AddItem(appkit.NewMenuItemWithAction("Check PATH...", "", func(sender objc.Object) {
+		c.showCommandLineToolCheck()
+	}))
+	repair := appkit.NewMenuItemWithAction("Repair Link", "", func(sender objc.Object) {
+		relinkCommand(filepath.Dir(symlinkLocation()))
+		c.renderCommandLineTool()
+	})
+	repair.SetEnabled(state != symlinkOK)
+	menu.AddItem(repair)
+}
+
+// showCommandLineToolCheck checks the link including PATH, which may take a
+// moment as the login shell starts.
+func (c *menuController) showCommandLineToolCheck() {
+	exe, _ := os.Executable()
+	link := symlinkLocation()
+	switch checkSymlink(link, exe, true) {
+	case symlinkOK:
+		showAlert("The batt Command Works", link+" links to this app and is on your PATH.")
+	case symlinkMissing:
+		showAlert("The batt Command Is Not Linked", "Use Repair Link to link it at "+link+".")
+	case symlinkBroken, symlinkStale:
+		showAlert("The batt Command Needs Repair", link+" does not link to this app, e.g. because batt was moved or updated. Use Repair Link to fix it.")
+	case symlinkNotOnPath:
+		showAlert("batt Is Not on Your PATH", fmt.Sprintf(symlinkNotOnPathAlertText, filepath.Dir(link)))
+	}
+}
+
+// checkSymlinkOnStart offers to repair a link left dangling by moving or
+// updating batt.app. Links to another batt, e.g. from Homebrew, are left
+// alone.
+func checkSymlinkOnStart() {
+	exe, err := os.Executable()
+	if err != nil || appBundlePath(exe) == "" {
+		return
+	}
+	if st := checkSymlink(symlinkLocation(), exe, false); st == symlinkBroken {
+		logrus.WithField("state", st).Info("batt command link needs repair")
+		showNotificationWithURL("batt Command Needs Repair", symlinkLocation()+" no longer links to batt.app. Click to repair it.", "batt://cli/repair")
+	}
+}
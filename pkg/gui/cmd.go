@@ -4,7 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"runtime/cgo"
+	"strings"
+	"time"
 
 	pkgerrors "github.com/pkg/errors"
 	"github.com/progrium/darwinkit/macos/appkit"
@@ -20,6 +21,7 @@ import (
 )
 
 func NewGUICommand(groupID string) *cobra.Command {
+	var prefs []string
 	cmd := &cobra.Command{
 		Use:     "gui",
 		Short:   "Start the batt GUI (debug)",
@@ -34,34 +36,156 @@ This command should not be called directly by the user. Users should use the .ap
 			if err != nil {
 				logrus.WithError(err).Fatal("Failed to get daemon-socket flag")
 			}
-			Run(unixSocketPath)
+			Run(unixSocketPath, prefs)
 		},
 	}
+	AddPrefFlag(cmd, &prefs)
 
 	return cmd
 }
 
-func Run(unixSocketPath string) {
+// NewMenubarIconCommand changes menubar icon preferences of the GUI.
+func NewMenubarIconCommand(groupID string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "menubar-icon",
+		Short:   "Show, hide or resize the menubar icon of batt.app",
+		GroupID: groupID,
+		Long: `Show, hide or resize the menubar icon of batt.app.
+
+These are per-user preferences of the menubar app, so do not run this command with sudo. A running menubar app picks up the change immediately.`,
+	}
+
+	newPrefCommand := func(use, short, key string, value bool) *cobra.Command {
+		return &cobra.Command{
+			Use:   use,
+			Short: short,
+			Args:  cobra.NoArgs,
+			RunE: func(_ *cobra.Command, _ []string) error {
+				if os.Geteuid() == 0 {
+					return fmt.Errorf("menubar icon preferences are per-user, please run this command without sudo")
+				}
+				setBoolPref(key, value)
+				notifyPrefsChanged()
+				logrus.Infof("menubar icon set to %s", use)
+				return nil
+			},
+		}
+	}
+
+	cmd.AddCommand(
+		newPrefCommand("show", "Show the menubar icon", prefHideMenubarIcon, false),
+		newPrefCommand("hide", "Hide the menubar icon, keeping the menubar app running", prefHideMenubarIcon, true),
+		newPrefCommand("compact", "Use a compact, square-sized menubar icon", prefCompactMenubarIcon, true),
+		newPrefCommand("regular", "Use the regular-sized menubar icon", prefCompactMenubarIcon, false),
+	)
+
+	return cmd
+}
+
+// NewShortcutCommand configures global keyboard shortcuts of the GUI.
+func NewShortcutCommand(groupID string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "shortcut",
+		Short:   "Configure global keyboard shortcuts of batt.app",
+		GroupID: groupID,
+		Long: `Configure global keyboard shortcuts of batt.app.
+
+Shortcuts are written as modifiers and a key joined by "+", e.g. "ctrl+opt+cmd+b". Supported modifiers are cmd, shift, opt and ctrl. Only letters and digits are supported as keys. Use "none" to remove a shortcut.
+
+Without arguments, the current shortcuts are printed. These are per-user preferences of the menubar app, so do not run this command with sudo.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			for _, a := range hotKeyActions {
+				shortcut := getStringPref(a.prefKey)
+				if shortcut == "" {
+					shortcut = "none"
+				}
+				cmd.Printf("%-17s %s\n", a.name+":", shortcut)
+			}
+		},
+	}
+
+	for _, a := range hotKeyActions {
+		cmd.AddCommand(&cobra.Command{
+			Use:   a.name + " <shortcut|none>",
+			Short: a.description,
+			Args:  cobra.ExactArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				if os.Geteuid() == 0 {
+					return fmt.Errorf("keyboard shortcuts are per-user, please run this command without sudo")
+				}
+				shortcut := strings.ToLower(args[0])
+				if shortcut == "none" {
+					shortcut = ""
+				} else if _, _, err := parseShortcut(shortcut); err != nil {
+					return err
+				}
+				setStringPref(a.prefKey, shortcut)
+				notifyPrefsChanged()
+				logrus.Infof("shortcut for %s set to %s", a.name, args[0])
+				return nil
+			},
+		})
+	}
+
+	return cmd
+}
+
+// AddPrefFlag adds the --pref flag, which overrides GUI preferences for one
+// run, to cmd.
+func AddPrefFlag(cmd *cobra.Command, prefs *[]string) {
+	cmd.Flags().StringArrayVar(prefs, "pref", nil, "override a preference of the menubar app for this run, as Key=value, e.g. CompactMenubarIcon=true (repeatable, also BATT_PREF_<Key>=value)")
+}
+
+// Run runs the menubar app. prefs override preferences for this run, as
+// Key=value pairs, after those from BATT_PREF_* environment variables.
+func Run(unixSocketPath string, prefs []string) {
+	overridePrefsFromEnv()
+	if err := overridePrefs(prefs); err != nil {
+		logrus.WithError(err).Fatal("Failed to override preferences")
+	}
 	apiClient := client.NewClient(unixSocketPath)
+	tracePhase("preferences")
 
 	app := appkit.Application_SharedApplication()
 	// Set up the menubar immediately to avoid using a dynamic
 	// Objective-C closure for NSApplicationDidFinishLaunching.
 	logrus.WithField("version", version.Version).WithField("gitCommit", version.GitCommit).Info("batt gui")
-	cleanup, ctrl := addMenubar(app, apiClient)
+	takeOverFromOtherInstances()
+	offerMoveToApplications()
+	tracePhase("instance checks")
+	// Ask the daemon while the menu is built.
+	hs := startHandshake(apiClient)
+	cleanup, ctrl := addMenubar(app, apiClient, hs)
 	defer cleanup()
 
 	// Start SSE subscription for daemon events (calibration phase changes)
-	go startEventBridge(apiClient, ctrl)
-
+	ctx, cancel := context.WithCancel(context.Background())
+	ctrl.eventCancel = cancel
+	ctrl.eventDone = make(chan struct{})
+	go func() {
+		defer close(ctrl.eventDone)
+		startEventBridge(ctx, apiClient)
+	}()
+	go ctrl.runCompanionFeed(ctx)
+	go runWatchdog(ctx)
+
+	finishStartupTrace()
 	app.Run()
 }
 
-// startEventBridge subscribes to client events and triggers UI refreshes on demand.
-func startEventBridge(api *client.Client, ctrl *menuController) {
-	ctx, cancel := context.WithCancel(context.Background())
-	ctrl.eventCancel = cancel
+// eventNotificationTitles are the daemon events shown as a notification with
+// their message, by title.
+var eventNotificationTitles = map[string]string{
+	events.CalibrationAction: "Calibration",
+	events.ConflictDetected:  "Charge Limiter Conflict",
+	events.TravelModeEnded:   "Travel Mode",
+	events.BatteryDraining:   "Battery Draining While Plugged In",
+	events.WeakAdapter:       "Power Adapter Too Weak",
+}
 
+// startEventBridge subscribes to client events and triggers UI refreshes on demand.
+func startEventBridge(ctx context.Context, api *client.Client) {
 	evCh := api.SubscribeEvents(ctx)
 
 	for ev := range evCh {
@@ -70,14 +194,10 @@ func startEventBridge(api *client.Client, ctrl *menuController) {
 			"data":  string(ev.Data),
 		}).Debug("new event")
 
-		if ev.Name == events.CalibrationAction {
-			payload, err := events.DecodeAs[events.CalibrationActionEvent](ev)
-			if err != nil {
-				logrus.WithError(err).Error("failed to decode calibration.action event")
-				continue
-			}
+		go runHook(ev)
 
-			showNotification("Calibration", payload.Message)
+		if title, ok := eventNotificationTitles[ev.Name]; ok {
+			showNotification(title, ev.Message())
 		} else if ev.Name == events.CalibrationPhase {
 			payload, err := events.DecodeAs[events.CalibrationPhaseEvent](ev)
 			if err != nil {
@@ -105,7 +225,7 @@ func startEventBridge(api *client.Client, ctrl *menuController) {
 }
 
 //nolint:gocyclo
-func addMenubar(app appkit.Application, apiClient *client.Client) (func(), *menuController) {
+func addMenubar(app appkit.Application, apiClient *client.Client, hs <-chan handshake) (func(), *menuController) {
 	menubarIcon := appkit.StatusBar_SystemStatusBar().StatusItemWithLength(appkit.VariableStatusItemLength)
 	objc.Retain(&menubarIcon)
 	setMenubarImage(menubarIcon, false, false, false)
@@ -121,17 +241,17 @@ func addMenubar(app appkit.Application, apiClient *client.Client) (func(), *menu
 	// Items with attributed titles
 	powerSystemItem := appkit.NewMenuItemWithAction("", "", func(sender objc.Object) {})
 	powerSystemItem.SetEnabled(true) // Changed to true for readability
-	powerSystemItem.SetAttributedTitle(formatPowerString("System", 0))
+	powerSystemItem.SetAttributedTitle(formatPowerString("System", 0, false))
 	powerFlowMenu.AddItem(powerSystemItem)
 
 	powerAdapterItem := appkit.NewMenuItemWithAction("", "", func(sender objc.Object) {})
 	powerAdapterItem.SetEnabled(true) // Changed to true for readability
-	powerAdapterItem.SetAttributedTitle(formatPowerString("Adapter", 0))
+	powerAdapterItem.SetAttributedTitle(formatPowerString("Adapter", 0, false))
 	powerFlowMenu.AddItem(powerAdapterItem)
 
 	powerBatteryItem := appkit.NewMenuItemWithAction("", "", func(sender objc.Object) {})
 	powerBatteryItem.SetEnabled(true) // Changed to true for readability
-	powerBatteryItem.SetAttributedTitle(formatPowerString("Battery", 0))
+	powerBatteryItem.SetAttributedTitle(formatPowerString("Battery", 0, false))
 	powerFlowMenu.AddItem(powerBatteryItem)
 
 	// Add Power Flow submenu near the top
@@ -139,7 +259,16 @@ func addMenubar(app appkit.Application, apiClient *client.Client) (func(), *menu
 
 	// ==================== INSTALL & STATES ====================
 
+	// ctrl is assigned below, once all menu items are created.
+	var ctrl *menuController
+
 	uninstallOrUpgrade := func(sender objc.Object) {
+		if isDaemonInstalled() {
+			if reason := ctrl.daemonBusyReason(); reason != "" && !ctrl.confirmUpgradeWhileBusy(reason) {
+				return
+			}
+		}
+
 		exe, err := os.Executable()
 		if err != nil {
 			logrus.WithError(err).Error("Failed to get executable path")
@@ -153,6 +282,11 @@ func addMenubar(app appkit.Application, apiClient *client.Client) (func(), *menu
 			showAlert("Installation failed", err.Error())
 			return
 		}
+		if err := waitForDaemon(apiClient, version.Version); err != nil {
+			logrus.WithError(err).Error("Daemon is not healthy after installation")
+			showAlert("Installation failed", err.Error()+"\n\nCheck "+battLogPath+" for details, then try again.")
+			return
+		}
 
 		err = startAppAtBoot()
 		if err != nil {
@@ -162,16 +296,25 @@ func addMenubar(app appkit.Application, apiClient *client.Client) (func(), *menu
 		}
 
 		setMenubarImage(menubarIcon, true, true, false)
+		offerMigration(apiClient)
+		ctrl.reportCompatibility()
 	}
 
 	upgradeItem := appkit.NewMenuItemWithAction("Upgrade Daemon...", "u", uninstallOrUpgrade)
-	upgradeItem.SetToolTip(`Your batt daemon is not compatible with this client version and needs to be upgraded. This is usually caused by a new client version that requires a new daemon version. You can upgrade the batt daemon by running this command.`)
+	upgradeItem.SetToolTip(upgradeTooltip)
 	menu.AddItem(upgradeItem)
 
 	installItem := appkit.NewMenuItemWithAction("Install Daemon...", "i", uninstallOrUpgrade)
 	installItem.SetToolTip(`Install the batt daemon. batt daemon is a component that controls charging. You must enter your password to install it because controlling charging is a privileged action.`)
 	menu.AddItem(installItem)
 
+	repairAccessItem := appkit.NewMenuItemWithAction("Repair Daemon Access...", "", func(sender objc.Object) {
+		ctrl.repairDaemonAccess()
+	})
+	repairAccessItem.SetToolTip(repairAccessTooltip)
+	repairAccessItem.SetHidden(true)
+	menu.AddItem(repairAccessItem)
+
 	stateItem := appkit.NewMenuItemWithAction("Loading...", "", func(sender objc.Object) {})
 	stateItem.SetEnabled(false)
 	menu.AddItem(stateItem)
@@ -180,6 +323,71 @@ func addMenubar(app appkit.Application, apiClient *client.Client) (func(), *menu
 	currentLimitItem.SetEnabled(false)
 	menu.AddItem(currentLimitItem)
 
+	policyMenu := appkit.NewMenuWithTitle("Current Policy")
+	policyMenu.SetAutoenablesItems(false)
+	policySubMenuItem := appkit.NewSubMenuItem(policyMenu)
+	policySubMenuItem.SetTitle("Current Policy")
+	policySubMenuItem.SetToolTip(policyTooltip)
+	menu.AddItem(policySubMenuItem)
+	newPolicyItem := func() appkit.MenuItem {
+		item := appkit.NewMenuItemWithAction("Loading...", "", func(sender objc.Object) {})
+		item.SetEnabled(false)
+		policyMenu.AddItem(item)
+		return item
+	}
+	policyDetailItem := newPolicyItem()
+	policyLimitsItem := newPolicyItem()
+	policySetByItem := newPolicyItem()
+
+	statusWindowItem := appkit.NewMenuItemWithAction("Show Status Window...", "s", func(sender objc.Object) {
+		ctrl.showStatusWindow()
+	})
+	statusWindowItem.SetToolTip(statusWindowTooltip)
+	menu.AddItem(statusWindowItem)
+
+	// Shown in place of the status window item while Option is held.
+	debugWindowItem := appkit.NewMenuItemWithAction("Show Debug Window...", "s", func(sender objc.Object) {
+		ctrl.showDebugWindow()
+	})
+	debugWindowItem.SetKeyEquivalentModifierMask(appkit.EventModifierFlagCommand | appkit.EventModifierFlagOption)
+	debugWindowItem.SetAlternate(true)
+	debugWindowItem.SetToolTip(debugWindowTooltip)
+	menu.AddItem(debugWindowItem)
+
+	paletteItem := appkit.NewMenuItemWithAction("Command Palette...", "k", func(sender objc.Object) {
+		ctrl.showCommandPalette()
+	})
+	paletteItem.SetToolTip(paletteTooltip)
+	menu.AddItem(paletteItem)
+
+	unsupportedItem := appkit.NewMenuItemWithAction("⚠️ This Mac Is Not Supported...", "", func(sender objc.Object) {
+		ctrl.showCapabilities()
+	})
+	unsupportedItem.SetToolTip(unsupportedTooltip)
+	unsupportedItem.SetHidden(true)
+	menu.AddItem(unsupportedItem)
+
+	conflictItem := appkit.NewMenuItemWithAction("⚠️ Another Charge Limiter Detected...", "", func(sender objc.Object) {
+		ctrl.showConflict()
+	})
+	conflictItem.SetToolTip(conflictTooltip)
+	conflictItem.SetHidden(true)
+	menu.AddItem(conflictItem)
+
+	// Shown for the rest of the session after an update, until clicked.
+	updated := checkUpdated()
+	var whatsNewItem appkit.MenuItem
+	whatsNewItem = appkit.NewMenuItemWithAction("What's New in "+version.Version+"...", "", func(sender objc.Object) {
+		openReleaseNotes()
+		setItemHidden(whatsNewItem, true)
+	})
+	whatsNewItem.SetToolTip(whatsNewTooltip)
+	whatsNewItem.SetHidden(!updated)
+	menu.AddItem(whatsNewItem)
+	if updated {
+		showNotification("batt Updated", "batt has been updated to "+version.Version+`. Choose "What's New" in the batt menu to see the release notes.`)
+	}
+
 	// ==================== QUICK LIMITS ====================
 	menu.AddItem(appkit.MenuItem_SeparatorItem())
 
@@ -275,6 +483,104 @@ Note that you must have a MagSafe LED on your MacBook to use this feature.`)
 	controlMagSafeDisableItem.SetToolTip(`Disable MagSafe LED control. The LED will stay in its default state (mostly orange).`)
 	controlMagSafeAlwaysOffItem.SetToolTip(`Force the MagSafe LED to stay off regardless of charging state.`)
 
+	optimizedChargingMenu := appkit.NewMenuWithTitle("Optimized Battery Charging")
+	optimizedChargingMenu.SetAutoenablesItems(false)
+	optimizedChargingSubMenuItem := appkit.NewSubMenuItem(optimizedChargingMenu)
+	optimizedChargingSubMenuItem.SetTitle("Optimized Battery Charging")
+	optimizedChargingSubMenuItem.SetToolTip(optimizedChargingTooltip)
+	advancedMenu.AddItem(optimizedChargingSubMenuItem)
+
+	optimizedChargingStatusItem := appkit.NewMenuItemWithAction("Loading...", "", func(sender objc.Object) {})
+	optimizedChargingStatusItem.SetEnabled(false)
+	optimizedChargingMenu.AddItem(optimizedChargingStatusItem)
+	optimizedChargingMenu.AddItem(appkit.MenuItem_SeparatorItem())
+
+	optimizedChargingItems := map[config.OptimizedChargingMode]appkit.MenuItem{}
+	for _, m := range optimizedChargingModes {
+		optimizedChargingItems[m.mode] = appkit.NewMenuItemWithAction(m.title, "", func(sender objc.Object) {
+			for mode, item := range optimizedChargingItems {
+				setCheckboxItem(item, mode == m.mode)
+			}
+
+			_, err := apiClient.SetOptimizedChargingMode(m.mode)
+			if err != nil {
+				logrus.WithError(err).Error("Failed to set optimized charging mode")
+				showAlert("Failed to set Optimized Battery Charging interplay", err.Error())
+				return
+			}
+			if m.mode == config.OptimizedChargingModeDisable && askOpenBatterySettings() {
+				openBatterySettings()
+			}
+		})
+		optimizedChargingItems[m.mode].SetToolTip(m.tooltip)
+		optimizedChargingMenu.AddItem(optimizedChargingItems[m.mode])
+	}
+
+	optimizedChargingMenu.AddItem(appkit.MenuItem_SeparatorItem())
+	optimizedChargingMenu.AddItem(appkit.NewMenuItemWithAction("Open Battery Settings...", "", func(sender objc.Object) {
+		openBatterySettings()
+	}))
+
+	energySamplingItem := checkBoxItem("Sample Energy Use on Battery", "", func(checked bool) {
+		_, err := apiClient.SetEnergySampling(checked)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to set energy sampling")
+			showAlert("Failed to set energy sampling", err.Error())
+			return
+		}
+	})
+	energySamplingItem.SetToolTip(energySamplingTooltip)
+	advancedMenu.AddItem(energySamplingItem)
+
+	energyReportItem := appkit.NewMenuItemWithAction("Energy Impact Report...", "", func(sender objc.Object) {
+		ctrl.showEnergyReport()
+	})
+	energyReportItem.SetToolTip(energyReportTooltip)
+	advancedMenu.AddItem(energyReportItem)
+
+	usageReportItem := appkit.NewMenuItemWithAction("Usage Statistics...", "", func(sender objc.Object) {
+		ctrl.showUsageReport()
+	})
+	usageReportItem.SetToolTip(usageReportTooltip)
+	advancedMenu.AddItem(usageReportItem)
+
+	monthlyReportItem := appkit.NewMenuItemWithAction("Last Month's Battery Report...", "", func(sender objc.Object) {
+		ctrl.showMonthlyReport("")
+	})
+	monthlyReportItem.SetToolTip(monthlyReportTooltip)
+	advancedMenu.AddItem(monthlyReportItem)
+
+	healthComparisonItem := appkit.NewMenuItemWithAction("Compare Battery Health...", "", func(sender objc.Object) {
+		ctrl.showHealthComparison()
+	})
+	healthComparisonItem.SetToolTip(healthComparisonTooltip)
+	advancedMenu.AddItem(healthComparisonItem)
+
+	smcDiagnosticsItem := appkit.NewMenuItemWithAction("SMC Diagnostics...", "", func(sender objc.Object) {
+		ctrl.showCapabilities()
+	})
+	smcDiagnosticsItem.SetToolTip(smcDiagnosticsTooltip)
+	advancedMenu.AddItem(smcDiagnosticsItem)
+
+	selfTestItem := appkit.NewMenuItemWithAction("Run Self-Test...", "", func(sender objc.Object) {
+		ctrl.runSelfTest()
+	})
+	selfTestItem.SetToolTip(selfTestTooltip)
+	advancedMenu.AddItem(selfTestItem)
+
+	hooksItem := appkit.NewMenuItemWithAction("Open Hooks Folder...", "", func(sender objc.Object) {
+		openHooksDir()
+	})
+	hooksItem.SetToolTip(hooksTooltip)
+	advancedMenu.AddItem(hooksItem)
+
+	appRulesMenu := appkit.NewMenuWithTitle("Charge to 100% While Running")
+	appRulesMenu.SetAutoenablesItems(false)
+	appRulesSubMenuItem := appkit.NewSubMenuItem(appRulesMenu)
+	appRulesSubMenuItem.SetTitle("Charge to 100% While Running")
+	appRulesSubMenuItem.SetToolTip(appRulesTooltip)
+	advancedMenu.AddItem(appRulesSubMenuItem)
+
 	preventIdleSleepItem := checkBoxItem("Prevent Idle Sleep when Charging", "", func(checked bool) {
 		// Perform action based on new state
 		_, err := apiClient.SetPreventIdleSleep(checked)
@@ -329,6 +635,17 @@ Does similar thing to prevent-idle-sleep, but works for manual sleep too.
 Note: please disable disable-charging-pre-sleep and prevent-idle-sleep, while this feature is in use`)
 	advancedMenu.AddItem(preventSystemSleepItem)
 
+	weakAdapterBoostItem := checkBoxItem("Charge Fully on Weak Adapter", "", func(checked bool) {
+		_, err := apiClient.SetWeakAdapterBoost(checked)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to set weak adapter boost")
+			showAlert("Failed to set weak adapter boost", err.Error())
+			return
+		}
+	})
+	weakAdapterBoostItem.SetToolTip(weakAdapterBoostTooltip)
+	advancedMenu.AddItem(weakAdapterBoostItem)
+
 	forceDischargeItem := checkBoxItem("Force Discharge...", "", func(checked bool) {
 		if checked {
 			alert := appkit.NewAlert()
@@ -375,25 +692,7 @@ It's recommended to run the calibration process every few months.`)
 	autoCalibrationItem.AddItem(calStatusItem)
 
 	calStartItem := appkit.NewMenuItemWithAction("Start", "", func(sender objc.Object) {
-		alert := appkit.NewAlert()
-		alert.SetIcon(appkit.Image_ImageWithSystemSymbolNameAccessibilityDescription("battery.100", "calibration"))
-		alert.SetAlertStyle(appkit.AlertStyleInformational)
-		alert.SetMessageText("Start Auto Calibration?")
-		alert.SetInformativeText(`This will:
-1. Discharge (to 15% by default) without sleep prevention.
-2. Charge to 100%.
-3. Hold at full charge (for 2 hours by default).
-4. Discharge back to previous charge limit.
-
-NOTES:
-• You can pause or cancel anytime from the menu.
-• Highly recommend keeping your Mac connected to power throughout the process to prevent the battery level from dropping below the threshold without timely charging.
-• If you are using Clamshell mode (using a Mac laptop with an external monitor and the lid closed), *the discharging process will cause your Mac to go to sleep*. So you should keep the lid open during the calibration process.`)
-		// Explicitly add two buttons and compare first-button response, consistent with update flow
-		alert.AddButtonWithTitle("Start")
-		alert.AddButtonWithTitle("Cancel")
-		response := alert.RunModal()
-		if response != appkit.AlertFirstButtonReturn {
+		if !confirmStartCalibration() {
 			logrus.Info("User cancelled auto calibration")
 			return
 		}
@@ -401,7 +700,7 @@ NOTES:
 			showAlert("Failed to start calibration", err.Error())
 			return
 		}
-		calStatusItem.SetTitle("Status: In Progress")
+		setItemTitle(calStatusItem, "Status: In Progress")
 	})
 	autoCalibrationItem.AddItem(calStartItem)
 
@@ -426,12 +725,92 @@ NOTES:
 			showAlert("Failed to cancel calibration", err.Error())
 			return
 		}
-		calStatusItem.SetTitle("Status: Idle")
+		setItemTitle(calStatusItem, "Status: Idle")
 	})
 	autoCalibrationItem.AddItem(calCancelItem)
 
 	advancedMenu.AddItem(appkit.MenuItem_SeparatorItem())
 
+	compactIconItem := checkBoxItem("Compact Menubar Icon", "", func(checked bool) {
+		setBoolPref(prefCompactMenubarIcon, checked)
+		if checked {
+			menubarIcon.SetLength(squareStatusItemLength)
+		} else {
+			menubarIcon.SetLength(appkit.VariableStatusItemLength)
+		}
+	})
+	compactIconItem.SetToolTip(compactIconTooltip)
+	advancedMenu.AddItem(compactIconItem)
+
+	systemChargeItem := checkBoxItem("Match macOS Battery Percentage", "", func(checked bool) {
+		setBoolPref(prefSystemCharge, checked)
+	})
+	systemChargeItem.SetToolTip(systemChargeTooltip)
+	advancedMenu.AddItem(systemChargeItem)
+
+	hideIconItem := appkit.NewMenuItemWithAction("Hide Menubar Icon...", "", func(sender objc.Object) {
+		alert := appkit.NewAlert()
+		alert.SetAlertStyle(appkit.AlertStyleInformational)
+		alert.SetMessageText("Hide Menubar Icon?")
+		alert.SetInformativeText(hideIconAlertText)
+		alert.AddButtonWithTitle("Hide")
+		alert.AddButtonWithTitle("Cancel")
+		if alert.RunModal() != appkit.AlertFirstButtonReturn {
+			return
+		}
+		setBoolPref(prefHideMenubarIcon, true)
+		menubarIcon.SetVisible(false)
+	})
+	hideIconItem.SetToolTip(hideIconTooltip)
+	advancedMenu.AddItem(hideIconItem)
+
+	loginItemItem := appkit.NewMenuItemWithAction("Start at Login: "+GetLoginItemStatus().String(), "", func(sender objc.Object) {
+		repairLoginItem()
+	})
+	loginItemItem.SetToolTip(loginItemTooltip)
+	advancedMenu.AddItem(loginItemItem)
+
+	nonRootAccessItem := checkBoxItem("Allow Access Without Password", "", func(checked bool) {
+		ctrl.setNonRootAccess(checked)
+	})
+	nonRootAccessItem.SetToolTip(nonRootAccessTooltip)
+	advancedMenu.AddItem(nonRootAccessItem)
+
+	cliMenu := appkit.NewMenuWithTitle("Command Line Tool")
+	cliMenu.SetAutoenablesItems(false)
+	cliSubMenuItem := appkit.NewSubMenuItem(cliMenu)
+	cliSubMenuItem.SetTitle("Command Line Tool")
+	cliSubMenuItem.SetToolTip(cliTooltip)
+	advancedMenu.AddItem(cliSubMenuItem)
+
+	daemonLogMenu := appkit.NewMenuWithTitle("Daemon Log")
+	daemonLogMenu.SetAutoenablesItems(false)
+	daemonLogSubMenuItem := appkit.NewSubMenuItem(daemonLogMenu)
+	daemonLogSubMenuItem.SetTitle("Daemon Log")
+	daemonLogSubMenuItem.SetToolTip(daemonLogTooltip)
+	advancedMenu.AddItem(daemonLogSubMenuItem)
+
+	daemonLogMenu.AddItem(appkit.NewMenuItemWithAction("Show Recent Log...", "", func(sender objc.Object) {
+		ctrl.showDebugWindow()
+	}))
+	daemonLogMenu.AddItem(appkit.MenuItem_SeparatorItem())
+	logLevelItems := map[string]appkit.MenuItem{}
+	for _, l := range daemonLogLevels {
+		logLevelItems[l.level] = appkit.NewMenuItemWithAction("Level: "+l.title, "", func(sender objc.Object) {
+			ctrl.setLogLevel(l.level)
+		})
+		daemonLogMenu.AddItem(logLevelItems[l.level])
+	}
+	daemonLogMenu.AddItem(appkit.MenuItem_SeparatorItem())
+	daemonLogMenu.AddItem(appkit.NewMenuItemWithAction("Rotate Log", "", func(sender objc.Object) {
+		ctrl.rotateLog()
+	}))
+	daemonLogMenu.AddItem(appkit.NewMenuItemWithAction("Clear Log...", "", func(sender objc.Object) {
+		ctrl.clearLog()
+	}))
+
+	advancedMenu.AddItem(appkit.MenuItem_SeparatorItem())
+
 	versionItem := appkit.NewMenuItemWithAction("Version: "+version.Version, "", func(sender objc.Object) {})
 	versionItem.SetEnabled(false)
 	advancedMenu.AddItem(versionItem)
@@ -465,8 +844,126 @@ NOTES:
 After uninstalling the batt daemon, no charging control will be present on your system and your Mac will charge to 100% as normal. The menubar app will still be present, but all options will be disabled. You can remove the menubar app by moving it to the trash.`)
 	advancedMenu.AddItem(uninstallItem)
 
+	uninstallEverythingItem := appkit.NewMenuItemWithAction("Uninstall batt Completely...", "", func(sender objc.Object) {
+		exe, err := os.Executable()
+		if err != nil {
+			logrus.WithError(err).Error("Failed to get executable path")
+			showAlert("Failed to get executable path", err.Error())
+			return
+		}
+
+		alert := appkit.NewAlert()
+		alert.SetAlertStyle(appkit.AlertStyleWarning)
+		alert.SetMessageText("Uninstall batt Completely?")
+		alert.SetInformativeText("The following will be removed:\n\n• " + strings.Join(uninstallEverythingItems(exe), "\n• ") + "\n\nYou will be asked for your password.")
+		alert.AddButtonWithTitle("Uninstall")
+		alert.AddButtonWithTitle("Cancel")
+		if alert.RunModal() != appkit.AlertFirstButtonReturn {
+			logrus.Info("User cancelled complete uninstall")
+			return
+		}
+
+		if err := uninstallEverything(exe); err != nil {
+			logrus.WithError(err).Error("Failed to uninstall batt")
+			showAlert("Failed to uninstall batt", err.Error())
+			return
+		}
+
+		logrus.Info("batt uninstalled, quitting")
+		ctrl.shutdown()
+		app.Terminate(nil)
+	})
+	uninstallEverythingItem.SetToolTip(uninstallEverythingTooltip)
+	advancedMenu.AddItem(uninstallEverythingItem)
+
 	// ==================== QUIT ====================
 	menu.AddItem(appkit.MenuItem_SeparatorItem())
+
+	travelModeMenu := appkit.NewMenuWithTitle("Travel Mode")
+	travelModeMenu.SetAutoenablesItems(false)
+	travelModeSubMenuItem := appkit.NewSubMenuItem(travelModeMenu)
+	travelModeSubMenuItem.SetTitle("Travel Mode")
+	travelModeSubMenuItem.SetToolTip(travelModeTooltip)
+	menu.AddItem(travelModeSubMenuItem)
+
+	travelModeStatusItem := appkit.NewMenuItemWithAction("Loading...", "", func(sender objc.Object) {})
+	travelModeStatusItem.SetEnabled(false)
+	travelModeMenu.AddItem(travelModeStatusItem)
+	travelModeMenu.AddItem(appkit.MenuItem_SeparatorItem())
+
+	setTravelMode := func(enabled bool, days int) {
+		_, err := apiClient.SetTravelMode(enabled, days)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to set travel mode")
+			showAlert("Failed to set travel mode", err.Error())
+		}
+	}
+	for _, d := range travelModeDurations {
+		travelModeMenu.AddItem(appkit.NewMenuItemWithAction(d.title, "", func(sender objc.Object) {
+			setTravelMode(true, d.days)
+		}))
+	}
+	travelModeOffItem := appkit.NewMenuItemWithAction("Turn Off", "", func(sender objc.Object) {
+		setTravelMode(false, 0)
+	})
+	travelModeOffItem.SetHidden(true)
+	travelModeMenu.AddItem(travelModeOffItem)
+
+	chargeByMenu := appkit.NewMenuWithTitle("Full Charge By")
+	chargeByMenu.SetAutoenablesItems(false)
+	chargeBySubMenuItem := appkit.NewSubMenuItem(chargeByMenu)
+	chargeBySubMenuItem.SetTitle("Full Charge By")
+	chargeBySubMenuItem.SetToolTip(chargeByTooltip)
+	menu.AddItem(chargeBySubMenuItem)
+
+	chargeByItems := map[string]appkit.MenuItem{}
+	for _, t := range chargeByTimes {
+		chargeByItems[t.at] = appkit.NewMenuItemWithAction(t.title, "", func(sender objc.Object) {
+			ret, err := apiClient.SetChargeBy(t.at)
+			if err != nil {
+				logrus.WithError(err).Error("Failed to set charge-by time")
+				showAlert("Failed to set Full Charge By", err.Error())
+				return
+			}
+			ctrl.renderChargeBy(t.at)
+			if t.at != "" {
+				showNotification("Full Charge By "+t.at, ret)
+			}
+		})
+		chargeByMenu.AddItem(chargeByItems[t.at])
+		if t.at == "" {
+			chargeByMenu.AddItem(appkit.MenuItem_SeparatorItem())
+		}
+	}
+
+	storageModeItem := checkBoxItem("Storage Mode", "", func(checked bool) {
+		_, err := apiClient.SetStorageMode(checked)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to set storage mode")
+			showAlert("Failed to set storage mode", err.Error())
+			return
+		}
+	})
+	storageModeItem.SetToolTip(storageModeTooltip)
+	menu.AddItem(storageModeItem)
+
+	pauseChargingItem := appkit.NewMenuItemWithAction("Pause Charging", "p", func(sender objc.Object) {
+		ctrl.toggleChargingPaused()
+	})
+	pauseChargingItem.SetToolTip(pauseChargingTooltip)
+	menu.AddItem(pauseChargingItem)
+
+	bypassItem := checkBoxItem("Run from Adapter at Current Level", "", func(checked bool) {
+		_, err := apiClient.SetBypass(checked)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to set bypass")
+			showAlert("Failed to run from the power adapter", err.Error())
+			return
+		}
+	})
+	bypassItem.SetToolTip(bypassTooltip)
+	menu.AddItem(bypassItem)
+
 	disableItem := appkit.NewMenuItemWithAction("Disable Charging Limit", "d", func(sender objc.Object) {
 		ret, err := apiClient.SetLimit(100)
 		if err != nil {
@@ -482,27 +979,60 @@ After uninstalling the batt daemon, no charging control will be present on your
 	menubarIcon.SetMenu(menu)
 
 	// ==================== CALLBACKS & OBSERVER ====================
-	ctrl := &menuController{
-		api:                         apiClient,
-		menubarIcon:                 menubarIcon,
-		powerFlowSubMenuItem:        powerFlowSubMenuItem,
-		installItem:                 installItem,
-		upgradeItem:                 upgradeItem,
-		stateItem:                   stateItem,
-		currentLimitItem:            currentLimitItem,
-		quickLimitsItem:             quickLimitsItem,
-		quickLimitsItems:            setQuickLimitsItems,
-		advancedSubMenuItem:         advancedSubMenuItem,
-		controlMagSafeLEDItem:       controlMagSafeLEDItem,
-		controlMagSafeEnableItem:    controlMagSafeEnableItem,
-		controlMagSafeDisableItem:   controlMagSafeDisableItem,
-		controlMagSafeAlwaysOffItem: controlMagSafeAlwaysOffItem,
-		preventIdleSleepItem:        preventIdleSleepItem,
-		disableChargingPreSleepItem: disableChargingPreSleepItem,
-		preventSystemSleepItem:      preventSystemSleepItem,
-		forceDischargeItem:          forceDischargeItem,
-		uninstallItem:               uninstallItem,
-		disableItem:                 disableItem,
+	ctrl = &menuController{
+		api:                          apiClient,
+		menubarIcon:                  menubarIcon,
+		powerFlowSubMenuItem:         powerFlowSubMenuItem,
+		installItem:                  installItem,
+		upgradeItem:                  upgradeItem,
+		stateItem:                    stateItem,
+		currentLimitItem:             currentLimitItem,
+		policySubMenuItem:            policySubMenuItem,
+		policyDetailItem:             policyDetailItem,
+		policyLimitsItem:             policyLimitsItem,
+		policySetByItem:              policySetByItem,
+		conflictItem:                 conflictItem,
+		quickLimitsItem:              quickLimitsItem,
+		quickLimitsItems:             setQuickLimitsItems,
+		advancedSubMenuItem:          advancedSubMenuItem,
+		controlMagSafeLEDItem:        controlMagSafeLEDItem,
+		controlMagSafeEnableItem:     controlMagSafeEnableItem,
+		controlMagSafeDisableItem:    controlMagSafeDisableItem,
+		controlMagSafeAlwaysOffItem:  controlMagSafeAlwaysOffItem,
+		optimizedChargingSubMenuItem: optimizedChargingSubMenuItem,
+		energySamplingItem:           energySamplingItem,
+		energyReportItem:             energyReportItem,
+		usageReportItem:              usageReportItem,
+		monthlyReportItem:            monthlyReportItem,
+		healthComparisonItem:         healthComparisonItem,
+		optimizedChargingStatusItem:  optimizedChargingStatusItem,
+		optimizedChargingItems:       optimizedChargingItems,
+		preventIdleSleepItem:         preventIdleSleepItem,
+		disableChargingPreSleepItem:  disableChargingPreSleepItem,
+		preventSystemSleepItem:       preventSystemSleepItem,
+		weakAdapterBoostItem:         weakAdapterBoostItem,
+		forceDischargeItem:           forceDischargeItem,
+		uninstallItem:                uninstallItem,
+		compactIconItem:              compactIconItem,
+		systemChargeItem:             systemChargeItem,
+		loginItemItem:                loginItemItem,
+		daemonLogSubMenuItem:         daemonLogSubMenuItem,
+		cliSubMenuItem:               cliSubMenuItem,
+		nonRootAccessItem:            nonRootAccessItem,
+		repairAccessItem:             repairAccessItem,
+		cliMenu:                      cliMenu,
+		appRulesSubMenuItem:          appRulesSubMenuItem,
+		appRulesMenu:                 appRulesMenu,
+		logLevelItems:                logLevelItems,
+		disableItem:                  disableItem,
+		travelModeSubMenuItem:        travelModeSubMenuItem,
+		travelModeStatusItem:         travelModeStatusItem,
+		travelModeOffItem:            travelModeOffItem,
+		storageModeItem:              storageModeItem,
+		pauseChargingItem:            pauseChargingItem,
+		bypassItem:                   bypassItem,
+		chargeBySubMenuItem:          chargeBySubMenuItem,
+		chargeByItems:                chargeByItems,
 		// Auto Calibration
 		autoCalSubMenuItem: autoCalibrationSub,
 		calStatusItem:      calStatusItem,
@@ -514,62 +1044,88 @@ After uninstalling the batt daemon, no charging control will be present on your
 		systemItem:  powerSystemItem,
 		adapterItem: powerAdapterItem,
 		batteryItem: powerBatteryItem,
+
+		resources: &cgoResources{},
 	}
+	ctrl.upgradeDaemon = func() { uninstallOrUpgrade(objc.Object{}) }
 
-	h := cgo.NewHandle(ctrl)
+	res := ctrl.resources
+	h := res.newHandle("menu controller", ctrl)
 	observerPtr := AttachPowerFlowObserver(menu, h)
-
-	cleanupFunc := func() {
-		logrus.Info("Cleaning up resources")
-		ReleasePowerFlowObserver(observerPtr)
-		h.Delete()
-	}
+	res.track("power flow observer", func() { ReleasePowerFlowObserver(observerPtr) })
+	appObserverPtr := attachAppObserver(h)
+	res.track("app observer", func() { releaseAppObserver(appObserverPtr) })
+	urlHandlerPtr := attachURLHandler(h)
+	res.track("URL handler", func() { releaseURLHandler(urlHandlerPtr) })
+	installHotKeyHandler(h)
+	res.track("hot key handler", removeHotKeyHandler)
+	appRulesObserverPtr := attachAppRulesObserver(h)
+	res.track("app rules observer", func() { releaseAppRulesObserver(appRulesObserverPtr) })
+	ctrl.renderAppRules()
+	ctrl.applyMenubarPrefs()
+	ctrl.registerHotKeys()
+	res.track("hot keys", ctrl.unregisterHotKeys)
+
+	cleanupFunc := ctrl.shutdown
 
 	// The quit action is now simplified to only terminate the app.
 	quitItem := appkit.NewMenuItemWithAction("Quit Menubar App", "q", func(sender objc.Object) {
-		if ctrl.eventCancel != nil {
-			logrus.Debug("Cancelling event subscription")
-			ctrl.eventCancel()
-		}
-
 		logrus.Info("Quitting client")
+		// Terminate exits without returning, so deferred cleanups never run.
+		ctrl.shutdown()
 		app.Terminate(nil)
 	})
 
 	quitItem.SetToolTip(quitTooltipInstalled)
 	menu.AddItem(quitItem)
 	ctrl.quitItem = quitItem
+	ctrl.setAccessibilityIdentifiers()
 
 	// The observer above will trigger onWillOpen/onDidClose/timer without using libffi closures.
 
+	tracePhase("menu")
+
+	// Checks that do not touch the menu and may notify run in the
+	// background, so they do not delay the menubar icon. The notifications
+	// themselves are delivered on the main thread.
+	go func() {
+		ctrl.notifyMonthlyReport(time.Now())
+		checkSymlinkOnStart()
+	}()
+
 	// Update icon onstart up
 	{
-		logrus.Info("Getting config")
-		rawConfig, err := apiClient.GetConfig()
-		if err != nil {
-			logrus.WithError(err).Warnf("Failed to get config")
+		h := <-hs
+		if h.configErr != nil {
+			logrus.WithError(h.configErr).Warnf("Failed to get config")
 			ctrl.toggleMenusRequiringInstall(false, false, false)
+			ctrl.renderDaemonAccess(h.configErr)
 			return cleanupFunc, ctrl
 		}
-		conf := config.NewFileFromConfig(rawConfig, "")
+		conf := config.NewFileFromConfig(h.config, "")
 		logrus.WithFields(conf.LogrusFields()).Info("Got config")
-		logrus.Info("Getting charging control capability")
-		capable, err := apiClient.GetChargingControlCapable()
-		if err != nil {
-			logrus.WithError(err).Warnf("Failed to get charging capablility")
+		ctrl.renderTravelMode(conf.TravelMode())
+		if h.pausedErr == nil {
+			ctrl.renderChargingPaused(h.paused)
+		}
+		remindStorageMode(conf.StorageMode(), time.Now())
+		if h.capableErr != nil {
+			logrus.WithError(h.capableErr).Warnf("Failed to get charging capablility")
 			ctrl.toggleMenusRequiringInstall(true, false, false)
 			return cleanupFunc, ctrl
 		}
-		logrus.WithField("capable", capable).Info("Got charging control capability")
-		logrus.Info("Getting daemon version")
-		daemonVersion, err := apiClient.GetVersion()
-		if err != nil {
-			logrus.WithError(err).Warnf("Failed to get version")
-			ctrl.toggleMenusRequiringInstall(true, capable, true)
+		logrus.WithField("capable", h.capable).Info("Got charging control capability")
+		if h.versionErr != nil {
+			logrus.WithError(h.versionErr).Warnf("Failed to get version")
+			ctrl.toggleMenusRequiringInstall(true, h.capable, true)
 		} else {
-			ctrl.toggleMenusRequiringInstall(true, capable, daemonVersion != version.Version)
+			ctrl.toggleMenusRequiringInstall(true, h.capable, h.version != version.Version)
+		}
+		logrus.WithField("daemonVersion", h.version).WithField("clientVersion", version.Version).Info("Got daemon")
+		if h.capable {
+			// Apps may have launched or quit while the menubar app was not running.
+			ctrl.applyAppRules()
 		}
-		logrus.WithField("daemonVersion", daemonVersion).WithField("clientVersion", version.Version).Info("Got daemon")
 	}
 
 	return cleanupFunc, ctrl
@@ -0,0 +1,139 @@
+package gui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/client"
+	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/limits"
+)
+
+// #cgo CFLAGS: -x objective-c
+// #cgo LDFLAGS: -framework Foundation
+// #include <stdlib.h>
+// // Implemented in companion.m.
+// void batt_companionWriteState(const char *json);
+// char *batt_companionTakeCommand(void);
+import "C"
+
+// companionInterval is how often the companion feed is synced. The
+// extension writes commands to the shared store and cannot call us.
+const companionInterval = 5 * time.Second
+
+// companionState is what the Control Center extension shows.
+type companionState struct {
+	Limit   int  `json:"limit"`
+	Enabled bool `json:"enabled"`
+	Paused  bool `json:"paused"`
+	Charge  int  `json:"charge"`
+	// Managed is whether the extension should show the limit as locked.
+	Managed bool `json:"managed"`
+}
+
+// Commands written back by the extension:
+//
+//	toggle-limit   toggle the limit between 100% and the previous limit
+//	pause, resume  pause or resume charging
+//	limit:<n>      set the charge limit
+func (c *menuController) handleCompanionCommand(cmd string) error {
+	switch {
+	case cmd == "toggle-limit":
+		c.toggleLimit()
+	case cmd == "pause", cmd == "resume":
+		if ret, err := c.api.SetChargingPaused(cmd == "pause"); err != nil {
+			return fmt.Errorf("failed to %s charging: %s%w", cmd, ret, err)
+		}
+	case strings.HasPrefix(cmd, "limit:"):
+		limit, err := strconv.Atoi(strings.TrimPrefix(cmd, "limit:"))
+		if err != nil {
+			return fmt.Errorf("invalid limit in %q", cmd)
+		}
+		if err := limits.ValidateUpper(limit, 0); err != nil {
+			return err
+		}
+		if ret, err := c.api.SetLimit(limit); err != nil {
+			return fmt.Errorf("failed to set limit: %s%w", ret, err)
+		}
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+	return nil
+}
+
+func takeCompanionCommand() string {
+	ccmd := C.batt_companionTakeCommand()
+	if ccmd == nil {
+		return ""
+	}
+	defer C.free(unsafe.Pointer(ccmd))
+	return C.GoString(ccmd)
+}
+
+func readCompanionState(api *client.Client) (*companionState, error) {
+	rawConfig, err := api.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	conf := config.NewFileFromConfig(rawConfig, "")
+	st := &companionState{
+		Limit:   conf.UpperLimit(),
+		Enabled: conf.UpperLimit() < 100,
+	}
+	// Older daemons do not know about these, which is fine.
+	st.Paused, _ = api.GetChargingPaused()
+	st.Charge, _ = api.GetCurrentCharge()
+	if b, err := api.GetBatteryInfo(); err == nil {
+		st.Charge = displayCharge(st.Charge, b)
+	}
+	if m, err := api.GetManaged(); err == nil {
+		st.Managed = m.LimitLocked()
+	}
+	return st, nil
+}
+
+// runCompanionFeed keeps the store shared with the Control Center extension
+// up to date and runs the commands it writes back, until ctx is canceled.
+// It does not touch the menu, since it does not run on the main thread.
+func (c *menuController) runCompanionFeed(ctx context.Context) {
+	ticker := time.NewTicker(companionInterval)
+	defer ticker.Stop()
+
+	var last []byte
+	for {
+		watchdogBeat(timerCompanionFeed, 4*companionInterval)
+		if cmd := takeCompanionCommand(); cmd != "" {
+			logrus.WithField("command", cmd).Info("Got command from Control Center")
+			if err := c.handleCompanionCommand(cmd); err != nil {
+				logrus.WithError(err).Error("Failed to run Control Center command")
+				showNotification("batt", err.Error())
+			}
+		}
+
+		if st, err := readCompanionState(c.api); err == nil {
+			b, _ := json.Marshal(st)
+			if string(b) != string(last) {
+				cjson := C.CString(string(b))
+				C.batt_companionWriteState(cjson)
+				C.free(unsafe.Pointer(cjson))
+				last = b
+			}
+		} else {
+			logrus.WithError(err).Debug("Failed to read state for Control Center")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			guiTimers.Fire(timerCompanionFeed, companionInterval)
+		}
+	}
+}
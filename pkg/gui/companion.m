@@ -0,0 +1,41 @@
+#import <Foundation/Foundation.h>
+#include <notify.h>
+#include <string.h>
+
+// The app group shared with the Control Center extension. Both sides read
+// and write the same NSUserDefaults suite.
+static NSString *const kBattCompanionGroup = @"group.cc.chlc.batt";
+static NSString *const kBattCompanionStateKey = @"state";
+static NSString *const kBattCompanionCommandKey = @"command";
+// Posted after the state changes, so the extension can reload its controls.
+static const char *kBattCompanionChangedNotification = "cc.chlc.batt.companion.changed";
+
+static NSUserDefaults *companionDefaults(void) {
+    static NSUserDefaults *defaults;
+    static dispatch_once_t once;
+    dispatch_once(&once, ^{
+        defaults = [[NSUserDefaults alloc] initWithSuiteName:kBattCompanionGroup];
+    });
+    return defaults;
+}
+
+void batt_companionWriteState(const char *json) {
+    @autoreleasepool {
+        [companionDefaults() setObject:[NSString stringWithUTF8String:json] forKey:kBattCompanionStateKey];
+        notify_post(kBattCompanionChangedNotification);
+    }
+}
+
+// Returns a malloc'd copy of the pending command and clears it, or NULL if
+// there is none. Caller frees.
+char *batt_companionTakeCommand(void) {
+    @autoreleasepool {
+        NSUserDefaults *defaults = companionDefaults();
+        NSString *cmd = [defaults stringForKey:kBattCompanionCommandKey];
+        if (cmd == nil) {
+            return NULL;
+        }
+        [defaults removeObjectForKey:kBattCompanionCommandKey];
+        return strdup([cmd UTF8String]);
+    }
+}
@@ -0,0 +1,67 @@
+package gui
+
+import (
+	"github.com/progrium/darwinkit/macos/appkit"
+	"github.com/sirupsen/logrus"
+)
+
+// daemonLogLevels are the choices in the Daemon Log menu, as named by logrus.
+var daemonLogLevels = []struct {
+	level string
+	title string
+}{
+	{level: "trace", title: "Trace"},
+	{level: "debug", title: "Debug"},
+	{level: "info", title: "Info"},
+	{level: "warning", title: "Warning"},
+	{level: "error", title: "Error"},
+}
+
+// daemonLogTailLines is how much of the daemon log the debug window shows.
+const daemonLogTailLines = 40
+
+// renderLogLevel checks the daemon's log level in the Daemon Log menu.
+func (c *menuController) renderLogLevel(level string) {
+	for l, item := range c.logLevelItems {
+		setCheckboxItem(item, l == level)
+	}
+}
+
+// setLogLevel changes the daemon's log level until it restarts.
+func (c *menuController) setLogLevel(level string) {
+	if _, err := c.api.SetLogLevel(level); err != nil {
+		logrus.WithError(err).Error("Failed to set daemon log level")
+		showAlert("Failed to set log level", err.Error())
+		return
+	}
+	c.renderLogLevel(level)
+}
+
+// rotateLog keeps the daemon log as a .1 file and empties it.
+func (c *menuController) rotateLog() {
+	ret, err := c.api.RotateLog()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to rotate daemon log")
+		showAlert("Failed to rotate log", err.Error())
+		return
+	}
+	showNotification("Daemon Log", ret)
+}
+
+// clearLog empties the daemon log after asking.
+func (c *menuController) clearLog() {
+	alert := appkit.NewAlert()
+	alert.SetAlertStyle(appkit.AlertStyleWarning)
+	alert.SetMessageText("Clear the Daemon Log?")
+	alert.SetInformativeText(clearLogAlertText)
+	alert.AddButtonWithTitle("Clear")
+	alert.AddButtonWithTitle("Cancel")
+	if alert.RunModal() != appkit.AlertFirstButtonReturn {
+		return
+	}
+
+	if _, err := c.api.ClearLog(); err != nil {
+		logrus.WithError(err).Error("Failed to clear daemon log")
+		showAlert("Failed to clear log", err.Error())
+	}
+}
@@ -0,0 +1,184 @@
+package gui
+
+import (
+	"fmt"
+	"runtime/cgo"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/client"
+	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/diagnostics"
+)
+
+// #include <stdint.h>
+// #include <stdlib.h>
+// // Implemented in debugwindow.m.
+// void *batt_createDebugWindow(uintptr_t handle);
+// void batt_debugWindowSetText(void *winPtr, const char *text);
+// void batt_showDebugWindow(void *winPtr);
+// void batt_releaseDebugWindow(void *winPtr);
+import "C"
+
+// debugWindow shows what the daemon decided recently and why, to diagnose
+// "why didn't it stop charging" reports. It is reached by holding Option
+// while the menu is open. It refreshes on an ObjC timer while visible.
+type debugWindow struct {
+	api    *client.Client
+	ptr    unsafe.Pointer
+	handle cgo.Handle
+}
+
+func newDebugWindow(api *client.Client) *debugWindow {
+	w := &debugWindow{api: api}
+	w.handle = cgo.NewHandle(w)
+	w.ptr = C.batt_createDebugWindow(C.uintptr_t(w.handle))
+	return w
+}
+
+// showDebugWindow creates the debug window if needed and brings it to front.
+func (c *menuController) showDebugWindow() {
+	if c.debugWin == nil {
+		c.debugWin = newDebugWindow(c.api)
+		c.resources.track("debug window", c.debugWin.release)
+	}
+	c.debugWin.show()
+}
+
+// show brings the window to front and starts periodic refreshes.
+func (w *debugWindow) show() {
+	C.batt_showDebugWindow(w.ptr)
+}
+
+func (w *debugWindow) release() {
+	C.batt_releaseDebugWindow(w.ptr)
+	w.ptr = nil
+	w.handle.Delete()
+}
+
+func (w *debugWindow) refresh() {
+	var b strings.Builder
+
+	rawConfig, err := w.api.GetConfig()
+	if err != nil {
+		w.setText("Daemon not running: " + err.Error())
+		return
+	}
+	conf := config.NewFileFromConfig(rawConfig, "")
+	charge, err1 := w.api.GetCurrentCharge()
+	charging, err2 := w.api.GetCharging()
+	pluggedIn, err3 := w.api.GetPluggedIn()
+	if err1 != nil || err2 != nil || err3 != nil {
+		b.WriteString("State: error\n")
+	} else {
+		fmt.Fprintf(&b, "State: %d%%, charging %s, adapter %s\n", charge, enabledText(charging), connectedText(pluggedIn))
+		if info, err := w.api.GetBatteryInfo(); err == nil && info.SystemCharge > 0 && info.SystemCharge != charge {
+			fmt.Fprintf(&b, "macOS shows %d%%, the limits apply to the SMC value above\n", info.SystemCharge)
+		}
+	}
+	fmt.Fprintf(&b, "Limit: %d%%, resume below %d%%\n", conf.UpperLimit(), conf.LowerLimit())
+
+	r, err := w.api.GetDebugReport()
+	if err != nil {
+		// Older daemons do not have the debug report.
+		b.WriteString("\nDebug report unavailable: " + err.Error() + "\n")
+		w.setText(b.String())
+		return
+	}
+	b.WriteString(formatDebugReport(r, time.Now()))
+
+	b.WriteString("\nWhat batt itself costs:\n")
+	if r.Self != nil {
+		b.WriteString(formatSelfAudit("Daemon", r.Self, time.Now()))
+	}
+	b.WriteString(formatSelfAudit("Menu bar app", getGUISelfAudit(), time.Now()))
+	b.WriteString("  Startup: " + formatStartupTrace() + "\n")
+
+	b.WriteString("\nCaches:\n")
+	for _, s := range append(r.Caches, menuCache.stats()...) {
+		fmt.Fprintf(&b, "  %-24s %d of %d, %d hits, %d misses, %d evicted\n", s.Name, s.Len, s.Cap, s.Hits, s.Misses, s.Evictions)
+	}
+
+	fmt.Fprintf(&b, "\nRecent daemon log:\n")
+	if log, err := w.api.GetLog(daemonLogTailLines); err == nil {
+		b.WriteString(log + "\n")
+	} else {
+		b.WriteString("  unavailable: " + err.Error() + "\n")
+	}
+	w.setText(b.String())
+}
+
+// formatDebugReport renders the report as plain text.
+func formatDebugReport(r *diagnostics.Report, now time.Time) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Policy: %s\n", r.Mode)
+	loops := make([]string, 0, len(r.RecentLoops))
+	for _, t := range r.RecentLoops {
+		loops = append(loops, fmt.Sprintf("%.0fs", now.Sub(t).Seconds()))
+	}
+	fmt.Fprintf(&b, "Maintain loop: every %.0fs", r.LoopInterval)
+	if r.PollReason != "" {
+		fmt.Fprintf(&b, " (%s)", r.PollReason)
+	}
+	fmt.Fprintf(&b, ", last ran %s ago", strings.Join(loops, ", "))
+	if r.LoopsMissed {
+		b.WriteString(" (loops missed, e.g. after sleep: enabling charging is held off)")
+	}
+	b.WriteString("\n")
+	if !r.NextCalibration.IsZero() {
+		fmt.Fprintf(&b, "Next calibration: %s\n", r.NextCalibration.Local().Format(time.DateTime))
+	}
+
+	b.WriteString("\nRecent transitions and SMC writes, newest first:\n")
+	if len(r.Entries) == 0 {
+		b.WriteString("  none yet\n")
+	}
+	for _, e := range r.Entries {
+		fmt.Fprintf(&b, "  %s  %-10s  %s", e.Time.Local().Format(time.TimeOnly), e.Kind, e.Message)
+		if e.Error != "" {
+			b.WriteString("  FAILED: " + e.Error)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func enabledText(b bool) string {
+	if b {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+func connectedText(b bool) string {
+	if b {
+		return "connected"
+	}
+	return "not connected"
+}
+
+func (w *debugWindow) setText(text string) {
+	cs := C.CString(text)
+	defer C.free(unsafe.Pointer(cs))
+	C.batt_debugWindowSetText(w.ptr, cs)
+}
+
+//export battDebugWindowRefresh
+func battDebugWindowRefresh(h C.uintptr_t) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("panic in battDebugWindowRefresh: %v", r)
+		}
+	}()
+	handle := cgo.Handle(h)
+	if v := handle.Value(); v != nil {
+		if w, ok := v.(*debugWindow); ok {
+			guiTimers.Fire(timerDebugRefresh, time.Second)
+			w.refresh()
+		}
+	}
+}
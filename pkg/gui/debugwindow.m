@@ -0,0 +1,126 @@
+#import <Cocoa/Cocoa.h>
+#include <stdint.h>
+
+// The time interval in seconds for the debug window refresh timer.
+static const NSTimeInterval kDebugWindowRefreshInterval = 1.0;
+
+// Callback exported from Go
+extern void battDebugWindowRefresh(uintptr_t handle);
+
+@interface BattDebugWindowController : NSObject <NSWindowDelegate>
+@property(nonatomic, assign) uintptr_t handle;
+@property(nonatomic, strong) NSWindow *window;
+@property(nonatomic, strong) NSTextView *textView;
+@property(nonatomic, strong) NSTimer *timer;
+- (instancetype)initWithHandle:(uintptr_t)handle;
+@end
+
+@implementation BattDebugWindowController
+- (instancetype)initWithHandle:(uintptr_t)handle {
+    if ((self = [super init])) {
+        _handle = handle;
+        [self buildWindow];
+    }
+    return self;
+}
+
+- (void)buildWindow {
+    NSRect frame = NSMakeRect(0, 0, 640, 480);
+    NSWindowStyleMask style = NSWindowStyleMaskTitled | NSWindowStyleMaskClosable |
+                              NSWindowStyleMaskMiniaturizable | NSWindowStyleMaskResizable;
+    self.window = [[NSWindow alloc] initWithContentRect:frame
+                                              styleMask:style
+                                                backing:NSBackingStoreBuffered
+                                                  defer:NO];
+    self.window.title = @"batt Debug";
+    self.window.identifier = @"batt.debugWindow";
+    self.window.releasedWhenClosed = NO;
+    self.window.delegate = self;
+    self.window.contentMinSize = NSMakeSize(420, 240);
+    [self.window setFrameAutosaveName:@"BattDebugWindow"];
+
+    NSScrollView *scroll = [NSTextView scrollableTextView];
+    scroll.frame = self.window.contentView.bounds;
+    scroll.autoresizingMask = NSViewWidthSizable | NSViewHeightSizable;
+    self.textView = (NSTextView *)scroll.documentView;
+    self.textView.editable = NO;
+    self.textView.selectable = YES;
+    self.textView.font = [NSFont monospacedSystemFontOfSize:12 weight:NSFontWeightRegular];
+    self.textView.textContainerInset = NSMakeSize(8, 8);
+    self.textView.identifier = @"batt.debug.text";
+    [self.window.contentView addSubview:scroll];
+    [self.window center];
+}
+
+- (void)setText:(NSString *)text {
+    // Keep the selection, so users can copy while it refreshes.
+    NSArray<NSValue *> *selection = self.textView.selectedRanges;
+    self.textView.string = text;
+    NSUInteger length = text.length;
+    NSMutableArray<NSValue *> *kept = [NSMutableArray array];
+    for (NSValue *v in selection) {
+        NSRange r = v.rangeValue;
+        if (NSMaxRange(r) <= length) {
+            [kept addObject:v];
+        }
+    }
+    if (kept.count > 0) {
+        self.textView.selectedRanges = kept;
+    }
+}
+
+- (void)show {
+    if (self.timer == nil) {
+        self.timer = [NSTimer timerWithTimeInterval:kDebugWindowRefreshInterval
+                                             target:self
+                                           selector:@selector(timerTick:)
+                                           userInfo:nil
+                                            repeats:YES];
+        [[NSRunLoop mainRunLoop] addTimer:self.timer forMode:NSDefaultRunLoopMode];
+    }
+    battDebugWindowRefresh(_handle);
+    [NSApp activateIgnoringOtherApps:YES];
+    [self.window makeKeyAndOrderFront:nil];
+}
+
+- (void)timerTick:(NSTimer *)timer {
+    battDebugWindowRefresh(_handle);
+}
+
+- (void)stopTimer {
+    if (self.timer) {
+        [self.timer invalidate];
+        self.timer = nil;
+    }
+}
+
+- (void)windowWillClose:(NSNotification *)note {
+    [self stopTimer];
+}
+@end
+
+void *batt_createDebugWindow(uintptr_t handle) {
+    BattDebugWindowController *ctrl = [[BattDebugWindowController alloc] initWithHandle:handle];
+    return (void *)CFBridgingRetain(ctrl);
+}
+
+void batt_debugWindowSetText(void *winPtr, const char *text) {
+    if (winPtr == NULL) return;
+    BattDebugWindowController *ctrl = (__bridge BattDebugWindowController *)winPtr;
+    [ctrl setText:(text ? [NSString stringWithUTF8String:text] : @"")];
+}
+
+void batt_showDebugWindow(void *winPtr) {
+    if (winPtr == NULL) return;
+    BattDebugWindowController *ctrl = (__bridge BattDebugWindowController *)winPtr;
+    [ctrl show];
+}
+
+void batt_releaseDebugWindow(void *winPtr) {
+    if (winPtr == NULL) return;
+    BattDebugWindowController *ctrl = (__bridge BattDebugWindowController *)winPtr;
+    [ctrl stopTimer];
+    ctrl.window.delegate = nil;
+    [ctrl.window close];
+    CFRelease(winPtr);
+}
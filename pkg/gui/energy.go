@@ -0,0 +1,69 @@
+package gui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/history"
+)
+
+// showEnergyReport shows the processes that used the most energy on battery.
+func (c *menuController) showEnergyReport() {
+	r, err := c.api.GetEnergyImpactReport()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get energy impact report")
+		showAlert("Failed to get energy impact report", err.Error())
+		return
+	}
+
+	var sb strings.Builder
+	if !r.Enabled {
+		sb.WriteString("Energy sampling is off. Turn on \"Sample Energy Use on Battery\" in the Advanced menu.\n\n")
+	}
+	if r.Since.IsZero() || len(r.Processes) == 0 {
+		sb.WriteString("No energy data on battery yet. Data is collected once a minute while on battery.")
+		showAlert("Energy Impact Report", sb.String())
+		return
+	}
+
+	duration := time.Duration(r.Duration * float64(time.Second)).Round(time.Minute)
+	fmt.Fprintf(&sb, "On battery since %s (%s sampled).\n\n", r.Since.Local().Format("Jan 2 15:04"), duration)
+	for i, p := range r.Processes {
+		fmt.Fprintf(&sb, "%d. %s: %.2f W average (%.0f J)\n", i+1, p.Name, p.AveragePower, p.Energy)
+	}
+	showAlert("Energy Impact Report", sb.String())
+}
+
+// usageReportDays is how far back the usage statistics go.
+const usageReportDays = 30
+
+// showUsageReport shows how the battery was used over the last month.
+func (c *menuController) showUsageReport() {
+	s, err := c.api.GetUsage(usageReportDays)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get usage statistics")
+		showAlert("Failed to get usage statistics", err.Error())
+		return
+	}
+	if s.Days == 0 {
+		showAlert("Usage Statistics", "No usage recorded yet. batt records how you use your battery while the daemon runs.")
+		return
+	}
+
+	total := s.OnBatteryHours + s.PluggedInHours
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Last %d days, with data on %d days since %s.\n\n", usageReportDays, s.Days, s.Since.Format("Jan 2"))
+	fmt.Fprintf(&sb, "On battery: %.1f h (%.0f%%)\n", s.OnBatteryHours, 100*s.OnBatteryHours/total)
+	fmt.Fprintf(&sb, "Plugged in: %.1f h (%.0f%%)\n", s.PluggedInHours, 100*s.PluggedInHours/total)
+	fmt.Fprintf(&sb, "Held at the limit: %.1f h\n", s.HeldHours)
+	fmt.Fprintf(&sb, "Above %d%%: %.1f h (%.0f%%)\n", history.HighCharge, s.HighChargeHours, 100*s.HighChargeHours/total)
+	fmt.Fprintf(&sb, "Average charge: %.0f%%\n", s.AverageCharge)
+	if s.Discharges > 0 {
+		fmt.Fprintf(&sb, "Average discharge: %.0f%% over %d times on battery\n", s.AverageDischarge, s.Discharges)
+	}
+	sb.WriteString("\nThese statistics are kept on this Mac only.")
+	showAlert("Usage Statistics", sb.String())
+}
@@ -5,13 +5,18 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime/cgo"
 	"strings"
+	"time"
 	"unsafe"
 
 	pkgerrors "github.com/pkg/errors"
 	"github.com/progrium/darwinkit/macos/appkit"
+	"github.com/progrium/darwinkit/objc"
 	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/client"
 )
 
 // #cgo CFLAGS: -x objective-c
@@ -25,7 +30,12 @@ import (
 // bool registerAppWithSMAppService(void);
 // bool unregisterAppWithSMAppService(void);
 // bool isRegisteredWithSMAppService(void);
+// int loginItemStatusWithSMAppService(void);
+// void openLoginItemsSettings(void);
+// bool batt_trashItem(const char *path);
 // void batt_showNotification(const char* title, const char* body);
+// void batt_showNotificationWithURL(const char* title, const char* body, const char* url);
+// void batt_setAccessibility(uintptr_t objPtr, const char *identifier, const char *label);
 import "C"
 
 //export battMenuWillOpen
@@ -68,6 +78,7 @@ func battMenuTimerFired(h C.uintptr_t) {
 	handle := cgo.Handle(h)
 	if v := handle.Value(); v != nil {
 		if c, ok := v.(*menuController); ok {
+			guiTimers.Fire(timerMenuRefresh, menuRefreshInterval)
 			c.onTimerTick()
 		}
 	}
@@ -112,12 +123,126 @@ func IsLoginItemRegistered() bool {
 	return bool(C.isRegisteredWithSMAppService())
 }
 
+// LoginItemStatus mirrors SMAppServiceStatus.
+type LoginItemStatus int
+
+const (
+	// LoginItemStatusUnavailable means SMAppService is not available (macOS < 13).
+	LoginItemStatusUnavailable LoginItemStatus = -1
+	// LoginItemStatusNotRegistered means the app is not registered as a login item.
+	LoginItemStatusNotRegistered LoginItemStatus = 0
+	// LoginItemStatusEnabled means the app will start at login.
+	LoginItemStatusEnabled LoginItemStatus = 1
+	// LoginItemStatusRequiresApproval means the app is registered, but the user
+	// has to allow it in System Settings > General > Login Items.
+	LoginItemStatusRequiresApproval LoginItemStatus = 2
+	// LoginItemStatusNotFound means macOS could not find the app, usually
+	// because it was moved after registering.
+	LoginItemStatusNotFound LoginItemStatus = 3
+)
+
+func (s LoginItemStatus) String() string {
+	switch s {
+	case LoginItemStatusUnavailable:
+		return "Unavailable"
+	case LoginItemStatusNotRegistered:
+		return "Not Registered"
+	case LoginItemStatusEnabled:
+		return "Enabled"
+	case LoginItemStatusRequiresApproval:
+		return "Requires Approval"
+	case LoginItemStatusNotFound:
+		return "Not Found"
+	default:
+		return fmt.Sprintf("Unknown (%d)", int(s))
+	}
+}
+
+// GetLoginItemStatus returns the login item status of the application.
+func GetLoginItemStatus() LoginItemStatus {
+	return LoginItemStatus(C.loginItemStatusWithSMAppService())
+}
+
+// OpenLoginItemsSettings opens System Settings > General > Login Items.
+func OpenLoginItemsSettings() {
+	C.openLoginItemsSettings()
+}
+
+// repairLoginItem diagnoses the login item and tries to fix it, asking the
+// user to approve it in System Settings when macOS requires so.
+func repairLoginItem() {
+	status := GetLoginItemStatus()
+	logrus.WithField("status", status).Info("Repairing login item")
+
+	switch status {
+	case LoginItemStatusEnabled:
+		showAlert("Login item is working", "batt is registered and will start at login.")
+		return
+	case LoginItemStatusUnavailable:
+		showAlert("Login item is not supported", "Starting at login requires macOS 13 or later.")
+		return
+	case LoginItemStatusNotFound:
+		// Usually the app was moved. Re-register from the current location.
+		_ = UnregisterLoginItem()
+		fallthrough
+	case LoginItemStatusNotRegistered:
+		if err := RegisterLoginItem(); err != nil {
+			logrus.WithError(err).Error("Failed to register login item")
+		}
+		status = GetLoginItemStatus()
+	}
+
+	switch status {
+	case LoginItemStatusEnabled:
+		showAlert("Login item repaired", "batt is registered and will start at login.")
+	case LoginItemStatusRequiresApproval:
+		askLoginItemApproval()
+	default:
+		showAlert("Failed to repair login item", fmt.Sprintf("Login item status: %s. You can add batt.app manually in System Settings > General > Login Items.", status))
+	}
+}
+
+// askLoginItemApproval explains that macOS needs user approval and offers to
+// open System Settings > Login Items.
+func askLoginItemApproval() {
+	alert := appkit.NewAlert()
+	alert.SetAlertStyle(appkit.AlertStyleInformational)
+	alert.SetMessageText("Allow batt to Start at Login")
+	alert.SetInformativeText(`macOS requires your approval before batt can start at login. In System Settings > General > Login Items, turn on batt under "Allow in the Background" or add it to "Open at Login".`)
+	alert.AddButtonWithTitle("Open System Settings")
+	alert.AddButtonWithTitle("Later")
+	if alert.RunModal() == appkit.AlertFirstButtonReturn {
+		OpenLoginItemsSettings()
+	}
+}
+
 var (
-	battSymlinkLocation = "/usr/local/bin/batt"
+	// Files created by the daemon with its default flags.
+	battConfigPath  = "/etc/batt.json"
+	battStatePath   = "/etc/batt.state.json"
+	battHistoryPath = "/etc/batt.history.json"
+	battLogPath     = "/tmp/batt.log"
+	// Shell completions are regenerated on every install, so they match
+	// the installed version. These are where Homebrew'ed shells look.
+	battCompletions = []struct{ shell, path string }{
+		{"zsh", "/usr/local/share/zsh/site-functions/_batt"},
+		{"bash", "/usr/local/etc/bash_completion.d/batt"},
+		{"fish", "/usr/local/share/fish/vendor_completions.d/batt.fish"},
+	}
 )
 
+// completionPaths returns the paths of all shell completion scripts, quoted
+// for the shell.
+func completionPaths() string {
+	var paths []string
+	for _, c := range battCompletions {
+		paths = append(paths, `"`+c.path+`"`)
+	}
+	return strings.Join(paths, " ")
+}
+
 func isDaemonInstalled() bool {
-	plistPath := "/Library/LaunchDaemons/cc.chlc.batt.plist"
+	plistPath := daemonPlistPath
 	_, err := os.Stat(plistPath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -157,6 +282,34 @@ func showNotification(title, body string) {
 	}()
 }
 
+// showNotificationWithURL shows a notification that opens the batt:// URL
+// when clicked. It is safe to call from any goroutine: the notification is
+// delivered and the delegate set on the main thread.
+func showNotificationWithURL(title, body, url string) {
+	ctitle := C.CString(title)
+	cbody := C.CString(body)
+	curl := C.CString(url)
+	defer C.free(unsafe.Pointer(ctitle))
+	defer C.free(unsafe.Pointer(cbody))
+	defer C.free(unsafe.Pointer(curl))
+	C.batt_showNotificationWithURL(ctitle, cbody, curl)
+}
+
+// setAccessibility sets the accessibility identifier and VoiceOver label of
+// a view or menu item. Empty strings are left untouched.
+func setAccessibility(obj objc.IObject, identifier, label string) {
+	var cid, clabel *C.char
+	if identifier != "" {
+		cid = C.CString(identifier)
+		defer C.free(unsafe.Pointer(cid))
+	}
+	if label != "" {
+		clabel = C.CString(label)
+		defer C.free(unsafe.Pointer(clabel))
+	}
+	C.batt_setAccessibility(C.uintptr_t(uintptr(obj.Ptr())), cid, clabel)
+}
+
 func showAlert(msg, body string) {
 	alert := appkit.NewAlert()
 	alert.SetIcon(appkit.Image_ImageWithSystemSymbolNameAccessibilityDescription("exclamationmark.triangle", "s"))
@@ -166,6 +319,28 @@ func showAlert(msg, body string) {
 	alert.RunModal()
 }
 
+// confirmStartCalibration asks the user to confirm starting auto calibration.
+func confirmStartCalibration() bool {
+	alert := appkit.NewAlert()
+	alert.SetIcon(appkit.Image_ImageWithSystemSymbolNameAccessibilityDescription("battery.100", "calibration"))
+	alert.SetAlertStyle(appkit.AlertStyleInformational)
+	alert.SetMessageText("Start Auto Calibration?")
+	alert.SetInformativeText(`This will:
+1. Discharge (to 15% by default) without sleep prevention.
+2. Charge to 100%.
+3. Hold at full charge (for 2 hours by default).
+4. Discharge back to previous charge limit.
+
+NOTES:
+• You can pause or cancel anytime from the menu.
+• Highly recommend keeping your Mac connected to power throughout the process to prevent the battery level from dropping below the threshold without timely charging.
+• If you are using Clamshell mode (using a Mac laptop with an external monitor and the lid closed), *the discharging process will cause your Mac to go to sleep*. So you should keep the lid open during the calibration process.`)
+	// Explicitly add two buttons and compare first-button response, consistent with update flow
+	alert.AddButtonWithTitle("Start")
+	alert.AddButtonWithTitle("Cancel")
+	return alert.RunModal() == appkit.AlertFirstButtonReturn
+}
+
 // uninstallDaemon removes daemon and resets charging limits.
 func uninstallDaemon(exe string) error {
 	shellScript := `
@@ -175,8 +350,8 @@ set -e
 		// Uninstall it first.
 		shellScript += fmt.Sprintf(`
 "%s" uninstall
-/bin/rm -f "%s" || true
-`, exe, battSymlinkLocation)
+/bin/rm -f "%s" %s || true
+`, exe, symlinkLocation(), completionPaths())
 	}
 
 	output := &bytes.Buffer{}
@@ -191,6 +366,106 @@ set -e
 	return nil
 }
 
+// appBundlePath returns the .app bundle containing exe, or "" if exe is not in a bundle.
+func appBundlePath(exe string) string {
+	// exe is .../batt.app/Contents/MacOS/batt
+	bundle := filepath.Dir(filepath.Dir(filepath.Dir(exe)))
+	if filepath.Ext(bundle) != ".app" {
+		return ""
+	}
+	return bundle
+}
+
+// runShellScript runs shellScript as the user, and again with administrator
+// privileges if that fails, e.g. because the user cannot write somewhere.
+// what is what the script does, for errors.
+func runShellScript(shellScript, what string) error {
+	output := &bytes.Buffer{}
+	cmd := exec.Command("/bin/sh", "-c", shellScript)
+	cmd.Stderr = output
+	cmd.Stdout = output
+	if err := cmd.Run(); err == nil {
+		return nil
+	}
+	logrus.WithField("output", output.String()).Infof("Failed to %s without privileges, asking for them", what)
+
+	output.Reset()
+	cmd = exec.Command("/usr/bin/osascript", "-e", fmt.Sprintf("do shell script \"%s\" with administrator privileges", escapeShellInAppleScript(shellScript)))
+	cmd.Stderr = output
+	cmd.Stdout = output
+	if err := cmd.Run(); err != nil {
+		return pkgerrors.Wrapf(err, "failed to %s: %s", what, output.String())
+	}
+	return nil
+}
+
+// trashItem moves path to the Trash and reports whether it succeeded.
+func trashItem(path string) bool {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	return bool(C.batt_trashItem(cpath))
+}
+
+// uninstallEverythingItems lists what uninstallEverything removes, for confirmation.
+func uninstallEverythingItems(exe string) []string {
+	items := []string{
+		"batt daemon (charging limits are reset, so your Mac charges to 100% again)",
+		"Command line symlink " + symlinkLocation() + " and shell completions",
+		"Config, state and history files " + battConfigPath + ", " + battStatePath + ", " + battHistoryPath,
+		"Daemon log " + battLogPath,
+		"Login item of the menubar app",
+		"Menubar app preferences",
+	}
+	if bundle := appBundlePath(exe); bundle != "" {
+		items = append(items, bundle+" (moved to Trash)")
+	}
+	return items
+}
+
+// uninstallEverything removes the daemon, all files batt created and finally
+// moves the app bundle to the Trash. The caller should quit afterwards.
+func uninstallEverything(exe string) error {
+	shellScript := `
+set -e
+`
+	if isDaemonInstalled() {
+		// Resets charging limits, unlike "uninstall --no-reset-charging".
+		shellScript += fmt.Sprintf(`
+"%s" uninstall
+`, exe)
+	}
+	shellScript += fmt.Sprintf(`
+/bin/rm -f "%s" "%s" "%s" "%s" "%s" %s || true
+`, symlinkLocation(), battConfigPath, battStatePath, battHistoryPath, battLogPath, completionPaths())
+
+	output := &bytes.Buffer{}
+	cmd := exec.Command("/usr/bin/osascript", "-e", fmt.Sprintf("do shell script \"%s\" with administrator privileges", escapeShellInAppleScript(shellScript)))
+	cmd.Stderr = output
+	cmd.Stdout = output
+	err := cmd.Run()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "failed to uninstall batt: %s", output.String())
+	}
+
+	// User-level leftovers. These are best-effort.
+	if IsLoginItemRegistered() {
+		if err := UnregisterLoginItem(); err != nil {
+			logrus.WithError(err).Warn("Failed to unregister login item")
+		}
+	}
+	if out, err := exec.Command("/usr/bin/defaults", "delete", "cc.chlc.batt").CombinedOutput(); err != nil {
+		logrus.WithError(err).WithField("output", string(out)).Debug("Failed to delete preferences")
+	}
+
+	if bundle := appBundlePath(exe); bundle != "" {
+		if !trashItem(bundle) {
+			return fmt.Errorf("failed to move %s to Trash, please remove it manually", bundle)
+		}
+	}
+
+	return nil
+}
+
 // installDaemon uninstalls existing daemons first (if exists), installs the batt daemon and creates a symlink to the executable.
 func installDaemon(exe string) error {
 	shellScript := `
@@ -202,14 +477,21 @@ set -e
 		shellScript += fmt.Sprintf(`
 "%s" uninstall --no-reset-charging
 /bin/rm -f "%s" || true
-`, exe, battSymlinkLocation)
+`, exe, symlinkLocation())
 	}
 
 	shellScript += fmt.Sprintf(`
 "%s" install --allow-non-root-access
-mkdir -p "$(dirname "%s")" # For whatever reason, some users don't have /usr/local/bin.
+mkdir -p "$(dirname "%s")" # Apple silicon Macs have no /usr/local/bin without Homebrew.
 /bin/ln -sf "%s" "%s" || true
-`, exe, battSymlinkLocation, exe, battSymlinkLocation)
+`, exe, symlinkLocation(), exe, symlinkLocation())
+	// Completions are best-effort, a missing shell should not fail the
+	// install.
+	for _, c := range battCompletions {
+		shellScript += fmt.Sprintf(`
+mkdir -p "$(dirname "%s")" && "%s" completion %s > "%s" || true
+`, c.path, exe, c.shell, c.path)
+	}
 
 	logrus.WithField("script", shellScript).Info("Installing daemon")
 
@@ -225,6 +507,29 @@ mkdir -p "$(dirname "%s")" # For whatever reason, some users don't have /usr/loc
 	return nil
 }
 
+// daemonStartTimeout is how long a freshly installed daemon has to answer.
+const daemonStartTimeout = 10 * time.Second
+
+// waitForDaemon waits until the daemon answers with the wanted version, so a
+// daemon that fails to start after installing or upgrading is reported right
+// away instead of leaving the app and the daemon mismatched.
+func waitForDaemon(api *client.Client, want string) error {
+	deadline := time.Now().Add(daemonStartTimeout)
+	var got string
+	var err error
+	for time.Now().Before(deadline) {
+		got, err = api.GetVersion()
+		if err == nil && got == want {
+			return nil
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	if err != nil {
+		return pkgerrors.Wrap(err, "the batt daemon did not start")
+	}
+	return fmt.Errorf("the batt daemon is still on version %s, expected %s", got, want)
+}
+
 func startAppAtBoot() error {
 	if IsLoginItemRegistered() {
 		logrus.Info("Application is already registered to start at login")
@@ -232,8 +537,16 @@ func startAppAtBoot() error {
 	}
 
 	if err := RegisterLoginItem(); err != nil {
+		if GetLoginItemStatus() == LoginItemStatusRequiresApproval {
+			askLoginItemApproval()
+			return nil
+		}
 		return pkgerrors.Wrapf(err, "failed to register application to start at login")
 	}
+	if GetLoginItemStatus() == LoginItemStatusRequiresApproval {
+		askLoginItemApproval()
+		return nil
+	}
 	logrus.Info("Application registered to start at login")
 	return nil
 }
@@ -0,0 +1,106 @@
+package gui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/events"
+)
+
+const (
+	hookTimeout = 30 * time.Second
+	// hookLogLimit caps how much hook output is logged.
+	hookLogLimit = 1024
+)
+
+// hookPayload is written to the stdin of hooks.
+type hookPayload struct {
+	Event string `json:"event"`
+	// Data is the typed payload of the event, see pkg/events.
+	Data json.RawMessage `json:"data"`
+}
+
+// hooksDir is where users put executables to run on daemon events.
+func hooksDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "Application Support", "batt", "hooks"), nil
+}
+
+// hookName returns the file name of the hook for an event, e.g.
+// on-limit-reached for limit.reached.
+func hookName(event string) string {
+	return "on-" + strings.ReplaceAll(event, ".", "-")
+}
+
+// runHook runs the hook for the event, if there is one. Hooks run here
+// rather than in the daemon, so they never run as root. A hook is enabled
+// while it is executable, so "chmod -x" turns it off without deleting it.
+func runHook(ev events.Event) {
+	dir, err := hooksDir()
+	if err != nil {
+		return
+	}
+	path := filepath.Join(dir, hookName(ev.Name))
+	fi, err := os.Stat(path)
+	if err != nil || !fi.Mode().IsRegular() || fi.Mode().Perm()&0o111 == 0 {
+		return
+	}
+	// Refuse hooks that someone else could have put there.
+	if st, ok := fi.Sys().(*syscall.Stat_t); fi.Mode().Perm()&0o022 != 0 || !ok || int(st.Uid) != os.Getuid() {
+		logrus.WithField("hook", path).Warn("Not running hook, it must be owned by you and writable only by you")
+		return
+	}
+
+	input, err := json.Marshal(hookPayload{Event: ev.Name, Data: ev.Data})
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(), "BATT_EVENT="+ev.Name)
+	out, err := cmd.CombinedOutput()
+	if len(out) > hookLogLimit {
+		out = out[:hookLogLimit]
+	}
+
+	l := logrus.WithFields(logrus.Fields{
+		"hook":   path,
+		"output": string(out),
+	})
+	if err != nil {
+		l.WithError(err).Warn("Hook failed")
+		return
+	}
+	l.Info("Hook finished")
+}
+
+// openHooksDir creates the hooks folder if needed and shows it in Finder.
+func openHooksDir() {
+	dir, err := hooksDir()
+	if err == nil {
+		err = os.MkdirAll(dir, 0o700)
+	}
+	if err == nil {
+		err = exec.Command("/usr/bin/open", dir).Run()
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to open hooks folder")
+		showAlert("Failed to open hooks folder", err.Error())
+	}
+}
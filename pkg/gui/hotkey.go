@@ -0,0 +1,202 @@
+package gui
+
+import (
+	"fmt"
+	"runtime/cgo"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/config"
+)
+
+// #cgo LDFLAGS: -framework Carbon
+// #include <stdint.h>
+// #include <stdbool.h>
+// // Implemented in hotkey.m.
+// bool batt_installHotKeyHandler(uintptr_t handle);
+// void batt_removeHotKeyHandler(void);
+// void *batt_registerHotKey(uint32_t hotKeyID, uint32_t keyCode, uint32_t modifiers);
+// void batt_unregisterHotKey(void *ref);
+import "C"
+
+// hotKeyAction is an action that can be bound to a global keyboard shortcut.
+type hotKeyAction struct {
+	id          uint32
+	name        string
+	prefKey     string
+	description string
+}
+
+const (
+	hotKeyIDToggleLimit uint32 = iota + 1
+	hotKeyIDShowStatus
+	hotKeyIDTogglePause
+	hotKeyIDCommandPalette
+)
+
+var hotKeyActions = []hotKeyAction{
+	{id: hotKeyIDToggleLimit, name: "toggle-limit", prefKey: "ShortcutToggleLimit", description: "Toggle the charge limit between 100% and the previous limit"},
+	{id: hotKeyIDShowStatus, name: "show-status", prefKey: "ShortcutShowStatus", description: "Show the status window"},
+	{id: hotKeyIDTogglePause, name: "toggle-pause", prefKey: "ShortcutTogglePause", description: "Pause or resume charging"},
+	{id: hotKeyIDCommandPalette, name: "command-palette", prefKey: "ShortcutCommandPalette", description: "Show the command palette to search all actions"},
+}
+
+// prefLimitBeforeDisable remembers the limit to restore when the toggle-limit
+// shortcut turns the limit back on.
+const prefLimitBeforeDisable = "LimitBeforeDisable"
+
+// Carbon modifier masks, from Events.h.
+const (
+	carbonCmdKey     uint32 = 1 << 8
+	carbonShiftKey   uint32 = 1 << 9
+	carbonOptionKey  uint32 = 1 << 11
+	carbonControlKey uint32 = 1 << 12
+)
+
+var shortcutModifiers = map[string]uint32{
+	"cmd":     carbonCmdKey,
+	"command": carbonCmdKey,
+	"shift":   carbonShiftKey,
+	"opt":     carbonOptionKey,
+	"option":  carbonOptionKey,
+	"alt":     carbonOptionKey,
+	"ctrl":    carbonControlKey,
+	"control": carbonControlKey,
+}
+
+// shortcutKeyCodes maps keys to virtual key codes (kVK_ANSI_*) of the ANSI layout.
+var shortcutKeyCodes = map[string]uint32{
+	"a": 0x00, "s": 0x01, "d": 0x02, "f": 0x03, "h": 0x04, "g": 0x05, "z": 0x06, "x": 0x07,
+	"c": 0x08, "v": 0x09, "b": 0x0B, "q": 0x0C, "w": 0x0D, "e": 0x0E, "r": 0x0F, "y": 0x10,
+	"t": 0x11, "1": 0x12, "2": 0x13, "3": 0x14, "4": 0x15, "6": 0x16, "5": 0x17, "9": 0x19,
+	"7": 0x1A, "8": 0x1C, "0": 0x1D, "o": 0x1F, "u": 0x20, "i": 0x22, "p": 0x23, "l": 0x25,
+	"j": 0x26, "k": 0x28, "n": 0x2D, "m": 0x2E,
+}
+
+// parseShortcut parses shortcuts like "ctrl+opt+cmd+b" into a Carbon key code
+// and modifier mask. At least one modifier is required, so a global shortcut
+// does not swallow a plain key press.
+func parseShortcut(s string) (keyCode, modifiers uint32, err error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(s)), "+")
+	key := parts[len(parts)-1]
+	for _, p := range parts[:len(parts)-1] {
+		m, ok := shortcutModifiers[strings.TrimSpace(p)]
+		if !ok {
+			return 0, 0, fmt.Errorf("unknown modifier %q", p)
+		}
+		modifiers |= m
+	}
+	if modifiers == 0 {
+		return 0, 0, fmt.Errorf("shortcut %q needs at least one modifier (cmd, shift, opt, ctrl)", s)
+	}
+	keyCode, ok := shortcutKeyCodes[strings.TrimSpace(key)]
+	if !ok {
+		return 0, 0, fmt.Errorf("unsupported key %q, only letters and digits are supported", key)
+	}
+	return keyCode, modifiers, nil
+}
+
+// registerHotKeys (re-)registers all shortcuts configured in preferences.
+func (c *menuController) registerHotKeys() {
+	c.unregisterHotKeys()
+
+	for _, a := range hotKeyActions {
+		shortcut := getStringPref(a.prefKey)
+		if shortcut == "" {
+			continue
+		}
+		keyCode, modifiers, err := parseShortcut(shortcut)
+		if err != nil {
+			logrus.WithError(err).WithField("action", a.name).Warn("Invalid keyboard shortcut")
+			continue
+		}
+		ref := C.batt_registerHotKey(C.uint32_t(a.id), C.uint32_t(keyCode), C.uint32_t(modifiers))
+		if ref == nil {
+			logrus.WithField("action", a.name).WithField("shortcut", shortcut).Warn("Failed to register keyboard shortcut, it may be used by another app")
+			continue
+		}
+		c.hotKeyRefs = append(c.hotKeyRefs, ref)
+		logrus.WithField("action", a.name).WithField("shortcut", shortcut).Debug("Registered keyboard shortcut")
+	}
+}
+
+func (c *menuController) unregisterHotKeys() {
+	for _, ref := range c.hotKeyRefs {
+		C.batt_unregisterHotKey(ref)
+	}
+	c.hotKeyRefs = nil
+}
+
+func installHotKeyHandler(h cgo.Handle) {
+	if !C.batt_installHotKeyHandler(C.uintptr_t(h)) {
+		logrus.Error("Failed to install keyboard shortcut handler")
+	}
+}
+
+func removeHotKeyHandler() {
+	C.batt_removeHotKeyHandler()
+}
+
+func (c *menuController) onHotKey(id uint32) {
+	switch id {
+	case hotKeyIDToggleLimit:
+		c.toggleLimit()
+	case hotKeyIDShowStatus:
+		c.showStatusWindow()
+	case hotKeyIDTogglePause:
+		c.toggleChargingPaused()
+	case hotKeyIDCommandPalette:
+		c.showCommandPalette()
+	}
+}
+
+// toggleLimit disables the charge limit, or restores the limit that was set
+// before it was disabled.
+func (c *menuController) toggleLimit() {
+	rawConfig, err := c.api.GetConfig()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get config")
+		showNotification("Charge Limit", "Failed to get config: "+err.Error())
+		return
+	}
+	conf := config.NewFileFromConfig(rawConfig, "")
+
+	limit := 100
+	if conf.UpperLimit() < 100 {
+		setStringPref(prefLimitBeforeDisable, strconv.Itoa(conf.UpperLimit()))
+	} else {
+		limit = 80
+		if v, err := strconv.Atoi(getStringPref(prefLimitBeforeDisable)); err == nil {
+			limit = v
+		}
+	}
+
+	ret, err := c.api.SetLimit(limit)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to set limit")
+		showNotification("Charge Limit", "Failed to set limit: "+ret+err.Error())
+		return
+	}
+	if limit == 100 {
+		showNotification("Charge Limit", "Charge limit disabled. Your Mac will charge to 100%.")
+	} else {
+		showNotification("Charge Limit", fmt.Sprintf("Charge limit set to %d%%.", limit))
+	}
+}
+
+//export battHotKeyPressed
+func battHotKeyPressed(h C.uintptr_t, id C.uint32_t) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("panic in battHotKeyPressed: %v", r)
+		}
+	}()
+	handle := cgo.Handle(h)
+	if v := handle.Value(); v != nil {
+		if c, ok := v.(*menuController); ok {
+			c.onHotKey(uint32(id))
+		}
+	}
+}
@@ -0,0 +1,50 @@
+#import <Carbon/Carbon.h>
+#include <stdint.h>
+#include <stdbool.h>
+
+// Callback exported from Go
+extern void battHotKeyPressed(uintptr_t handle, uint32_t hotKeyID);
+
+// Four-char signature identifying batt hot keys.
+static const OSType kBattHotKeySignature = 'batt';
+
+static EventHandlerRef gHotKeyHandlerRef = NULL;
+
+static OSStatus battHotKeyHandler(EventHandlerCallRef next, EventRef event, void *userData) {
+    EventHotKeyID hotKeyID;
+    OSStatus err = GetEventParameter(event, kEventParamDirectObject, typeEventHotKeyID, NULL,
+                                     sizeof(hotKeyID), NULL, &hotKeyID);
+    if (err != noErr || hotKeyID.signature != kBattHotKeySignature) {
+        return eventNotHandledErr;
+    }
+    battHotKeyPressed((uintptr_t)userData, hotKeyID.id);
+    return noErr;
+}
+
+bool batt_installHotKeyHandler(uintptr_t handle) {
+    if (gHotKeyHandlerRef != NULL) return true;
+    EventTypeSpec spec = {kEventClassKeyboard, kEventHotKeyPressed};
+    OSStatus err = InstallApplicationEventHandler(&battHotKeyHandler, 1, &spec, (void *)handle, &gHotKeyHandlerRef);
+    return err == noErr;
+}
+
+void batt_removeHotKeyHandler(void) {
+    if (gHotKeyHandlerRef == NULL) return;
+    RemoveEventHandler(gHotKeyHandlerRef);
+    gHotKeyHandlerRef = NULL;
+}
+
+// Returns an EventHotKeyRef, or NULL if the combination could not be
+// registered (e.g. it is already taken by another app).
+void *batt_registerHotKey(uint32_t hotKeyID, uint32_t keyCode, uint32_t modifiers) {
+    EventHotKeyID id = {kBattHotKeySignature, hotKeyID};
+    EventHotKeyRef ref = NULL;
+    OSStatus err = RegisterEventHotKey(keyCode, modifiers, id, GetApplicationEventTarget(), 0, &ref);
+    if (err != noErr) return NULL;
+    return ref;
+}
+
+void batt_unregisterHotKey(void *ref) {
+    if (ref == NULL) return;
+    UnregisterEventHotKey((EventHotKeyRef)ref);
+}
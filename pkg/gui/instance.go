@@ -0,0 +1,23 @@
+package gui
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// // Implemented in instance.m.
+// int batt_terminateOtherInstances(double timeout);
+import "C"
+
+// takeOverTimeout is how long (in seconds) other instances get to quit
+// before they are force quit.
+const takeOverTimeout = 5
+
+// takeOverFromOtherInstances makes sure this is the only running instance of
+// the menubar app. Two instances would show two menubar icons, and both react
+// to daemon events. The newest instance wins, since it is usually the one
+// just installed by an update.
+func takeOverFromOtherInstances() {
+	if n := int(C.batt_terminateOtherInstances(C.double(takeOverTimeout))); n > 0 {
+		logrus.WithField("count", n).Info("Took over from other running instances")
+	}
+}
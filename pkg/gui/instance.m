@@ -0,0 +1,40 @@
+#import <Cocoa/Cocoa.h>
+#include <unistd.h>
+
+// batt_terminateOtherInstances asks other running instances of this app (e.g.
+// an old version still alive after an update) to quit, so this one can take
+// over the status item. Instances that have not quit within timeout seconds
+// are force quit. It returns the number of other instances found.
+int batt_terminateOtherInstances(double timeout) {
+    @autoreleasepool {
+        NSString *bundleID = [[NSBundle mainBundle] bundleIdentifier];
+        // Not running from the .app bundle, e.g. `batt gui` during development.
+        if (bundleID == nil) {
+            return 0;
+        }
+
+        pid_t me = getpid();
+        NSMutableArray<NSRunningApplication *> *others = [NSMutableArray array];
+        for (NSRunningApplication *app in [NSRunningApplication runningApplicationsWithBundleIdentifier:bundleID]) {
+            if (app.processIdentifier == me) {
+                continue;
+            }
+            [others addObject:app];
+            // Sends a quit event, so the other instance shuts down cleanly.
+            [app terminate];
+        }
+
+        NSDate *deadline = [NSDate dateWithTimeIntervalSinceNow:timeout];
+        for (NSRunningApplication *app in others) {
+            // terminated is updated through the run loop.
+            while (!app.terminated && [deadline timeIntervalSinceNow] > 0) {
+                [[NSRunLoop currentRunLoop] runMode:NSDefaultRunLoopMode
+                                         beforeDate:[NSDate dateWithTimeIntervalSinceNow:0.1]];
+            }
+            if (!app.terminated) {
+                [app forceTerminate];
+            }
+        }
+        return (int)others.count;
+    }
+}
@@ -0,0 +1,185 @@
+package gui
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/user"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/diagnostics"
+	"github.com/charlie0129/batt/pkg/utils/osver"
+	"github.com/charlie0129/batt/pkg/version"
+)
+
+const (
+	newIssueURL = "https://github.com/charlie0129/batt/issues/new"
+	// errorReportThreshold is how many times in a row an error must happen
+	// before batt offers to report it.
+	errorReportThreshold = 3
+	// maxIssueBodyLength keeps the prefilled issue URL below what browsers
+	// and GitHub accept. The log is cut from the oldest lines to fit.
+	maxIssueBodyLength = 6000
+)
+
+// urlPattern matches URLs in the daemon log.
+var urlPattern = regexp.MustCompile(`https?://[^\s"'<>]+`)
+
+// errorKind groups errors that are counted and reported together.
+type errorKind string
+
+const (
+	errorDaemonUnreachable errorKind = "daemon-unreachable"
+	errorSMCWrite          errorKind = "smc-write"
+)
+
+var errorKindTitles = map[errorKind]string{
+	errorDaemonUnreachable: "The batt daemon is unreachable",
+	errorSMCWrite:          "Writing to the SMC fails",
+}
+
+// repeatedErrors counts the errors of each kind in a row. Each kind is
+// offered for reporting once per run.
+var repeatedErrors = struct {
+	sync.Mutex
+	counts  map[errorKind]int
+	last    map[errorKind]string
+	offered map[errorKind]bool
+	// smcCheckedAt is the time of the newest SMC write already counted.
+	smcCheckedAt time.Time
+}{
+	counts:  map[errorKind]int{},
+	last:    map[errorKind]string{},
+	offered: map[errorKind]bool{},
+}
+
+// noteError counts an error of kind. Once it has happened
+// errorReportThreshold times in a row, a notification offers to report it.
+func noteError(kind errorKind, detail string) {
+	repeatedErrors.Lock()
+	repeatedErrors.counts[kind]++
+	repeatedErrors.last[kind] = detail
+	offer := repeatedErrors.counts[kind] >= errorReportThreshold && !repeatedErrors.offered[kind]
+	if offer {
+		repeatedErrors.offered[kind] = true
+	}
+	repeatedErrors.Unlock()
+
+	if !offer {
+		return
+	}
+	logrus.WithField("kind", kind).Info("Offering to report repeated error")
+	showNotificationWithURL("batt Keeps Failing", errorKindTitles[kind]+". Click to report it on GitHub with your environment details.", "batt://issue/"+string(kind))
+}
+
+// noteSuccess resets the count of errors of kind.
+func noteSuccess(kind errorKind) {
+	repeatedErrors.Lock()
+	defer repeatedErrors.Unlock()
+	repeatedErrors.counts[kind] = 0
+}
+
+// noteSMCWrites counts the SMC writes in the debug report since the last
+// call, failed ones as errors.
+func noteSMCWrites(r *diagnostics.Report) {
+	repeatedErrors.Lock()
+	since := repeatedErrors.smcCheckedAt
+	repeatedErrors.Unlock()
+
+	// Entries are the most recent first.
+	for i := len(r.Entries) - 1; i >= 0; i-- {
+		e := r.Entries[i]
+		if e.Kind != diagnostics.KindSMCWrite || !e.Time.After(since) {
+			continue
+		}
+		repeatedErrors.Lock()
+		repeatedErrors.smcCheckedAt = e.Time
+		repeatedErrors.Unlock()
+		if e.Error != "" {
+			noteError(errorSMCWrite, e.Message+": "+e.Error)
+		} else {
+			noteSuccess(errorSMCWrite)
+		}
+	}
+}
+
+// redact removes URL paths, the home directory, user name and host name
+// from s.
+func redact(s string) string {
+	// Webhook URLs carry their credentials, so keep only the host of any
+	// URL, as GET /config does.
+	s = urlPattern.ReplaceAllStringFunc(s, func(u string) string {
+		if r := config.RedactURL(u); r != "" {
+			return r + "/<redacted>"
+		}
+		return u
+	})
+	if home, err := os.UserHomeDir(); err == nil && home != "" && home != "/" {
+		s = strings.ReplaceAll(s, home, "~")
+	}
+	if u, err := user.Current(); err == nil && len(u.Username) > 1 {
+		s = strings.ReplaceAll(s, u.Username, "<user>")
+	}
+	if host, err := os.Hostname(); err == nil && host != "" {
+		s = strings.ReplaceAll(s, strings.TrimSuffix(host, ".local"), "<host>")
+	}
+	return s
+}
+
+// issueBody prefills a bug report about kind with the environment and the
+// redacted daemon log.
+func (c *menuController) issueBody(kind errorKind) string {
+	repeatedErrors.Lock()
+	count, last := repeatedErrors.counts[kind], repeatedErrors.last[kind]
+	repeatedErrors.Unlock()
+
+	daemonVersion, err := c.api.GetVersion()
+	if err != nil {
+		daemonVersion = "unreachable"
+	}
+	model, _ := unix.Sysctl("hw.model")
+
+	var sb strings.Builder
+	sb.WriteString("<!-- Please describe what you were doing when this happened. Check below for anything you would rather not share. -->\n\n")
+	fmt.Fprintf(&sb, "### What happened\n\n%s, %d times in a row.\n\n", errorKindTitles[kind], count)
+	fmt.Fprintf(&sb, "Last error:\n\n```\n%s\n```\n\n", redact(last))
+	sb.WriteString("### Environment\n\n")
+	fmt.Fprintf(&sb, "- batt.app: %s (%s)\n", version.Version, version.GitCommit)
+	fmt.Fprintf(&sb, "- Daemon: %s\n", daemonVersion)
+	fmt.Fprintf(&sb, "- macOS: %s\n", osver.Get())
+	fmt.Fprintf(&sb, "- Mac: %s\n", model)
+
+	head := sb.String()
+	log, err := c.api.GetLog(daemonLogTailLines)
+	if err != nil || log == "" {
+		return head
+	}
+	log = redact(strings.TrimSpace(log))
+	room := maxIssueBodyLength - len(head) - 64
+	if room <= 0 {
+		return head
+	}
+	if len(log) > room {
+		log = log[len(log)-room:]
+		if i := strings.IndexByte(log, '\n'); i >= 0 {
+			log = log[i+1:]
+		}
+	}
+	return head + "\n### Daemon log\n\n```\n" + log + "\n```\n"
+}
+
+// reportIssue opens a prefilled GitHub issue about kind in the browser.
+func (c *menuController) reportIssue(kind errorKind) error {
+	q := url.Values{}
+	q.Set("title", errorKindTitles[kind])
+	q.Set("body", c.issueBody(kind))
+	return exec.Command("/usr/bin/open", newIssueURL+"?"+q.Encode()).Run()
+}
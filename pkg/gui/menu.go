@@ -5,6 +5,12 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"os/user"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
 
 	"github.com/progrium/darwinkit/macos/appkit"
 	"github.com/progrium/darwinkit/macos/foundation"
@@ -12,6 +18,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/charlie0129/batt/pkg/calibration"
+	"github.com/charlie0129/batt/pkg/capability"
 	"github.com/charlie0129/batt/pkg/client"
 	"github.com/charlie0129/batt/pkg/config"
 	"github.com/charlie0129/batt/pkg/powerinfo"
@@ -34,6 +41,8 @@ type menuController struct {
 	upgradeItem      appkit.MenuItem
 	stateItem        appkit.MenuItem
 	currentLimitItem appkit.MenuItem
+	conflictItem     appkit.MenuItem
+	unsupportedItem  appkit.MenuItem
 	quickLimitsItem  appkit.MenuItem
 	quickLimitsItems map[int]appkit.MenuItem
 
@@ -45,11 +54,35 @@ type menuController struct {
 	controlMagSafeDisableItem   appkit.MenuItem
 	controlMagSafeAlwaysOffItem appkit.MenuItem
 
+	optimizedChargingSubMenuItem appkit.MenuItem
+	optimizedChargingStatusItem  appkit.MenuItem
+	optimizedChargingItems       map[config.OptimizedChargingMode]appkit.MenuItem
+
+	energySamplingItem appkit.MenuItem
+	energyReportItem   appkit.MenuItem
+	usageReportItem    appkit.MenuItem
+	monthlyReportItem  appkit.MenuItem
+	// healthComparisonItem compares the battery health with the model baseline.
+	healthComparisonItem appkit.MenuItem
+
 	preventIdleSleepItem        appkit.MenuItem
 	disableChargingPreSleepItem appkit.MenuItem
 	preventSystemSleepItem      appkit.MenuItem
+	weakAdapterBoostItem        appkit.MenuItem
 	forceDischargeItem          appkit.MenuItem
 	uninstallItem               appkit.MenuItem
+	compactIconItem             appkit.MenuItem
+	systemChargeItem            appkit.MenuItem
+	loginItemItem               appkit.MenuItem
+	nonRootAccessItem           appkit.MenuItem
+	// repairAccessItem is shown when the daemon refuses the menubar app.
+	repairAccessItem     appkit.MenuItem
+	daemonLogSubMenuItem appkit.MenuItem
+	logLevelItems        map[string]appkit.MenuItem
+	appRulesSubMenuItem  appkit.MenuItem
+	appRulesMenu         appkit.Menu
+	cliSubMenuItem       appkit.MenuItem
+	cliMenu              appkit.Menu
 
 	// Auto Calibration
 	autoCalSubMenuItem appkit.MenuItem
@@ -61,14 +94,76 @@ type menuController struct {
 
 	// Quit/disable
 	disableItem appkit.MenuItem
-	quitItem    appkit.MenuItem
+
+	travelModeSubMenuItem appkit.MenuItem
+	travelModeStatusItem  appkit.MenuItem
+	travelModeOffItem     appkit.MenuItem
+	// travelBadge is whether the travel mode badge is shown.
+	travelBadge bool
+
+	policySubMenuItem appkit.MenuItem
+	policyDetailItem  appkit.MenuItem
+	policyLimitsItem  appkit.MenuItem
+	policySetByItem   appkit.MenuItem
+
+	chargeBySubMenuItem appkit.MenuItem
+	chargeByItems       map[string]appkit.MenuItem
+
+	pauseChargingItem appkit.MenuItem
+	// chargingPaused is the last known state, to know which way to toggle.
+	chargingPaused bool
+	pausedBadge    bool
+	// badges is the title currently shown next to the menubar icon.
+	badges string
+
+	bypassItem      appkit.MenuItem
+	storageModeItem appkit.MenuItem
+	quitItem        appkit.MenuItem
 
 	// Calibration cached parameters
 	calThreshold   int
 	calHoldMinutes int
 
+	// hotKeyRefs are the registered global keyboard shortcuts.
+	hotKeyRefs []unsafe.Pointer
+
+	// appearance is the current effective appearance of the app.
+	appearance appearance
+	// lastPower is the last power telemetry, kept to re-render on appearance changes.
+	lastPower *powerinfo.PowerTelemetry
+
+	// upgradeDaemon installs the daemon of this app over the running one.
+	upgradeDaemon func()
+	// upgradeDeferred is whether an upgrade waits for the daemon to be done.
+	upgradeDeferred atomic.Bool
+
+	// capabilities are the SMC keys probed by the daemon. They do not change
+	// while the daemon runs, so they are fetched once.
+	capabilities *capability.Report
+
+	// statusWin is created lazily when first shown.
+	statusWin *statusWindow
+	// palette is created lazily when first shown.
+	palette *commandPalette
+	// debugWin is created lazily when first shown.
+	debugWin *debugWindow
+
 	// eventCancel cancels the SSE event subscription goroutine
 	eventCancel context.CancelFunc
+	// eventDone is closed once the SSE event subscription goroutine exits.
+	eventDone chan struct{}
+
+	shutdownOnce sync.Once
+
+	// resources are released on teardown.
+	resources *cgoResources
+}
+
+// teardown releases all cgo handles and Objective-C objects of the
+// controller. It is safe to call more than once.
+func (c *menuController) teardown() {
+	logrus.Info("Cleaning up resources")
+	c.resources.releaseAll()
 }
 
 func (c *menuController) onWillOpen() {
@@ -89,47 +184,86 @@ func (c *menuController) toggleMenusRequiringInstall(battInstalled, capable, nee
 	setMenubarImage(c.menubarIcon, battInstalled, capable, needUpgrade)
 
 	// Visible when installed, capable, and no upgrade needed.
-	c.powerFlowSubMenuItem.SetHidden(!battInstalled || !capable || needUpgrade)
+	setItemHidden(c.powerFlowSubMenuItem, !battInstalled || !capable || needUpgrade)
 
-	c.installItem.SetHidden(battInstalled)
+	setItemHidden(c.installItem, battInstalled)
 	// Show when installed AND (needs upgrade OR not capable)
-	c.upgradeItem.SetHidden(!battInstalled || (!needUpgrade && capable))
+	setItemHidden(c.upgradeItem, !battInstalled || (!needUpgrade && capable))
 	// Show when installed AND capable
-	c.stateItem.SetHidden(!battInstalled || !capable)
-	c.currentLimitItem.SetHidden(!battInstalled || !capable)
+	setItemHidden(c.stateItem, !battInstalled || !capable)
+	setItemHidden(c.currentLimitItem, !battInstalled || !capable)
+	setItemHidden(c.policySubMenuItem, !battInstalled || !capable || needUpgrade)
+	setItemHidden(c.unsupportedItem, !battInstalled || capable)
+	if !battInstalled || !capable {
+		setItemHidden(c.conflictItem, true)
+	}
 
 	// Show when installed AND capable AND no upgrade needed
-	c.quickLimitsItem.SetHidden(!battInstalled || !capable || needUpgrade)
+	setItemHidden(c.quickLimitsItem, !battInstalled || !capable || needUpgrade)
 	for _, it := range c.quickLimitsItems {
-		it.SetHidden(!battInstalled || !capable || needUpgrade)
+		setItemHidden(it, !battInstalled || !capable || needUpgrade)
 	}
 
-	c.advancedSubMenuItem.SetHidden(!battInstalled)
-	c.controlMagSafeLEDItem.SetHidden(!battInstalled || !capable || needUpgrade)
-	c.preventIdleSleepItem.SetHidden(!battInstalled || !capable || needUpgrade)
-	c.disableChargingPreSleepItem.SetHidden(!battInstalled || !capable || needUpgrade)
-	c.preventSystemSleepItem.SetHidden(!battInstalled || !capable || needUpgrade)
-	c.forceDischargeItem.SetHidden(!battInstalled || !capable || needUpgrade)
-	c.autoCalSubMenuItem.SetHidden(!battInstalled || !capable || needUpgrade)
-	c.uninstallItem.SetHidden(!battInstalled)
-
-	c.disableItem.SetHidden(!battInstalled || !capable || needUpgrade)
+	setItemHidden(c.advancedSubMenuItem, !battInstalled)
+	setItemHidden(c.controlMagSafeLEDItem, !battInstalled || !capable || needUpgrade)
+	setItemHidden(c.optimizedChargingSubMenuItem, !battInstalled || !capable || needUpgrade)
+	setItemHidden(c.energySamplingItem, !battInstalled || needUpgrade)
+	setItemHidden(c.energyReportItem, !battInstalled || needUpgrade)
+	setItemHidden(c.usageReportItem, !battInstalled || needUpgrade)
+	setItemHidden(c.monthlyReportItem, !battInstalled || needUpgrade)
+	setItemHidden(c.healthComparisonItem, !battInstalled || needUpgrade)
+	setItemHidden(c.preventIdleSleepItem, !battInstalled || !capable || needUpgrade)
+	setItemHidden(c.disableChargingPreSleepItem, !battInstalled || !capable || needUpgrade)
+	setItemHidden(c.preventSystemSleepItem, !battInstalled || !capable || needUpgrade)
+	setItemHidden(c.weakAdapterBoostItem, !battInstalled || !capable || needUpgrade)
+	setItemHidden(c.forceDischargeItem, !battInstalled || !capable || needUpgrade)
+	setItemHidden(c.autoCalSubMenuItem, !battInstalled || !capable || needUpgrade)
+	setItemHidden(c.daemonLogSubMenuItem, !battInstalled || needUpgrade)
+	setItemHidden(c.cliSubMenuItem, !battInstalled || needUpgrade)
+	setItemHidden(c.nonRootAccessItem, !battInstalled || needUpgrade)
+	setItemHidden(c.appRulesSubMenuItem, !battInstalled || !capable || needUpgrade)
+	setItemHidden(c.uninstallItem, !battInstalled)
+
+	setItemHidden(c.disableItem, !battInstalled || !capable || needUpgrade)
+	setItemHidden(c.travelModeSubMenuItem, !battInstalled || !capable || needUpgrade)
+	setItemHidden(c.storageModeItem, !battInstalled || !capable || needUpgrade)
+	setItemHidden(c.pauseChargingItem, !battInstalled || !capable || needUpgrade)
+	setItemHidden(c.bypassItem, !battInstalled || !capable || needUpgrade)
+	setItemHidden(c.chargeBySubMenuItem, !battInstalled || !capable || needUpgrade)
+	if battInstalled && capable && !needUpgrade {
+		c.hideUnsupportedFeatures()
+	}
 
 	// Display difference quit tooltip based on whether daemon is installed.
 	if battInstalled {
-		c.quitItem.SetToolTip(quitTooltipInstalled)
+		setItemToolTip(c.quitItem, quitTooltipInstalled)
 	} else {
-		c.quitItem.SetToolTip(quitTooltipNotInstalled)
+		setItemToolTip(c.quitItem, quitTooltipNotInstalled)
 	}
 }
 
 func (c *menuController) refreshOnOpen() {
+	setItemTitle(c.loginItemItem, "Start at Login: "+GetLoginItemStatus().String())
+	// Rendered on open rather than at launch, since it checks the file system.
+	c.renderCommandLineTool()
+
 	rawConfig, err := c.api.GetConfig()
 	if err != nil {
 		logrus.WithError(err).Error("Failed to get config")
+		// The daemon may be reinstalled or upgraded, probe again next time.
+		c.capabilities = nil
 		c.toggleMenusRequiringInstall(false, false, false)
+		c.renderDaemonAccess(err)
+		if isDaemonInstalled() {
+			noteError(errorDaemonUnreachable, err.Error())
+		}
 		return
 	}
+	c.renderDaemonAccess(nil)
+	noteSuccess(errorDaemonUnreachable)
+	if r, err := c.api.GetDebugReport(); err == nil {
+		noteSMCWrites(r)
+	}
 	capable, err := c.api.GetChargingControlCapable()
 	if err != nil {
 		logrus.WithError(err).Error("Failed to get charging capablility")
@@ -143,30 +277,33 @@ func (c *menuController) refreshOnOpen() {
 	} else {
 		c.toggleMenusRequiringInstall(true, capable, daemonVersion != version.Version)
 	}
+	if err != nil || daemonVersion != version.Version || !capable {
+		c.renderUpgradeItem()
+	}
 	logrus.WithField("daemonVersion", daemonVersion).WithField("clientVersion", version.Version).Info("Got daemon")
 
 	isCharging, err := c.api.GetCharging()
 	if err != nil {
 		logrus.WithError(err).Error("Failed to get charging state")
-		c.stateItem.SetTitle("State: Error")
+		setItemTitle(c.stateItem, "State: Error")
 		return
 	}
 	isPluggedIn, err := c.api.GetPluggedIn()
 	if err != nil {
 		logrus.WithError(err).Error("Failed to get plugged in state")
-		c.stateItem.SetTitle("State: Error")
+		setItemTitle(c.stateItem, "State: Error")
 		return
 	}
 	currentCharge, err := c.api.GetCurrentCharge()
 	if err != nil {
 		logrus.WithError(err).Error("Failed to get current charge")
-		c.stateItem.SetTitle("State: Error")
+		setItemTitle(c.stateItem, "State: Error")
 		return
 	}
 	batteryInfo, err := c.api.GetBatteryInfo()
 	if err != nil {
 		logrus.WithError(err).Error("Failed to get battery info")
-		c.stateItem.SetTitle("State: Error")
+		setItemTitle(c.stateItem, "State: Error")
 		return
 	}
 
@@ -175,26 +312,29 @@ func (c *menuController) refreshOnOpen() {
 	// Cache calibration params for formatting
 	c.calThreshold = conf.CalibrationDischargeThreshold()
 	c.calHoldMinutes = conf.CalibrationHoldDurationMinutes()
-	c.currentLimitItem.SetTitle(fmt.Sprintf("Current Limit: %d%%", conf.UpperLimit()))
+	managed, err := c.api.GetManaged()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get managed settings")
+		managed = &config.Managed{}
+	}
+	setItemTitle(c.currentLimitItem, describeCurrentLimit(conf, managed))
 	for limit, item := range c.quickLimitsItems {
 		setCheckboxItem(item, limit == conf.UpperLimit())
+		setItemEnabled(item, !managed.LimitLocked())
 	}
-
-	state := "Not Charging"
-	switch batteryInfo.State {
-	case powerinfo.Charging:
-		state = "Charging"
-	case powerinfo.Discharging:
-		if batteryInfo.ChargeRate != 0 {
-			state = "Discharging"
-		}
-	case powerinfo.Full:
-		state = "Full"
-	}
-	c.stateItem.SetTitle("State: " + state)
-	if !isCharging && isPluggedIn && conf.UpperLimit() < 100 && currentCharge < conf.LowerLimit() {
-		c.stateItem.SetTitle("State: Will Charge Soon")
-	}
+	// Settings enforced by a configuration profile are shown, but cannot
+	// be changed.
+	setItemEnabled(c.disableItem, !managed.LimitLocked())
+	setItemEnabled(c.travelModeSubMenuItem, !managed.LimitLocked())
+	setItemEnabled(c.storageModeItem, !managed.LimitLocked())
+	setItemEnabled(c.bypassItem, !managed.LimitLocked())
+	setItemEnabled(c.energySamplingItem, !managed.EnergySamplingLocked())
+	setCheckboxItem(c.nonRootAccessItem, conf.AllowNonRootAccess())
+
+	state := describeBatteryState(batteryInfo, conf, isCharging, isPluggedIn, currentCharge)
+	setItemTitle(c.stateItem, "State: "+state)
+	// Let VoiceOver announce the essentials on the status item itself.
+	setAccessibility(c.menubarIcon.Button(), "", fmt.Sprintf("batt, %s, battery %d%%, limit %d%%", state, displayCharge(currentCharge, batteryInfo), conf.UpperLimit()))
 
 	magSafeMode := conf.ControlMagSafeLED()
 	switch magSafeMode {
@@ -212,15 +352,222 @@ func (c *menuController) refreshOnOpen() {
 		setCheckboxItem(c.controlMagSafeAlwaysOffItem, false)
 	}
 
+	if st, err := c.api.GetConflictStatus(); err == nil {
+		setItemHidden(c.conflictItem, !st.Detected)
+	} else {
+		logrus.WithError(err).Error("Failed to get conflict status")
+		setItemHidden(c.conflictItem, true)
+	}
+
+	for mode, item := range c.optimizedChargingItems {
+		setCheckboxItem(item, mode == conf.OptimizedChargingMode())
+	}
+	if st, err := c.api.GetOptimizedChargingStatus(); err == nil {
+		switch {
+		case st.Engaged:
+			setItemTitle(c.optimizedChargingStatusItem, "macOS is holding the charge")
+		case st.BuiltInLimit:
+			setItemTitle(c.optimizedChargingStatusItem, "macOS seems to limit charging to 80%")
+		default:
+			setItemTitle(c.optimizedChargingStatusItem, "macOS is not holding the charge")
+		}
+	} else {
+		logrus.WithError(err).Error("Failed to get optimized charging status")
+		setItemTitle(c.optimizedChargingStatusItem, "Status: Error")
+	}
+
+	c.renderTravelMode(conf.TravelMode())
+	setCheckboxItem(c.storageModeItem, conf.StorageMode() != nil)
+	c.renderChargeBy(conf.ChargeBy())
+	if p, err := c.api.GetPolicy(); err == nil {
+		c.renderPolicy(p)
+	} else {
+		logrus.WithError(err).Error("Failed to get policy")
+		setItemTitle(c.policySubMenuItem, "Current Policy: Error")
+	}
+	if paused, err := c.api.GetChargingPaused(); err == nil {
+		c.renderChargingPaused(paused)
+	} else {
+		logrus.WithError(err).Error("Failed to get charging paused state")
+	}
+	if level, err := c.api.GetLogLevel(); err == nil {
+		c.renderLogLevel(level)
+	}
+	if level, err := c.api.GetBypass(); err == nil {
+		setCheckboxItem(c.bypassItem, level > 0)
+		if level > 0 {
+			setItemTitle(c.bypassItem, fmt.Sprintf("Run from Adapter at Current Level (%d%%)", level))
+		} else {
+			setItemTitle(c.bypassItem, "Run from Adapter at Current Level")
+		}
+	} else {
+		logrus.WithError(err).Error("Failed to get bypass state")
+	}
+	remindStorageMode(conf.StorageMode(), time.Now())
+	c.notifyMonthlyReport(time.Now())
+	setCheckboxItem(c.energySamplingItem, conf.EnergySampling())
 	setCheckboxItem(c.preventIdleSleepItem, conf.PreventIdleSleep())
 	setCheckboxItem(c.disableChargingPreSleepItem, conf.DisableChargingPreSleep())
 	setCheckboxItem(c.preventSystemSleepItem, conf.PreventSystemSleep())
+	setCheckboxItem(c.weakAdapterBoostItem, conf.WeakAdapterBoost())
 	if adapter, err := c.api.GetAdapter(); err == nil {
 		setCheckboxItem(c.forceDischargeItem, !adapter)
 	} else {
 		logrus.WithError(err).Error("Failed to get adapter")
-		c.forceDischargeItem.SetEnabled(false)
+		setItemEnabled(c.forceDischargeItem, false)
+	}
+}
+
+// getCapabilities returns the cached SMC capabilities of this Mac.
+func (c *menuController) getCapabilities() (*capability.Report, error) {
+	if c.capabilities != nil {
+		return c.capabilities, nil
+	}
+	r, err := c.api.GetCapabilities()
+	if err != nil {
+		return nil, err
+	}
+	c.capabilities = r
+	return r, nil
+}
+
+// runSelfTest runs the self-test and shows the pass/fail matrix.
+func (c *menuController) runSelfTest() {
+	t, err := c.api.RunSelfTest()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to run self-test")
+		showAlert("Failed to run self-test", err.Error())
+		return
+	}
+	if t.Passed() {
+		showAlert("Self-Test Passed", t.String())
+	} else {
+		showAlert("Self-Test Failed", t.String()+"\nPlease include these results when reporting an issue.")
+	}
+}
+
+// showCapabilities shows the probed SMC keys and the active strategies, and
+// explains why this Mac is not supported if so.
+func (c *menuController) showCapabilities() {
+	r, err := c.getCapabilities()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get capabilities")
+		showAlert("Failed to get capabilities", err.Error())
+		return
+	}
+
+	var sb strings.Builder
+	if !r.ChargingControl {
+		sb.WriteString("batt cannot find the SMC keys that control charging on this Mac, so it cannot limit charging. batt only supports Apple Silicon MacBooks.\n\n")
+	}
+	sb.WriteString(formatCompatibility(r))
+	fmt.Fprintf(&sb, "\nCharging strategy: %s\n", describeStrategy(r.ChargingStrategy))
+	fmt.Fprintf(&sb, "Adapter strategy: %s\n", describeStrategy(r.AdapterStrategy))
+	if r.StrategyOverridden {
+		sb.WriteString("Strategies are overridden in the config.\n")
 	}
+	sb.WriteString("\nSMC keys:\n")
+	for _, k := range r.Keys {
+		status := "missing"
+		if k.Available {
+			status = "available"
+		}
+		fmt.Fprintf(&sb, "%s (%s): %s\n", k.Key, k.Description, status)
+	}
+	showAlert("Compatibility Report", sb.String())
+}
+
+// formatCompatibility lists the features of batt this Mac supports.
+func formatCompatibility(r *capability.Report) string {
+	var sb strings.Builder
+	if r.Model != "" {
+		fmt.Fprintf(&sb, "Model: %s\n", r.Model)
+	}
+	for _, f := range r.Features() {
+		mark := "✅"
+		if !f.Supported {
+			mark = "❌"
+		}
+		fmt.Fprintf(&sb, "%s %s\n", mark, f.Name)
+	}
+	return sb.String()
+}
+
+// hideUnsupportedFeatures hides the menu items of features this Mac does
+// not support, instead of letting them fail.
+func (c *menuController) hideUnsupportedFeatures() {
+	r, err := c.getCapabilities()
+	if err != nil {
+		logrus.WithError(err).Debug("Failed to get capabilities")
+		return
+	}
+	if !r.MagSafeLED {
+		setItemHidden(c.controlMagSafeLEDItem, true)
+	}
+	if !r.AdapterControl {
+		setItemHidden(c.forceDischargeItem, true)
+		setItemHidden(c.autoCalSubMenuItem, true)
+	}
+}
+
+// prefCompatibilityReported is set once the user has been told about
+// features this Mac does not support, so we only do so during onboarding.
+const prefCompatibilityReported = "CompatibilityReported"
+
+// reportCompatibility runs once during onboarding and tells the user if
+// this Mac does not support some features.
+func (c *menuController) reportCompatibility() {
+	if getBoolPref(prefCompatibilityReported) {
+		return
+	}
+	r, err := c.getCapabilities()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get capabilities")
+		return
+	}
+	setBoolPref(prefCompatibilityReported, true)
+	if len(r.Unsupported()) == 0 {
+		return
+	}
+	showAlert("Some features are not available on this Mac", formatCompatibility(r)+"\nThe menu items of unavailable features are hidden. See Advanced -> SMC Diagnostics... for details.")
+}
+
+func describeStrategy(s *capability.Strategy) string {
+	if s == nil {
+		return "unsupported"
+	}
+	return fmt.Sprintf("%s (%s)", s.Name, s.Description)
+}
+
+// showConflict explains a detected conflict with another charge limiter.
+func (c *menuController) showConflict() {
+	st, err := c.api.GetConflictStatus()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get conflict status")
+		showAlert("Failed to get conflict status", err.Error())
+		return
+	}
+	if !st.Detected {
+		showAlert("No conflict detected", "No other program changed charging recently.")
+		return
+	}
+	text := st.Message + fmt.Sprintf("\n\nDetected %d time(s), last at %s.", st.Count, st.LastDetected.Local().Format("15:04:05"))
+	showAlert("Another charge limiter is changing charging", text)
+}
+
+// renderPowerFlow renders the last power telemetry into the Power Flow submenu.
+func (c *menuController) renderPowerFlow() {
+	info := c.lastPower
+	if info == nil {
+		return
+	}
+	hc := c.appearance.highContrast
+	setPowerItem(c.systemItem, "System", info.Calculations.SystemPower, hc)
+	setPowerItem(c.adapterItem, "Adapter", info.Calculations.ACPower, hc)
+	setPowerItem(c.batteryItem, "Battery", info.Calculations.BatteryPower, hc)
+	setAccessibility(c.systemItem, "", powerAccessibilityLabel("System", info.Calculations.SystemPower))
+	setAccessibility(c.adapterItem, "", powerAccessibilityLabel("Adapter", info.Calculations.ACPower))
+	setAccessibility(c.batteryItem, "", powerAccessibilityLabel("Battery", info.Calculations.BatteryPower))
 }
 
 // updateTelemetryOnce fetches both power and calibration in a single call and updates the UI.
@@ -234,10 +581,8 @@ func (c *menuController) updateTelemetryOnce() {
 	}
 	// Power section
 	if tr.Power != nil {
-		info := tr.Power
-		c.systemItem.SetAttributedTitle(formatPowerString("System", info.Calculations.SystemPower))
-		c.adapterItem.SetAttributedTitle(formatPowerString("Adapter", info.Calculations.ACPower))
-		c.batteryItem.SetAttributedTitle(formatPowerString("Battery", info.Calculations.BatteryPower))
+		c.lastPower = tr.Power
+		c.renderPowerFlow()
 	}
 	// Calibration section
 	if tr.Calibration != nil {
@@ -246,73 +591,185 @@ func (c *menuController) updateTelemetryOnce() {
 		// Title of submenu
 		if !isIdle {
 			if st.Paused {
-				c.autoCalSubMenuItem.SetTitle("Auto Calibration (Experimental) Paused...")
+				setItemTitle(c.autoCalSubMenuItem, "Auto Calibration (Experimental) Paused...")
 			} else {
-				c.autoCalSubMenuItem.SetTitle("Auto Calibration (Experimental) In Progress...")
+				setItemTitle(c.autoCalSubMenuItem, "Auto Calibration (Experimental) In Progress...")
 			}
 		} else {
-			c.autoCalSubMenuItem.SetTitle("Auto Calibration (Experimental)...")
+			setItemTitle(c.autoCalSubMenuItem, "Auto Calibration (Experimental)...")
 		}
 		// Enable/disable action items
-		c.calStartItem.SetEnabled(isIdle)
-		c.calCancelItem.SetEnabled(!isIdle)
+		setItemEnabled(c.calStartItem, isIdle)
+		setItemEnabled(c.calCancelItem, !isIdle)
 		if st.Paused {
-			c.calPauseItem.SetEnabled(false)
-			c.calResumeItem.SetEnabled(true)
+			setItemEnabled(c.calPauseItem, false)
+			setItemEnabled(c.calResumeItem, true)
 		} else {
-			c.calPauseItem.SetEnabled(!isIdle)
-			c.calResumeItem.SetEnabled(false)
+			setItemEnabled(c.calPauseItem, !isIdle)
+			setItemEnabled(c.calResumeItem, false)
 		}
 
 		// Format status line
 		switch st.Phase {
 		case calibration.PhaseIdle:
-			c.calStatusItem.SetTitle("Status: Idle")
+			setItemTitle(c.calStatusItem, "Status: Idle")
 		case calibration.PhaseDischarge:
-			c.calStatusItem.SetTitle(fmt.Sprintf("Status: Discharging (%d%% → %d%%)", st.ChargePercent, c.calThreshold))
+			setItemTitle(c.calStatusItem, fmt.Sprintf("Status: Discharging (%d%% → %d%%)", st.ChargePercent, c.calThreshold))
 		case calibration.PhaseCharge:
-			c.calStatusItem.SetTitle(fmt.Sprintf("Status: Charging (%d%% → 100%%)", st.ChargePercent))
+			setItemTitle(c.calStatusItem, fmt.Sprintf("Status: Charging (%d%% → 100%%)", st.ChargePercent))
 		case calibration.PhaseHold:
 			hrs := st.RemainingHoldSecs / 3600
 			mins := (st.RemainingHoldSecs % 3600) / 60
 			secs := st.RemainingHoldSecs % 60
-			c.calStatusItem.SetTitle(fmt.Sprintf("Status: Holding (%02d:%02d:%02d left)", hrs, mins, secs))
+			setItemTitle(c.calStatusItem, fmt.Sprintf("Status: Holding (%02d:%02d:%02d left)", hrs, mins, secs))
 		case calibration.PhasePostHold:
 			if st.TargetPercent > 0 {
-				c.calStatusItem.SetTitle(fmt.Sprintf("Status: Discharging (%d%% → %d%%)", st.ChargePercent, st.TargetPercent))
+				setItemTitle(c.calStatusItem, fmt.Sprintf("Status: Discharging (%d%% → %d%%)", st.ChargePercent, st.TargetPercent))
 			} else { // Should not happen.
-				c.calStatusItem.SetTitle("Status: Discharging to previous limit...")
+				setItemTitle(c.calStatusItem, "Status: Discharging to previous limit...")
 			}
 		case calibration.PhaseRestore:
-			c.calStatusItem.SetTitle("Status: Restoring settings...")
+			setItemTitle(c.calStatusItem, "Status: Restoring settings...")
 		case calibration.PhaseError:
 			if st.Message != "" {
-				c.calStatusItem.SetTitle("Status: Error - " + st.Message)
+				setItemTitle(c.calStatusItem, "Status: Error - "+st.Message)
 			} else {
-				c.calStatusItem.SetTitle("Status: Error")
+				setItemTitle(c.calStatusItem, "Status: Error")
 			}
 		}
 
 		// Do not let the user change settings when we are trying to calibrate.
 		if st.Phase == calibration.PhaseIdle || st.Phase == calibration.PhaseError || st.Paused {
-			c.forceDischargeItem.SetEnabled(true)
-			c.uninstallItem.SetEnabled(true)
-			c.disableItem.SetEnabled(true)
+			setItemEnabled(c.forceDischargeItem, true)
+			setItemEnabled(c.uninstallItem, true)
+			setItemEnabled(c.disableItem, true)
 			for _, i := range c.quickLimitsItems {
-				i.SetEnabled(true)
+				setItemEnabled(i, true)
 			}
 		} else {
-			c.forceDischargeItem.SetEnabled(false)
-			c.uninstallItem.SetEnabled(false)
-			c.disableItem.SetEnabled(false)
+			setItemEnabled(c.forceDischargeItem, false)
+			setItemEnabled(c.uninstallItem, false)
+			setItemEnabled(c.disableItem, false)
 			for _, i := range c.quickLimitsItems {
-				i.SetEnabled(false)
+				setItemEnabled(i, false)
 			}
 		}
 	}
 }
 
-func formatPowerString(label string, value float64) foundation.AttributedString {
+// describeBatteryState returns a short human-readable charging state.
+// displayCharge returns the charge to show: the raw SMC value the limits
+// apply to, or the percentage of the macOS battery indicator if the user
+// prefers that and the daemon reports it.
+func displayCharge(smcCharge int, batteryInfo *powerinfo.Battery) int {
+	if batteryInfo != nil && batteryInfo.SystemCharge > 0 && getBoolPref(prefSystemCharge) {
+		return batteryInfo.SystemCharge
+	}
+	return smcCharge
+}
+
+func describeBatteryState(batteryInfo *powerinfo.Battery, conf config.Config, isCharging, isPluggedIn bool, currentCharge int) string {
+	if !isCharging && isPluggedIn && conf.UpperLimit() < 100 && currentCharge < conf.LowerLimit() {
+		return "Will Charge Soon"
+	}
+	switch batteryInfo.State {
+	case powerinfo.Charging:
+		return "Charging"
+	case powerinfo.Discharging:
+		if batteryInfo.ChargeRate != 0 {
+			return "Discharging"
+		}
+	case powerinfo.Full:
+		return "Full"
+	}
+	return "Not Charging"
+}
+
+// powerAccessibilityLabel spells out a power flow value for VoiceOver, which
+// does not read the padded, colored menu title well.
+// describeCurrentLimit shows the limit, and who set it if that was another
+// user, since the limit is shared by all users of this Mac.
+func describeCurrentLimit(conf config.Config, managed *config.Managed) string {
+	title := fmt.Sprintf("Current Limit: %d%%", conf.UpperLimit())
+	if managed.LimitLocked() {
+		return title + " (managed by your organization)"
+	}
+	if conf.LimitPolicy() == config.LimitPolicyAdminLocked {
+		title += " 🔒"
+	}
+	by, _ := conf.LimitChangedBy()
+	if by == "" {
+		return title
+	}
+	if u, err := user.Current(); err == nil && u.Username == by {
+		return title
+	}
+	return title + " (set by " + by + ")"
+}
+
+// setPowerItem renders a power value into item, skipping the (relatively
+// expensive) attributed string when the displayed value is unchanged.
+func setPowerItem(item appkit.MenuItem, label string, value float64, highContrast bool) {
+	key := fmt.Sprintf("%s|%.2f|%t", label, value, highContrast)
+	setItemAttributedTitle(item, key, func() foundation.AttributedString {
+		return formatPowerString(label, value, highContrast)
+	})
+}
+
+func powerAccessibilityLabel(label string, value float64) string {
+	switch {
+	case label == "System" || value == 0:
+		return fmt.Sprintf("%s power %.2f watts", label, math.Abs(value))
+	case value > 0:
+		return fmt.Sprintf("%s power plus %.2f watts", label, value)
+	default:
+		return fmt.Sprintf("%s power minus %.2f watts", label, -value)
+	}
+}
+
+// setAccessibilityIdentifiers assigns stable identifiers to menu items for UI test automation.
+func (c *menuController) setAccessibilityIdentifiers() {
+	setAccessibility(c.menubarIcon.Button(), "batt.menubarIcon", "batt")
+	for id, item := range map[string]appkit.MenuItem{
+		"powerFlow":               c.powerFlowSubMenuItem,
+		"powerFlow.system":        c.systemItem,
+		"powerFlow.adapter":       c.adapterItem,
+		"powerFlow.battery":       c.batteryItem,
+		"install":                 c.installItem,
+		"upgrade":                 c.upgradeItem,
+		"state":                   c.stateItem,
+		"currentLimit":            c.currentLimitItem,
+		"advanced":                c.advancedSubMenuItem,
+		"controlMagSafeLED":       c.controlMagSafeLEDItem,
+		"preventIdleSleep":        c.preventIdleSleepItem,
+		"disableChargingPreSleep": c.disableChargingPreSleepItem,
+		"preventSystemSleep":      c.preventSystemSleepItem,
+		"weakAdapterBoost":        c.weakAdapterBoostItem,
+		"forceDischarge":          c.forceDischargeItem,
+		"uninstall":               c.uninstallItem,
+		"compactIcon":             c.compactIconItem,
+		"systemCharge":            c.systemChargeItem,
+		"loginItem":               c.loginItemItem,
+		"autoCalibration":         c.autoCalSubMenuItem,
+		"autoCalibration.status":  c.calStatusItem,
+		"autoCalibration.start":   c.calStartItem,
+		"autoCalibration.pause":   c.calPauseItem,
+		"autoCalibration.resume":  c.calResumeItem,
+		"autoCalibration.cancel":  c.calCancelItem,
+		"disable":                 c.disableItem,
+		"bypass":                  c.bypassItem,
+		"quit":                    c.quitItem,
+	} {
+		setAccessibility(item, "batt.menu."+id, "")
+	}
+	for limit, item := range c.quickLimitsItems {
+		setAccessibility(item, fmt.Sprintf("batt.menu.quickLimit.%d", limit), "")
+	}
+}
+
+// formatPowerString renders a power flow line. With highContrast, the label
+// uses the primary label color because the secondary one is hard to read.
+func formatPowerString(label string, value float64, highContrast bool) foundation.AttributedString {
 	var color appkit.Color
 	sign := " " // Default to a space for alignment. This is crucial.
 
@@ -359,7 +816,11 @@ func formatPowerString(label string, value float64) foundation.AttributedString
 	}
 
 	// Set the label part to the standard secondary gray color.
-	attrStr.AddAttributeValueRange(foundation.AttributedStringKey("NSColor"), appkit.Color_SecondaryLabelColor(), labelRange)
+	labelColor := appkit.Color_SecondaryLabelColor()
+	if highContrast {
+		labelColor = appkit.Color_LabelColor()
+	}
+	attrStr.AddAttributeValueRange(foundation.AttributedStringKey("NSColor"), labelColor, labelRange)
 	// Set the value part to its specific color (green, red, or white).
 	attrStr.AddAttributeValueRange(foundation.AttributedStringKey("NSColor"), color, valueRange)
 
@@ -401,6 +862,9 @@ func checkBoxItem(title, charCode string, cb func(checked bool)) appkit.MenuItem
 }
 
 func setCheckboxItem(menuItem appkit.MenuItem, checked bool) {
+	if !changed(menuCache, menuCache.checked, menuItem, checked) {
+		return
+	}
 	if checked {
 		menuItem.SetState(appkit.ControlStateValueOn)
 	} else {
@@ -0,0 +1,121 @@
+package gui
+
+import (
+	"sync"
+	"unsafe"
+
+	"github.com/progrium/darwinkit/macos/appkit"
+	"github.com/progrium/darwinkit/macos/foundation"
+
+	"github.com/charlie0129/batt/pkg/utils/lru"
+)
+
+// menuStateSize caps how many menu items the state is remembered for.
+// Submenus that are rebuilt, e.g. Command Line Tool, get new items every
+// time and should be emptied with removeAllMenuItems. Items evicted are
+// simply updated on their next change.
+const menuStateSize = 512
+
+// menuState remembers the state last applied to each menu item, so that
+// refreshing the menu only makes cgo calls for items that actually changed.
+// The menu is refreshed on every open and every few seconds while open, and
+// most of the time nothing changes.
+//
+// All runtime updates of menu items must go through the set* helpers below,
+// otherwise the cache goes stale. Setting up items when building the menu is
+// fine, since unknown items are always updated.
+type menuState struct {
+	mu       sync.Mutex
+	titles   *lru.Cache[unsafe.Pointer, string]
+	hidden   *lru.Cache[unsafe.Pointer, bool]
+	enabled  *lru.Cache[unsafe.Pointer, bool]
+	checked  *lru.Cache[unsafe.Pointer, bool]
+	tooltips *lru.Cache[unsafe.Pointer, string]
+	// attributed are the keys of attributed titles, since attributed
+	// strings themselves cannot be compared cheaply.
+	attributed *lru.Cache[unsafe.Pointer, string]
+}
+
+var menuCache = &menuState{
+	titles:     lru.New[unsafe.Pointer, string]("menu titles", menuStateSize),
+	hidden:     lru.New[unsafe.Pointer, bool]("menu hidden", menuStateSize),
+	enabled:    lru.New[unsafe.Pointer, bool]("menu enabled", menuStateSize),
+	checked:    lru.New[unsafe.Pointer, bool]("menu checked", menuStateSize),
+	tooltips:   lru.New[unsafe.Pointer, string]("menu tooltips", menuStateSize),
+	attributed: lru.New[unsafe.Pointer, string]("menu attributed titles", menuStateSize),
+}
+
+// changed records v for item in m and reports whether it differs from the
+// previous value.
+func changed[T comparable](s *menuState, m *lru.Cache[unsafe.Pointer, T], item appkit.MenuItem, v T) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if old, ok := m.Get(item.Ptr()); ok && old == v {
+		return false
+	}
+	m.Add(item.Ptr(), v)
+	return true
+}
+
+// forget drops the state of items, e.g. because they are about to be
+// removed from the menu.
+func (s *menuState) forget(items []appkit.MenuItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range items {
+		p := item.Ptr()
+		s.titles.Remove(p)
+		s.hidden.Remove(p)
+		s.enabled.Remove(p)
+		s.checked.Remove(p)
+		s.tooltips.Remove(p)
+		s.attributed.Remove(p)
+	}
+}
+
+// removeAllMenuItems empties a submenu that is about to be rebuilt. The state
+// of its items is forgotten first: new items may reuse the address of a
+// freed one, and a stale entry would then skip updating them.
+func removeAllMenuItems(menu appkit.Menu) {
+	menuCache.forget(menu.ItemArray())
+	menu.RemoveAllItems()
+}
+
+// stats returns the stats of the caches, for the debug window.
+func (s *menuState) stats() []lru.Stats {
+	return []lru.Stats{s.titles.Stats(), s.hidden.Stats(), s.enabled.Stats(), s.checked.Stats(), s.tooltips.Stats(), s.attributed.Stats()}
+}
+
+func setItemTitle(item appkit.MenuItem, title string) {
+	if changed(menuCache, menuCache.titles, item, title) {
+		item.SetTitle(title)
+	}
+}
+
+func setItemHidden(item appkit.MenuItem, hidden bool) {
+	if changed(menuCache, menuCache.hidden, item, hidden) {
+		item.SetHidden(hidden)
+	}
+}
+
+func setItemEnabled(item appkit.MenuItem, enabled bool) {
+	if changed(menuCache, menuCache.enabled, item, enabled) {
+		item.SetEnabled(enabled)
+	}
+}
+
+func setItemToolTip(item appkit.MenuItem, tooltip string) {
+	if changed(menuCache, menuCache.tooltips, item, tooltip) {
+		item.SetToolTip(tooltip)
+	}
+}
+
+// setItemAttributedTitle sets the attributed title built by build, unless
+// key is the same as last time.
+func setItemAttributedTitle(item appkit.MenuItem, key string, build func() foundation.AttributedString) {
+	if changed(menuCache, menuCache.attributed, item, key) {
+		item.SetAttributedTitle(build())
+	}
+}
@@ -0,0 +1,154 @@
+package gui
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/progrium/darwinkit/macos/appkit"
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/client"
+	"github.com/charlie0129/batt/pkg/limits"
+)
+
+// prefMigrationChecked is set once the user has been offered to migrate, so
+// we only ask during onboarding.
+const prefMigrationChecked = "MigrationChecked"
+
+// otherTool describes another charge limiter batt can import settings from.
+// Upstream batt needs no entry: it uses the same config file (/etc/batt.json),
+// so its settings are picked up by installing this daemon.
+type otherTool struct {
+	name string
+	// prefDomains are defaults domains that may hold the charge limit.
+	prefDomains []string
+	// limitKey is the defaults key of the charge limit in percent.
+	limitKey string
+	// helperLabels are launchd labels of the tool's privileged helper.
+	helperLabels []string
+}
+
+var otherTools = []otherTool{
+	{
+		name:         "AlDente",
+		prefDomains:  []string{"com.apphousekitchen.aldente-pro", "com.davidwernhart.AlDente"},
+		limitKey:     "chargeVal",
+		helperLabels: []string{"com.apphousekitchen.aldente-pro.helper", "com.davidwernhart.Helper"},
+	},
+}
+
+// detectedTool is an installation of another tool found on this Mac.
+type detectedTool struct {
+	tool *otherTool
+	// limit is the imported charge limit, 0 if unknown.
+	limit int
+	// helpers are the installed launchd labels of the tool's helper.
+	helpers []string
+}
+
+func helperPlistPath(label string) string {
+	return "/Library/LaunchDaemons/" + label + ".plist"
+}
+
+// detectOtherTools finds other charge limiters and reads their charge limits.
+func detectOtherTools() []detectedTool {
+	var found []detectedTool
+	for i := range otherTools {
+		t := &otherTools[i]
+		d := detectedTool{tool: t}
+		for _, domain := range t.prefDomains {
+			out, err := exec.Command("/usr/bin/defaults", "read", domain, t.limitKey).Output()
+			if err != nil {
+				continue
+			}
+			if v, err := strconv.Atoi(strings.TrimSpace(string(out))); err == nil && limits.ValidateUpper(v, 0) == nil {
+				d.limit = v
+				break
+			}
+		}
+		for _, label := range t.helperLabels {
+			if _, err := os.Stat(helperPlistPath(label)); err == nil {
+				d.helpers = append(d.helpers, label)
+			}
+		}
+		if d.limit != 0 || len(d.helpers) > 0 {
+			logrus.WithFields(logrus.Fields{
+				"tool":    t.name,
+				"limit":   d.limit,
+				"helpers": d.helpers,
+			}).Info("Found other charge limiter")
+			found = append(found, d)
+		}
+	}
+	return found
+}
+
+// disableHelpers stops and unloads the launchd helpers of another tool, so it
+// does not fight with batt over the same SMC keys. The plists are kept, so
+// the other tool can be re-enabled by reinstalling its helper.
+func disableHelpers(labels []string) error {
+	shellScript := ""
+	for _, label := range labels {
+		shellScript += fmt.Sprintf(`
+/bin/launchctl bootout system/%s || true
+/bin/launchctl disable system/%s || true
+`, label, label)
+	}
+
+	output := &bytes.Buffer{}
+	cmd := exec.Command("/usr/bin/osascript", "-e", fmt.Sprintf("do shell script \"%s\" with administrator privileges", escapeShellInAppleScript(shellScript)))
+	cmd.Stderr = output
+	cmd.Stdout = output
+	if err := cmd.Run(); err != nil {
+		return pkgerrors.Wrapf(err, "failed to disable helpers: %s", output.String())
+	}
+	return nil
+}
+
+// offerMigration runs once during onboarding. If another charge limiter is
+// found, it offers to import its limit and to disable its helper.
+func offerMigration(api *client.Client) {
+	if getBoolPref(prefMigrationChecked) {
+		return
+	}
+	setBoolPref(prefMigrationChecked, true)
+
+	for _, d := range detectOtherTools() {
+		text := fmt.Sprintf("%s is installed on this Mac.", d.tool.name)
+		if d.limit != 0 {
+			text += fmt.Sprintf(" Its charge limit is %d%%, which batt can import.", d.limit)
+		}
+		if len(d.helpers) > 0 {
+			text += fmt.Sprintf("\n\nRunning two charge limiters at the same time makes them fight over the same settings. batt can disable the %s helper for you (you will be asked for your password). You can re-enable it from %s later.", d.tool.name, d.tool.name)
+		}
+
+		alert := appkit.NewAlert()
+		alert.SetAlertStyle(appkit.AlertStyleInformational)
+		alert.SetMessageText(fmt.Sprintf("Migrate from %s?", d.tool.name))
+		alert.SetInformativeText(text)
+		alert.AddButtonWithTitle("Migrate")
+		alert.AddButtonWithTitle("Not Now")
+		if alert.RunModal() != appkit.AlertFirstButtonReturn {
+			logrus.WithField("tool", d.tool.name).Info("User declined migration")
+			continue
+		}
+
+		if d.limit != 0 {
+			if ret, err := api.SetLimit(d.limit); err != nil {
+				logrus.WithError(err).Error("Failed to set limit")
+				showAlert("Failed to import charge limit", ret+err.Error())
+			}
+		}
+		if len(d.helpers) > 0 {
+			if err := disableHelpers(d.helpers); err != nil {
+				logrus.WithError(err).Error("Failed to disable helpers")
+				showAlert(fmt.Sprintf("Failed to disable %s", d.tool.name), err.Error())
+			}
+		}
+	}
+}
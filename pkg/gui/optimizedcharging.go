@@ -0,0 +1,55 @@
+package gui
+
+import (
+	"os/exec"
+
+	"github.com/progrium/darwinkit/macos/appkit"
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/config"
+)
+
+const batterySettingsURL = "x-apple.systempreferences:com.apple.Battery-Settings.extension"
+
+// optimizedChargingModes are the choices in the Optimized Battery Charging menu.
+var optimizedChargingModes = []struct {
+	mode    config.OptimizedChargingMode
+	title   string
+	tooltip string
+}{
+	{
+		mode:    config.OptimizedChargingModeCoexist,
+		title:   "Coexist",
+		tooltip: `Keep enforcing the batt limit while macOS holds the charge. Whichever limit is lower wins. This is the default.`,
+	},
+	{
+		mode:    config.OptimizedChargingModeDefer,
+		title:   "Defer to macOS",
+		tooltip: `Let macOS manage charging while Optimized Battery Charging or the built-in limit holds the charge, and resume the batt limit afterwards.`,
+	},
+	{
+		mode:    config.OptimizedChargingModeDisable,
+		title:   "Disable (Recommended)",
+		tooltip: `Keep enforcing the batt limit and notify you when macOS holds the charge, so you can turn Optimized Battery Charging off. macOS provides no way for batt to turn it off for you.`,
+	},
+}
+
+// askOpenBatterySettings explains how to turn off Optimized Battery Charging.
+func askOpenBatterySettings() bool {
+	alert := appkit.NewAlert()
+	alert.SetAlertStyle(appkit.AlertStyleInformational)
+	alert.SetMessageText("Turn Off Optimized Battery Charging")
+	alert.SetInformativeText(`batt cannot turn off Optimized Battery Charging for you. In System Settings > Battery, click the "i" next to Battery Health and turn off Optimized Battery Charging. On macOS 26.4 or later, also turn off the built-in charge limit.
+
+batt will notify you if macOS is still holding the charge.`)
+	alert.AddButtonWithTitle("Open System Settings")
+	alert.AddButtonWithTitle("Later")
+	return alert.RunModal() == appkit.AlertFirstButtonReturn
+}
+
+func openBatterySettings() {
+	if err := exec.Command("/usr/bin/open", batterySettingsURL).Run(); err != nil {
+		logrus.WithError(err).Error("Failed to open battery settings")
+		showAlert("Failed to open System Settings", err.Error())
+	}
+}
@@ -0,0 +1,241 @@
+package gui
+
+import (
+	"fmt"
+	"runtime/cgo"
+	"sort"
+	"strings"
+	"unicode"
+	"unsafe"
+
+	"github.com/sirupsen/logrus"
+)
+
+// #include <stdint.h>
+// #include <stdlib.h>
+// // Implemented in palette.m.
+// void *batt_createPalette(uintptr_t handle);
+// void batt_paletteSetItems(void *palPtr, const char **titles, int count);
+// void batt_showPalette(void *palPtr);
+// void batt_releasePalette(void *palPtr);
+import "C"
+
+// paletteAction is an entry of the command palette.
+type paletteAction struct {
+	title string
+	run   func(c *menuController) error
+}
+
+// urlAction performs a batt:// URL, so the palette does exactly what the
+// URL scheme does.
+func urlAction(title, url string) paletteAction {
+	return paletteAction{title: title, run: func(c *menuController) error {
+		return c.handleURL(url)
+	}}
+}
+
+// paletteActions are all actions offered in the command palette.
+func paletteActions() []paletteAction {
+	var actions []paletteAction
+	for _, limit := range []int{50, 60, 70, 80, 90} {
+		actions = append(actions, urlAction(fmt.Sprintf("Set Charge Limit to %d%%", limit), fmt.Sprintf("batt://limit/%d", limit)))
+	}
+	actions = append(actions,
+		urlAction("Disable Charge Limit", "batt://limit/100"),
+		urlAction("Pause Charging", "batt://charging/pause"),
+		urlAction("Resume Charging", "batt://charging/resume"),
+		paletteAction{title: "Run from Adapter at Current Level", run: func(c *menuController) error {
+			_, err := c.api.SetBypass(true)
+			return err
+		}},
+		paletteAction{title: "Stop Running from Adapter", run: func(c *menuController) error {
+			_, err := c.api.SetBypass(false)
+			return err
+		}},
+		paletteAction{title: "Travel Mode Until Turned Off", run: func(c *menuController) error {
+			_, err := c.api.SetTravelMode(true, 0)
+			return err
+		}},
+		paletteAction{title: "Turn Off Travel Mode", run: func(c *menuController) error {
+			_, err := c.api.SetTravelMode(false, 0)
+			return err
+		}},
+		paletteAction{title: "Turn On Storage Mode", run: func(c *menuController) error {
+			_, err := c.api.SetStorageMode(true)
+			return err
+		}},
+		paletteAction{title: "Turn Off Storage Mode", run: func(c *menuController) error {
+			_, err := c.api.SetStorageMode(false)
+			return err
+		}},
+		urlAction("Start Auto Calibration", "batt://calibrate"),
+		urlAction("Show Status Window", "batt://status"),
+		paletteAction{title: "Show Debug Window", run: func(c *menuController) error {
+			c.showDebugWindow()
+			return nil
+		}},
+		paletteAction{title: "Show Usage Statistics", run: func(c *menuController) error {
+			c.showUsageReport()
+			return nil
+		}},
+		urlAction("Show Last Month's Battery Report", "batt://report"),
+		paletteAction{title: "Compare Battery Health", run: func(c *menuController) error {
+			c.showHealthComparison()
+			return nil
+		}},
+		paletteAction{title: "Show Compatibility Report", run: func(c *menuController) error {
+			c.showCapabilities()
+			return nil
+		}},
+		urlAction("Open Preferences", "batt://prefs"),
+		urlAction("Check for Updates", "batt://update/check"),
+		urlAction("Show Menubar Icon", "batt://menubar-icon/show"),
+		urlAction("Hide Menubar Icon", "batt://menubar-icon/hide"),
+		urlAction("Use Compact Menubar Icon", "batt://menubar-icon/compact"),
+		urlAction("Use Regular Menubar Icon", "batt://menubar-icon/regular"),
+	)
+	return actions
+}
+
+// fuzzyScore tells if the letters of query appear in title in order, and
+// how well: consecutive letters and letters starting a word score higher.
+func fuzzyScore(query, title string) (int, bool) {
+	q := []rune(strings.ToLower(strings.Join(strings.Fields(query), "")))
+	t := []rune(strings.ToLower(title))
+	score, qi, last := 0, 0, -2
+	for ti := 0; ti < len(t) && qi < len(q); ti++ {
+		if t[ti] != q[qi] {
+			continue
+		}
+		score++
+		if ti == last+1 {
+			score += 5
+		}
+		if ti == 0 || !unicode.IsLetter(t[ti-1]) && !unicode.IsDigit(t[ti-1]) {
+			score += 3
+		}
+		last = ti
+		qi++
+	}
+	return score, qi == len(q)
+}
+
+// filterPaletteActions returns the actions matching query, best first.
+func filterPaletteActions(actions []paletteAction, query string) []paletteAction {
+	type match struct {
+		action paletteAction
+		score  int
+	}
+	var matches []match
+	for _, a := range actions {
+		if score, ok := fuzzyScore(query, a.title); ok {
+			matches = append(matches, match{a, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	ret := make([]paletteAction, len(matches))
+	for i, m := range matches {
+		ret[i] = m.action
+	}
+	return ret
+}
+
+// commandPalette is a small panel listing all batt actions with fuzzy
+// search, for users who prefer the keyboard over nested menus. The panel
+// lives in palette.m; Go filters the actions and runs the chosen one.
+type commandPalette struct {
+	ctrl    *menuController
+	ptr     unsafe.Pointer
+	handle  cgo.Handle
+	actions []paletteAction
+	// shown are the actions currently listed, matching the query.
+	shown []paletteAction
+}
+
+func newCommandPalette(ctrl *menuController) *commandPalette {
+	p := &commandPalette{ctrl: ctrl, actions: paletteActions()}
+	p.handle = cgo.NewHandle(p)
+	p.ptr = C.batt_createPalette(C.uintptr_t(p.handle))
+	return p
+}
+
+// showCommandPalette creates the command palette if needed and shows it.
+func (c *menuController) showCommandPalette() {
+	if c.palette == nil {
+		c.palette = newCommandPalette(c)
+		c.resources.track("command palette", c.palette.release)
+	}
+	c.palette.setQuery("")
+	C.batt_showPalette(c.palette.ptr)
+}
+
+// setQuery lists the actions matching query.
+func (p *commandPalette) setQuery(query string) {
+	p.shown = filterPaletteActions(p.actions, query)
+
+	titles := make([]*C.char, len(p.shown))
+	for i, a := range p.shown {
+		titles[i] = C.CString(a.title)
+	}
+	defer func() {
+		for _, t := range titles {
+			C.free(unsafe.Pointer(t))
+		}
+	}()
+
+	var ptr **C.char
+	if len(titles) > 0 {
+		ptr = &titles[0]
+	}
+	C.batt_paletteSetItems(p.ptr, ptr, C.int(len(titles)))
+}
+
+// run performs the listed action at index. The panel is already closed.
+func (p *commandPalette) run(index int) {
+	if index < 0 || index >= len(p.shown) {
+		return
+	}
+	a := p.shown[index]
+	logrus.WithField("action", a.title).Info("Running action from command palette")
+	if err := a.run(p.ctrl); err != nil {
+		logrus.WithError(err).WithField("action", a.title).Error("Failed to run action from command palette")
+		showAlert("Failed to "+strings.ToLower(a.title[:1])+a.title[1:], err.Error())
+	}
+}
+
+func (p *commandPalette) release() {
+	C.batt_releasePalette(p.ptr)
+	p.ptr = nil
+	p.handle.Delete()
+}
+
+//export battPaletteQueryChanged
+func battPaletteQueryChanged(h C.uintptr_t, query *C.char) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("panic in battPaletteQueryChanged: %v", r)
+		}
+	}()
+	handle := cgo.Handle(h)
+	if v := handle.Value(); v != nil {
+		if p, ok := v.(*commandPalette); ok {
+			p.setQuery(C.GoString(query))
+		}
+	}
+}
+
+//export battPaletteRun
+func battPaletteRun(h C.uintptr_t, index C.int) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("panic in battPaletteRun: %v", r)
+		}
+	}()
+	handle := cgo.Handle(h)
+	if v := handle.Value(); v != nil {
+		if p, ok := v.(*commandPalette); ok {
+			p.run(int(index))
+		}
+	}
+}
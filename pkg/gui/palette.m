@@ -0,0 +1,205 @@
+#import <Cocoa/Cocoa.h>
+#include <stdint.h>
+
+// Callbacks exported from Go
+extern void battPaletteQueryChanged(uintptr_t handle, const char *query);
+extern void battPaletteRun(uintptr_t handle, int index);
+
+// BattPalettePanel can become key without a title bar, so the search field
+// gets keyboard input.
+@interface BattPalettePanel : NSPanel
+@end
+
+@implementation BattPalettePanel
+- (BOOL)canBecomeKeyWindow {
+    return YES;
+}
+@end
+
+@interface BattPaletteController : NSObject <NSWindowDelegate, NSTableViewDataSource, NSTableViewDelegate, NSSearchFieldDelegate>
+@property(nonatomic, assign) uintptr_t handle;
+@property(nonatomic, strong) BattPalettePanel *panel;
+@property(nonatomic, strong) NSSearchField *searchField;
+@property(nonatomic, strong) NSTableView *tableView;
+@property(nonatomic, strong) NSArray<NSString *> *items;
+- (instancetype)initWithHandle:(uintptr_t)handle;
+@end
+
+@implementation BattPaletteController
+- (instancetype)initWithHandle:(uintptr_t)handle {
+    if ((self = [super init])) {
+        _handle = handle;
+        _items = @[];
+        [self buildPanel];
+    }
+    return self;
+}
+
+- (void)buildPanel {
+    NSRect frame = NSMakeRect(0, 0, 480, 320);
+    self.panel = [[BattPalettePanel alloc] initWithContentRect:frame
+                                                     styleMask:NSWindowStyleMaskBorderless | NSWindowStyleMaskNonactivatingPanel
+                                                       backing:NSBackingStoreBuffered
+                                                         defer:NO];
+    self.panel.identifier = @"batt.palette";
+    self.panel.level = NSFloatingWindowLevel;
+    self.panel.releasedWhenClosed = NO;
+    self.panel.hidesOnDeactivate = YES;
+    self.panel.delegate = self;
+    self.panel.hasShadow = YES;
+    self.panel.backgroundColor = [NSColor clearColor];
+    self.panel.opaque = NO;
+
+    NSVisualEffectView *background = [[NSVisualEffectView alloc] initWithFrame:frame];
+    background.material = NSVisualEffectMaterialMenu;
+    background.state = NSVisualEffectStateActive;
+    background.wantsLayer = YES;
+    background.layer.cornerRadius = 10;
+    background.layer.masksToBounds = YES;
+    self.panel.contentView = background;
+
+    self.searchField = [[NSSearchField alloc] initWithFrame:NSMakeRect(12, frame.size.height - 40, frame.size.width - 24, 28)];
+    self.searchField.placeholderString = @"Search batt actions";
+    self.searchField.delegate = self;
+    self.searchField.identifier = @"batt.palette.search";
+    self.searchField.autoresizingMask = NSViewWidthSizable | NSViewMinYMargin;
+    [background addSubview:self.searchField];
+
+    NSScrollView *scroll = [[NSScrollView alloc] initWithFrame:NSMakeRect(8, 8, frame.size.width - 16, frame.size.height - 56)];
+    scroll.hasVerticalScroller = YES;
+    scroll.drawsBackground = NO;
+    scroll.autoresizingMask = NSViewWidthSizable | NSViewHeightSizable;
+
+    self.tableView = [[NSTableView alloc] initWithFrame:scroll.bounds];
+    NSTableColumn *column = [[NSTableColumn alloc] initWithIdentifier:@"title"];
+    column.resizingMask = NSTableColumnAutoresizingMask;
+    [self.tableView addTableColumn:column];
+    self.tableView.headerView = nil;
+    self.tableView.backgroundColor = [NSColor clearColor];
+    self.tableView.rowHeight = 24;
+    self.tableView.columnAutoresizingStyle = NSTableViewUniformColumnAutoresizingStyle;
+    self.tableView.dataSource = self;
+    self.tableView.delegate = self;
+    self.tableView.target = self;
+    self.tableView.doubleAction = @selector(runSelected:);
+    self.tableView.identifier = @"batt.palette.actions";
+    scroll.documentView = self.tableView;
+    [background addSubview:scroll];
+}
+
+- (void)setItems:(NSArray<NSString *> *)items {
+    _items = items;
+    [self.tableView reloadData];
+    if (items.count > 0) {
+        [self.tableView selectRowIndexes:[NSIndexSet indexSetWithIndex:0] byExtendingSelection:NO];
+        [self.tableView scrollRowToVisible:0];
+    }
+}
+
+- (void)show {
+    self.searchField.stringValue = @"";
+    [self.panel center];
+    [NSApp activateIgnoringOtherApps:YES];
+    [self.panel makeKeyAndOrderFront:nil];
+    [self.panel makeFirstResponder:self.searchField];
+}
+
+- (void)runSelected:(id)sender {
+    NSInteger row = self.tableView.selectedRow;
+    if (row < 0) return;
+    // Close first, so alerts and windows opened by the action are not
+    // hidden behind the panel.
+    [self.panel orderOut:nil];
+    battPaletteRun(_handle, (int)row);
+}
+
+- (void)moveSelectionBy:(NSInteger)delta {
+    NSInteger count = (NSInteger)self.items.count;
+    if (count == 0) return;
+    NSInteger row = MAX(0, MIN(count - 1, self.tableView.selectedRow + delta));
+    [self.tableView selectRowIndexes:[NSIndexSet indexSetWithIndex:row] byExtendingSelection:NO];
+    [self.tableView scrollRowToVisible:row];
+}
+
+// NSSearchFieldDelegate
+
+- (void)controlTextDidChange:(NSNotification *)note {
+    battPaletteQueryChanged(_handle, self.searchField.stringValue.UTF8String);
+}
+
+- (BOOL)control:(NSControl *)control textView:(NSTextView *)textView doCommandBySelector:(SEL)selector {
+    if (selector == @selector(moveUp:)) {
+        [self moveSelectionBy:-1];
+        return YES;
+    }
+    if (selector == @selector(moveDown:)) {
+        [self moveSelectionBy:1];
+        return YES;
+    }
+    if (selector == @selector(insertNewline:)) {
+        [self runSelected:nil];
+        return YES;
+    }
+    if (selector == @selector(cancelOperation:)) {
+        [self.panel orderOut:nil];
+        return YES;
+    }
+    return NO;
+}
+
+// NSTableViewDataSource and NSTableViewDelegate
+
+- (NSInteger)numberOfRowsInTableView:(NSTableView *)tableView {
+    return (NSInteger)self.items.count;
+}
+
+- (NSView *)tableView:(NSTableView *)tableView viewForTableColumn:(NSTableColumn *)column row:(NSInteger)row {
+    NSTextField *label = [tableView makeViewWithIdentifier:@"batt.palette.row" owner:self];
+    if (label == nil) {
+        label = [NSTextField labelWithString:@""];
+        label.identifier = @"batt.palette.row";
+        label.font = [NSFont systemFontOfSize:14];
+        label.lineBreakMode = NSLineBreakByTruncatingTail;
+    }
+    label.stringValue = self.items[row];
+    return label;
+}
+
+// NSWindowDelegate
+
+- (void)windowDidResignKey:(NSNotification *)note {
+    [self.panel orderOut:nil];
+}
+@end
+
+void *batt_createPalette(uintptr_t handle) {
+    BattPaletteController *ctrl = [[BattPaletteController alloc] initWithHandle:handle];
+    return (void *)CFBridgingRetain(ctrl);
+}
+
+void batt_paletteSetItems(void *palPtr, const char **titles, int count) {
+    if (palPtr == NULL) return;
+    BattPaletteController *ctrl = (__bridge BattPaletteController *)palPtr;
+    NSMutableArray<NSString *> *items = [NSMutableArray arrayWithCapacity:count];
+    for (int i = 0; i < count; i++) {
+        [items addObject:[NSString stringWithUTF8String:titles[i]]];
+    }
+    [ctrl setItems:items];
+}
+
+void batt_showPalette(void *palPtr) {
+    if (palPtr == NULL) return;
+    BattPaletteController *ctrl = (__bridge BattPaletteController *)palPtr;
+    [ctrl show];
+}
+
+void batt_releasePalette(void *palPtr) {
+    if (palPtr == NULL) return;
+    BattPaletteController *ctrl = (__bridge BattPaletteController *)palPtr;
+    ctrl.panel.delegate = nil;
+    ctrl.tableView.dataSource = nil;
+    ctrl.tableView.delegate = nil;
+    ctrl.searchField.delegate = nil;
+    [ctrl.panel close];
+    CFRelease(palPtr);
+}
@@ -0,0 +1,48 @@
+package gui
+
+import "github.com/progrium/darwinkit/macos/appkit"
+
+// chargingPausedBadge is shown next to the menubar icon while charging is
+// paused.
+const chargingPausedBadge = "⏸︎"
+
+// renderChargingPaused updates the Pause/Resume Charging item and the
+// menubar badge.
+func (c *menuController) renderChargingPaused(paused bool) {
+	c.chargingPaused = paused
+	if paused {
+		setItemTitle(c.pauseChargingItem, "Resume Charging")
+	} else {
+		setItemTitle(c.pauseChargingItem, "Pause Charging")
+	}
+	c.pausedBadge = paused
+	c.renderBadges()
+}
+
+// toggleChargingPaused pauses or resumes charging, whichever is the opposite
+// of the current state.
+func (c *menuController) toggleChargingPaused() {
+	paused := !c.chargingPaused
+	if _, err := c.api.SetChargingPaused(paused); err != nil {
+		if paused {
+			showAlert("Failed to pause charging", err.Error())
+		} else {
+			showAlert("Failed to resume charging", err.Error())
+		}
+		return
+	}
+	c.renderChargingPaused(paused)
+}
+
+// confirmPauseCharging asks before a batt:// URL pauses charging. Links can
+// be clicked in a browser, and a pause lasts until charging is resumed.
+func confirmPauseCharging() bool {
+	alert := appkit.NewAlert()
+	alert.SetIcon(appkit.Image_ImageWithSystemSymbolNameAccessibilityDescription("pause.circle", "pause charging"))
+	alert.SetAlertStyle(appkit.AlertStyleWarning)
+	alert.SetMessageText("Pause Charging?")
+	alert.SetInformativeText("A link asked batt to pause charging. Your Mac will not charge, even when plugged in, until you choose Resume Charging in the batt menu.")
+	alert.AddButtonWithTitle("Pause")
+	alert.AddButtonWithTitle("Cancel")
+	return alert.RunModal() == appkit.AlertFirstButtonReturn
+}
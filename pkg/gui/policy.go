@@ -0,0 +1,45 @@
+package gui
+
+import (
+	"fmt"
+
+	"github.com/charlie0129/batt/pkg/config"
+)
+
+// policyModeTitles are the short names of the policy modes.
+var policyModeTitles = map[config.PolicyMode]string{
+	config.PolicyModeLimit:       "Charge Limit",
+	config.PolicyModeDisabled:    "No Limit",
+	config.PolicyModeTravel:      "Travel Mode",
+	config.PolicyModeStorage:     "Storage Mode",
+	config.PolicyModeChargeBy:    "Full Charge By",
+	config.PolicyModeWeakAdapter: "Weak Adapter",
+	config.PolicyModePaused:      "Paused",
+	config.PolicyModeTooHot:      "Too Hot to Charge",
+	config.PolicyModeBypass:      "Run from Adapter",
+	config.PolicyModeCalibration: "Auto Calibration",
+}
+
+// renderPolicy updates the read-only Current Policy menu, so users can tell
+// why charging stopped where it did.
+func (c *menuController) renderPolicy(p *config.Policy) {
+	title, ok := policyModeTitles[p.Mode]
+	if !ok {
+		title = string(p.Mode)
+	}
+	setItemTitle(c.policySubMenuItem, "Current Policy: "+title)
+	setItemTitle(c.policyDetailItem, p.Detail)
+	setItemTitle(c.policyLimitsItem, fmt.Sprintf("Limit: %d%%, resume charging below %d%%", p.Upper, p.Lower))
+
+	setBy := "Set by: unknown"
+	switch {
+	case p.Managed:
+		setBy = "Set by: your organization (configuration profile)"
+	case p.SetBy != "":
+		setBy = "Set by: " + p.SetBy
+		if !p.SetAt.IsZero() {
+			setBy += " on " + p.SetAt.Local().Format("Jan _2 15:04")
+		}
+	}
+	setItemTitle(c.policySetByItem, setBy)
+}
@@ -0,0 +1,218 @@
+package gui
+
+import (
+	"fmt"
+	"os"
+	"runtime/cgo"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/progrium/darwinkit/macos/appkit"
+	"github.com/sirupsen/logrus"
+)
+
+// #include <stdint.h>
+// #include <stdbool.h>
+// #include <stdlib.h>
+// // Implemented in prefs.m.
+// bool batt_getBoolPref(const char *key);
+// void batt_setBoolPref(const char *key, bool value);
+// char *batt_getStringPref(const char *key);
+// void batt_setStringPref(const char *key, const char *value);
+// void batt_postPrefsChanged(void);
+// void *batt_attachAppObserver(uintptr_t handle);
+// void batt_releaseAppObserver(void *obsPtr);
+import "C"
+
+// GUI preference keys. Unlike the daemon config, these are per-user and only
+// affect the menubar app.
+const (
+	prefHideMenubarIcon    = "HideMenubarIcon"
+	prefCompactMenubarIcon = "CompactMenubarIcon"
+	// prefSystemCharge shows the charge as the macOS battery indicator does,
+	// instead of the raw SMC value the daemon limits on.
+	prefSystemCharge = "SystemMatchedCharge"
+	// prefLastRunVersion is the version of batt.app that ran last, to tell
+	// when it has been updated.
+	prefLastRunVersion = "LastRunVersion"
+	// prefStorageRemindedAt is when the user was last reminded to check on
+	// a Mac in storage mode.
+	prefStorageRemindedAt = "StorageRemindedAt"
+)
+
+// squareStatusItemLength is NSSquareStatusItemLength, which darwinkit does not export.
+const squareStatusItemLength float64 = -2
+
+// prefEnvPrefix is the prefix of environment variables that override a
+// preference for one run, e.g. BATT_PREF_HideMenubarIcon=true.
+const prefEnvPrefix = "BATT_PREF_"
+
+var (
+	prefOverridesMu sync.Mutex
+	// prefOverrides are preferences overridden for this run by flags or
+	// environment variables. They are never saved, and changing the
+	// preference in the menu drops its override.
+	prefOverrides = map[string]string{}
+)
+
+// overridePrefs overrides preferences for this run from "Key=value" pairs,
+// e.g. "CompactMenubarIcon=true". Keys are the ones shown by
+// `defaults read cc.chlc.batt`.
+func overridePrefs(pairs []string) error {
+	prefOverridesMu.Lock()
+	defer prefOverridesMu.Unlock()
+	for _, p := range pairs {
+		key, value, ok := strings.Cut(p, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("invalid preference override %q, use Key=value", p)
+		}
+		prefOverrides[key] = value
+	}
+	return nil
+}
+
+// overridePrefsFromEnv reads overrides from BATT_PREF_* environment
+// variables. Flags are applied after, so they win.
+func overridePrefsFromEnv() {
+	var pairs []string
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, prefEnvPrefix) {
+			pairs = append(pairs, strings.TrimPrefix(kv, prefEnvPrefix))
+		}
+	}
+	if err := overridePrefs(pairs); err != nil {
+		logrus.WithError(err).Warn("Ignoring preference overrides from environment")
+	}
+}
+
+// prefOverride returns the override of key, if any.
+func prefOverride(key string) (string, bool) {
+	prefOverridesMu.Lock()
+	defer prefOverridesMu.Unlock()
+	v, ok := prefOverrides[key]
+	return v, ok
+}
+
+func dropPrefOverride(key string) {
+	prefOverridesMu.Lock()
+	defer prefOverridesMu.Unlock()
+	delete(prefOverrides, key)
+}
+
+func getBoolPref(key string) bool {
+	if v, ok := prefOverride(key); ok {
+		b, err := strconv.ParseBool(v)
+		if err == nil {
+			return b
+		}
+		logrus.WithField("key", key).WithError(err).Warn("Ignoring invalid preference override")
+	}
+	ckey := C.CString(key)
+	defer C.free(unsafe.Pointer(ckey))
+	return bool(C.batt_getBoolPref(ckey))
+}
+
+func setBoolPref(key string, value bool) {
+	dropPrefOverride(key)
+	ckey := C.CString(key)
+	defer C.free(unsafe.Pointer(ckey))
+	C.batt_setBoolPref(ckey, C.bool(value))
+}
+
+func getStringPref(key string) string {
+	if v, ok := prefOverride(key); ok {
+		return v
+	}
+	ckey := C.CString(key)
+	defer C.free(unsafe.Pointer(ckey))
+	cval := C.batt_getStringPref(ckey)
+	if cval == nil {
+		return ""
+	}
+	defer C.free(unsafe.Pointer(cval))
+	return C.GoString(cval)
+}
+
+// setStringPref sets a string preference. An empty value removes the key.
+func setStringPref(key, value string) {
+	dropPrefOverride(key)
+	ckey := C.CString(key)
+	defer C.free(unsafe.Pointer(ckey))
+	if value == "" {
+		C.batt_setStringPref(ckey, nil)
+		return
+	}
+	cval := C.CString(value)
+	defer C.free(unsafe.Pointer(cval))
+	C.batt_setStringPref(ckey, cval)
+}
+
+// notifyPrefsChanged tells a running menubar app to re-read its preferences.
+func notifyPrefsChanged() {
+	C.batt_postPrefsChanged()
+}
+
+// attachAppObserver listens for app reopen events and preference changes
+// made by the CLI. Call releaseAppObserver to free.
+func attachAppObserver(h cgo.Handle) unsafe.Pointer {
+	return C.batt_attachAppObserver(C.uintptr_t(h))
+}
+
+func releaseAppObserver(ptr unsafe.Pointer) {
+	C.batt_releaseAppObserver(ptr)
+}
+
+// applyMenubarPrefs applies icon visibility and size preferences to the status item.
+// The charge display preference is picked up on the next menu update.
+func (c *menuController) applyMenubarPrefs() {
+	hidden := getBoolPref(prefHideMenubarIcon)
+	compact := getBoolPref(prefCompactMenubarIcon)
+	logrus.WithFields(logrus.Fields{
+		"hidden":  hidden,
+		"compact": compact,
+	}).Debug("Applying menubar icon preferences")
+
+	c.menubarIcon.SetVisible(!hidden)
+	if compact {
+		c.menubarIcon.SetLength(squareStatusItemLength)
+	} else {
+		c.menubarIcon.SetLength(appkit.VariableStatusItemLength)
+	}
+	setCheckboxItem(c.compactIconItem, compact)
+	setCheckboxItem(c.systemChargeItem, getBoolPref(prefSystemCharge))
+}
+
+//export battApplyPrefs
+func battApplyPrefs(h C.uintptr_t) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("panic in battApplyPrefs: %v", r)
+		}
+	}()
+	handle := cgo.Handle(h)
+	if v := handle.Value(); v != nil {
+		if c, ok := v.(*menuController); ok {
+			c.applyMenubarPrefs()
+			c.registerHotKeys()
+		}
+	}
+}
+
+//export battAppReopened
+func battAppReopened(h C.uintptr_t) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("panic in battAppReopened: %v", r)
+		}
+	}()
+	handle := cgo.Handle(h)
+	if v := handle.Value(); v != nil {
+		if c, ok := v.(*menuController); ok {
+			logrus.Info("App reopened, showing menubar icon")
+			setBoolPref(prefHideMenubarIcon, false)
+			c.applyMenubarPrefs()
+		}
+	}
+}
@@ -0,0 +1,146 @@
+#import <Cocoa/Cocoa.h>
+#import <CoreServices/CoreServices.h>
+#include <stdint.h>
+#include <stdbool.h>
+#include <string.h>
+
+// GUI preferences are stored with CFPreferences under the app bundle ID, so
+// both the .app and the batt CLI (which has no bundle) use the same domain.
+static CFStringRef const kBattPrefsDomain = CFSTR("cc.chlc.batt");
+// Posted by the CLI after it changes GUI preferences.
+static NSString *const kBattPrefsChangedNotification = @"cc.chlc.batt.prefsChanged";
+
+// Callbacks exported from Go
+extern void battApplyPrefs(uintptr_t handle);
+extern void battAppReopened(uintptr_t handle);
+extern void battAppWillTerminate(uintptr_t handle);
+extern void battAppearanceChanged(uintptr_t handle, bool dark, bool highContrast);
+
+bool batt_getBoolPref(const char *key) {
+    CFStringRef cfKey = CFStringCreateWithCString(NULL, key, kCFStringEncodingUTF8);
+    CFPreferencesAppSynchronize(kBattPrefsDomain);
+    Boolean valid = false;
+    Boolean value = CFPreferencesGetAppBooleanValue(cfKey, kBattPrefsDomain, &valid);
+    CFRelease(cfKey);
+    return valid && value;
+}
+
+void batt_setBoolPref(const char *key, bool value) {
+    CFStringRef cfKey = CFStringCreateWithCString(NULL, key, kCFStringEncodingUTF8);
+    CFPreferencesSetAppValue(cfKey, value ? kCFBooleanTrue : kCFBooleanFalse, kBattPrefsDomain);
+    CFPreferencesAppSynchronize(kBattPrefsDomain);
+    CFRelease(cfKey);
+}
+
+// Returns a malloc'd copy of the string value, or NULL if unset. Caller frees.
+char *batt_getStringPref(const char *key) {
+    CFStringRef cfKey = CFStringCreateWithCString(NULL, key, kCFStringEncodingUTF8);
+    CFPreferencesAppSynchronize(kBattPrefsDomain);
+    CFPropertyListRef value = CFPreferencesCopyAppValue(cfKey, kBattPrefsDomain);
+    CFRelease(cfKey);
+    if (value == NULL) return NULL;
+    char *out = NULL;
+    if (CFGetTypeID(value) == CFStringGetTypeID()) {
+        out = strdup([(__bridge NSString *)value UTF8String]);
+    }
+    CFRelease(value);
+    return out;
+}
+
+// Sets the string value, or removes the key if value is NULL.
+void batt_setStringPref(const char *key, const char *value) {
+    CFStringRef cfKey = CFStringCreateWithCString(NULL, key, kCFStringEncodingUTF8);
+    CFStringRef cfValue = value ? CFStringCreateWithCString(NULL, value, kCFStringEncodingUTF8) : NULL;
+    CFPreferencesSetAppValue(cfKey, cfValue, kBattPrefsDomain);
+    CFPreferencesAppSynchronize(kBattPrefsDomain);
+    if (cfValue) CFRelease(cfValue);
+    CFRelease(cfKey);
+}
+
+void batt_postPrefsChanged(void) {
+    [[NSDistributedNotificationCenter defaultCenter] postNotificationName:kBattPrefsChangedNotification
+                                                                   object:nil
+                                                                 userInfo:nil
+                                                       deliverImmediately:YES];
+}
+
+@interface BattAppObserver : NSObject
+@property(nonatomic, assign) uintptr_t handle;
+- (instancetype)initWithHandle:(uintptr_t)handle;
+@end
+
+@implementation BattAppObserver
+- (instancetype)initWithHandle:(uintptr_t)handle {
+    if ((self = [super init])) {
+        _handle = handle;
+    }
+    return self;
+}
+// Sent when the user launches the app again while it is already running
+// (e.g. from Finder or Spotlight), which is how a hidden icon is brought back.
+- (void)handleReopen:(NSAppleEventDescriptor *)event withReplyEvent:(NSAppleEventDescriptor *)reply {
+    battAppReopened(_handle);
+}
+- (void)prefsChanged:(NSNotification *)note {
+    battApplyPrefs(_handle);
+}
+// Sent on every termination, including logout and shutdown, not only when
+// the user quits from the menu.
+- (void)willTerminate:(NSNotification *)note {
+    // Shutting down releases this observer, keep it alive until we return.
+    BattAppObserver *keepAlive = self;
+    battAppWillTerminate(keepAlive.handle);
+}
+- (void)notifyAppearance {
+    NSAppearanceName name = [NSApp.effectiveAppearance
+        bestMatchFromAppearancesWithNames:@[ NSAppearanceNameAqua, NSAppearanceNameDarkAqua ]];
+    bool dark = [name isEqualToString:NSAppearanceNameDarkAqua];
+    bool highContrast = [[NSWorkspace sharedWorkspace] accessibilityDisplayShouldIncreaseContrast];
+    battAppearanceChanged(_handle, dark, highContrast);
+}
+- (void)observeValueForKeyPath:(NSString *)keyPath
+                      ofObject:(id)object
+                        change:(NSDictionary *)change
+                       context:(void *)context {
+    [self notifyAppearance];
+}
+- (void)accessibilityDisplayOptionsChanged:(NSNotification *)note {
+    [self notifyAppearance];
+}
+@end
+
+void *batt_attachAppObserver(uintptr_t handle) {
+    BattAppObserver *obs = [[BattAppObserver alloc] initWithHandle:handle];
+    [[NSAppleEventManager sharedAppleEventManager] setEventHandler:obs
+                                                       andSelector:@selector(handleReopen:withReplyEvent:)
+                                                     forEventClass:kCoreEventClass
+                                                        andEventID:kAEReopenApplication];
+    [[NSDistributedNotificationCenter defaultCenter] addObserver:obs
+                                                        selector:@selector(prefsChanged:)
+                                                            name:kBattPrefsChangedNotification
+                                                          object:nil];
+    [[NSNotificationCenter defaultCenter] addObserver:obs
+                                             selector:@selector(willTerminate:)
+                                                 name:NSApplicationWillTerminateNotification
+                                               object:nil];
+    // Dark/light mode switches change effectiveAppearance; increased contrast
+    // is only reported through the workspace notification.
+    [NSApp addObserver:obs forKeyPath:@"effectiveAppearance" options:NSKeyValueObservingOptionInitial context:NULL];
+    [[[NSWorkspace sharedWorkspace] notificationCenter] addObserver:obs
+                                                           selector:@selector(accessibilityDisplayOptionsChanged:)
+                                                               name:NSWorkspaceAccessibilityDisplayOptionsDidChangeNotification
+                                                             object:nil];
+    return (void *)CFBridgingRetain(obs);
+}
+
+void batt_releaseAppObserver(void *obsPtr) {
+    if (obsPtr == NULL) return;
+    BattAppObserver *obs = (__bridge BattAppObserver *)obsPtr;
+    [[NSAppleEventManager sharedAppleEventManager] removeEventHandlerForEventClass:kCoreEventClass
+                                                                        andEventID:kAEReopenApplication];
+    [[NSDistributedNotificationCenter defaultCenter] removeObserver:obs];
+    [[NSNotificationCenter defaultCenter] removeObserver:obs];
+    [NSApp removeObserver:obs forKeyPath:@"effectiveAppearance"];
+    [[[NSWorkspace sharedWorkspace] notificationCenter] removeObserver:obs];
+    CFRelease(obsPtr);
+}
@@ -0,0 +1,104 @@
+package gui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/history"
+)
+
+// prefReportNotifiedMonth is the last month whose report was notified, in
+// history.MonthLayout.
+const prefReportNotifiedMonth = "ReportNotifiedMonth"
+
+// lastMonth returns the month before now, in history.MonthLayout.
+func lastMonth(now time.Time) string {
+	return time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.Local).Format(history.MonthLayout)
+}
+
+// monthTitle formats a month in history.MonthLayout for display.
+func monthTitle(month string) string {
+	m, err := time.ParseInLocation(history.MonthLayout, month, time.Local)
+	if err != nil {
+		return month
+	}
+	return m.Format("January 2006")
+}
+
+// notifyMonthlyReport sums up last month in a notification once the month
+// is over, like the Screen Time summary. Clicking it shows the full report.
+func (c *menuController) notifyMonthlyReport(now time.Time) {
+	month := lastMonth(now)
+	// Months sort as strings.
+	if getStringPref(prefReportNotifiedMonth) >= month {
+		return
+	}
+	r, err := c.api.GetMonthlyReport(month)
+	if err != nil {
+		logrus.WithError(err).Debug("Failed to get monthly report")
+		return
+	}
+	setStringPref(prefReportNotifiedMonth, month)
+	if r.Days == 0 {
+		return
+	}
+
+	summary := fmt.Sprintf("%d charge cycles added, %.0f%% average charge, %.0f h held at the limit.", r.CyclesAdded, r.AverageCharge, r.HeldHours)
+	if r.HealthEnd > 0 && r.HealthEnd != r.HealthStart {
+		summary += fmt.Sprintf(" Battery health %d%% → %d%%.", r.HealthStart, r.HealthEnd)
+	}
+	showNotificationWithURL("Your "+monthTitle(month)+" Battery Report", summary+" Click for details.", "batt://report/"+month)
+}
+
+// showMonthlyReport shows the report of month, or of last month if empty.
+func (c *menuController) showMonthlyReport(month string) {
+	r, err := c.api.GetMonthlyReport(month)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get monthly report")
+		showAlert("Failed to get monthly report", err.Error())
+		return
+	}
+
+	title := monthTitle(r.Month) + " Battery Report"
+	if r.Days == 0 {
+		showAlert(title, "No usage recorded in "+monthTitle(r.Month)+".")
+		return
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Data on %d days.\n\n", r.Days)
+	fmt.Fprintf(&sb, "Charge cycles added: %d\n", r.CyclesAdded)
+	if r.HealthEnd > 0 {
+		fmt.Fprintf(&sb, "Battery health: %d%% → %d%% (%+d%%)\n", r.HealthStart, r.HealthEnd, r.HealthEnd-r.HealthStart)
+	}
+	fmt.Fprintf(&sb, "Average charge: %.0f%%\n", r.AverageCharge)
+	fmt.Fprintf(&sb, "Held at the limit: %.1f h\n", r.HeldHours)
+	fmt.Fprintf(&sb, "On battery: %.1f h\n", r.OnBatteryHours)
+	fmt.Fprintf(&sb, "Above %d%%: %.1f h\n", history.HighCharge, r.HighChargeHours)
+	sb.WriteString("\nThis report is kept on this Mac only.")
+	showAlert(title, sb.String())
+}
+
+// showHealthComparison compares the battery health with the baseline of this
+// Mac model.
+func (c *menuController) showHealthComparison() {
+	r, err := c.api.GetHealthComparison()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get health comparison")
+		showAlert("Failed to compare battery health", err.Error())
+		return
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Battery health: %d%% at %d cycles\n", r.Health, r.CycleCount)
+	fmt.Fprintf(&sb, "Expected: %.0f%% (%+.0f%%)\n", r.Expected, r.Difference)
+	if len(r.Curve) > 1 {
+		first, last := r.Curve[0], r.Curve[len(r.Curve)-1]
+		fmt.Fprintf(&sb, "Recorded: %d%% at %d cycles → %d%% at %d cycles\n", first.Health, first.Cycles, last.Health, last.Cycles)
+	}
+	sb.WriteString("\n" + history.Caveats)
+	showAlert(r.Summary(), sb.String())
+}
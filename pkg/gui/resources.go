@@ -0,0 +1,122 @@
+package gui
+
+import (
+	"runtime/cgo"
+	"slices"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// cgoResources owns the cgo handles and Objective-C objects handed out to
+// the Objective-C side, so that they are released together, in reverse order
+// of acquisition, when the menubar controller is torn down. This way nothing
+// relies on remembering to pair every attach with a release.
+//
+// Build with -tags debug to enable leak detection, see resources_debug.go.
+type cgoResources struct {
+	mu   sync.Mutex
+	live []*cgoResource
+	// tornDown is set by releaseAll. Resources acquired after that are
+	// released right away.
+	tornDown bool
+}
+
+type cgoResource struct {
+	name    string
+	release func()
+	// site is where the resource was acquired. Only recorded with leak
+	// detection enabled.
+	site     string
+	released bool
+}
+
+// track registers release to be called on teardown. The returned func
+// releases the resource early; calling it more than once is harmless.
+func (r *cgoResources) track(name string, release func()) func() {
+	res := &cgoResource{name: name, release: release}
+	if leakDetection {
+		res.site = callerSite(2)
+	}
+
+	r.mu.Lock()
+	if r.tornDown {
+		r.mu.Unlock()
+		logrus.WithField("resource", name).Warn("Resource acquired after teardown, releasing it")
+		res.free()
+		return func() {}
+	}
+	if leakDetection {
+		for _, other := range r.live {
+			if other.name == name {
+				logrus.WithFields(logrus.Fields{
+					"resource": name,
+					"site":     res.site,
+					"previous": other.site,
+				}).Warn("Possible leak: resource acquired again without releasing the previous one")
+			}
+		}
+	}
+	r.live = append(r.live, res)
+	r.mu.Unlock()
+
+	return func() { r.release(res) }
+}
+
+// newHandle creates a cgo handle for v that is deleted on teardown.
+func (r *cgoResources) newHandle(name string, v any) cgo.Handle {
+	h := cgo.NewHandle(v)
+	r.track(name+" handle", h.Delete)
+	return h
+}
+
+func (r *cgoResources) release(res *cgoResource) {
+	r.mu.Lock()
+	if res.released {
+		r.mu.Unlock()
+		if leakDetection {
+			logrus.WithFields(logrus.Fields{
+				"resource": res.name,
+				"site":     callerSite(3),
+			}).Warn("Resource released twice")
+		}
+		return
+	}
+	res.released = true
+	r.live = slices.DeleteFunc(r.live, func(other *cgoResource) bool { return other == res })
+	r.mu.Unlock()
+
+	res.free()
+}
+
+// releaseAll releases all live resources, the most recently acquired first.
+func (r *cgoResources) releaseAll() {
+	r.mu.Lock()
+	live := r.live
+	r.live = nil
+	r.tornDown = true
+	for _, res := range live {
+		res.released = true
+	}
+	r.mu.Unlock()
+
+	for i := len(live) - 1; i >= 0; i-- {
+		live[i].free()
+	}
+	logrus.WithField("count", len(live)).Debug("Released cgo resources")
+}
+
+// free calls the release func. A panicking release must not keep the
+// remaining resources from being released.
+func (res *cgoResource) free() {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.WithField("resource", res.name).Errorf("Panic while releasing resource: %v", r)
+		}
+	}()
+	logrus.WithFields(logrus.Fields{
+		"resource": res.name,
+		"site":     res.site,
+	}).Trace("Releasing resource")
+	res.release()
+}
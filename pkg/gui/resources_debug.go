@@ -0,0 +1,21 @@
+//go:build debug
+
+package gui
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// leakDetection records where each cgo resource is acquired, and warns about
+// resources acquired twice or released twice.
+const leakDetection = true
+
+// callerSite returns the file:line skip frames up the stack.
+func callerSite(skip int) string {
+	_, file, line, ok := runtime.Caller(skip)
+	if !ok {
+		return "unknown"
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
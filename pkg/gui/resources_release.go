@@ -0,0 +1,9 @@
+//go:build !debug
+
+package gui
+
+const leakDetection = false
+
+func callerSite(int) string {
+	return ""
+}
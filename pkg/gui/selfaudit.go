@@ -0,0 +1,60 @@
+package gui
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/charlie0129/batt/pkg/diagnostics"
+	"github.com/charlie0129/batt/pkg/utils/procusage"
+)
+
+// Names of the app's own timers, as counted in the self-audit.
+const (
+	timerMenuRefresh    = "menu refresh (while open)"
+	timerCompanionFeed  = "Control Center sync"
+	timerDebugRefresh   = "debug window refresh"
+	menuRefreshInterval = time.Second
+)
+
+var (
+	guiTimers = diagnostics.NewTimerCounter()
+	// guiAuditSince is when the app started.
+	guiAuditSince = time.Now()
+)
+
+// getGUISelfAudit reports what the app has cost since it started.
+func getGUISelfAudit() *diagnostics.SelfAudit {
+	a := &diagnostics.SelfAudit{
+		Since:      guiAuditSince,
+		CgoCalls:   runtime.NumCgoCall(),
+		Goroutines: runtime.NumGoroutine(),
+		Timers:     guiTimers.Stats(),
+	}
+	if u, err := procusage.Self(); err == nil {
+		a.CPUSeconds = u.CPU.Seconds()
+		a.Wakeups = u.Wakeups
+		a.Energy = float64(u.Energy) / 1e9
+	}
+	return a
+}
+
+// formatSelfAudit renders what process, e.g. "Daemon", has cost.
+func formatSelfAudit(process string, a *diagnostics.SelfAudit, now time.Time) string {
+	var b strings.Builder
+	up := now.Sub(a.Since).Round(time.Second)
+	fmt.Fprintf(&b, "%s, up %s:\n", process, up)
+	fmt.Fprintf(&b, "  CPU %.1fs (%.3f%%), energy %.1f J\n", a.CPUSeconds, 100*a.CPUSeconds/max(up.Seconds(), 1), a.Energy)
+	fmt.Fprintf(&b, "  CPU wakeups %d (%.0f/h), cgo calls %d (%.0f/h), goroutines %d\n",
+		a.Wakeups, a.PerHour(float64(a.Wakeups), now), a.CgoCalls, a.PerHour(float64(a.CgoCalls), now), a.Goroutines)
+	fmt.Fprintf(&b, "  Timer wakeups %.0f/h", a.PerHour(float64(a.TimerFires()), now))
+	if a.IdleBudget > 0 {
+		fmt.Fprintf(&b, ", budget when idle %.0f/h", a.IdleBudget)
+	}
+	b.WriteString("\n")
+	for _, t := range a.Timers {
+		fmt.Fprintf(&b, "    %-24s every %4.0fs, fired %d times\n", t.Name, t.Interval, t.Fires)
+	}
+	return b.String()
+}
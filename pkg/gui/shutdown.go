@@ -0,0 +1,60 @@
+package gui
+
+import (
+	"runtime/cgo"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// #include <stdint.h>
+import "C"
+
+// shutdownTimeout bounds how long shutdown waits for background goroutines,
+// so a stuck daemon connection never keeps the app from quitting.
+const shutdownTimeout = 2 * time.Second
+
+// shutdown stops the GUI subsystems in order:
+//
+//  1. stop receiving daemon events, so nothing updates the menu afterwards;
+//  2. release Objective-C observers and cgo handles.
+//
+// Preferences need no flushing since every write is synchronized right away,
+// and the daemon keeps running (and scheduling) on its own.
+//
+// It is called when quitting from the menu and when the app is terminated
+// otherwise (e.g. on logout), and only runs once.
+func (c *menuController) shutdown() {
+	c.shutdownOnce.Do(func() {
+		logrus.Info("Shutting down")
+
+		if c.eventCancel != nil {
+			logrus.Debug("Cancelling event subscription")
+			c.eventCancel()
+		}
+		if c.eventDone != nil {
+			select {
+			case <-c.eventDone:
+			case <-time.After(shutdownTimeout):
+				logrus.Warn("Timed out waiting for the event subscription to stop")
+			}
+		}
+
+		c.teardown()
+	})
+}
+
+//export battAppWillTerminate
+func battAppWillTerminate(h C.uintptr_t) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("panic in battAppWillTerminate: %v", r)
+		}
+	}()
+	handle := cgo.Handle(h)
+	if v := handle.Value(); v != nil {
+		if c, ok := v.(*menuController); ok {
+			c.shutdown()
+		}
+	}
+}
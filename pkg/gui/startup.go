@@ -0,0 +1,104 @@
+package gui
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/client"
+	"github.com/charlie0129/batt/pkg/config"
+)
+
+// startupTarget is how long launching may take until the menubar icon shows.
+// The trace warns when it is exceeded.
+const startupTarget = 200 * time.Millisecond
+
+// launchTime is as close to process start as Go lets us get.
+var launchTime = time.Now()
+
+// startupPhase is a step of launching and when it ended, since launchTime.
+type startupPhase struct {
+	name    string
+	elapsed time.Duration
+}
+
+// startupTrace records how long each step of launching took, to find out
+// what delays the menubar icon.
+var startupTrace struct {
+	sync.Mutex
+	phases []startupPhase
+}
+
+// tracePhase records that the step name of launching has ended.
+func tracePhase(name string) {
+	elapsed := time.Since(launchTime)
+	startupTrace.Lock()
+	startupTrace.phases = append(startupTrace.phases, startupPhase{name: name, elapsed: elapsed})
+	startupTrace.Unlock()
+	logrus.WithFields(logrus.Fields{
+		"phase":   name,
+		"elapsed": elapsed.Round(time.Microsecond),
+	}).Debug("Startup phase")
+}
+
+// finishStartupTrace logs how long launching took in total.
+func finishStartupTrace() {
+	tracePhase("ready")
+	elapsed := time.Since(launchTime)
+	l := logrus.WithFields(logrus.Fields{
+		"elapsed": elapsed.Round(time.Millisecond),
+		"phases":  formatStartupTrace(),
+	})
+	if elapsed > startupTarget {
+		l.Warnf("Startup took longer than %s", startupTarget)
+		return
+	}
+	l.Info("Started")
+}
+
+// formatStartupTrace renders when each step of launching ended. Steps may
+// run concurrently, e.g. the daemon handshake.
+func formatStartupTrace() string {
+	startupTrace.Lock()
+	defer startupTrace.Unlock()
+
+	parts := make([]string, 0, len(startupTrace.phases))
+	for _, p := range startupTrace.phases {
+		parts = append(parts, fmt.Sprintf("%s at %s", p.name, p.elapsed.Round(100*time.Microsecond)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// handshake is what the menu needs from the daemon when it starts.
+type handshake struct {
+	config     *config.RawFileConfig
+	configErr  error
+	paused     bool
+	pausedErr  error
+	capable    bool
+	capableErr error
+	version    string
+	versionErr error
+}
+
+// startHandshake asks the daemon for what the menu needs, concurrently and
+// while the menu is built. Receive from the channel once the menu is ready.
+func startHandshake(api *client.Client) <-chan handshake {
+	ch := make(chan handshake, 1)
+	go func() {
+		var h handshake
+		var wg sync.WaitGroup
+		wg.Add(4)
+		go func() { defer wg.Done(); h.config, h.configErr = api.GetConfig() }()
+		go func() { defer wg.Done(); h.paused, h.pausedErr = api.GetChargingPaused() }()
+		go func() { defer wg.Done(); h.capable, h.capableErr = api.GetChargingControlCapable() }()
+		go func() { defer wg.Done(); h.version, h.versionErr = api.GetVersion() }()
+		wg.Wait()
+		tracePhase("daemon handshake")
+		ch <- h
+	}()
+	return ch
+}
@@ -0,0 +1,343 @@
+package gui
+
+import (
+	"fmt"
+	"runtime/cgo"
+	"strings"
+	"unsafe"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/calibration"
+	"github.com/charlie0129/batt/pkg/capability"
+	"github.com/charlie0129/batt/pkg/client"
+	"github.com/charlie0129/batt/pkg/config"
+	"github.com/charlie0129/batt/pkg/limits"
+	"github.com/charlie0129/batt/pkg/powerinfo"
+)
+
+// #include <stdint.h>
+// #include <stdbool.h>
+// #include <stdlib.h>
+// // Implemented in statuswindow.m.
+// void *batt_createStatusWindow(uintptr_t handle);
+// int batt_statusWindowAddRow(void *winPtr, const char *title, const char *identifier);
+// void batt_statusWindowSetValue(void *winPtr, int row, const char *value);
+// void batt_statusWindowSetLimit(void *winPtr, int limit, int lower, bool enabled);
+// void batt_statusWindowSetPaused(void *winPtr, bool paused);
+// void batt_statusWindowSetHighContrast(void *winPtr, bool highContrast);
+// void batt_showStatusWindow(void *winPtr);
+// void batt_releaseStatusWindow(void *winPtr);
+import "C"
+
+// statusWindowRow is a row in the status window, in display order.
+type statusWindowRow int
+
+const (
+	rowState statusWindowRow = iota
+	rowCharge
+	rowLimit
+	rowPluggedIn
+	rowSystemPower
+	rowAdapterPower
+	rowBatteryPower
+	rowCycleCount
+	rowHealth
+	rowTemperature
+	rowCalibration
+	rowDaemonVersion
+	rowSMCKeys
+	rowStrategy
+	numStatusWindowRows
+)
+
+var statusWindowRowTitles = [numStatusWindowRows]string{
+	rowState:         "State",
+	rowCharge:        "Current Charge",
+	rowLimit:         "Charge Limit",
+	rowPluggedIn:     "Power Adapter",
+	rowSystemPower:   "System Power",
+	rowAdapterPower:  "Adapter Power",
+	rowBatteryPower:  "Battery Power",
+	rowCycleCount:    "Cycle Count",
+	rowHealth:        "Battery Health",
+	rowTemperature:   "Temperature",
+	rowCalibration:   "Calibration",
+	rowDaemonVersion: "Daemon Version",
+	rowSMCKeys:       "SMC Keys",
+	rowStrategy:      "Control Strategy",
+}
+
+// statusWindow is a resizable window showing detailed battery information
+// and a charge limit slider. On Macs with a Touch Bar, a limit slider and a
+// Pause Charging button are shown there while the window is key. The window
+// itself lives in statuswindow.m; Go only feeds it values. It refreshes on
+// an ObjC timer while it is visible.
+type statusWindow struct {
+	api    *client.Client
+	ptr    unsafe.Pointer
+	handle cgo.Handle
+	rows   [numStatusWindowRows]C.int
+	// capabilities are cached, since the daemon only probes once.
+	capabilities *capability.Report
+	// togglePause pauses or resumes charging, keeping the menu in sync.
+	togglePause func()
+}
+
+func newStatusWindow(api *client.Client) *statusWindow {
+	w := &statusWindow{api: api}
+	w.handle = cgo.NewHandle(w)
+	w.ptr = C.batt_createStatusWindow(C.uintptr_t(w.handle))
+	for i, title := range statusWindowRowTitles {
+		cs := C.CString(title)
+		// Accessibility identifiers for UI test automation, e.g. batt.status.current-charge
+		cid := C.CString("batt.status." + strings.ToLower(strings.ReplaceAll(title, " ", "-")))
+		w.rows[i] = C.batt_statusWindowAddRow(w.ptr, cs, cid)
+		C.free(unsafe.Pointer(cs))
+		C.free(unsafe.Pointer(cid))
+	}
+	return w
+}
+
+// showStatusWindow creates the status window if needed and brings it to front.
+func (c *menuController) showStatusWindow() {
+	if c.statusWin == nil {
+		c.statusWin = newStatusWindow(c.api)
+		c.statusWin.togglePause = c.toggleChargingPaused
+		c.resources.track("status window", c.statusWin.release)
+		c.statusWin.setHighContrast(c.appearance.highContrast)
+	}
+	c.statusWin.show()
+}
+
+// show brings the window to front and starts periodic refreshes.
+func (w *statusWindow) show() {
+	C.batt_showStatusWindow(w.ptr)
+}
+
+func (w *statusWindow) release() {
+	C.batt_releaseStatusWindow(w.ptr)
+	w.ptr = nil
+	w.handle.Delete()
+}
+
+func (w *statusWindow) setHighContrast(highContrast bool) {
+	C.batt_statusWindowSetHighContrast(w.ptr, C.bool(highContrast))
+}
+
+func (w *statusWindow) setValue(row statusWindowRow, value string) {
+	cs := C.CString(value)
+	defer C.free(unsafe.Pointer(cs))
+	C.batt_statusWindowSetValue(w.ptr, w.rows[row], cs)
+}
+
+func (w *statusWindow) setError(rows ...statusWindowRow) {
+	for _, row := range rows {
+		w.setValue(row, "Error")
+	}
+}
+
+func (w *statusWindow) refresh() {
+	rawConfig, err := w.api.GetConfig()
+	if err != nil {
+		logrus.WithError(err).Debug("Failed to get config")
+		w.capabilities = nil
+		for row := statusWindowRow(0); row < numStatusWindowRows; row++ {
+			w.setValue(row, "Daemon not running")
+		}
+		C.batt_statusWindowSetLimit(w.ptr, 100, 100, false)
+		return
+	}
+	conf := config.NewFileFromConfig(rawConfig, "")
+	w.setValue(rowLimit, fmt.Sprintf("%d%% (resumes at %d%%)", conf.UpperLimit(), conf.LowerLimit()))
+
+	isCharging, err1 := w.api.GetCharging()
+	isPluggedIn, err2 := w.api.GetPluggedIn()
+	currentCharge, err3 := w.api.GetCurrentCharge()
+	batteryInfo, err4 := w.api.GetBatteryInfo()
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+		logrus.Debug("Failed to get battery state")
+		w.setError(rowState, rowCharge, rowPluggedIn)
+	} else {
+		w.setValue(rowState, describeBatteryState(batteryInfo, conf, isCharging, isPluggedIn, currentCharge))
+		w.setValue(rowCharge, fmt.Sprintf("%d%%", displayCharge(currentCharge, batteryInfo)))
+		if isPluggedIn {
+			w.setValue(rowPluggedIn, "Connected")
+		} else {
+			w.setValue(rowPluggedIn, "Not connected")
+		}
+	}
+
+	if v, err := w.api.GetVersion(); err == nil {
+		w.setValue(rowDaemonVersion, v)
+	} else {
+		w.setError(rowDaemonVersion)
+	}
+
+	if w.capabilities == nil {
+		if r, err := w.api.GetCapabilities(); err == nil {
+			w.capabilities = r
+		}
+	}
+	if r := w.capabilities; r != nil {
+		keys := strings.Join(r.Available(), ", ")
+		if !r.ChargingControl {
+			keys += " (charging control unsupported)"
+		}
+		w.setValue(rowSMCKeys, keys)
+		strategy := "charging: " + describeStrategy(r.ChargingStrategy) + ", adapter: " + describeStrategy(r.AdapterStrategy)
+		if r.StrategyOverridden {
+			strategy += " (overridden)"
+		}
+		w.setValue(rowStrategy, strategy)
+	} else {
+		w.setError(rowSMCKeys, rowStrategy)
+	}
+
+	calibrating := false
+	tr, err := w.api.GetTelemetry(true, true)
+	if err != nil || tr == nil {
+		w.setError(rowSystemPower, rowAdapterPower, rowBatteryPower, rowCycleCount, rowHealth, rowCalibration)
+	} else {
+		if tr.Power != nil {
+			w.setValue(rowSystemPower, fmt.Sprintf("%.2f W", tr.Power.Calculations.SystemPower))
+			w.setValue(rowAdapterPower, fmt.Sprintf("%.2f W", tr.Power.Calculations.ACPower))
+			w.setValue(rowBatteryPower, fmt.Sprintf("%+.2f W", tr.Power.Calculations.BatteryPower))
+			w.setValue(rowCycleCount, fmt.Sprintf("%d", tr.Power.Battery.CycleCount))
+			w.setValue(rowHealth, fmt.Sprintf("%d%%", tr.Power.Calculations.HealthByMaxCapacity))
+		}
+		if tr.Calibration != nil {
+			st := tr.Calibration
+			calibrating = st.Phase != calibration.PhaseIdle && st.Phase != calibration.PhaseError && !st.Paused
+			phase := string(st.Phase)
+			if st.Paused {
+				phase += " (paused)"
+			}
+			w.setValue(rowCalibration, phase)
+		}
+	}
+
+	if temps, err := w.api.GetTemperatures(); err != nil {
+		w.setError(rowTemperature)
+	} else {
+		w.setValue(rowTemperature, describeTemperatures(temps))
+	}
+
+	// Do not let the user change the limit while calibrating, same as the menu.
+	C.batt_statusWindowSetLimit(w.ptr, C.int(conf.UpperLimit()), C.int(conf.LowerLimit()), C.bool(!calibrating))
+	if paused, err := w.api.GetChargingPaused(); err == nil {
+		C.batt_statusWindowSetPaused(w.ptr, C.bool(paused))
+	}
+}
+
+// describeTemperatures lists the sensor readings and their limits on one line.
+func describeTemperatures(temps []powerinfo.Temperature) string {
+	if len(temps) == 0 {
+		return "No sensors found"
+	}
+	parts := make([]string, 0, len(temps))
+	for _, t := range temps {
+		s := fmt.Sprintf("%s %.1f°C", t.Name, t.Celsius)
+		switch {
+		case t.TooHot:
+			s += fmt.Sprintf(" (above %d°C, charging stopped)", t.Limit)
+		case t.Limit > 0:
+			s += fmt.Sprintf(" (limit %d°C)", t.Limit)
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (w *statusWindow) onLimitChanged(limit int) {
+	ret, err := w.api.SetLimit(limit)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to set limit")
+		showAlert("Failed to set limit", ret+err.Error())
+	}
+	w.refresh()
+}
+
+// onLowerLimitChanged sets where charging resumes, as the distance from the
+// upper limit, which is what the daemon stores.
+func (w *statusWindow) onLowerLimitChanged(lower int) {
+	rawConfig, err := w.api.GetConfig()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get config")
+		return
+	}
+	upper := config.NewFileFromConfig(rawConfig, "").UpperLimit()
+	delta := upper - lower
+	if err := limits.ValidateLowerDelta(upper, delta); err != nil {
+		showAlert("Invalid lower limit", err.Error())
+		w.refresh()
+		return
+	}
+	ret, err := w.api.SetLowerLimitDelta(delta)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to set lower limit delta")
+		showAlert("Failed to set lower limit", ret+err.Error())
+	}
+	w.refresh()
+}
+
+//export battStatusWindowRefresh
+func battStatusWindowRefresh(h C.uintptr_t) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("panic in battStatusWindowRefresh: %v", r)
+		}
+	}()
+	handle := cgo.Handle(h)
+	if v := handle.Value(); v != nil {
+		if w, ok := v.(*statusWindow); ok {
+			w.refresh()
+		}
+	}
+}
+
+//export battStatusWindowLimitChanged
+func battStatusWindowLimitChanged(h C.uintptr_t, limit C.int) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("panic in battStatusWindowLimitChanged: %v", r)
+		}
+	}()
+	handle := cgo.Handle(h)
+	if v := handle.Value(); v != nil {
+		if w, ok := v.(*statusWindow); ok {
+			w.onLimitChanged(int(limit))
+		}
+	}
+}
+
+//export battStatusWindowLowerLimitChanged
+func battStatusWindowLowerLimitChanged(h C.uintptr_t, lower C.int) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("panic in battStatusWindowLowerLimitChanged: %v", r)
+		}
+	}()
+	handle := cgo.Handle(h)
+	if v := handle.Value(); v != nil {
+		if w, ok := v.(*statusWindow); ok {
+			w.onLowerLimitChanged(int(lower))
+		}
+	}
+}
+
+//export battStatusWindowTogglePause
+func battStatusWindowTogglePause(h C.uintptr_t) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("panic in battStatusWindowTogglePause: %v", r)
+		}
+	}()
+	handle := cgo.Handle(h)
+	if v := handle.Value(); v != nil {
+		if w, ok := v.(*statusWindow); ok && w.togglePause != nil {
+			w.togglePause()
+			w.refresh()
+		}
+	}
+}
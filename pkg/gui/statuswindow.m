@@ -0,0 +1,351 @@
+#import <Cocoa/Cocoa.h>
+#include <stdint.h>
+#include <stdbool.h>
+
+// The time interval in seconds for the status window refresh timer.
+static const NSTimeInterval kStatusWindowRefreshInterval = 2.0;
+
+// Touch Bar item identifiers.
+static NSTouchBarItemIdentifier const kTouchBarLimitItem = @"cc.chlc.batt.touchbar.limit";
+static NSTouchBarItemIdentifier const kTouchBarPauseItem = @"cc.chlc.batt.touchbar.pause";
+
+// Callbacks exported from Go
+extern void battStatusWindowRefresh(uintptr_t handle);
+extern void battStatusWindowLimitChanged(uintptr_t handle, int limit);
+extern void battStatusWindowLowerLimitChanged(uintptr_t handle, int lower);
+extern void battStatusWindowTogglePause(uintptr_t handle);
+
+@interface BattStatusWindowController : NSObject <NSWindowDelegate, NSTouchBarDelegate>
+@property(nonatomic, assign) uintptr_t handle;
+@property(nonatomic, strong) NSWindow *window;
+@property(nonatomic, strong) NSGridView *grid;
+@property(nonatomic, strong) NSMutableArray<NSTextField *> *titleFields;
+@property(nonatomic, strong) NSMutableArray<NSTextField *> *valueFields;
+@property(nonatomic, strong) NSSlider *limitSlider;
+@property(nonatomic, strong) NSTextField *limitValueLabel;
+@property(nonatomic, strong) NSSlider *lowerSlider;
+@property(nonatomic, strong) NSTextField *lowerValueLabel;
+@property(nonatomic, strong) NSTextField *bandLabel;
+// lowerDelta is how far below the limit charging resumes.
+@property(nonatomic, assign) int lowerDelta;
+// The Touch Bar controls, shown on Macs with a Touch Bar while the window
+// is key.
+@property(nonatomic, strong) NSSliderTouchBarItem *touchBarSlider;
+@property(nonatomic, strong) NSButton *touchBarPauseButton;
+@property(nonatomic, strong) NSTimer *timer;
+- (instancetype)initWithHandle:(uintptr_t)handle;
+@end
+
+@implementation BattStatusWindowController
+- (instancetype)initWithHandle:(uintptr_t)handle {
+    if ((self = [super init])) {
+        _handle = handle;
+        _titleFields = [NSMutableArray array];
+        _valueFields = [NSMutableArray array];
+        [self buildWindow];
+    }
+    return self;
+}
+
+- (void)buildWindow {
+    NSRect frame = NSMakeRect(0, 0, 420, 420);
+    NSWindowStyleMask style = NSWindowStyleMaskTitled | NSWindowStyleMaskClosable |
+                              NSWindowStyleMaskMiniaturizable | NSWindowStyleMaskResizable;
+    self.window = [[NSWindow alloc] initWithContentRect:frame
+                                              styleMask:style
+                                                backing:NSBackingStoreBuffered
+                                                  defer:NO];
+    self.window.title = @"batt";
+    self.window.identifier = @"batt.statusWindow";
+    self.window.releasedWhenClosed = NO;
+    self.window.delegate = self;
+    self.window.contentMinSize = NSMakeSize(360, 320);
+    [self.window setFrameAutosaveName:@"BattStatusWindow"];
+
+    self.grid = [NSGridView gridViewWithNumberOfColumns:2 rows:0];
+    self.grid.rowSpacing = 6;
+    self.grid.columnSpacing = 12;
+    [self.grid columnAtIndex:0].xPlacement = NSGridCellPlacementTrailing;
+
+    NSTextField *limitTitle = [NSTextField labelWithString:@"Charge Limit:"];
+    limitTitle.textColor = [NSColor secondaryLabelColor];
+    [self.titleFields addObject:limitTitle];
+
+    self.limitSlider = [NSSlider sliderWithValue:80 minValue:10 maxValue:100 target:self action:@selector(limitSliderMoved:)];
+    self.limitSlider.continuous = YES;
+    self.limitSlider.numberOfTickMarks = 10;
+    self.limitSlider.allowsTickMarkValuesOnly = NO;
+    self.limitSlider.identifier = @"batt.status.limitSlider";
+    self.limitSlider.accessibilityLabel = @"Charge Limit";
+
+    self.limitValueLabel = [NSTextField labelWithString:@"80%"];
+    self.limitValueLabel.font = [NSFont monospacedDigitSystemFontOfSize:13 weight:NSFontWeightMedium];
+    [self.limitValueLabel setContentHuggingPriority:NSLayoutPriorityRequired forOrientation:NSLayoutConstraintOrientationHorizontal];
+
+    NSStackView *limitRow = [NSStackView stackViewWithViews:@[ limitTitle, self.limitSlider, self.limitValueLabel ]];
+    limitRow.orientation = NSUserInterfaceLayoutOrientationHorizontal;
+    limitRow.spacing = 8;
+
+    NSTextField *lowerTitle = [NSTextField labelWithString:@"Resume Below:"];
+    lowerTitle.textColor = [NSColor secondaryLabelColor];
+    [self.titleFields addObject:lowerTitle];
+
+    self.lowerSlider = [NSSlider sliderWithValue:80 minValue:10 maxValue:100 target:self action:@selector(lowerSliderMoved:)];
+    self.lowerSlider.continuous = YES;
+    self.lowerSlider.identifier = @"batt.status.lowerLimitSlider";
+    self.lowerSlider.accessibilityLabel = @"Resume Charging Below";
+
+    self.lowerValueLabel = [NSTextField labelWithString:@"80%"];
+    self.lowerValueLabel.font = [NSFont monospacedDigitSystemFontOfSize:13 weight:NSFontWeightMedium];
+    [self.lowerValueLabel setContentHuggingPriority:NSLayoutPriorityRequired forOrientation:NSLayoutConstraintOrientationHorizontal];
+
+    NSStackView *lowerRow = [NSStackView stackViewWithViews:@[ lowerTitle, self.lowerSlider, self.lowerValueLabel ]];
+    lowerRow.orientation = NSUserInterfaceLayoutOrientationHorizontal;
+    lowerRow.spacing = 8;
+
+    // Keep the titles the same width, so the sliders line up.
+    [lowerTitle.widthAnchor constraintEqualToAnchor:limitTitle.widthAnchor].active = YES;
+    [self.lowerSlider.widthAnchor constraintEqualToAnchor:self.limitSlider.widthAnchor].active = YES;
+
+    self.bandLabel = [NSTextField wrappingLabelWithString:@""];
+    self.bandLabel.textColor = [NSColor secondaryLabelColor];
+    self.bandLabel.identifier = @"batt.status.band";
+    [self.titleFields addObject:self.bandLabel];
+
+    NSStackView *root = [NSStackView stackViewWithViews:@[ self.grid, [self separator], limitRow, lowerRow, self.bandLabel ]];
+    root.orientation = NSUserInterfaceLayoutOrientationVertical;
+    root.alignment = NSLayoutAttributeLeading;
+    root.spacing = 16;
+    root.edgeInsets = NSEdgeInsetsMake(20, 20, 20, 20);
+    root.translatesAutoresizingMaskIntoConstraints = NO;
+
+    NSView *content = self.window.contentView;
+    [content addSubview:root];
+    [NSLayoutConstraint activateConstraints:@[
+        [root.leadingAnchor constraintEqualToAnchor:content.leadingAnchor],
+        [root.trailingAnchor constraintEqualToAnchor:content.trailingAnchor],
+        [root.topAnchor constraintEqualToAnchor:content.topAnchor],
+        [root.bottomAnchor constraintLessThanOrEqualToAnchor:content.bottomAnchor],
+        [limitRow.trailingAnchor constraintEqualToAnchor:root.trailingAnchor constant:-20],
+        [lowerRow.trailingAnchor constraintEqualToAnchor:root.trailingAnchor constant:-20],
+        [self.bandLabel.trailingAnchor constraintEqualToAnchor:root.trailingAnchor constant:-20],
+    ]];
+    [self.window center];
+
+    self.window.touchBar = [self makeTouchBar];
+}
+
+- (NSTouchBar *)makeTouchBar {
+    NSTouchBar *bar = [[NSTouchBar alloc] init];
+    bar.delegate = self;
+    bar.defaultItemIdentifiers = @[ kTouchBarLimitItem, kTouchBarPauseItem ];
+    return bar;
+}
+
+- (NSTouchBarItem *)touchBar:(NSTouchBar *)touchBar makeItemForIdentifier:(NSTouchBarItemIdentifier)identifier {
+    if ([identifier isEqualToString:kTouchBarLimitItem]) {
+        self.touchBarSlider = [[NSSliderTouchBarItem alloc] initWithIdentifier:identifier];
+        self.touchBarSlider.label = @"Limit 80%";
+        self.touchBarSlider.slider.minValue = 10;
+        self.touchBarSlider.slider.maxValue = 100;
+        self.touchBarSlider.slider.intValue = self.limitSlider.intValue;
+        // Only send the value when the finger is lifted, like the window
+        // slider does on mouse up.
+        self.touchBarSlider.slider.continuous = NO;
+        self.touchBarSlider.target = self;
+        self.touchBarSlider.action = @selector(touchBarSliderMoved:);
+        self.touchBarSlider.customizationLabel = @"Charge Limit";
+        [self updateTouchBarLimit:self.limitSlider.intValue enabled:self.limitSlider.enabled];
+        return self.touchBarSlider;
+    }
+    if ([identifier isEqualToString:kTouchBarPauseItem]) {
+        NSCustomTouchBarItem *item = [[NSCustomTouchBarItem alloc] initWithIdentifier:identifier];
+        self.touchBarPauseButton = [NSButton buttonWithTitle:@"Pause Charging" target:self action:@selector(touchBarPausePressed:)];
+        item.view = self.touchBarPauseButton;
+        item.customizationLabel = @"Pause Charging";
+        return item;
+    }
+    return nil;
+}
+
+- (void)touchBarSliderMoved:(NSSliderTouchBarItem *)sender {
+    int limit = (int)lround(sender.slider.doubleValue);
+    self.limitSlider.intValue = limit;
+    [self updateLimitLabel:limit];
+    [self updateTouchBarLimit:limit enabled:YES];
+    battStatusWindowLimitChanged(_handle, limit);
+}
+
+- (void)touchBarPausePressed:(NSButton *)sender {
+    battStatusWindowTogglePause(_handle);
+}
+
+- (void)updateTouchBarLimit:(int)limit enabled:(BOOL)enabled {
+    if (self.touchBarSlider == nil) return;
+    self.touchBarSlider.slider.intValue = limit;
+    self.touchBarSlider.slider.enabled = enabled;
+    self.touchBarSlider.label = limit >= 100 ? @"No Limit" : [NSString stringWithFormat:@"Limit %d%%", limit];
+}
+
+- (void)setPaused:(BOOL)paused {
+    self.touchBarPauseButton.title = paused ? @"Resume Charging" : @"Pause Charging";
+}
+
+- (NSBox *)separator {
+    NSBox *box = [[NSBox alloc] init];
+    box.boxType = NSBoxSeparator;
+    return box;
+}
+
+- (int)addRowWithTitle:(NSString *)title identifier:(NSString *)identifier {
+    NSTextField *label = [NSTextField labelWithString:[title stringByAppendingString:@":"]];
+    label.textColor = [NSColor secondaryLabelColor];
+    NSTextField *value = [NSTextField labelWithString:@"Loading..."];
+    value.font = [NSFont monospacedDigitSystemFontOfSize:13 weight:NSFontWeightRegular];
+    value.selectable = YES;
+    // Let VoiceOver read "<title>, <value>" on the value field alone.
+    value.accessibilityLabel = title;
+    value.identifier = identifier;
+    [self.grid addRowWithViews:@[ label, value ]];
+    [self.titleFields addObject:label];
+    [self.valueFields addObject:value];
+    return (int)(self.valueFields.count - 1);
+}
+
+- (void)limitSliderMoved:(NSSlider *)sender {
+    int limit = (int)lround(sender.doubleValue);
+    [self updateLimitLabel:limit];
+    // Only tell Go once the user releases the knob, so dragging does not
+    // flood the daemon with intermediate values.
+    NSEvent *event = [NSApp currentEvent];
+    if (event == nil || event.type == NSEventTypeLeftMouseUp || event.type == NSEventTypeKeyDown) {
+        battStatusWindowLimitChanged(_handle, limit);
+    }
+}
+
+- (void)lowerSliderMoved:(NSSlider *)sender {
+    int lower = (int)lround(sender.doubleValue);
+    [self updateLabelsWithLimit:self.limitSlider.intValue lower:lower];
+    NSEvent *event = [NSApp currentEvent];
+    if (event == nil || event.type == NSEventTypeLeftMouseUp || event.type == NSEventTypeKeyDown) {
+        battStatusWindowLowerLimitChanged(_handle, lower);
+    }
+}
+
+- (void)updateLimitLabel:(int)limit {
+    // The daemon keeps the band when the limit changes.
+    [self updateLabelsWithLimit:limit lower:limit - self.lowerDelta];
+}
+
+- (void)updateLabelsWithLimit:(int)limit lower:(int)lower {
+    self.limitValueLabel.stringValue = [NSString stringWithFormat:@"%d%%", limit];
+    self.limitSlider.accessibilityValueDescription = [NSString stringWithFormat:@"%d percent", limit];
+    self.lowerValueLabel.stringValue = [NSString stringWithFormat:@"%d%%", lower];
+    self.lowerSlider.accessibilityValueDescription = [NSString stringWithFormat:@"%d percent", lower];
+    if (limit >= 100) {
+        self.bandLabel.stringValue = @"The charge limit is off, the battery charges to 100%.";
+    } else {
+        self.bandLabel.stringValue = [NSString stringWithFormat:@"Charging stops at %d%% and resumes below %d%%.", limit, lower];
+    }
+}
+
+- (void)setHighContrast:(BOOL)highContrast {
+    // Secondary label color is hard to read with increased contrast.
+    NSColor *color = highContrast ? [NSColor labelColor] : [NSColor secondaryLabelColor];
+    for (NSTextField *f in self.titleFields) {
+        f.textColor = color;
+    }
+}
+
+- (void)show {
+    if (self.timer == nil) {
+        // Default mode only: the timer must not fire while the user is
+        // dragging the slider, otherwise the refresh would move the knob.
+        self.timer = [NSTimer timerWithTimeInterval:kStatusWindowRefreshInterval
+                                             target:self
+                                           selector:@selector(timerTick:)
+                                           userInfo:nil
+                                            repeats:YES];
+        [[NSRunLoop mainRunLoop] addTimer:self.timer forMode:NSDefaultRunLoopMode];
+    }
+    battStatusWindowRefresh(_handle);
+    [NSApp activateIgnoringOtherApps:YES];
+    [self.window makeKeyAndOrderFront:nil];
+}
+
+- (void)timerTick:(NSTimer *)timer {
+    battStatusWindowRefresh(_handle);
+}
+
+- (void)stopTimer {
+    if (self.timer) {
+        [self.timer invalidate];
+        self.timer = nil;
+    }
+}
+
+- (void)windowWillClose:(NSNotification *)note {
+    [self stopTimer];
+}
+@end
+
+void *batt_createStatusWindow(uintptr_t handle) {
+    BattStatusWindowController *ctrl = [[BattStatusWindowController alloc] initWithHandle:handle];
+    return (void *)CFBridgingRetain(ctrl);
+}
+
+int batt_statusWindowAddRow(void *winPtr, const char *title, const char *identifier) {
+    if (winPtr == NULL) return -1;
+    BattStatusWindowController *ctrl = (__bridge BattStatusWindowController *)winPtr;
+    return [ctrl addRowWithTitle:(title ? [NSString stringWithUTF8String:title] : @"")
+                      identifier:(identifier ? [NSString stringWithUTF8String:identifier] : nil)];
+}
+
+void batt_statusWindowSetValue(void *winPtr, int row, const char *value) {
+    if (winPtr == NULL) return;
+    BattStatusWindowController *ctrl = (__bridge BattStatusWindowController *)winPtr;
+    if (row < 0 || row >= (int)ctrl.valueFields.count) return;
+    ctrl.valueFields[row].stringValue = value ? [NSString stringWithUTF8String:value] : @"";
+}
+
+void batt_statusWindowSetLimit(void *winPtr, int limit, int lower, bool enabled) {
+    if (winPtr == NULL) return;
+    BattStatusWindowController *ctrl = (__bridge BattStatusWindowController *)winPtr;
+    ctrl.limitSlider.intValue = limit;
+    ctrl.limitSlider.enabled = enabled;
+    // The lower limit can go up to the upper limit, and means nothing
+    // without one.
+    ctrl.lowerSlider.maxValue = limit;
+    ctrl.lowerSlider.intValue = lower;
+    ctrl.lowerSlider.enabled = enabled && limit < 100;
+    ctrl.lowerDelta = limit - lower;
+    [ctrl updateLabelsWithLimit:limit lower:lower];
+    [ctrl updateTouchBarLimit:limit enabled:enabled];
+}
+
+void batt_statusWindowSetPaused(void *winPtr, bool paused) {
+    if (winPtr == NULL) return;
+    BattStatusWindowController *ctrl = (__bridge BattStatusWindowController *)winPtr;
+    [ctrl setPaused:paused];
+}
+
+void batt_statusWindowSetHighContrast(void *winPtr, bool highContrast) {
+    if (winPtr == NULL) return;
+    BattStatusWindowController *ctrl = (__bridge BattStatusWindowController *)winPtr;
+    [ctrl setHighContrast:highContrast];
+}
+
+void batt_showStatusWindow(void *winPtr) {
+    if (winPtr == NULL) return;
+    BattStatusWindowController *ctrl = (__bridge BattStatusWindowController *)winPtr;
+    [ctrl show];
+}
+
+void batt_releaseStatusWindow(void *winPtr) {
+    if (winPtr == NULL) return;
+    BattStatusWindowController *ctrl = (__bridge BattStatusWindowController *)winPtr;
+    [ctrl stopTimer];
+    ctrl.window.delegate = nil;
+    [ctrl.window close];
+    CFRelease(winPtr);
+}
@@ -0,0 +1,30 @@
+package gui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charlie0129/batt/pkg/config"
+)
+
+// storageReminderInterval is how often to remind the user to check on a Mac
+// in storage mode. Batteries slowly self-discharge even when the Mac is off.
+const storageReminderInterval = 30 * 24 * time.Hour
+
+// remindStorageMode reminds the user to check on the battery about once a
+// month while storage mode is on.
+func remindStorageMode(sm *config.StorageMode, now time.Time) {
+	if sm == nil {
+		return
+	}
+	last := sm.Since
+	if t, err := time.Parse(time.RFC3339, getStringPref(prefStorageRemindedAt)); err == nil && t.After(last) {
+		last = t
+	}
+	if now.Sub(last) < storageReminderInterval {
+		return
+	}
+
+	setStringPref(prefStorageRemindedAt, now.Format(time.RFC3339))
+	showNotification("Storage Mode", fmt.Sprintf("This Mac has been in storage mode since %s. Check that the battery is still around 50%%, and charge it if it dropped below 20%%.", sm.Since.Local().Format("Jan _2, 2006")))
+}
@@ -7,4 +7,92 @@ Since the batt daemon is still running, batt can continue to control charging. T
 
 If you want to stop batt completely (menubar app and the daemon), you can use the "Disable Charging Limit" command. To uninstall, you can use the "Uninstall Daemon" command in the Advanced menu.`
 	quitTooltipNotInstalled = `Quit the batt menubar app.`
+
+	statusWindowTooltip = `Open a window with detailed battery information, such as power flow, cycle count and battery health. The window refreshes automatically while it is open, and lets you adjust the charge limit and where charging resumes with sliders.`
+	paletteTooltip      = `Search all batt actions by typing a few letters, e.g. "p c" for Pause Charging. Assign a global shortcut with "batt shortcut command-palette".`
+	debugWindowTooltip  = `Open a window showing recent charging decisions, SMC writes and maintain loop timing, to find out why batt did or did not stop charging.`
+
+	compactIconTooltip = `Use a fixed, square-sized menubar icon. This takes less space in the menubar and plays nicely with menubar managers like Bartender or Ice.`
+
+	systemChargeTooltip = `Show the battery percentage as the macOS battery indicator does. The raw value batt reads from the SMC often differs by a few percent, and charge limits always apply to the raw value.`
+
+	nonRootAccessTooltip = `Let users other than root control the batt daemon, so the menubar app and the batt command work without your password. The menubar app needs this. Turning it off locks the menubar app out until you turn it on again with your password.`
+
+	upgradeTooltip = `Your batt daemon is not compatible with this client version and needs to be upgraded. This is usually caused by a new client version that requires a new daemon version. You can upgrade the batt daemon by running this command.`
+
+	upgradeBusyTooltip = `Your batt daemon needs to be upgraded, but it is busy with %s. Upgrading restarts the daemon, which could leave the limit lifted at 100%% or the adapter disabled, so better wait until it is done. You can still upgrade now.`
+
+	upgradeBusyAlertText = `The batt daemon is busy with %s. Upgrading restarts it, which could leave the charge limit lifted at 100%% or the power adapter disabled.
+
+batt can notify you once the daemon is done, so you can upgrade then.`
+
+	repairAccessTooltip = `The batt daemon refuses the menubar app, e.g. because a macOS update changed the permissions of its socket. Enter your password to let the menubar app access it again.`
+
+	disableNonRootAccessAlertText = `The menubar app runs as you, not root, so it will no longer be able to control charging. The batt command will need sudo.
+
+To undo this, click Repair Daemon Access... in this menu and enter your password.`
+
+	cliTooltip = `Choose where the batt command is linked for use in Terminal, check that it is on your PATH, and repair the link after batt.app was moved or updated. Apple silicon Macs have no /usr/local/bin without Homebrew, so batt creates it or you can pick Homebrew's /opt/homebrew/bin or your own ~/bin.`
+
+	symlinkNotOnPathAlertText = `%s is not on the PATH of your login shell, so typing batt in Terminal will not find it. Pick another directory in Advanced > Command Line Tool, or add it to your PATH in your shell profile.`
+
+	daemonLogTooltip = `Show the recent log of the batt daemon, change how much it logs, or rotate and clear the log at /tmp/batt.log. The log level goes back to the default when the daemon restarts. The log is rotated automatically when it grows above 10 MB.`
+
+	clearLogAlertText = `The log of the batt daemon will be emptied. If you are about to report an issue, attach the log first.`
+
+	hideIconTooltip = `Hide the batt menubar icon while keeping the menubar app running.`
+
+	hideIconAlertText = `The menubar app will keep running in the background. To bring the icon back, do one of the following:
+
+- Open batt.app again (e.g. from Finder or Spotlight).
+- Run "batt menubar-icon show" in Terminal (without sudo).
+- Open the URL batt://menubar-icon/show.`
+
+	loginItemTooltip = `Whether the menubar app starts at login. Click to diagnose and repair the login item, e.g. when macOS requires your approval in System Settings or when batt.app was moved after it was registered.`
+
+	optimizedChargingTooltip = `Choose how batt interacts with macOS Optimized Battery Charging and the built-in charge limit of newer macOS. While one of them holds the charge (usually at 80%), macOS and batt both control charging, which can make charging behave unpredictably. It is recommended to turn Optimized Battery Charging off when using batt.`
+
+	unsupportedTooltip = `batt could not find the SMC keys it needs to control charging on this Mac. Click to see which keys are available.`
+
+	weakAdapterBoostTooltip = `When your power adapter cannot keep up with your Mac and the battery drains although plugged in, charge to 100% whenever possible until you unplug. You are notified about a weak adapter either way.`
+
+	energySamplingTooltip = `While on battery, sample the energy used by every process once a minute, so you can find out what drains your battery. Off by default.`
+
+	energyReportTooltip = `Show the processes that used the most energy during the current (or last) discharging session. Requires "Sample Energy Use on Battery".`
+
+	smcDiagnosticsTooltip = `Show which features of batt this Mac supports, which SMC keys exist on it and which strategy batt uses to control charging and the power adapter. Strategies are picked automatically by model and firmware, and can be overridden with "chargingStrategy" and "adapterStrategy" in /etc/batt.json.`
+
+	travelModeTooltip = `Charge to 100% for a trip, then go back to your limit. Travel mode also pauses the calibration schedule. Both are restored when you turn travel mode off or when it ends by itself. Setting a limit in the meantime ends travel mode without restoring anything.`
+
+	policyTooltip = `What currently decides when your Mac charges, and who set the charge limit. Useful to understand why charging stopped at a given percent.`
+
+	selfTestTooltip = `Check that batt can control charging on this Mac: read the battery and power adapter, and briefly flip charging to check that it follows. Charging is restored right away and the power adapter is never turned off.`
+
+	hooksTooltip = `Run your own scripts when something happens, e.g. put an executable named on-limit-reached in this folder. Hooks get the event as JSON on stdin and are stopped after 30 seconds. Remove the executable permission to turn a hook off.`
+
+	chargeByTooltip = `Have the battery full by this time every day, e.g. when you leave for work. batt estimates how long charging to 100% takes, using how fast your Mac charged before, and starts charging just in time. Otherwise the charge limit applies, so the battery spends as little time as possible at full charge overnight.`
+
+	pauseChargingTooltip = `Stop charging right away, regardless of the charge limit, until you resume it. Your Mac keeps running on wall power. Restarting the Mac resumes charging.`
+
+	bypassTooltip = `Run this Mac from the power adapter without cycling the battery, holding it at the current charge. If the charge drops anyway, e.g. with a weak adapter, batt charges back up to it. Turn it off to follow the charge limit again. Restarting the Mac turns it off.`
+
+	usageReportTooltip = `See how you used your battery over the last 30 days: time on battery and plugged in, how deep you discharge and how long the charge stayed above 80%. The statistics are kept on this Mac only.`
+
+	monthlyReportTooltip = `Sum up last month: charge cycles added, battery health change, average charge and hours held at the limit. batt also notifies you with this summary when a month ends.`
+
+	moveToApplicationsAlertText = `batt is running from outside the Applications folder, e.g. from Downloads or a disk image. The batt daemon runs the app it was installed from, so it stops working once this copy is deleted or the disk image is ejected.
+
+batt can move itself to the Applications folder and relaunch from there.`
+
+	healthComparisonTooltip = `Compare your battery health at its cycle count with Apple's rating for this Mac model, and see how it changed over the cycles batt has recorded.`
+
+	appRulesTooltip = `Charge to 100% while apps that need a full battery are running, e.g. a video editor or a virtual machine you take on the road. batt turns travel mode on when one of the apps launches and off again when they have all quit. Travel mode you turned on yourself is left alone.`
+
+	storageModeTooltip = `Prepare this Mac to be stored unused for a long time. Holds the battery between 45% and 50%, the charge it ages the slowest at, and pauses the calibration schedule. If the battery is above 50%, the power adapter is disabled until it drains to 50%. Turning storage mode off restores your limits and schedule.`
+
+	whatsNewTooltip = `batt.app has been updated. Open the release notes of this version on GitHub.`
+
+	conflictTooltip = `Another program changed charging behind batt's back, most likely another charge limiter such as AlDente. Two charge limiters writing the same settings fight each other. Click for details.`
+
+	uninstallEverythingTooltip = `Remove batt from your Mac entirely: the daemon, the command line symlink, config, state and log files, the login item and preferences of the menubar app. Charging limits are reset and batt.app is moved to the Trash. You must enter your password.`
 )
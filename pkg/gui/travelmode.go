@@ -0,0 +1,63 @@
+package gui
+
+import (
+	"fmt"
+
+	"github.com/charlie0129/batt/pkg/config"
+)
+
+// travelModeBadge is shown next to the menubar icon while travel mode is on.
+const travelModeBadge = "✈︎"
+
+// renderBadges shows the badges of the modes that are on next to the
+// menubar icon.
+func (c *menuController) renderBadges() {
+	badges := ""
+	if c.pausedBadge {
+		badges += chargingPausedBadge
+	}
+	if c.travelBadge {
+		badges += travelModeBadge
+	}
+	if badges != c.badges {
+		c.badges = badges
+		c.menubarIcon.Button().SetTitle(badges)
+	}
+}
+
+// travelModeDurations are the choices in the Travel Mode menu. 0 days means
+// until turned off.
+var travelModeDurations = []struct {
+	days  int
+	title string
+}{
+	{days: 0, title: "Until Turned Off"},
+	{days: 1, title: "For 1 Day"},
+	{days: 3, title: "For 3 Days"},
+	{days: 7, title: "For 1 Week"},
+}
+
+// renderTravelMode updates the Travel Mode menu and the menubar badge. The
+// daemon ends travel mode by itself when it expires, which shows up the next
+// time the menu is opened.
+func (c *menuController) renderTravelMode(tm *config.TravelMode) {
+	on := tm != nil
+	status := "Off"
+	switch {
+	case on && tm.Until.IsZero():
+		status = "Charging to 100% until turned off"
+	case on:
+		status = "Charging to 100% until " + tm.Until.Local().Format("Jan _2 15:04")
+	}
+	setItemTitle(c.travelModeStatusItem, status)
+	setItemHidden(c.travelModeOffItem, !on)
+
+	if on {
+		setItemTitle(c.travelModeSubMenuItem, fmt.Sprintf("Travel Mode: On (%d%% after)", tm.Limit))
+	} else {
+		setItemTitle(c.travelModeSubMenuItem, "Travel Mode")
+	}
+
+	c.travelBadge = on
+	c.renderBadges()
+}
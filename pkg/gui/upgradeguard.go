@@ -0,0 +1,96 @@
+package gui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/progrium/darwinkit/macos/appkit"
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/calibration"
+)
+
+const (
+	// upcomingCalibrationWindow is how soon a scheduled calibration holds off
+	// upgrading the daemon, so it does not start while the daemon restarts.
+	upcomingCalibrationWindow = 15 * time.Minute
+	// upgradeRecheckInterval is how often a deferred upgrade checks whether
+	// the daemon is still busy.
+	upgradeRecheckInterval = 30 * time.Second
+)
+
+// daemonBusyReason returns why the daemon should not be replaced now, or ""
+// if it may. Restarting the daemon in the middle of a calibration or a forced
+// discharge can leave the limit lifted at 100% or the adapter disabled.
+// Daemons too old to answer are never busy.
+func (c *menuController) daemonBusyReason() string {
+	if t, err := c.api.GetTelemetry(false, true); err == nil && t.Calibration != nil {
+		if p := t.Calibration.Phase; p != calibration.PhaseIdle && p != calibration.PhaseError {
+			if t.Calibration.Paused {
+				return "a paused calibration"
+			}
+			return "calibration in progress"
+		}
+	}
+	if enabled, err := c.api.GetAdapter(); err == nil && !enabled {
+		return "forced discharge in progress"
+	}
+	if r, err := c.api.GetDebugReport(); err == nil && !r.NextCalibration.IsZero() {
+		if until := time.Until(r.NextCalibration); until < upcomingCalibrationWindow {
+			return "calibration about to start"
+		}
+	}
+	return ""
+}
+
+// confirmUpgradeWhileBusy asks whether to upgrade the daemon although it is
+// busy for reason. It returns false if the upgrade is deferred, and then
+// notifies once the daemon is done.
+func (c *menuController) confirmUpgradeWhileBusy(reason string) bool {
+	alert := appkit.NewAlert()
+	alert.SetAlertStyle(appkit.AlertStyleWarning)
+	alert.SetMessageText("Upgrade the batt Daemon Later?")
+	alert.SetInformativeText(fmt.Sprintf(upgradeBusyAlertText, reason))
+	alert.AddButtonWithTitle("Notify Me When Done")
+	alert.AddButtonWithTitle("Upgrade Anyway")
+	if alert.RunModal() != appkit.AlertFirstButtonReturn {
+		logrus.WithField("reason", reason).Warn("Upgrading daemon although it is busy")
+		return true
+	}
+	c.deferUpgrade(reason)
+	return false
+}
+
+// deferUpgrade waits in the background until the daemon is no longer busy,
+// then offers the upgrade in a notification, which showNotificationWithURL
+// hands to the main thread.
+func (c *menuController) deferUpgrade(reason string) {
+	if c.upgradeDeferred.Swap(true) {
+		return
+	}
+	logrus.WithField("reason", reason).Info("Deferring daemon upgrade")
+	go func() {
+		defer c.upgradeDeferred.Store(false)
+		for c.daemonBusyReason() != "" {
+			time.Sleep(upgradeRecheckInterval)
+		}
+		if !isDaemonInstalled() {
+			return
+		}
+		logrus.Info("Daemon no longer busy, offering deferred upgrade")
+		showNotificationWithURL("batt Daemon Ready to Upgrade", "The daemon is done. Click to upgrade it now.", "batt://daemon/upgrade")
+	}()
+}
+
+// renderUpgradeItem shows on the Upgrade Daemon item why upgrading should
+// wait, if it should.
+func (c *menuController) renderUpgradeItem() {
+	reason := c.daemonBusyReason()
+	if reason == "" {
+		setItemTitle(c.upgradeItem, "Upgrade Daemon...")
+		setItemToolTip(c.upgradeItem, upgradeTooltip)
+		return
+	}
+	setItemTitle(c.upgradeItem, "Upgrade Daemon (Waiting: "+reason+")...")
+	setItemToolTip(c.upgradeItem, fmt.Sprintf(upgradeBusyTooltip, reason))
+}
@@ -0,0 +1,209 @@
+package gui
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"runtime/cgo"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/progrium/darwinkit/macos/appkit"
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/limits"
+)
+
+// #include <stdint.h>
+// // Implemented in urlscheme.m.
+// void *batt_attachURLHandler(uintptr_t handle);
+// void batt_releaseURLHandler(void *ptr);
+import "C"
+
+const (
+	urlScheme   = "batt"
+	releasesURL = "https://github.com/charlie0129/batt/releases"
+)
+
+// attachURLHandler registers the batt:// URL handler. Call releaseURLHandler to free.
+func attachURLHandler(h cgo.Handle) unsafe.Pointer {
+	return C.batt_attachURLHandler(C.uintptr_t(h))
+}
+
+func releaseURLHandler(ptr unsafe.Pointer) {
+	C.batt_releaseURLHandler(ptr)
+}
+
+// handleURL performs the action of a batt:// URL. Supported URLs:
+//
+//	batt://limit/<percentage>  set the charge limit (asks for confirmation)
+//	batt://charging/pause      pause charging (asks for confirmation)
+//	batt://charging/resume     resume charging
+//	batt://calibrate           start auto calibration (asks for confirmation)
+//	batt://status              show the status window
+//	batt://report[/<YYYY-MM>]  show the monthly report, by default of last month
+//	batt://prefs               show the status window
+//	batt://update/check        open the releases page
+//	batt://issue/<kind>        report a repeated error on GitHub
+//	batt://cli/repair          link the batt command to this app again
+//	batt://daemon/upgrade      upgrade the daemon to the version of this app
+//	                           (asks for confirmation)
+//	batt://menubar-icon/<show|hide|compact|regular>
+func (c *menuController) handleURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return pkgerrors.Wrap(err, "invalid URL")
+	}
+	if u.Scheme != urlScheme {
+		return fmt.Errorf("unsupported URL scheme: %s", u.Scheme)
+	}
+	action := u.Host
+	arg := strings.Trim(u.Path, "/")
+
+	switch action {
+	case "limit":
+		limit, err := strconv.Atoi(arg)
+		if err != nil {
+			return pkgerrors.Wrapf(err, "invalid limit %q", arg)
+		}
+		if err := limits.ValidateUpper(limit, 0); err != nil {
+			return err
+		}
+		if !confirmURLLimit(limit) {
+			logrus.Info("User cancelled setting the limit")
+			return nil
+		}
+		ret, err := c.api.SetLimit(limit)
+		if err != nil {
+			return pkgerrors.Wrapf(err, "failed to set limit: %s", ret)
+		}
+	case "charging":
+		var paused bool
+		switch arg {
+		case "pause":
+			paused = true
+		case "resume":
+			paused = false
+		default:
+			return fmt.Errorf("unknown charging action: %s", arg)
+		}
+		if paused && !confirmPauseCharging() {
+			logrus.Info("User cancelled pausing charging")
+			return nil
+		}
+		ret, err := c.api.SetChargingPaused(paused)
+		if err != nil {
+			return pkgerrors.Wrapf(err, "failed to %s charging: %s", arg, ret)
+		}
+		c.renderChargingPaused(paused)
+	case "calibrate":
+		if !confirmStartCalibration() {
+			logrus.Info("User cancelled auto calibration")
+			return nil
+		}
+		if _, err := c.api.StartCalibration(); err != nil {
+			return pkgerrors.Wrap(err, "failed to start calibration")
+		}
+	case "status", "prefs":
+		c.showStatusWindow()
+	case "report":
+		c.showMonthlyReport(arg)
+	case "update":
+		if arg != "check" {
+			return fmt.Errorf("unknown update action: %s", arg)
+		}
+		// batt has no built-in updater; point the user to the releases page.
+		if err := exec.Command("/usr/bin/open", releasesURL).Run(); err != nil {
+			return pkgerrors.Wrap(err, "failed to open releases page")
+		}
+	case "issue":
+		if _, ok := errorKindTitles[errorKind(arg)]; !ok {
+			return fmt.Errorf("unknown error kind: %s", arg)
+		}
+		if err := c.reportIssue(errorKind(arg)); err != nil {
+			return pkgerrors.Wrap(err, "failed to open issue page")
+		}
+	case "cli":
+		if arg != "repair" {
+			return fmt.Errorf("unknown cli action: %s", arg)
+		}
+		relinkCommand(filepath.Dir(symlinkLocation()))
+		c.renderCommandLineTool()
+	case "daemon":
+		if arg != "upgrade" {
+			return fmt.Errorf("unknown daemon action: %s", arg)
+		}
+		if !confirmURLUpgrade() {
+			logrus.Info("User cancelled upgrading the daemon")
+			return nil
+		}
+		c.upgradeDaemon()
+	case "menubar-icon":
+		switch arg {
+		case "show":
+			setBoolPref(prefHideMenubarIcon, false)
+		case "hide":
+			setBoolPref(prefHideMenubarIcon, true)
+		case "compact":
+			setBoolPref(prefCompactMenubarIcon, true)
+		case "regular":
+			setBoolPref(prefCompactMenubarIcon, false)
+		default:
+			return fmt.Errorf("unknown menubar-icon action: %s", arg)
+		}
+		c.applyMenubarPrefs()
+	default:
+		return fmt.Errorf("unknown action: %s", action)
+	}
+
+	return nil
+}
+
+// confirmURLLimit asks before a batt:// URL changes the charge limit, since
+// any web page or document can open one.
+func confirmURLLimit(limit int) bool {
+	alert := appkit.NewAlert()
+	alert.SetIcon(appkit.Image_ImageWithSystemSymbolNameAccessibilityDescription("battery.75", "charge limit"))
+	alert.SetAlertStyle(appkit.AlertStyleWarning)
+	alert.SetMessageText(fmt.Sprintf("Set Charge Limit to %d%%?", limit))
+	alert.SetInformativeText("A link asked batt to change your charge limit. Only continue if you opened it on purpose.")
+	alert.AddButtonWithTitle("Set Limit")
+	alert.AddButtonWithTitle("Cancel")
+	return alert.RunModal() == appkit.AlertFirstButtonReturn
+}
+
+// confirmURLUpgrade asks before a batt:// URL reinstalls the daemon, which
+// runs as root.
+func confirmURLUpgrade() bool {
+	alert := appkit.NewAlert()
+	alert.SetIcon(appkit.Image_ImageWithSystemSymbolNameAccessibilityDescription("arrow.up.circle", "upgrade daemon"))
+	alert.SetAlertStyle(appkit.AlertStyleWarning)
+	alert.SetMessageText("Upgrade the batt Daemon?")
+	alert.SetInformativeText("A link asked batt to install the daemon of this app over the running one. Only continue if you opened it on purpose.")
+	alert.AddButtonWithTitle("Upgrade")
+	alert.AddButtonWithTitle("Cancel")
+	return alert.RunModal() == appkit.AlertFirstButtonReturn
+}
+
+//export battOpenURL
+func battOpenURL(h C.uintptr_t, u *C.char) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("panic in battOpenURL: %v", r)
+		}
+	}()
+	raw := C.GoString(u)
+	handle := cgo.Handle(h)
+	if v := handle.Value(); v != nil {
+		if c, ok := v.(*menuController); ok {
+			logrus.WithField("url", raw).Info("Handling URL")
+			if err := c.handleURL(raw); err != nil {
+				logrus.WithError(err).WithField("url", raw).Error("Failed to handle URL")
+				showAlert("Failed to handle URL", err.Error())
+			}
+		}
+	}
+}
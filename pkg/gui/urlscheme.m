@@ -0,0 +1,41 @@
+#import <Cocoa/Cocoa.h>
+#import <CoreServices/CoreServices.h>
+#include <stdint.h>
+
+// Callback exported from Go
+extern void battOpenURL(uintptr_t handle, char *url);
+
+@interface BattURLHandler : NSObject
+@property(nonatomic, assign) uintptr_t handle;
+- (instancetype)initWithHandle:(uintptr_t)handle;
+@end
+
+@implementation BattURLHandler
+- (instancetype)initWithHandle:(uintptr_t)handle {
+    if ((self = [super init])) {
+        _handle = handle;
+    }
+    return self;
+}
+- (void)handleGetURL:(NSAppleEventDescriptor *)event withReplyEvent:(NSAppleEventDescriptor *)reply {
+    NSString *url = [[event paramDescriptorForKeyword:keyDirectObject] stringValue];
+    if (url == nil) return;
+    battOpenURL(_handle, (char *)[url UTF8String]);
+}
+@end
+
+void *batt_attachURLHandler(uintptr_t handle) {
+    BattURLHandler *h = [[BattURLHandler alloc] initWithHandle:handle];
+    [[NSAppleEventManager sharedAppleEventManager] setEventHandler:h
+                                                       andSelector:@selector(handleGetURL:withReplyEvent:)
+                                                     forEventClass:kInternetEventClass
+                                                        andEventID:kAEGetURL];
+    return (void *)CFBridgingRetain(h);
+}
+
+void batt_releaseURLHandler(void *ptr) {
+    if (ptr == NULL) return;
+    [[NSAppleEventManager sharedAppleEventManager] removeEventHandlerForEventClass:kInternetEventClass
+                                                                        andEventID:kAEGetURL];
+    CFRelease(ptr);
+}
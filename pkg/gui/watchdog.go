@@ -0,0 +1,139 @@
+package gui
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// #include <stdint.h>
+// // Implemented in watchdog.m.
+// void batt_watchdogPing(void);
+// uint64_t batt_watchdogBeats(void);
+import "C"
+
+const (
+	// watchdogInterval is how often the watchdog pings the main queue and
+	// checks for progress.
+	watchdogInterval = 2 * time.Second
+	// mainQueueDeadline is how long the main queue may not run a ping.
+	// Modal alerts keep running it, so this only trips if the main thread
+	// is blocked.
+	mainQueueDeadline = 10 * time.Second
+	// watchdogMainQueue is the name of the main queue in reports.
+	watchdogMainQueue = "main queue"
+	// maxStackDump bounds the stack dump written to the log.
+	maxStackDump = 1 << 20
+)
+
+// watchdog tracks when the main queue and subsystem goroutines last made
+// progress. Subsystems call watchdogBeat in their loops.
+var watchdog = struct {
+	sync.Mutex
+	last      map[string]time.Time
+	deadlines map[string]time.Duration
+	// stuck are the subsystems already reported, until they recover.
+	stuck map[string]bool
+}{
+	last:      map[string]time.Time{},
+	deadlines: map[string]time.Duration{},
+	stuck:     map[string]bool{},
+}
+
+// watchdogBeat records that the subsystem name made progress. It must beat
+// again within deadline. It does nothing unless built with -tags debug.
+func watchdogBeat(name string, deadline time.Duration) {
+	if !watchdogEnabled {
+		return
+	}
+	watchdog.Lock()
+	defer watchdog.Unlock()
+	watchdog.last[name] = time.Now()
+	watchdog.deadlines[name] = deadline
+}
+
+// newlyStuck returns the subsystems that have not made progress within their
+// deadline and were not reported yet, and forgets those that recovered.
+func newlyStuck(now time.Time) []string {
+	watchdog.Lock()
+	defer watchdog.Unlock()
+
+	var ret []string
+	for name, last := range watchdog.last {
+		late := now.Sub(last) > watchdog.deadlines[name]
+		switch {
+		case late && !watchdog.stuck[name]:
+			watchdog.stuck[name] = true
+			ret = append(ret, name)
+		case !late && watchdog.stuck[name]:
+			delete(watchdog.stuck, name)
+			logrus.WithField("subsystem", name).Warn("Watchdog: recovered")
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// runWatchdog pings the main queue and checks all subsystems until ctx is
+// canceled. It does nothing unless built with -tags debug.
+func runWatchdog(ctx context.Context) {
+	if !watchdogEnabled {
+		return
+	}
+	logrus.Info("Watchdog started")
+
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+
+	var beats C.uint64_t
+	watchdogBeat(watchdogMainQueue, mainQueueDeadline)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if b := C.batt_watchdogBeats(); b != beats {
+			beats = b
+			watchdogBeat(watchdogMainQueue, mainQueueDeadline)
+		}
+		C.batt_watchdogPing()
+
+		if stuck := newlyStuck(time.Now()); len(stuck) > 0 {
+			reportStuck(stuck)
+		}
+	}
+}
+
+// reportStuck dumps all goroutine stacks to the log and offers to relaunch.
+// The alert is shown by osascript, since the main thread may be the one
+// that is stuck.
+func reportStuck(stuck []string) {
+	buf := make([]byte, maxStackDump)
+	buf = buf[:runtime.Stack(buf, true)]
+	logrus.WithField("subsystems", stuck).Errorf("Watchdog: no progress, dumping all goroutines:\n%s", buf)
+
+	go func() {
+		script := `display alert "batt appears stuck" message "No progress in: ` + strings.Join(stuck, ", ") + `. All stacks were written to the log." as critical buttons {"Keep Waiting", "Relaunch"} default button "Keep Waiting"`
+		out, err := exec.Command("/usr/bin/osascript", "-e", script).Output()
+		if err != nil || !strings.Contains(string(out), "Relaunch") {
+			return
+		}
+		exe, err := os.Executable()
+		if err != nil {
+			os.Exit(1)
+		}
+		if bundle := appBundlePath(exe); bundle != "" {
+			_ = exec.Command("/usr/bin/open", "-n", bundle).Start()
+		}
+		os.Exit(1)
+	}()
+}
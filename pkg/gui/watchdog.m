@@ -0,0 +1,17 @@
+#import <Foundation/Foundation.h>
+#include <stdint.h>
+
+static volatile uint64_t battMainQueueBeats = 0;
+
+// batt_watchdogPing asks the main queue to count a beat. If the main thread
+// is stuck, the beat never happens.
+void batt_watchdogPing(void) {
+    dispatch_async(dispatch_get_main_queue(), ^{
+        battMainQueueBeats++;
+    });
+}
+
+// batt_watchdogBeats returns how many beats the main queue has counted.
+uint64_t batt_watchdogBeats(void) {
+    return battMainQueueBeats;
+}
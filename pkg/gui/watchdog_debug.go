@@ -0,0 +1,7 @@
+//go:build debug
+
+package gui
+
+// watchdogEnabled watches the main queue and subsystem goroutines, and
+// dumps all stacks when one of them stops making progress.
+const watchdogEnabled = true
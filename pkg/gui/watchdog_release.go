@@ -0,0 +1,5 @@
+//go:build !debug
+
+package gui
+
+const watchdogEnabled = false
@@ -0,0 +1,45 @@
+package gui
+
+import (
+	"os/exec"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/version"
+)
+
+// checkUpdated remembers the version of this run and reports whether
+// batt.app was updated since it last ran. Fresh installs and development
+// builds are not considered updates.
+func checkUpdated() bool {
+	if version.Version == "UNKNOWN" {
+		return false
+	}
+	previous := getStringPref(prefLastRunVersion)
+	if previous == version.Version {
+		return false
+	}
+	setStringPref(prefLastRunVersion, version.Version)
+	logrus.WithFields(logrus.Fields{
+		"previous": previous,
+		"current":  version.Version,
+	}).Info("First run of this version")
+	return previous != ""
+}
+
+// releaseNotesURL is the GitHub release page of the running version.
+func releaseNotesURL() string {
+	tag := version.Version
+	if !strings.HasPrefix(tag, "v") {
+		tag = "v" + tag
+	}
+	return releasesURL + "/tag/" + tag
+}
+
+func openReleaseNotes() {
+	if err := exec.Command("/usr/bin/open", releaseNotesURL()).Run(); err != nil {
+		logrus.WithError(err).Error("Failed to open release notes")
+		showAlert("Failed to open release notes", err.Error())
+	}
+}
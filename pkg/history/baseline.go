@@ -0,0 +1,174 @@
+package history
+
+// CurvePoint is the battery health at a cycle count.
+type CurvePoint struct {
+	Cycles int `json:"cycles"`
+	Health int `json:"health"`
+}
+
+// Baseline is the health a battery is expected to keep over its cycles.
+type Baseline struct {
+	// Name describes the Macs it applies to, e.g. "14-inch MacBook Pro (2021)".
+	Name   string       `json:"name"`
+	Points []CurvePoint `json:"points"`
+	// Source is where the curve comes from.
+	Source string `json:"source"`
+}
+
+// ratedCurve is Apple's rating for the batteries of all Apple silicon
+// notebooks: up to 80% of the original capacity at 1000 cycles. It is the
+// only curve published for them. Batteries usually keep more than that, so
+// it is a floor rather than an average.
+var ratedCurve = []CurvePoint{{Cycles: 0, Health: 100}, {Cycles: 1000, Health: 80}}
+
+const ratedSource = "Apple's rating of up to 80% of the original capacity at 1000 cycles"
+
+// modelNames maps hw.model identifiers to the names Apple sells them under.
+var modelNames = map[string]string{
+	"MacBookAir10,1": "MacBook Air (M1, 2020)",
+	"MacBookPro17,1": "13-inch MacBook Pro (M1, 2020)",
+	"MacBookPro18,1": "16-inch MacBook Pro (2021)",
+	"MacBookPro18,2": "16-inch MacBook Pro (2021)",
+	"MacBookPro18,3": "14-inch MacBook Pro (2021)",
+	"MacBookPro18,4": "14-inch MacBook Pro (2021)",
+	"Mac14,2":        "MacBook Air (M2, 2022)",
+	"Mac14,7":        "13-inch MacBook Pro (M2, 2022)",
+	"Mac14,5":        "14-inch MacBook Pro (2023)",
+	"Mac14,9":        "14-inch MacBook Pro (2023)",
+	"Mac14,6":        "16-inch MacBook Pro (2023)",
+	"Mac14,10":       "16-inch MacBook Pro (2023)",
+	"Mac14,15":       "15-inch MacBook Air (M2, 2023)",
+	"Mac15,3":        "14-inch MacBook Pro (M3, Nov 2023)",
+	"Mac15,6":        "14-inch MacBook Pro (Nov 2023)",
+	"Mac15,8":        "14-inch MacBook Pro (Nov 2023)",
+	"Mac15,10":       "14-inch MacBook Pro (Nov 2023)",
+	"Mac15,7":        "16-inch MacBook Pro (Nov 2023)",
+	"Mac15,9":        "16-inch MacBook Pro (Nov 2023)",
+	"Mac15,11":       "16-inch MacBook Pro (Nov 2023)",
+	"Mac15,12":       "13-inch MacBook Air (M3, 2024)",
+	"Mac15,13":       "15-inch MacBook Air (M3, 2024)",
+	"Mac16,1":        "14-inch MacBook Pro (M4, 2024)",
+	"Mac16,6":        "14-inch MacBook Pro (2024)",
+	"Mac16,8":        "14-inch MacBook Pro (2024)",
+	"Mac16,5":        "16-inch MacBook Pro (2024)",
+	"Mac16,7":        "16-inch MacBook Pro (2024)",
+	"Mac16,12":       "13-inch MacBook Air (M4, 2025)",
+	"Mac16,13":       "15-inch MacBook Air (M4, 2025)",
+}
+
+// BaselineFor returns the baseline of the hw.model identifier model.
+func BaselineFor(model string) Baseline {
+	name, ok := modelNames[model]
+	if !ok {
+		name = "this Mac"
+	}
+	return Baseline{Name: name, Points: ratedCurve, Source: ratedSource}
+}
+
+// Expected returns the health expected at cycles, interpolated between the
+// points and extrapolated from the last two past the end.
+func (b Baseline) Expected(cycles int) float64 {
+	p := b.Points
+	if len(p) == 0 {
+		return 100
+	}
+	if len(p) == 1 || cycles <= p[0].Cycles {
+		return float64(p[0].Health)
+	}
+	i := 1
+	for i < len(p)-1 && cycles > p[i].Cycles {
+		i++
+	}
+	a, z := p[i-1], p[i]
+	slope := float64(z.Health-a.Health) / float64(z.Cycles-a.Cycles)
+	return max(0, float64(a.Health)+slope*float64(cycles-a.Cycles))
+}
+
+const (
+	// TypicalMargin is how many percent of health around the baseline still
+	// count as typical. Health readings alone move by about that much.
+	TypicalMargin = 3
+	// MinComparableCycles is the cycle count below which a comparison says
+	// little, since new batteries often read above or below 100%.
+	MinComparableCycles = 50
+)
+
+// Verdicts of a Comparison.
+const (
+	VerdictBetter   = "better"
+	VerdictTypical  = "typical"
+	VerdictWorse    = "worse"
+	VerdictTooEarly = "too-early"
+)
+
+// Comparison compares the battery health with the baseline of the Mac model.
+type Comparison struct {
+	Model      string  `json:"model"`
+	CycleCount int     `json:"cycleCount"`
+	Health     int     `json:"health"`
+	Expected   float64 `json:"expected"`
+	// Difference is Health minus Expected, in percent of design capacity.
+	Difference float64  `json:"difference"`
+	Verdict    string   `json:"verdict"`
+	Baseline   Baseline `json:"baseline"`
+	// Curve is this battery's health over its cycles, from the history.
+	Curve []CurvePoint `json:"curve"`
+}
+
+// Compare compares health at cycles with the baseline of model.
+func Compare(model string, cycles, health int) Comparison {
+	b := BaselineFor(model)
+	c := Comparison{
+		Model:      model,
+		CycleCount: cycles,
+		Health:     health,
+		Expected:   b.Expected(cycles),
+		Baseline:   b,
+	}
+	c.Difference = float64(health) - c.Expected
+	switch {
+	case cycles < MinComparableCycles:
+		c.Verdict = VerdictTooEarly
+	case c.Difference > TypicalMargin:
+		c.Verdict = VerdictBetter
+	case c.Difference < -TypicalMargin:
+		c.Verdict = VerdictWorse
+	default:
+		c.Verdict = VerdictTypical
+	}
+	return c
+}
+
+// Summary describes the comparison in one sentence.
+func (c Comparison) Summary() string {
+	switch c.Verdict {
+	case VerdictTooEarly:
+		return "Too few cycles yet to compare with Apple's rating for " + c.Baseline.Name + "."
+	case VerdictBetter:
+		return "Better than Apple's rating for " + c.Baseline.Name + "."
+	case VerdictWorse:
+		return "Below Apple's rating for " + c.Baseline.Name + "."
+	default:
+		return "In line with Apple's rating for " + c.Baseline.Name + "."
+	}
+}
+
+// Caveats are shown with every comparison.
+const Caveats = `The baseline is ` + ratedSource + `, not measured from other Macs, and is the same for every model. Health readings drift by a few percent with temperature and battery calibration, and the first cycles say little about how a battery ages.`
+
+// Curve returns the health over the cycles of days, which must be in order,
+// with the last reading at each cycle count.
+func Curve(days []Day) []CurvePoint {
+	var ret []CurvePoint
+	for _, d := range days {
+		if d.CycleCount == 0 || d.Health == 0 {
+			continue
+		}
+		if n := len(ret); n > 0 && ret[n-1].Cycles == d.CycleCount {
+			ret[n-1].Health = d.Health
+			continue
+		}
+		ret = append(ret, CurvePoint{Cycles: d.CycleCount, Health: d.Health})
+	}
+	return ret
+}
@@ -0,0 +1,134 @@
+// Package history holds the daily usage records the daemon keeps, and the
+// statistics computed from them. Records never leave the Mac.
+package history
+
+import "time"
+
+// DayLayout is the time.Parse layout of Day.Date, in local time.
+const DayLayout = "2006-01-02"
+
+// HighCharge is the charge above which time is counted as spent at a high
+// charge, which ages the battery faster.
+const HighCharge = 80
+
+// Day sums up the usage of one day.
+type Day struct {
+	Date string `json:"date"`
+	// OnBatterySeconds and PluggedInSeconds are how long the Mac ran on
+	// battery and on the adapter. Time asleep is not counted.
+	OnBatterySeconds float64 `json:"onBatterySeconds"`
+	PluggedInSeconds float64 `json:"pluggedInSeconds"`
+	// HighChargeSeconds is how long the charge was above HighCharge.
+	HighChargeSeconds float64 `json:"highChargeSeconds"`
+	// HeldSeconds is how long batt held the charge at the limit while
+	// plugged in.
+	HeldSeconds float64 `json:"heldSeconds"`
+	// ChargeSum is the charge multiplied by the seconds at it, to average
+	// the charge over the day.
+	ChargeSum float64 `json:"chargeSum"`
+	// Discharges is how many times the Mac ran on battery, and
+	// DischargeDepth the sum of how many percent each of them used.
+	Discharges     int `json:"discharges"`
+	DischargeDepth int `json:"dischargeDepth"`
+	// CycleCount and Health are the last readings of the day, 0 if none.
+	// Health is the maximum capacity in percent of the design capacity.
+	CycleCount int `json:"cycleCount,omitempty"`
+	Health     int `json:"health,omitempty"`
+}
+
+// Seconds is the time counted on the day.
+func (d Day) Seconds() float64 {
+	return d.OnBatterySeconds + d.PluggedInSeconds
+}
+
+// Stats are the usage statistics over a number of days.
+type Stats struct {
+	// Since is the first day with data, zero if there is none.
+	Since time.Time `json:"since"`
+	Days  int       `json:"days"`
+
+	OnBatteryHours   float64 `json:"onBatteryHours"`
+	PluggedInHours   float64 `json:"pluggedInHours"`
+	HighChargeHours  float64 `json:"highChargeHours"`
+	HeldHours        float64 `json:"heldHours"`
+	AverageCharge    float64 `json:"averageCharge"`
+	Discharges       int     `json:"discharges"`
+	AverageDischarge float64 `json:"averageDischarge"`
+}
+
+// Summarize computes the statistics of days, which must be in order.
+func Summarize(days []Day) Stats {
+	var s Stats
+	var seconds, chargeSum float64
+	var depth int
+	for _, d := range days {
+		if d.Seconds() == 0 {
+			continue
+		}
+		if s.Since.IsZero() {
+			s.Since, _ = time.ParseInLocation(DayLayout, d.Date, time.Local)
+		}
+		s.Days++
+		s.OnBatteryHours += d.OnBatterySeconds / 3600
+		s.PluggedInHours += d.PluggedInSeconds / 3600
+		s.HighChargeHours += d.HighChargeSeconds / 3600
+		s.HeldHours += d.HeldSeconds / 3600
+		s.Discharges += d.Discharges
+		depth += d.DischargeDepth
+		seconds += d.Seconds()
+		chargeSum += d.ChargeSum
+	}
+	if seconds > 0 {
+		s.AverageCharge = chargeSum / seconds
+	}
+	if s.Discharges > 0 {
+		s.AverageDischarge = float64(depth) / float64(s.Discharges)
+	}
+	return s
+}
+
+// MonthLayout is the time.Parse layout of Report.Month.
+const MonthLayout = "2006-01"
+
+// Report sums up a month, like the Screen Time weekly summary.
+type Report struct {
+	Month string `json:"month"`
+	Stats
+	// CyclesAdded is how many charge cycles the battery went through.
+	CyclesAdded int `json:"cyclesAdded"`
+	// HealthStart and HealthEnd are the battery health at the start and the
+	// end of the month, 0 if unknown.
+	HealthStart int `json:"healthStart,omitempty"`
+	HealthEnd   int `json:"healthEnd,omitempty"`
+}
+
+// MonthReport sums up the days of month, given as in MonthLayout. The last
+// readings before the month are its starting point, so the cycles and health
+// change of its first day are counted too.
+func MonthReport(days []Day, month string) Report {
+	r := Report{Month: month}
+	var inMonth []Day
+	startCycles := 0
+	for _, d := range days {
+		switch {
+		case d.Date < month:
+			if d.CycleCount > 0 {
+				startCycles, r.HealthStart = d.CycleCount, d.Health
+			}
+		case len(d.Date) >= len(month) && d.Date[:len(month)] == month:
+			inMonth = append(inMonth, d)
+			if d.CycleCount > 0 {
+				if startCycles == 0 {
+					startCycles, r.HealthStart = d.CycleCount, d.Health
+				}
+				r.CyclesAdded = d.CycleCount - startCycles
+				r.HealthEnd = d.Health
+			}
+		}
+	}
+	if r.HealthEnd == 0 {
+		r.HealthStart = 0
+	}
+	r.Stats = Summarize(inMonth)
+	return r
+}
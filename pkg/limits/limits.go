@@ -0,0 +1,70 @@
+// Package limits validates charge limits and calibration settings. The daemon
+// enforces these rules, and the CLI and the GUI use the same ones to reject
+// bad input early, so every frontend reports the same errors.
+package limits
+
+import (
+	"fmt"
+)
+
+const (
+	// MinUpper is the lowest upper limit. Lower limits make little sense
+	// and risk deep discharges.
+	MinUpper = 10
+	// MaxUpper disables the charge limit.
+	MaxUpper = 100
+	// MinLower is the lowest the lower limit (upper limit minus the lower
+	// limit delta) may be.
+	MinLower = 10
+	// MinLowerDelta is the smallest lower limit delta. The lower limit must
+	// stay below the upper limit.
+	MinLowerDelta = 1
+
+	MinDischargeThreshold = 10
+	MaxDischargeThreshold = 50
+
+	MinHoldMinutes = 10
+	MaxHoldMinutes = 24 * 60
+)
+
+// Error is returned when a setting is out of its valid range. Frontends can
+// use Min and Max, e.g. to clamp a slider, instead of parsing the message.
+type Error struct {
+	Setting string
+	Value   int
+	Min     int
+	Max     int
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s must be between %d and %d, got %d", e.Setting, e.Min, e.Max, e.Value)
+}
+
+func checkRange(setting string, value, minimum, maximum int) error {
+	if value < minimum || value > maximum {
+		return &Error{Setting: setting, Value: value, Min: minimum, Max: maximum}
+	}
+	return nil
+}
+
+// ValidateUpper validates an upper limit, keeping the lower limit (the upper
+// limit minus lowerDelta) at least MinLower.
+func ValidateUpper(upper, lowerDelta int) error {
+	return checkRange("limit", upper, max(MinUpper, MinLower+lowerDelta), MaxUpper)
+}
+
+// ValidateLowerDelta validates the lower limit delta, i.e., the hysteresis
+// between the upper and lower limit, for the given upper limit.
+func ValidateLowerDelta(upper, lowerDelta int) error {
+	return checkRange("lower limit delta", lowerDelta, MinLowerDelta, upper-MinLower)
+}
+
+// ValidateDischargeThreshold validates the calibration discharge threshold.
+func ValidateDischargeThreshold(threshold int) error {
+	return checkRange("calibration discharge threshold", threshold, MinDischargeThreshold, MaxDischargeThreshold)
+}
+
+// ValidateHoldMinutes validates the calibration hold duration.
+func ValidateHoldMinutes(minutes int) error {
+	return checkRange("calibration hold duration (minutes)", minutes, MinHoldMinutes, MaxHoldMinutes)
+}
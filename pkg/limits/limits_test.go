@@ -0,0 +1,79 @@
+package limits
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateUpper(t *testing.T) {
+	tests := []struct {
+		upper, lowerDelta int
+		wantErr           bool
+	}{
+		{upper: 80, lowerDelta: 2},
+		{upper: 100, lowerDelta: 2},
+		{upper: 10, lowerDelta: 0},
+		{upper: 12, lowerDelta: 2},
+		{upper: 11, lowerDelta: 2, wantErr: true},
+		{upper: 9, lowerDelta: 0, wantErr: true},
+		{upper: 101, lowerDelta: 2, wantErr: true},
+	}
+	for _, tt := range tests {
+		err := ValidateUpper(tt.upper, tt.lowerDelta)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateUpper(%d, %d) error = %v, wantErr %t", tt.upper, tt.lowerDelta, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateLowerDelta(t *testing.T) {
+	tests := []struct {
+		upper, lowerDelta int
+		wantErr           bool
+	}{
+		{upper: 80, lowerDelta: 1},
+		{upper: 80, lowerDelta: 70},
+		{upper: 80, lowerDelta: 0, wantErr: true},
+		{upper: 80, lowerDelta: -1, wantErr: true},
+		{upper: 80, lowerDelta: 71, wantErr: true},
+		{upper: 10, lowerDelta: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		err := ValidateLowerDelta(tt.upper, tt.lowerDelta)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateLowerDelta(%d, %d) error = %v, wantErr %t", tt.upper, tt.lowerDelta, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateCalibration(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr bool
+	}{
+		{name: "threshold min", err: ValidateDischargeThreshold(MinDischargeThreshold)},
+		{name: "threshold max", err: ValidateDischargeThreshold(MaxDischargeThreshold)},
+		{name: "threshold too low", err: ValidateDischargeThreshold(MinDischargeThreshold - 1), wantErr: true},
+		{name: "threshold too high", err: ValidateDischargeThreshold(MaxDischargeThreshold + 1), wantErr: true},
+		{name: "hold min", err: ValidateHoldMinutes(MinHoldMinutes)},
+		{name: "hold max", err: ValidateHoldMinutes(MaxHoldMinutes)},
+		{name: "hold too short", err: ValidateHoldMinutes(MinHoldMinutes - 1), wantErr: true},
+		{name: "hold too long", err: ValidateHoldMinutes(MaxHoldMinutes + 1), wantErr: true},
+	}
+	for _, tt := range tests {
+		if (tt.err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %t", tt.name, tt.err, tt.wantErr)
+		}
+	}
+}
+
+func TestErrorRange(t *testing.T) {
+	var lerr *Error
+	if !errors.As(ValidateLowerDelta(80, 0), &lerr) {
+		t.Fatal("ValidateLowerDelta() should return *Error")
+	}
+	if lerr.Min != MinLowerDelta || lerr.Max != 70 {
+		t.Errorf("range = [%d, %d], want [%d, 70]", lerr.Min, lerr.Max, MinLowerDelta)
+	}
+}
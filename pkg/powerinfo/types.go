@@ -1,5 +1,7 @@
 package powerinfo
 
+import "time"
+
 // BatteryState represents the charging state of the battery.
 type BatteryState int
 
@@ -18,11 +20,16 @@ const (
 // - Design: mAh
 // - ChargeRate: mW (may be negative when discharging)
 // - DesignVoltage: Volts
+// - SystemCharge: percent
 type Battery struct {
 	State         BatteryState `json:"State"`
 	Design        int          `json:"Design"`
 	ChargeRate    int          `json:"ChargeRate"`
 	DesignVoltage float64      `json:"DesignVoltage"`
+	// SystemCharge is the charge as shown by the macOS battery indicator,
+	// which often differs from the raw SMC value by a few percent. It is
+	// 0 from older daemons.
+	SystemCharge int `json:"SystemCharge,omitempty"`
 }
 
 // PowerTelemetry holds a simplified snapshot used by the GUI.
@@ -42,3 +49,37 @@ type PowerTelemetry struct {
 		HealthByMaxCapacity int     `json:"HealthByMaxCapacity"`
 	} `json:"Calculations"`
 }
+
+// Temperature is the reading of an SMC temperature sensor.
+type Temperature struct {
+	// Sensor is the SMC key, e.g. "TB0T".
+	Sensor  string  `json:"sensor"`
+	Name    string  `json:"name"`
+	Celsius float64 `json:"celsius"`
+	// Limit is the temperature above which charging stops, 0 if none.
+	Limit int `json:"limit,omitempty"`
+	// TooHot is true while this sensor keeps charging disabled.
+	TooHot bool `json:"tooHot,omitempty"`
+}
+
+// ProcessEnergy is the energy a process used while on battery.
+type ProcessEnergy struct {
+	Name string `json:"name"`
+	// Energy is in joules.
+	Energy float64 `json:"energy"`
+	// AveragePower is Energy divided by the sampled time on battery, in watts.
+	AveragePower float64 `json:"averagePower"`
+}
+
+// EnergyImpactReport lists the processes that used the most energy during
+// the current (or last) discharging session.
+type EnergyImpactReport struct {
+	// Enabled is true if energy sampling is turned on.
+	Enabled bool `json:"enabled"`
+	// Since is when the discharging session started. Zero if nothing was
+	// sampled yet.
+	Since time.Time `json:"since"`
+	// Duration is the sampled time on battery, in seconds.
+	Duration  float64         `json:"duration"`
+	Processes []ProcessEnergy `json:"processes"`
+}
@@ -40,3 +40,74 @@ var allKeys = []string{
 	BatteryVoltageKey,
 	BatteryPowerKey,
 }
+
+// keyDescriptions are human-readable descriptions of allKeys.
+var keyDescriptions = map[string]string{
+	MagSafeLedKey:     "MagSafe LED",
+	ACPowerKey:        "AC power",
+	ChargingKey1:      "Charging control",
+	ChargingKey2:      "Charging control",
+	ChargingKey3:      "Charging control (Tahoe firmware)",
+	AdapterKey1:       "Adapter control",
+	AdapterKey2:       "Adapter control",
+	AdapterKey3:       "Adapter control (Tahoe firmware)",
+	BatteryChargeKey:  "Battery charge",
+	DCInCurrentKey:    "DC in current",
+	DCInVoltageKey:    "DC in voltage",
+	DCInPowerKey:      "DC in power",
+	BatteryCurrentKey: "Battery current",
+	BatteryVoltageKey: "Battery voltage",
+	BatteryPowerKey:   "Battery power",
+}
+
+// temperatureSensors are the temperature keys read for the thermal limits.
+// Keys a Mac does not have are skipped. Ts0P (palm rest) and TCHP (charger)
+// may be added once verified on Apple Silicon.
+var temperatureSensors = []TemperatureSensor{
+	{Key: "TB0T", Name: "Battery"},
+}
+
+// chargingStrategies are tried in order; the first one whose keys all exist
+// is used.
+var chargingStrategies = []Strategy{
+	{
+		Name:        "ch0b-ch0c",
+		Description: "Apple Silicon, pre-Tahoe firmware",
+		Keys:        []string{ChargingKey1, ChargingKey2},
+		Enabled:     []byte{0x00},
+		Disabled:    []byte{0x02},
+	},
+	{
+		Name:        "chte",
+		Description: "Apple Silicon, Tahoe firmware",
+		Keys:        []string{ChargingKey3},
+		Enabled:     []byte{0x00, 0x00, 0x00, 0x00},
+		Disabled:    []byte{0x01, 0x00, 0x00, 0x00},
+	},
+}
+
+// adapterStrategies are tried in order; the first one whose key exists is
+// used.
+var adapterStrategies = []Strategy{
+	{
+		Name:        "ch0i",
+		Description: "Apple Silicon, pre-Tahoe firmware",
+		Keys:        []string{AdapterKey1},
+		Enabled:     []byte{0x00},
+		Disabled:    []byte{0x01},
+	},
+	{
+		Name:        "ch0j",
+		Description: "Apple Silicon, pre-Tahoe firmware",
+		Keys:        []string{AdapterKey2},
+		Enabled:     []byte{0x00},
+		Disabled:    []byte{0x01},
+	},
+	{
+		Name:        "chie",
+		Description: "Apple Silicon, Tahoe firmware",
+		Keys:        []string{AdapterKey3},
+		Enabled:     []byte{0x00},
+		Disabled:    []byte{0x08},
+	},
+}
@@ -0,0 +1,115 @@
+package smc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/charlie0129/batt/pkg/utils/ptr"
+)
+
+// Scenario scripts a Simulation, e.g. unplugging the adapter after a while,
+// for developing and testing clients against a simulated daemon.
+type Scenario struct {
+	// Charge is the initial charge in percent, 0 keeps the default.
+	Charge int `json:"charge,omitempty"`
+	// Unplugged starts without a power adapter.
+	Unplugged bool           `json:"unplugged,omitempty"`
+	Steps     []ScenarioStep `json:"steps,omitempty"`
+	// Repeat starts over from the first step after the last one.
+	Repeat bool `json:"repeat,omitempty"`
+}
+
+// ScenarioStep changes the simulated battery. Unset fields are kept.
+type ScenarioStep struct {
+	// AfterMinutes is the simulated time since the previous step, or since
+	// the start for the first step.
+	AfterMinutes float64 `json:"afterMinutes"`
+	PluggedIn    *bool   `json:"pluggedIn,omitempty"`
+	Charge       *int    `json:"charge,omitempty"`
+}
+
+func (s ScenarioStep) after() time.Duration {
+	return time.Duration(s.AfterMinutes * float64(time.Minute))
+}
+
+// Scenarios are the built-in scenarios by name.
+var Scenarios = map[string]Scenario{
+	"charging": {
+		Charge: 20,
+	},
+	"draining": {
+		Charge:    90,
+		Unplugged: true,
+	},
+	"unplug-replug": {
+		Charge: 80,
+		Steps: []ScenarioStep{
+			{AfterMinutes: 15, PluggedIn: ptr.To(false)},
+			{AfterMinutes: 30, PluggedIn: ptr.To(true)},
+		},
+	},
+	"adapter-flapping": {
+		Charge: 60,
+		Steps: []ScenarioStep{
+			{AfterMinutes: 1, PluggedIn: ptr.To(false)},
+			{AfterMinutes: 1, PluggedIn: ptr.To(true)},
+		},
+		Repeat: true,
+	},
+}
+
+// ScenarioNames returns the names of the built-in scenarios, sorted.
+func ScenarioNames() []string {
+	names := make([]string, 0, len(Scenarios))
+	for name := range Scenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadScenario returns the built-in scenario of the name, or reads it from
+// the JSON file at the path otherwise.
+func LoadScenario(nameOrPath string) (*Scenario, error) {
+	if sc, ok := Scenarios[nameOrPath]; ok {
+		return &sc, nil
+	}
+
+	data, err := os.ReadFile(nameOrPath)
+	if err != nil {
+		return nil, fmt.Errorf("not a built-in scenario (%v) or a readable file: %w", ScenarioNames(), err)
+	}
+	var sc Scenario
+	if err := json.Unmarshal(data, &sc); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario %s: %w", nameOrPath, err)
+	}
+	if err := sc.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", nameOrPath, err)
+	}
+	return &sc, nil
+}
+
+// Validate checks the scenario for values the simulation cannot run.
+func (sc *Scenario) Validate() error {
+	if sc.Charge < 0 || sc.Charge > 100 {
+		return fmt.Errorf("charge %d is out of range 0-100", sc.Charge)
+	}
+	var total float64
+	for i, step := range sc.Steps {
+		if step.AfterMinutes < 0 {
+			return fmt.Errorf("step %d: afterMinutes must not be negative", i+1)
+		}
+		if step.Charge != nil && (*step.Charge < 0 || *step.Charge > 100) {
+			return fmt.Errorf("step %d: charge %d is out of range 0-100", i+1, *step.Charge)
+		}
+		total += step.AfterMinutes
+	}
+	if sc.Repeat && total <= 0 {
+		return errors.New("a repeating scenario must take some time")
+	}
+	return nil
+}
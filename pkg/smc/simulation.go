@@ -0,0 +1,243 @@
+package smc
+
+import (
+	"bytes"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/charlie0129/gosmc"
+	"github.com/sirupsen/logrus"
+)
+
+// SimulationOptions configures a Simulation. Zero values take defaults.
+type SimulationOptions struct {
+	// Charge is the initial battery charge in percent. Defaults to 50.
+	Charge int
+	// Unplugged starts the simulation without a power adapter.
+	Unplugged bool
+	// ChargeRate is how fast the battery charges, in percent per minute.
+	// Defaults to 1.
+	ChargeRate float64
+	// DrainRate is how fast the battery drains without an adapter, in
+	// percent per minute. Defaults to 0.5.
+	DrainRate float64
+	// Speed makes simulated time pass faster than wall time, e.g. 60
+	// simulates an hour per minute. Defaults to 1.
+	Speed float64
+	// Scenario scripts the simulation, optional. Its initial state takes
+	// precedence over Charge and Unplugged.
+	Scenario *Scenario
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// Simulation is a battery model behind a simulated SMC connection, to try
+// out charging policies without touching the real SMC. It has the keys of
+// an Apple Silicon Mac with pre-Tahoe firmware. Writes are logged and
+// change the model instead of the hardware.
+type Simulation struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	opts SimulationOptions
+
+	charge    float64
+	pluggedIn bool
+	last      time.Time
+
+	// elapsed is the simulated time since the start.
+	elapsed time.Duration
+	// nextStep is the index of the next scenario step, due at nextStepAt.
+	nextStep   int
+	nextStepAt time.Duration
+}
+
+// NewSimulation returns a new Simulation. Charging and the adapter start
+// enabled.
+func NewSimulation(opts SimulationOptions) *Simulation {
+	if opts.Charge == 0 {
+		opts.Charge = 50
+	}
+	if opts.ChargeRate == 0 {
+		opts.ChargeRate = 1
+	}
+	if opts.DrainRate == 0 {
+		opts.DrainRate = 0.5
+	}
+	if opts.Speed == 0 {
+		opts.Speed = 1
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	s := &Simulation{
+		data: map[string][]byte{
+			MagSafeLedKey: {byte(LEDSystem)},
+			ChargingKey1:  {0x00},
+			ChargingKey2:  {0x00},
+			AdapterKey1:   {0x00},
+		},
+		opts:      opts,
+		charge:    float64(opts.Charge),
+		pluggedIn: !opts.Unplugged,
+		last:      opts.Now(),
+	}
+	if sc := opts.Scenario; sc != nil {
+		if sc.Charge != 0 {
+			s.charge = float64(sc.Charge)
+		}
+		s.pluggedIn = !sc.Unplugged
+		if len(sc.Steps) > 0 {
+			s.nextStepAt = sc.Steps[0].after()
+			s.applyDueSteps()
+		}
+	}
+	return s
+}
+
+// NewSimulated returns an AppleSMC backed by the simulation instead of the
+// real SMC.
+func NewSimulated(sim *Simulation) *AppleSMC {
+	return &AppleSMC{
+		conn:         sim,
+		capabilities: make(map[string]bool),
+	}
+}
+
+// SetPluggedIn connects or disconnects the simulated power adapter.
+func (s *Simulation) SetPluggedIn(pluggedIn bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.advance()
+	s.pluggedIn = pluggedIn
+	logrus.WithField("pluggedIn", pluggedIn).Info("simulated power adapter changed")
+}
+
+// advance updates the charge for the time passed since the last call,
+// applying scenario steps on the way.
+func (s *Simulation) advance() {
+	now := s.opts.Now()
+	d := time.Duration(float64(now.Sub(s.last)) * s.opts.Speed)
+	s.last = now
+
+	for d > 0 {
+		step := d
+		if s.hasNextStep() && s.nextStepAt-s.elapsed < step {
+			step = s.nextStepAt - s.elapsed
+		}
+		s.drift(step.Minutes())
+		s.elapsed += step
+		d -= step
+		s.applyDueSteps()
+	}
+}
+
+func (s *Simulation) hasNextStep() bool {
+	return s.opts.Scenario != nil && s.nextStep < len(s.opts.Scenario.Steps)
+}
+
+// applyDueSteps applies the scenario steps that are due.
+func (s *Simulation) applyDueSteps() {
+	sc := s.opts.Scenario
+	for s.hasNextStep() && s.nextStepAt <= s.elapsed {
+		step := sc.Steps[s.nextStep]
+		if step.PluggedIn != nil && *step.PluggedIn != s.pluggedIn {
+			s.pluggedIn = *step.PluggedIn
+			logrus.WithField("pluggedIn", s.pluggedIn).Info("simulated power adapter changed")
+		}
+		if step.Charge != nil {
+			s.charge = float64(*step.Charge)
+			logrus.WithField("charge", *step.Charge).Info("simulated battery charge changed")
+		}
+
+		s.nextStep++
+		if s.nextStep == len(sc.Steps) && sc.Repeat {
+			s.nextStep = 0
+		}
+		if s.hasNextStep() {
+			s.nextStepAt += sc.Steps[s.nextStep].after()
+		}
+	}
+}
+
+// drift charges or drains the battery for the simulated minutes.
+func (s *Simulation) drift(minutes float64) {
+	adapter := s.pluggedIn && bytes.Equal(s.data[AdapterKey1], []byte{0x00})
+	charging := bytes.Equal(s.data[ChargingKey1], []byte{0x00})
+	switch {
+	case adapter && charging:
+		s.charge = math.Min(100, s.charge+s.opts.ChargeRate*minutes)
+	case !adapter:
+		s.charge = math.Max(0, s.charge-s.opts.DrainRate*minutes)
+	default:
+		// The adapter powers the Mac and the charge is held.
+	}
+}
+
+// Open implements gosmc.Connection.
+func (s *Simulation) Open() error {
+	logrus.Warn("using a simulated battery, the SMC is not touched")
+	return nil
+}
+
+// Close implements gosmc.Connection.
+func (s *Simulation) Close() error {
+	return nil
+}
+
+// Read implements gosmc.Connection.
+func (s *Simulation) Read(key string) (gosmc.SMCVal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.advance()
+
+	var v []byte
+	switch key {
+	case BatteryChargeKey:
+		v = []byte{byte(s.charge)}
+	case ACPowerKey:
+		v = []byte{0x00}
+		if s.pluggedIn {
+			v = []byte{0x01}
+		}
+	default:
+		var ok bool
+		v, ok = s.data[key]
+		if !ok {
+			return gosmc.SMCVal{}, gosmc.ErrNoData
+		}
+	}
+
+	return gosmc.SMCVal{
+		Key:      key,
+		DataType: "hex_",
+		Bytes:    bytes.Clone(v),
+	}, nil
+}
+
+// Write implements gosmc.Connection.
+func (s *Simulation) Write(key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Account for the time before the write with the old state.
+	s.advance()
+
+	if _, ok := s.data[key]; !ok {
+		return gosmc.ErrNoData
+	}
+	if !bytes.Equal(s.data[key], value) {
+		logrus.WithFields(logrus.Fields{
+			"key":    key,
+			"what":   keyDescriptions[key],
+			"val":    value,
+			"charge": int(s.charge),
+		}).Info("simulated SMC write")
+	}
+	s.data[key] = bytes.Clone(value)
+
+	return nil
+}
@@ -3,6 +3,8 @@ package smc
 import (
 	"github.com/charlie0129/gosmc"
 	"github.com/sirupsen/logrus"
+
+	"github.com/charlie0129/batt/pkg/capability"
 )
 
 // AppleSMC is a wrapper of gosmc.Connection.
@@ -11,6 +13,11 @@ type AppleSMC struct {
 	// capabilities is a map of SMC keys and their availability. Cached
 	// after Open() call to avoid unnecessary SMC reads.
 	capabilities map[string]bool
+	// chargingStrategy and adapterStrategy are selected from the probed
+	// capabilities in Open(), unless overridden. nil means unsupported.
+	chargingStrategy   *Strategy
+	adapterStrategy    *Strategy
+	strategyOverridden bool
 }
 
 // New returns a new AppleSMC.
@@ -48,9 +55,32 @@ func (c *AppleSMC) Open() error {
 		c.capabilities[key] = c.test(key)
 	}
 
+	// Errors are only returned for overrides.
+	c.chargingStrategy, _ = selectStrategy(chargingStrategies, c.capabilities, "")
+	c.adapterStrategy, _ = selectStrategy(adapterStrategies, c.capabilities, "")
+
 	return nil
 }
 
+// Capabilities returns the SMC keys probed in Open(), in probing order, and
+// whether they exist on this Mac.
+func (c *AppleSMC) Capabilities() []capability.Key {
+	keys := make([]capability.Key, 0, len(allKeys))
+	for _, key := range allKeys {
+		keys = append(keys, capability.Key{
+			Key:         key,
+			Description: keyDescriptions[key],
+			Available:   c.capabilities[key],
+		})
+	}
+	return keys
+}
+
+// IsAdapterControlCapable returns whether the adapter can be enabled and disabled.
+func (c *AppleSMC) IsAdapterControlCapable() bool {
+	return c.adapterStrategy != nil
+}
+
 // Close closes the connection.
 func (c *AppleSMC) Close() error {
 	return c.conn.Close()
@@ -0,0 +1,117 @@
+package smc
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Strategy describes how to toggle a feature (charging or the adapter) with
+// SMC keys. Different Mac models and firmware generations use different keys
+// and values, so strategies are kept in data-driven tables (see
+// chargingStrategies and adapterStrategies) instead of being hard-coded.
+type Strategy struct {
+	Name        string
+	Description string
+	// Keys must all exist for the strategy to be usable. All of them are
+	// written; the first one is read to get the current state.
+	Keys     []string
+	Enabled  []byte
+	Disabled []byte
+}
+
+func (s *Strategy) usable(capabilities map[string]bool) bool {
+	for _, key := range s.Keys {
+		if !capabilities[key] {
+			return false
+		}
+	}
+	return len(s.Keys) > 0
+}
+
+// selectStrategy returns the strategy named override, or the first usable
+// strategy if override is empty. It returns nil if none is usable. An
+// override must still be usable: writing keys this Mac does not have would
+// silently do nothing.
+func selectStrategy(strategies []Strategy, capabilities map[string]bool, override string) (*Strategy, error) {
+	if override != "" {
+		for i := range strategies {
+			if strategies[i].Name != override {
+				continue
+			}
+			if !strategies[i].usable(capabilities) {
+				return nil, fmt.Errorf("strategy %q is not supported on this Mac, it needs SMC keys %v", override, strategies[i].Keys)
+			}
+			return &strategies[i], nil
+		}
+		return nil, fmt.Errorf("unknown strategy %q", override)
+	}
+	for i := range strategies {
+		if strategies[i].usable(capabilities) {
+			return &strategies[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *AppleSMC) readStrategy(s *Strategy) (bool, error) {
+	v, err := c.Read(s.Keys[0])
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(v.Bytes, s.Enabled), nil
+}
+
+func (c *AppleSMC) writeStrategy(s *Strategy, enabled bool) error {
+	value := s.Disabled
+	if enabled {
+		value = s.Enabled
+	}
+	for _, key := range s.Keys {
+		if err := c.Write(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetStrategyOverrides forces the named charging and adapter strategies
+// instead of auto-selecting them by the probed keys. Empty names keep the
+// auto-selected strategy. Must be called after Open().
+func (c *AppleSMC) SetStrategyOverrides(charging, adapter string) error {
+	if charging == "" && adapter == "" {
+		return nil
+	}
+	// Validate both before applying either, so an invalid override does not
+	// leave the other one half-applied.
+	chargingStrategy, adapterStrategy := c.chargingStrategy, c.adapterStrategy
+	if charging != "" {
+		s, err := selectStrategy(chargingStrategies, c.capabilities, charging)
+		if err != nil {
+			return fmt.Errorf("invalid charging strategy: %w", err)
+		}
+		chargingStrategy = s
+	}
+	if adapter != "" {
+		s, err := selectStrategy(adapterStrategies, c.capabilities, adapter)
+		if err != nil {
+			return fmt.Errorf("invalid adapter strategy: %w", err)
+		}
+		adapterStrategy = s
+	}
+	c.chargingStrategy, c.adapterStrategy = chargingStrategy, adapterStrategy
+	c.strategyOverridden = true
+
+	logrus.WithFields(logrus.Fields{
+		"charging": charging,
+		"adapter":  adapter,
+	}).Info("SMC strategy overridden")
+	return nil
+}
+
+// Strategies returns the active charging and adapter strategies, either of
+// which may be nil if unsupported, and whether they were overridden.
+func (c *AppleSMC) Strategies() (charging, adapter *Strategy, overridden bool) {
+	return c.chargingStrategy, c.adapterStrategy, c.strategyOverridden
+}
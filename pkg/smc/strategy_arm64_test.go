@@ -0,0 +1,59 @@
+package smc
+
+import "testing"
+
+func TestSelectStrategy(t *testing.T) {
+	tests := []struct {
+		name         string
+		capabilities map[string]bool
+		override     string
+		want         string
+		wantErr      bool
+	}{
+		{name: "pre-Tahoe", capabilities: map[string]bool{ChargingKey1: true, ChargingKey2: true}, want: "ch0b-ch0c"},
+		{name: "Tahoe", capabilities: map[string]bool{ChargingKey3: true}, want: "chte"},
+		// Unlike before the strategy tables, one of CH0B and CH0C alone is
+		// not enough: both are written to stop charging.
+		{name: "only CH0B", capabilities: map[string]bool{ChargingKey1: true}, want: ""},
+		{name: "override", capabilities: map[string]bool{ChargingKey1: true, ChargingKey2: true, ChargingKey3: true}, override: "chte", want: "chte"},
+		{name: "override with missing keys", capabilities: map[string]bool{ChargingKey3: true}, override: "ch0b-ch0c", wantErr: true},
+		{name: "unknown override", capabilities: map[string]bool{ChargingKey3: true}, override: "nope", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := selectStrategy(chargingStrategies, tt.capabilities, tt.override)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectStrategy() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			got := ""
+			if s != nil {
+				got = s.Name
+			}
+			if got != tt.want {
+				t.Errorf("selectStrategy() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetStrategyOverridesIsAtomic(t *testing.T) {
+	capabilities := map[string]bool{ChargingKey1: true, ChargingKey2: true, ChargingKey3: true, AdapterKey1: true}
+	c := &AppleSMC{capabilities: capabilities}
+	c.chargingStrategy, _ = selectStrategy(chargingStrategies, capabilities, "")
+	c.adapterStrategy, _ = selectStrategy(adapterStrategies, capabilities, "")
+
+	if err := c.SetStrategyOverrides("chte", "chie"); err == nil {
+		t.Fatal("SetStrategyOverrides() should reject an adapter strategy whose key is missing")
+	}
+	charging, adapter, overridden := c.Strategies()
+	if charging.Name != "ch0b-ch0c" || adapter.Name != "ch0i" || overridden {
+		t.Errorf("Strategies() = %q, %q, %t, want the auto-selected ones", charging.Name, adapter.Name, overridden)
+	}
+
+	if err := c.SetStrategyOverrides("chte", ""); err != nil {
+		t.Fatalf("SetStrategyOverrides() error = %v", err)
+	}
+	if charging, _, overridden := c.Strategies(); charging.Name != "chte" || !overridden {
+		t.Errorf("Strategies() = %q, %t, want the chte override", charging.Name, overridden)
+	}
+}
@@ -0,0 +1,63 @@
+package smc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/charlie0129/gosmc"
+	"github.com/sirupsen/logrus"
+)
+
+// TemperatureSensor is an SMC temperature key.
+type TemperatureSensor struct {
+	Key  string
+	Name string
+}
+
+// TemperatureSensors returns the temperature keys batt knows about.
+func TemperatureSensors() []TemperatureSensor {
+	return temperatureSensors
+}
+
+// decodeTemperature converts an SMC temperature value to degrees Celsius.
+// Apple Silicon uses little-endian floats, Intel Macs signed 8.8 fixed point.
+func decodeTemperature(v gosmc.SMCVal) (float64, error) {
+	switch strings.TrimSpace(v.DataType) {
+	case "flt":
+		if len(v.Bytes) != 4 {
+			return 0, fmt.Errorf("incorrect data length %d!=4", len(v.Bytes))
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(v.Bytes))), nil
+	case "sp78":
+		if len(v.Bytes) != 2 {
+			return 0, fmt.Errorf("incorrect data length %d!=2", len(v.Bytes))
+		}
+		return float64(int16(binary.BigEndian.Uint16(v.Bytes))) / 256, nil
+	default:
+		return 0, fmt.Errorf("unsupported temperature data type %q", v.DataType)
+	}
+}
+
+// GetTemperatures returns the readings of the temperature sensors this Mac
+// has, in degrees Celsius, keyed by SMC key.
+func (c *AppleSMC) GetTemperatures() map[string]float64 {
+	logrus.Tracef("GetTemperatures called")
+
+	ret := make(map[string]float64)
+	for _, s := range temperatureSensors {
+		v, err := c.Read(s.Key)
+		if err != nil {
+			continue
+		}
+		t, err := decodeTemperature(v)
+		if err != nil {
+			logrus.WithError(err).WithField("key", s.Key).Debug("failed to decode temperature")
+			continue
+		}
+		ret[s.Key] = t
+	}
+
+	return ret
+}
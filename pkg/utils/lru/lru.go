@@ -0,0 +1,113 @@
+// Package lru implements a cache that holds up to a fixed number of entries
+// and evicts the least recently used first, so long-running processes such
+// as the menubar app do not grow without bound.
+package lru
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Stats describe how well a cache works, for diagnostics.
+type Stats struct {
+	Name      string `json:"name"`
+	Len       int    `json:"len"`
+	Cap       int    `json:"cap"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+}
+
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// Cache is a least recently used cache. It is safe for concurrent use.
+type Cache[K comparable, V any] struct {
+	mu    sync.Mutex
+	ll    *list.List
+	items map[K]*list.Element
+	stats Stats
+}
+
+// New returns a cache named name, which holds up to capacity entries.
+func New[K comparable, V any](name string, capacity int) *Cache[K, V] {
+	return &Cache[K, V]{
+		ll:    list.New(),
+		items: map[K]*list.Element{},
+		stats: Stats{Name: name, Cap: max(capacity, 1)},
+	}
+}
+
+// Get returns the value of key and marks it as recently used.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		c.stats.Hits++
+		c.ll.MoveToFront(e)
+		return e.Value.(*entry[K, V]).value, true
+	}
+	c.stats.Misses++
+	var zero V
+	return zero, false
+}
+
+// Add sets the value of key, evicting the least recently used entry if the
+// cache is full.
+func (c *Cache[K, V]) Add(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		e.Value.(*entry[K, V]).value = value
+		c.ll.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&entry[K, V]{key: key, value: value})
+	if c.ll.Len() > c.stats.Cap {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*entry[K, V]).key)
+		c.stats.Evictions++
+	}
+}
+
+// Remove removes key, if present.
+func (c *Cache[K, V]) Remove(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		c.ll.Remove(e)
+		delete(c.items, key)
+	}
+}
+
+// Purge removes all entries. The stats are kept.
+func (c *Cache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ll.Init()
+	clear(c.items)
+}
+
+// Len returns the number of entries.
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// Stats returns the stats of the cache.
+func (c *Cache[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.stats
+	s.Len = c.ll.Len()
+	return s
+}
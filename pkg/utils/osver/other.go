@@ -0,0 +1,9 @@
+//go:build !darwin || !cgo
+
+package osver
+
+// Get returns the zero Version, since there is no macOS version to read.
+// This lets the version comparisons build and be tested on other platforms.
+func Get() Version {
+	return Version{}
+}
@@ -0,0 +1,43 @@
+//go:build darwin && cgo
+
+package procusage
+
+/*
+#include <stdint.h>
+#include <unistd.h>
+#include <libproc.h>
+#include <sys/resource.h>
+
+static int batt_selfUsage(uint64_t *wakeups, uint64_t *energy) {
+	struct rusage_info_v4 ri;
+	if (proc_pid_rusage(getpid(), RUSAGE_INFO_V4, (rusage_info_t *)&ri) != 0) {
+		return -1;
+	}
+	*wakeups = ri.ri_interrupt_wkups + ri.ri_pkg_idle_wkups;
+	*energy = ri.ri_billed_energy;
+	return 0;
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"syscall"
+	"time"
+)
+
+// Self returns the usage of the current process.
+func Self() (Usage, error) {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return Usage{}, err
+	}
+	u := Usage{CPU: time.Duration(ru.Utime.Nano() + ru.Stime.Nano())}
+
+	var wakeups, energy C.uint64_t
+	if C.batt_selfUsage(&wakeups, &energy) != 0 {
+		return u, errors.New("proc_pid_rusage failed")
+	}
+	u.Wakeups, u.Energy = uint64(wakeups), uint64(energy)
+	return u, nil
+}
@@ -0,0 +1,10 @@
+//go:build !darwin || !cgo
+
+package procusage
+
+import "errors"
+
+// Self is only implemented on macOS.
+func Self() (Usage, error) {
+	return Usage{}, errors.New("not supported on this platform")
+}
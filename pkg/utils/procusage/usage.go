@@ -0,0 +1,14 @@
+// Package procusage reads what the current process costs the system.
+package procusage
+
+import "time"
+
+// Usage is what the current process used since it started.
+type Usage struct {
+	CPU time.Duration
+	// Wakeups are how often the process woke a CPU, from idle or by an
+	// interrupt, as counted by the kernel.
+	Wakeups uint64
+	// Energy is the energy billed to the process, in nanojoules.
+	Energy uint64
+}